@@ -56,6 +56,8 @@ pub enum SendMessageType {
     Profile = 13,
     ProcEvents = 14,
     AlarmEvent = 15,
+    OpenTelemetryLog = 16,
+    OpenTelemetryMetrics = 17,
 }
 
 impl fmt::Display for SendMessageType {
@@ -77,6 +79,8 @@ impl fmt::Display for SendMessageType {
             Self::Profile => write!(f, "profile"),
             Self::ProcEvents => write!(f, "proc_events"),
             Self::AlarmEvent => write!(f, "alarm_event"),
+            Self::OpenTelemetryLog => write!(f, "open_telemetry_log"),
+            Self::OpenTelemetryMetrics => write!(f, "open_telemetry_metrics"),
         }
     }
 }
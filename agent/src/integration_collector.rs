@@ -127,6 +127,38 @@ impl Sendable for OpenTelemetryCompressed {
     }
 }
 
+// OTLP logs的protobuf数据，由ingester按照 https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/logs/v1/logs.proto 解析
+#[derive(Debug, PartialEq)]
+pub struct OpenTelemetryLog(Vec<u8>);
+
+impl Sendable for OpenTelemetryLog {
+    fn encode(mut self, buf: &mut Vec<u8>) -> Result<usize, prost::EncodeError> {
+        let length = self.0.len();
+        buf.append(&mut self.0);
+        Ok(length)
+    }
+
+    fn message_type(&self) -> SendMessageType {
+        SendMessageType::OpenTelemetryLog
+    }
+}
+
+// OTLP metrics的protobuf数据，由ingester按照 https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/metrics/v1/metrics.proto 解析
+#[derive(Debug, PartialEq)]
+pub struct OpenTelemetryMetrics(Vec<u8>);
+
+impl Sendable for OpenTelemetryMetrics {
+    fn encode(mut self, buf: &mut Vec<u8>) -> Result<usize, prost::EncodeError> {
+        let length = self.0.len();
+        buf.append(&mut self.0);
+        Ok(length)
+    }
+
+    fn message_type(&self) -> SendMessageType {
+        SendMessageType::OpenTelemetryMetrics
+    }
+}
+
 /// Prometheus metrics, in snappy compressed petabytes of data
 /// You can refer to https://github.com/prometheus/prometheus/tree/main/documentation/examples/remote_storage/example_write_adapter to parse
 pub struct PrometheusExtra {
@@ -591,6 +623,8 @@ async fn handler(
     otel_sender: DebugSender<OpenTelemetry>,
     compressed_otel_sender: DebugSender<OpenTelemetryCompressed>,
     otel_l7_stats_sender: DebugSender<BatchedBox<L7Stats>>,
+    otel_log_sender: DebugSender<OpenTelemetryLog>,
+    otel_metrics_sender: DebugSender<OpenTelemetryMetrics>,
     prometheus_sender: DebugSender<BoxedPrometheusExtra>,
     telegraf_sender: DebugSender<TelegrafMetric>,
     profile_sender: DebugSender<Profile>,
@@ -670,6 +704,45 @@ async fn handler(
 
             Ok(Response::builder().body(Body::empty()).unwrap())
         }
+        // OpenTelemetry log integration, the ExportLogsServiceRequest body is forwarded as LogsData
+        // OTel logs are integrated together with OTel traces, so they share the switch
+        (&Method::POST, "/api/v1/otel/log") => {
+            if external_trace_integration_disabled {
+                return Ok(Response::builder().body(Body::empty()).unwrap());
+            }
+            let (part, body) = req.into_parts();
+            let whole_body = match aggregate_with_catch_exception(body, &exception_handler).await {
+                Ok(b) => b,
+                Err(e) => {
+                    return Ok(e);
+                }
+            };
+            let logs_data = decode_metric(whole_body, &part.headers)?;
+            if let Err(Error::Terminated(..)) = otel_log_sender.send(OpenTelemetryLog(logs_data)) {
+                warn!("sender queue has terminated");
+            }
+            Ok(Response::builder().body(Body::empty()).unwrap())
+        }
+        // OpenTelemetry metrics integration, the ExportMetricsServiceRequest body is forwarded as MetricsData
+        (&Method::POST, "/api/v1/otel/metric") => {
+            if external_metric_integration_disabled {
+                return Ok(Response::builder().body(Body::empty()).unwrap());
+            }
+            let (part, body) = req.into_parts();
+            let whole_body = match aggregate_with_catch_exception(body, &exception_handler).await {
+                Ok(b) => b,
+                Err(e) => {
+                    return Ok(e);
+                }
+            };
+            let metrics_data = decode_metric(whole_body, &part.headers)?;
+            if let Err(Error::Terminated(..)) =
+                otel_metrics_sender.send(OpenTelemetryMetrics(metrics_data))
+            {
+                warn!("sender queue has terminated");
+            }
+            Ok(Response::builder().body(Body::empty()).unwrap())
+        }
         // Prometheus integration
         (&Method::POST, "/api/v1/prometheus") => {
             if external_metric_integration_disabled {
@@ -879,6 +952,8 @@ pub struct MetricServer {
     otel_sender: DebugSender<OpenTelemetry>,
     compressed_otel_sender: DebugSender<OpenTelemetryCompressed>,
     otel_l7_stats_sender: DebugSender<BatchedBox<L7Stats>>,
+    otel_log_sender: DebugSender<OpenTelemetryLog>,
+    otel_metrics_sender: DebugSender<OpenTelemetryMetrics>,
     prometheus_sender: DebugSender<BoxedPrometheusExtra>,
     telegraf_sender: DebugSender<TelegrafMetric>,
     profile_sender: DebugSender<Profile>,
@@ -903,6 +978,8 @@ impl MetricServer {
         otel_sender: DebugSender<OpenTelemetry>,
         compressed_otel_sender: DebugSender<OpenTelemetryCompressed>,
         otel_l7_stats_sender: DebugSender<BatchedBox<L7Stats>>,
+        otel_log_sender: DebugSender<OpenTelemetryLog>,
+        otel_metrics_sender: DebugSender<OpenTelemetryMetrics>,
         prometheus_sender: DebugSender<BoxedPrometheusExtra>,
         telegraf_sender: DebugSender<TelegrafMetric>,
         profile_sender: DebugSender<Profile>,
@@ -940,6 +1017,8 @@ impl MetricServer {
                 prometheus_extra_config: Arc::new(prometheus_extra_config),
                 log_parser_config: Arc::new(log_parser_config),
                 otel_l7_stats_sender,
+                otel_log_sender,
+                otel_metrics_sender,
                 external_profile_integration_disabled,
                 external_trace_integration_disabled,
                 external_metric_integration_disabled,
@@ -970,6 +1049,8 @@ impl MetricServer {
         let otel_sender = self.otel_sender.clone();
         let compressed_otel_sender = self.compressed_otel_sender.clone();
         let otel_l7_stats_sender = self.otel_l7_stats_sender.clone();
+        let otel_log_sender = self.otel_log_sender.clone();
+        let otel_metrics_sender = self.otel_metrics_sender.clone();
         let prometheus_sender = self.prometheus_sender.clone();
         let telegraf_sender = self.telegraf_sender.clone();
         let profile_sender = self.profile_sender.clone();
@@ -1039,6 +1120,8 @@ impl MetricServer {
                     let otel_sender = otel_sender.clone();
                     let compressed_otel_sender = compressed_otel_sender.clone();
                     let otel_l7_stats_sender = otel_l7_stats_sender.clone();
+                    let otel_log_sender = otel_log_sender.clone();
+                    let otel_metrics_sender = otel_metrics_sender.clone();
                     let prometheus_sender = prometheus_sender.clone();
                     let telegraf_sender = telegraf_sender.clone();
                     let profile_sender = profile_sender.clone();
@@ -1054,6 +1137,8 @@ impl MetricServer {
                         let otel_sender = otel_sender.clone();
                         let compressed_otel_sender = compressed_otel_sender.clone();
                         let otel_l7_stats_sender = otel_l7_stats_sender.clone();
+                        let otel_log_sender = otel_log_sender.clone();
+                        let otel_metrics_sender = otel_metrics_sender.clone();
                         let prometheus_sender = prometheus_sender.clone();
                         let telegraf_sender = telegraf_sender.clone();
                         let profile_sender = profile_sender.clone();
@@ -1075,6 +1160,8 @@ impl MetricServer {
                                     otel_sender.clone(),
                                     compressed_otel_sender.clone(),
                                     otel_l7_stats_sender.clone(),
+                                    otel_log_sender.clone(),
+                                    otel_metrics_sender.clone(),
                                     prometheus_sender.clone(),
                                     telegraf_sender.clone(),
                                     profile_sender.clone(),
@@ -75,8 +75,8 @@ use crate::{
     },
     handler::{NpbBuilder, PacketHandlerBuilder},
     integration_collector::{
-        BoxedPrometheusExtra, MetricServer, OpenTelemetry, OpenTelemetryCompressed, Profile,
-        TelegrafMetric,
+        BoxedPrometheusExtra, MetricServer, OpenTelemetry, OpenTelemetryCompressed,
+        OpenTelemetryLog, OpenTelemetryMetrics, Profile, TelegrafMetric,
     },
     metric::document::BoxedDocument,
     monitor::Monitor,
@@ -1230,6 +1230,8 @@ pub struct AgentComponents {
     pub otel_uniform_sender: UniformSenderThread<OpenTelemetry>,
     pub prometheus_uniform_sender: UniformSenderThread<BoxedPrometheusExtra>,
     pub telegraf_uniform_sender: UniformSenderThread<TelegrafMetric>,
+    pub otel_log_uniform_sender: UniformSenderThread<OpenTelemetryLog>,
+    pub otel_metrics_uniform_sender: UniformSenderThread<OpenTelemetryMetrics>,
     pub profile_uniform_sender: UniformSenderThread<Profile>,
     pub packet_sequence_parsers: Vec<PacketSequenceParser>, // Enterprise Edition Feature: packet-sequence
     pub packet_sequence_uniform_sender: UniformSenderThread<BoxedPacketSequenceBlock>, // Enterprise Edition Feature: packet-sequence
@@ -2413,6 +2415,49 @@ impl AgentComponents {
             true,
         );
 
+        let otel_log_queue_name = "1-otel-log-to-sender";
+        let (otel_log_sender, otel_log_receiver, counter) = queue::bounded_with_debug(
+            yaml_config.external_metrics_sender_queue_size,
+            otel_log_queue_name,
+            &queue_debugger,
+        );
+        stats_collector.register_countable(
+            "queue",
+            Countable::Owned(Box::new(counter)),
+            vec![StatsOption::Tag("module", otel_log_queue_name.to_string())],
+        );
+        let otel_log_uniform_sender = UniformSenderThread::new(
+            otel_log_queue_name,
+            Arc::new(otel_log_receiver),
+            config_handler.sender(),
+            stats_collector.clone(),
+            exception_handler.clone(),
+            true,
+        );
+
+        let otel_metrics_queue_name = "1-otel-metrics-to-sender";
+        let (otel_metrics_sender, otel_metrics_receiver, counter) = queue::bounded_with_debug(
+            yaml_config.external_metrics_sender_queue_size,
+            otel_metrics_queue_name,
+            &queue_debugger,
+        );
+        stats_collector.register_countable(
+            "queue",
+            Countable::Owned(Box::new(counter)),
+            vec![StatsOption::Tag(
+                "module",
+                otel_metrics_queue_name.to_string(),
+            )],
+        );
+        let otel_metrics_uniform_sender = UniformSenderThread::new(
+            otel_metrics_queue_name,
+            Arc::new(otel_metrics_receiver),
+            config_handler.sender(),
+            stats_collector.clone(),
+            exception_handler.clone(),
+            true,
+        );
+
         let compressed_otel_queue_name = "1-compressed-otel-to-sender";
         let (compressed_otel_sender, compressed_otel_receiver, counter) = queue::bounded_with_debug(
             yaml_config.external_metrics_sender_queue_size,
@@ -2441,6 +2486,8 @@ impl AgentComponents {
             otel_sender,
             compressed_otel_sender,
             l7_stats_sender,
+            otel_log_sender,
+            otel_metrics_sender,
             prometheus_sender,
             telegraf_sender,
             profile_sender,
@@ -2516,6 +2563,8 @@ impl AgentComponents {
             otel_uniform_sender,
             prometheus_uniform_sender,
             telegraf_uniform_sender,
+            otel_log_uniform_sender,
+            otel_metrics_uniform_sender,
             profile_uniform_sender,
             proc_event_uniform_sender,
             tap_mode: candidate_config.tap_mode,
@@ -2606,6 +2655,8 @@ impl AgentComponents {
             self.compressed_otel_uniform_sender.start();
             self.prometheus_uniform_sender.start();
             self.telegraf_uniform_sender.start();
+            self.otel_log_uniform_sender.start();
+            self.otel_metrics_uniform_sender.start();
             self.profile_uniform_sender.start();
             self.proc_event_uniform_sender.start();
             if self.config.metric_server.enabled {
@@ -2688,6 +2739,12 @@ impl AgentComponents {
         if let Some(h) = self.telegraf_uniform_sender.notify_stop() {
             join_handles.push(h);
         }
+        if let Some(h) = self.otel_log_uniform_sender.notify_stop() {
+            join_handles.push(h);
+        }
+        if let Some(h) = self.otel_metrics_uniform_sender.notify_stop() {
+            join_handles.push(h);
+        }
         if let Some(h) = self.profile_uniform_sender.notify_stop() {
             join_handles.push(h);
         }
@@ -61,6 +61,7 @@ const (
 	DOMAIN_TYPE_ESHORE            DomainType = 26 // eshore
 	DOMAIN_TYPE_CLOUD_TOWER       DomainType = 27 // cloudtower
 	DOMAIN_TYPE_NFVO              DomainType = 28 // nfvo
+	DOMAIN_TYPE_CMDB              DomainType = 29 // cmdb
 )
 
 var DomainTypes []DomainType = []DomainType{
@@ -85,6 +86,7 @@ var DomainTypes []DomainType = []DomainType{
 	DOMAIN_TYPE_ESHORE,
 	DOMAIN_TYPE_CLOUD_TOWER,
 	DOMAIN_TYPE_NFVO,
+	DOMAIN_TYPE_CMDB,
 }
 
 func GetDomainTypeByName(domainTypeName string) DomainType {
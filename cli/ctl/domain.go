@@ -23,6 +23,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/bitly/go-simplejson"
 	"github.com/spf13/cobra"
 	"sigs.k8s.io/yaml"
 
@@ -36,7 +37,7 @@ func RegisterDomainCommand() *cobra.Command {
 		Use:   "domain",
 		Short: "domain operation commands",
 		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Printf("please run with 'list | create | update | delete | example | additional-resource'.\n")
+			fmt.Printf("please run with 'list | create | update | delete | dry-run | example | additional-resource'.\n")
 		},
 	}
 
@@ -84,11 +85,22 @@ func RegisterDomainCommand() *cobra.Command {
 		},
 	}
 
+	var dryRunOutput string
+	dryRun := &cobra.Command{
+		Use:     "dry-run [name]",
+		Short:   "fetch cloud data and preview resources to be added, updated or deleted by next sync, without writing",
+		Example: "deepflow-ctl domain dry-run deepflow-domain\ndeepflow-ctl domain dry-run deepflow-domain -o yaml",
+		Run: func(cmd *cobra.Command, args []string) {
+			dryRunDomain(cmd, args, dryRunOutput)
+		},
+	}
+	dryRun.Flags().StringVarP(&dryRunOutput, "output", "o", "", "output format, yaml shows details of each resource")
+
 	exampleCmd := &cobra.Command{
 		Use:     "example domain_type",
 		Short:   "example domain create yaml",
 		Long:    "supported types: " + strings.Trim(fmt.Sprint(common.DomainTypes), "[]"),
-		Example: "deepflow-ctl domain example agent_sync \nsupport example type: aliyun | aws | baidu_bce | filereader | agent_sync | \nhuawei | kubernetes | qingcloud | tencent ",
+		Example: "deepflow-ctl domain example agent_sync \nsupport example type: aliyun | aws | baidu_bce | cmdb | filereader | agent_sync | \nhuawei | kubernetes | qingcloud | tencent ",
 		Run: func(cmd *cobra.Command, args []string) {
 			exampleDomainConfig(cmd, args)
 		},
@@ -98,6 +110,7 @@ func RegisterDomainCommand() *cobra.Command {
 	Domain.AddCommand(create)
 	Domain.AddCommand(update)
 	Domain.AddCommand(delete)
+	Domain.AddCommand(dryRun)
 	Domain.AddCommand(exampleCmd)
 	Domain.AddCommand(RegisterDomainAdditionalResourceCommand())
 	return Domain
@@ -286,6 +299,112 @@ func deleteDomain(cmd *cobra.Command, args []string) {
 	}
 }
 
+func dryRunDomain(cmd *cobra.Command, args []string, output string) {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "must specify name.\nExample: %s\n", cmd.Example)
+		return
+	}
+
+	server := common.GetServerInfo(cmd)
+	url := fmt.Sprintf("http://%s:%d/v2/domains/?name=%s", server.IP, server.Port, args[0])
+	response, err := common.CURLPerform("GET", url, nil, "", []common.HTTPOption{common.WithTimeout(common.GetTimeout(cmd))}...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	if len(response.Get("DATA").MustArray()) == 0 {
+		fmt.Fprintf(os.Stderr, "domain name: %s not found\n", args[0])
+		return
+	}
+	domain := response.Get("DATA").GetIndex(0)
+	lcuuid := domain.Get("LCUUID").MustString()
+
+	// 预演需在负责该云平台同步的控制器上执行
+	podIP, err := getControllerPodIP(cmd, server, domain.Get("CONTROLLER_IP").MustString())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	url = fmt.Sprintf("http://%s:%d/v1/dry-run/%s/", podIP, server.SvcPort, lcuuid)
+	response, err = common.CURLPerform("GET", url, nil, "", []common.HTTPOption{common.WithTimeout(common.GetTimeout(cmd))}...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	data := response.Get("DATA")
+
+	if output == "yaml" {
+		jData, _ := data.MarshalJSON()
+		yData, _ := yaml.JSONToYAML(jData)
+		fmt.Printf(string(yData))
+		return
+	}
+
+	if skipReason := data.Get("SkipReason").MustString(); skipReason != "" {
+		fmt.Printf("sync will be skipped: %s\n", skipReason)
+		if errMsg := data.Get("ErrorMessage").MustString(); errMsg != "" {
+			fmt.Printf("error message: %s\n", errMsg)
+		}
+		return
+	}
+	if data.Get("Blocked").MustBool() {
+		fmt.Println("sync will be blocked by delete protection:")
+		for _, reason := range data.Get("BlockedReasons").MustStringArray() {
+			fmt.Printf("  %s\n", reason)
+		}
+	}
+	printDryRunResources(data.Get("Resources"))
+	for subDomainLcuuid := range data.Get("SubDomainResources").MustMap() {
+		fmt.Printf("\nsub_domain: %s\n", subDomainLcuuid)
+		printDryRunResources(data.Get("SubDomainResources").Get(subDomainLcuuid))
+	}
+}
+
+func getControllerPodIP(cmd *cobra.Command, server *common.Server, controllerIP string) (string, error) {
+	url := fmt.Sprintf("http://%s:%d/v1/controllers/", server.IP, server.Port)
+	response, err := common.CURLPerform("GET", url, nil, "", []common.HTTPOption{common.WithTimeout(common.GetTimeout(cmd))}...)
+	if err != nil {
+		return "", err
+	}
+	for i := range response.Get("DATA").MustArray() {
+		controller := response.Get("DATA").GetIndex(i)
+		if controller.Get("IP").MustString() == controllerIP {
+			return controller.Get("POD_IP").MustString(), nil
+		}
+	}
+	return "", fmt.Errorf("controller (ip: %s) not found", controllerIP)
+}
+
+func printDryRunResources(resources *simplejson.Json) {
+	t := table.New()
+	t.SetHeader([]string{"RESOURCE_TYPE", "EXISTING", "TO_ADD", "TO_UPDATE", "TO_DELETE", "DELETE_PERCENT"})
+	tableItems := [][]string{}
+	for i := range resources.MustArray() {
+		r := resources.GetIndex(i)
+		existingCount := r.Get("ExistingCount").MustInt()
+		addCount := r.Get("AddCount").MustInt()
+		updateCount := r.Get("UpdateCount").MustInt()
+		deleteCount := r.Get("DeleteCount").MustInt()
+		if existingCount == 0 && addCount == 0 {
+			continue
+		}
+		var deletePercent float64
+		if existingCount != 0 {
+			deletePercent = float64(deleteCount) * 100 / float64(existingCount)
+		}
+		tableItems = append(tableItems, []string{
+			r.Get("ResourceType").MustString(),
+			fmt.Sprintf("%d", existingCount),
+			fmt.Sprintf("%d", addCount),
+			fmt.Sprintf("%d", updateCount),
+			fmt.Sprintf("%d", deleteCount),
+			fmt.Sprintf("%.2f%%", deletePercent),
+		})
+	}
+	t.AppendBulk(tableItems)
+	t.Render()
+}
+
 func exampleDomainConfig(cmd *cobra.Command, args []string) {
 	if len(args) == 0 {
 		fmt.Fprintf(os.Stderr, "must specify domain_type.\nExample: %s\n%s\n", cmd.Example, cmd.Long)
@@ -311,6 +430,8 @@ func exampleDomainConfig(cmd *cobra.Command, args []string) {
 		fmt.Printf(string(example.YamlDomainGenesis))
 	case common.DOMAIN_TYPE_FILEREADER:
 		fmt.Printf(string(example.YamlDomainFileReader))
+	case common.DOMAIN_TYPE_CMDB:
+		fmt.Printf(string(example.YamlDomainCMDB))
 	default:
 		err := fmt.Sprintf("domain_type %s not supported\n", args[0])
 		fmt.Fprintln(os.Stderr, err)
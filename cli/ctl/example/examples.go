@@ -27,6 +27,9 @@ var YamlDomainAws []byte
 //go:embed domain_baidubce.yaml
 var YamlDomainBaiduBce []byte
 
+//go:embed domain_cmdb.yaml
+var YamlDomainCMDB []byte
+
 //go:embed domain_filereader.yaml
 var YamlDomainFileReader []byte
 
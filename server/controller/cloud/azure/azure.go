@@ -0,0 +1,318 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bitly/go-simplejson"
+	"github.com/op/go-logging"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/config"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/statsd"
+)
+
+var log = logging.MustGetLogger("cloud.azure")
+
+// ARM各资源提供程序的API版本
+const (
+	API_VERSION_SUBSCRIPTION      = "2022-12-01"
+	API_VERSION_RESOURCE_GROUP    = "2021-04-01"
+	API_VERSION_NETWORK           = "2023-05-01"
+	API_VERSION_COMPUTE           = "2023-03-01"
+	API_VERSION_CONTAINER_SERVICE = "2023-05-01"
+)
+
+type Azure struct {
+	lcuuid         string
+	lcuuidGenerate string
+	name           string
+	httpTimeout    int
+	config         *Config
+	token          *Token
+	toolDataSet    *ToolDataSet       // 处理资源数据时，构建的需要提供给其他资源使用的工具数据
+	cloudStatsd    statsd.CloudStatsd // 性能监控
+	debugger       *cloudcommon.Debugger
+}
+
+func NewAzure(domain mysql.Domain, globalCloudCfg config.CloudConfig) (*Azure, error) {
+	conf := &Config{}
+	err := conf.LoadFromString(domain.Config)
+	if err != nil {
+		return nil, err
+	}
+	return newAzure(domain, globalCloudCfg, conf), nil
+}
+
+func newAzure(domain mysql.Domain, globalCloudCfg config.CloudConfig, conf *Config) *Azure {
+	return &Azure{
+		lcuuid: domain.Lcuuid,
+		// TODO: display_name后期需要修改为uuid_generate
+		lcuuidGenerate: domain.DisplayName,
+		name:           domain.Name,
+		httpTimeout:    globalCloudCfg.HTTPTimeout,
+		config:         conf,
+		debugger:       cloudcommon.NewDebugger(domain.Name),
+	}
+}
+
+func (a *Azure) ClearDebugLog() {
+	a.debugger.Clear()
+}
+
+// CheckAuth 校验service principal能否申请token，且有权限访问配置的订阅
+func (a *Azure) CheckAuth() error {
+	token, err := a.createToken()
+	if err != nil {
+		return err
+	}
+	_, err = a.request(http.MethodGet, a.getURL("", API_VERSION_SUBSCRIPTION, nil), token.token, nil)
+	return err
+}
+
+func (a *Azure) GetCloudData() (model.Resource, error) {
+	a.cloudStatsd = statsd.NewCloudStatsd()
+	a.toolDataSet = NewToolDataSet()
+	var resource model.Resource
+
+	regions, err := a.getRegions()
+	if err != nil {
+		return resource, err
+	}
+	err = a.getResourceGroups()
+	if err != nil {
+		return resource, err
+	}
+
+	vpcs, networks, subnets, err := a.getVPCs()
+	if err != nil {
+		return resource, err
+	}
+	resource.VPCs = vpcs
+	resource.Networks = networks
+	resource.Subnets = subnets
+
+	err = a.getPublicIPs()
+	if err != nil {
+		return resource, err
+	}
+
+	sgs, sgRules, err := a.getSecurityGroups()
+	if err != nil {
+		return resource, err
+	}
+	resource.SecurityGroups = sgs
+	resource.SecurityGroupRules = sgRules
+
+	vifs, ips, fIPs, vmSGs, err := a.getVInterfaces()
+	if err != nil {
+		return resource, err
+	}
+	resource.VInterfaces = append(resource.VInterfaces, vifs...)
+	resource.IPs = append(resource.IPs, ips...)
+	resource.FloatingIPs = fIPs
+	resource.VMSecurityGroups = vmSGs
+
+	vms, err := a.getVMs()
+	if err != nil {
+		return resource, err
+	}
+	resource.VMs = vms
+
+	natGateways, natVIFs, natIPs, err := a.getNATGateways()
+	if err != nil {
+		return resource, err
+	}
+	resource.NATGateways = natGateways
+	resource.VInterfaces = append(resource.VInterfaces, natVIFs...)
+	resource.IPs = append(resource.IPs, natIPs...)
+
+	lbs, lbListeners, lbTargetServers, lbVIFs, lbIPs, err := a.getLBs()
+	if err != nil {
+		return resource, err
+	}
+	resource.LBs = lbs
+	resource.LBListeners = lbListeners
+	resource.LBTargetServers = lbTargetServers
+	resource.VInterfaces = append(resource.VInterfaces, lbVIFs...)
+	resource.IPs = append(resource.IPs, lbIPs...)
+
+	// 附属容器集群
+	subDomains, err := a.getSubDomains()
+	if err != nil {
+		return resource, err
+	}
+	resource.SubDomains = subDomains
+
+	log.Debugf("region resource num info: %v", a.toolDataSet.regionLcuuidToResourceNum)
+	log.Debugf("az resource num info: %v", a.toolDataSet.azLcuuidToResourceNum)
+	resource.Regions = cloudcommon.EliminateEmptyRegions(regions, a.toolDataSet.regionLcuuidToResourceNum)
+	resource.AZs = cloudcommon.EliminateEmptyAZs(a.toolDataSet.azs, a.toolDataSet.azLcuuidToResourceNum)
+
+	a.cloudStatsd.ResCount = statsd.GetResCount(resource)
+	statsd.MetaStatsd.RegisterStatsdTable(a)
+
+	a.debugger.Refresh()
+	return resource, nil
+}
+
+func (a *Azure) GetStatter() statsd.StatsdStatter {
+	globalTags := map[string]string{
+		"domain_name": a.name,
+		"domain":      a.lcuuid,
+		"platform":    common.AZURE_EN,
+	}
+
+	return statsd.StatsdStatter{
+		GlobalTags: globalTags,
+		Element:    statsd.GetCloudStatsd(a.cloudStatsd),
+	}
+}
+
+// getURL 返回订阅下path对应的ARM地址
+func (a *Azure) getURL(path, apiVersion string, params url.Values) string {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("api-version", apiVersion)
+	return fmt.Sprintf("%s/subscriptions/%s%s?%s", a.config.ManagementURL, url.PathEscape(a.config.SubscriptionID), path, params.Encode())
+}
+
+// getRawData 获取ARM列表接口的value，并按照响应中的nextLink翻页
+func (a *Azure) getRawData(resourceType, requestURL string) (jsonList []*simplejson.Json, err error) {
+	token, err := a.getToken()
+	if err != nil {
+		return nil, err
+	}
+	statsdAPIStartTime := time.Now()
+	nextURL := requestURL
+	for nextURL != "" {
+		resp, err := a.request(http.MethodGet, nextURL, token.token, nil)
+		if err != nil {
+			return []*simplejson.Json{}, err
+		}
+		jData := resp.Get("value")
+		for i := range jData.MustArray() {
+			jsonList = append(jsonList, jData.GetIndex(i))
+		}
+		link := resp.Get("nextLink").MustString()
+		if link == nextURL {
+			break
+		}
+		nextURL = link
+	}
+	a.cloudStatsd.RefreshAPIMoniter(resourceType, len(jsonList), statsdAPIStartTime)
+
+	a.debugger.WriteJson(resourceType, requestURL, jsonList)
+	return
+}
+
+// getResourceGroupRawData 逐个资源组获取provider下的资源，仅有资源组权限的service principal也可以同步
+func (a *Azure) getResourceGroupRawData(provider, apiVersion string, params url.Values) ([]*simplejson.Json, error) {
+	var jsonList []*simplejson.Json
+	for _, rg := range a.toolDataSet.resourceGroups {
+		path := fmt.Sprintf("/resourceGroups/%s/providers/%s", url.PathEscape(rg), provider)
+		jResources, err := a.getRawData(provider, a.getURL(path, apiVersion, params))
+		if err != nil {
+			return nil, err
+		}
+		for _, jr := range jResources {
+			// 只处理同步区域中的资源
+			if _, ok := a.toolDataSet.locationToRegionLcuuid[jr.Get("location").MustString()]; !ok {
+				continue
+			}
+			jsonList = append(jsonList, jr)
+		}
+	}
+	return jsonList, nil
+}
+
+func (a *Azure) request(method, requestURL, token string, body io.Reader) (*simplejson.Json, error) {
+	log.Debugf("%s url: %s", method, requestURL)
+	req, err := http.NewRequest(method, requestURL, body)
+	if err != nil {
+		log.Errorf("new request (%s) failed: %s", requestURL, err.Error())
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	client := cloudcommon.GetUnverifyHTTPClient(time.Second * time.Duration(a.httpTimeout))
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Errorf("request (%s) failed: %s", requestURL, err.Error())
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Errorf("read response (%s) failed: %s", requestURL, err.Error())
+		return nil, err
+	}
+	jResp, jErr := simplejson.NewJson(respBody)
+	if resp.StatusCode != http.StatusOK {
+		// ARM及AAD的错误信息分别位于error.message和error_description中
+		msg := resp.Status
+		if jErr == nil {
+			if m := jResp.GetPath("error", "message").MustString(); m != "" {
+				msg = m
+			} else if m := jResp.Get("error_description").MustString(); m != "" {
+				msg = m
+			}
+		}
+		err = errors.New(fmt.Sprintf("request (%s) failed: %s", requestURL, msg))
+		log.Error(err.Error())
+		return nil, err
+	}
+	if jErr != nil {
+		log.Errorf("convert response (%s) to json failed: %s", requestURL, jErr.Error())
+		return nil, jErr
+	}
+	return jResp, nil
+}
+
+// ARM资源ID不区分大小写，不同资源中引用的同一ID大小写可能不一致
+func (a *Azure) getLcuuid(id string) string {
+	return common.GenerateUUID(strings.ToLower(id))
+}
+
+// getResourceGroupName 从资源ID中解析资源组名称
+func getResourceGroupName(id string) string {
+	parts := strings.Split(id, "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if strings.EqualFold(parts[i], "resourceGroups") {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	cloudtest "github.com/deepflowio/deepflow/server/controller/cloud/common/test"
+	cloudconfig "github.com/deepflowio/deepflow/server/controller/cloud/config"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/statsd"
+	statsdconfig "github.com/deepflowio/deepflow/server/controller/statsd/config"
+)
+
+const TEST_TOKEN = "test-token"
+
+// newAzureServer 返回按请求URI回放testfiles中记录的ARM响应的服务
+func newAzureServer(t *testing.T) *httptest.Server {
+	return cloudtest.NewReplayServer(t, "./testfiles/azure-api.json", func(w http.ResponseWriter, r *http.Request) bool {
+		if strings.HasSuffix(r.URL.Path, "/oauth2/v2.0/token") {
+			if r.Method != "POST" || r.FormValue("client_secret") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":"invalid_client","error_description":"Invalid client secret provided."}`))
+				return false
+			}
+			return true
+		}
+		if r.Header.Get("Authorization") != "Bearer "+TEST_TOKEN {
+			w.WriteHeader(http.StatusUnauthorized)
+			return false
+		}
+		return true
+	})
+}
+
+func newTestAzure(serverURL, secret string) *Azure {
+	domain := mysql.Domain{Name: "test_azure", DisplayName: "test_azure"}
+	conf := &Config{
+		TenantID:       "tenant-1",
+		ClientID:       "client-1",
+		ClientSecret:   secret,
+		SubscriptionID: "sub-1",
+		LoginURL:       serverURL,
+		ManagementURL:  serverURL,
+	}
+	return newAzure(domain, cloudconfig.CloudConfig{HTTPTimeout: 30}, conf)
+}
+
+func TestAzure(t *testing.T) {
+	Convey("TestAzure", t, func() {
+		cloudconfig.SetCloudGlobalConfig(cloudconfig.CloudConfig{HTTPTimeout: 30})
+		statsd.NewStatsdMonitor(statsdconfig.StatsdConfig{})
+		server := newAzureServer(t)
+		defer server.Close()
+
+		Convey("service principal with a wrong secret should fail to auth", func() {
+			err := newTestAzure(server.URL, "wrong").CheckAuth()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "Invalid client secret provided.")
+		})
+
+		azure := newTestAzure(server.URL, "secret")
+		So(azure.CheckAuth(), ShouldBeNil)
+
+		data, err := azure.GetCloudData()
+		So(err, ShouldBeNil)
+
+		Convey("only regions with resources should be synced", func() {
+			So(len(data.Regions), ShouldEqual, 1)
+			So(data.Regions[0].Label, ShouldEqual, "eastus")
+			So(data.Regions[0].Name, ShouldEqual, "East US")
+			azLabels := []string{}
+			for _, az := range data.AZs {
+				azLabels = append(azLabels, az.Label)
+			}
+			So(azLabels, ShouldResemble, []string{"eastus", "eastus-1"})
+		})
+
+		Convey("each virtual network should be a vpc and each subnet a network", func() {
+			So(len(data.VPCs), ShouldEqual, 1)
+			So(data.VPCs[0].CIDR, ShouldEqual, "10.1.0.0/16")
+			So(len(data.Networks), ShouldEqual, 2)
+			So(len(data.Subnets), ShouldEqual, 2)
+			So(data.Subnets[1].CIDR, ShouldEqual, "10.1.16.0/20")
+		})
+
+		Convey("vms should be synced with nic ip and power state", func() {
+			So(len(data.VMs), ShouldEqual, 1)
+			vm := data.VMs[0]
+			So(vm.Name, ShouldEqual, "vm1")
+			So(vm.IP, ShouldEqual, "10.1.0.4")
+			So(vm.State, ShouldEqual, common.VM_STATE_RUNNING)
+			So(vm.VPCLcuuid, ShouldEqual, data.VPCs[0].Lcuuid)
+			So(vm.AZLcuuid, ShouldEqual, data.AZs[1].Lcuuid)
+			So(vm.CloudTags, ShouldResemble, map[string]string{"env": "test"})
+		})
+
+		Convey("only nics attached to vms should be synced", func() {
+			deviceTypes := map[int]int{}
+			for _, vif := range data.VInterfaces {
+				deviceTypes[vif.DeviceType]++
+			}
+			So(deviceTypes, ShouldResemble, map[int]int{
+				common.VIF_DEVICE_TYPE_VM:          2,
+				common.VIF_DEVICE_TYPE_NAT_GATEWAY: 1,
+				common.VIF_DEVICE_TYPE_LB:          1,
+			})
+			So(data.VInterfaces[0].Mac, ShouldEqual, "00:0d:3a:12:34:56")
+			So(len(data.IPs), ShouldEqual, 4)
+			So(data.FloatingIPs, ShouldResemble, []model.FloatingIP{{
+				Lcuuid:        common.GenerateUUID(data.VInterfaces[0].Lcuuid + "20.1.1.1"),
+				IP:            "20.1.1.1",
+				VMLcuuid:      data.VMs[0].Lcuuid,
+				NetworkLcuuid: common.NETWORK_ISP_LCUUID,
+				VPCLcuuid:     data.VPCs[0].Lcuuid,
+				RegionLcuuid:  data.Regions[0].Lcuuid,
+			}})
+		})
+
+		Convey("nsgs of nics and subnets should be synced", func() {
+			So(len(data.SecurityGroups), ShouldEqual, 1)
+			So(len(data.SecurityGroupRules), ShouldEqual, 2)
+			So(data.SecurityGroupRules[0].LocalPortRange, ShouldEqual, "22-22")
+			So(data.SecurityGroupRules[0].RemotePortRange, ShouldEqual, "0-65535")
+			So(data.SecurityGroupRules[1].Action, ShouldEqual, cloudcommon.SECURITY_GROUP_RULE_DROP)
+			So(len(data.VMSecurityGroups), ShouldEqual, 1)
+		})
+
+		Convey("nat gateways should be synced with public ips", func() {
+			So(len(data.NATGateways), ShouldEqual, 1)
+			So(data.NATGateways[0].FloatingIPs, ShouldEqual, "20.1.1.2")
+			So(data.NATGateways[0].VPCLcuuid, ShouldEqual, data.VPCs[0].Lcuuid)
+		})
+
+		Convey("load balancers with a public frontend should be external", func() {
+			So(len(data.LBs), ShouldEqual, 1)
+			So(data.LBs[0].VIP, ShouldEqual, "20.1.1.3")
+			So(data.LBs[0].Model, ShouldEqual, common.LB_MODEL_EXTERNAL)
+			So(len(data.LBListeners), ShouldEqual, 1)
+			So(data.LBListeners[0].Protocol, ShouldEqual, "TCP")
+			So(data.LBListeners[0].Port, ShouldEqual, 80)
+			So(len(data.LBTargetServers), ShouldEqual, 2)
+			So(data.LBTargetServers[0].Type, ShouldEqual, common.LB_SERVER_TYPE_VM)
+			So(data.LBTargetServers[0].VMLcuuid, ShouldEqual, data.VMs[0].Lcuuid)
+			So(data.LBTargetServers[0].Port, ShouldEqual, 8080)
+			So(data.LBTargetServers[1].Type, ShouldEqual, common.LB_SERVER_TYPE_IP)
+		})
+
+		Convey("aks clusters should be synced as sub domains", func() {
+			So(len(data.SubDomains), ShouldEqual, 1)
+			So(data.SubDomains[0].ClusterID, ShouldEqual, "aks1")
+			So(data.SubDomains[0].VpcUUID, ShouldEqual, data.VPCs[0].Lcuuid)
+		})
+	})
+}
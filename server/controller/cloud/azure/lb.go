@@ -0,0 +1,223 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"strings"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+type lbFrontend struct {
+	ip      string
+	network model.Network
+	public  bool
+}
+
+type lbBackend struct {
+	serverType int
+	ip         string
+	vmLcuuid   string
+	vpcLcuuid  string
+}
+
+func (a *Azure) getLBs() ([]model.LB, []model.LBListener, []model.LBTargetServer, []model.VInterface, []model.IP, error) {
+	var lbs []model.LB
+	var lbListeners []model.LBListener
+	var lbTargetServers []model.LBTargetServer
+	var vifs []model.VInterface
+	var ips []model.IP
+
+	jLBs, err := a.getResourceGroupRawData("Microsoft.Network/loadBalancers", API_VERSION_NETWORK, nil)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+	for i := range jLBs {
+		jLB := jLBs[i]
+		name := jLB.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jLB, []string{"id", "name", "location", "properties"}) {
+			log.Infof("exclude lb: %s, missing attr", name)
+			continue
+		}
+		jProps := jLB.Get("properties")
+
+		// 前端IP配置可以是子网内的私有IP，也可以是公网IP
+		var vpcLcuuid string
+		lbModel := common.LB_MODEL_INTERNAL
+		frontendIDs := []string{}
+		frontendIDToFrontend := make(map[string]lbFrontend)
+		jFrontends := jProps.Get("frontendIPConfigurations")
+		for j := range jFrontends.MustArray() {
+			jFrontend := jFrontends.GetIndex(j)
+			frontendID := strings.ToLower(jFrontend.Get("id").MustString())
+			jFrontendProps := jFrontend.Get("properties")
+			if publicIP, ok := a.toolDataSet.publicIPIDToIP[strings.ToLower(jFrontendProps.GetPath("publicIPAddress", "id").MustString())]; ok {
+				lbModel = common.LB_MODEL_EXTERNAL
+				frontendIDToFrontend[frontendID] = lbFrontend{ip: publicIP, public: true}
+				frontendIDs = append(frontendIDs, frontendID)
+				continue
+			}
+			network, ok := a.toolDataSet.subnetIDToNetwork[strings.ToLower(jFrontendProps.GetPath("subnet", "id").MustString())]
+			privateIP := jFrontendProps.Get("privateIPAddress").MustString()
+			if !ok || privateIP == "" {
+				log.Infof("exclude lb (%s) frontend: %s, missing ip info", name, jFrontend.Get("name").MustString())
+				continue
+			}
+			if vpcLcuuid == "" {
+				vpcLcuuid = network.VPCLcuuid
+			}
+			frontendIDToFrontend[frontendID] = lbFrontend{ip: privateIP, network: network}
+			frontendIDs = append(frontendIDs, frontendID)
+		}
+
+		// 后端池成员可以是虚拟机网卡的IP配置，也可以是直接指定的IP
+		poolIDToBackends := make(map[string][]lbBackend)
+		jPools := jProps.Get("backendAddressPools")
+		for j := range jPools.MustArray() {
+			jPool := jPools.GetIndex(j)
+			poolID := strings.ToLower(jPool.Get("id").MustString())
+			jIPConfigs := jPool.GetPath("properties", "backendIPConfigurations")
+			for k := range jIPConfigs.MustArray() {
+				ipConfig, ok := a.toolDataSet.ipConfigIDToIPConfig[strings.ToLower(jIPConfigs.GetIndex(k).Get("id").MustString())]
+				if !ok {
+					continue
+				}
+				poolIDToBackends[poolID] = append(poolIDToBackends[poolID], lbBackend{
+					serverType: common.LB_SERVER_TYPE_VM,
+					ip:         ipConfig.IP,
+					vmLcuuid:   ipConfig.VMLcuuid,
+					vpcLcuuid:  ipConfig.VPCLcuuid,
+				})
+				if vpcLcuuid == "" {
+					vpcLcuuid = ipConfig.VPCLcuuid
+				}
+			}
+			jAddresses := jPool.GetPath("properties", "loadBalancerBackendAddresses")
+			for k := range jAddresses.MustArray() {
+				ip := jAddresses.GetIndex(k).GetPath("properties", "ipAddress").MustString()
+				if ip == "" {
+					continue
+				}
+				poolIDToBackends[poolID] = append(poolIDToBackends[poolID], lbBackend{
+					serverType: common.LB_SERVER_TYPE_IP,
+					ip:         ip,
+				})
+			}
+		}
+		if vpcLcuuid == "" {
+			log.Infof("exclude lb: %s, missing vpc info", name)
+			continue
+		}
+
+		lbLcuuid := a.getLcuuid(jLB.Get("id").MustString())
+		regionLcuuid := a.toolDataSet.locationToRegionLcuuid[jLB.Get("location").MustString()]
+		var vips []string
+		for _, frontendID := range frontendIDs {
+			frontend := frontendIDToFrontend[frontendID]
+			vips = append(vips, frontend.ip)
+
+			vifLcuuid := a.getLcuuid(frontendID)
+			vif := model.VInterface{
+				Lcuuid:        vifLcuuid,
+				Type:          common.VIF_TYPE_LAN,
+				Mac:           common.VIF_DEFAULT_MAC,
+				DeviceLcuuid:  lbLcuuid,
+				DeviceType:    common.VIF_DEVICE_TYPE_LB,
+				NetworkLcuuid: frontend.network.Lcuuid,
+				VPCLcuuid:     vpcLcuuid,
+				RegionLcuuid:  regionLcuuid,
+			}
+			ip := model.IP{
+				Lcuuid:           common.GenerateUUID(vifLcuuid + frontend.ip),
+				VInterfaceLcuuid: vifLcuuid,
+				IP:               frontend.ip,
+				RegionLcuuid:     regionLcuuid,
+			}
+			if frontend.public {
+				vif.Type = common.VIF_TYPE_WAN
+				vif.NetworkLcuuid = common.NETWORK_ISP_LCUUID
+			} else {
+				ip.SubnetLcuuid = a.getSubnetLcuuid(frontend.network.Lcuuid, frontend.ip)
+			}
+			vifs = append(vifs, vif)
+			ips = append(ips, ip)
+		}
+		lbs = append(
+			lbs,
+			model.LB{
+				Lcuuid:       lbLcuuid,
+				Name:         name,
+				Label:        jProps.Get("resourceGuid").MustString(),
+				Model:        lbModel,
+				VIP:          strings.Join(vips, ","),
+				VPCLcuuid:    vpcLcuuid,
+				RegionLcuuid: regionLcuuid,
+			},
+		)
+		a.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+
+		jRules := jProps.Get("loadBalancingRules")
+		for j := range jRules.MustArray() {
+			jRule := jRules.GetIndex(j)
+			jRuleProps := jRule.Get("properties")
+			frontend, ok := frontendIDToFrontend[strings.ToLower(jRuleProps.GetPath("frontendIPConfiguration", "id").MustString())]
+			if !ok {
+				log.Infof("exclude lb (%s) rule: %s, missing frontend info", name, jRule.Get("name").MustString())
+				continue
+			}
+			listenerLcuuid := a.getLcuuid(jRule.Get("id").MustString())
+			protocol := strings.ToUpper(jRuleProps.Get("protocol").MustString())
+			lbListeners = append(
+				lbListeners,
+				model.LBListener{
+					Lcuuid:   listenerLcuuid,
+					LBLcuuid: lbLcuuid,
+					Name:     jRule.Get("name").MustString(),
+					IPs:      frontend.ip,
+					Protocol: protocol,
+					Port:     jRuleProps.Get("frontendPort").MustInt(),
+				},
+			)
+
+			backendPort := jRuleProps.Get("backendPort").MustInt()
+			poolID := strings.ToLower(jRuleProps.GetPath("backendAddressPool", "id").MustString())
+			for _, backend := range poolIDToBackends[poolID] {
+				backendVPCLcuuid := backend.vpcLcuuid
+				if backendVPCLcuuid == "" {
+					backendVPCLcuuid = vpcLcuuid
+				}
+				lbTargetServers = append(
+					lbTargetServers,
+					model.LBTargetServer{
+						Lcuuid:           common.GenerateUUID(listenerLcuuid + backend.ip),
+						LBLcuuid:         lbLcuuid,
+						LBListenerLcuuid: listenerLcuuid,
+						Type:             backend.serverType,
+						IP:               backend.ip,
+						VMLcuuid:         backend.vmLcuuid,
+						Protocol:         protocol,
+						Port:             backendPort,
+						VPCLcuuid:        backendVPCLcuuid,
+					},
+				)
+			}
+		}
+	}
+	return lbs, lbListeners, lbTargetServers, vifs, ips, nil
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"strings"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+// getNATGateways NAT网关本身不属于虚拟网络，其VPC由关联的子网确定
+func (a *Azure) getNATGateways() ([]model.NATGateway, []model.VInterface, []model.IP, error) {
+	var natGateways []model.NATGateway
+	var vifs []model.VInterface
+	var ips []model.IP
+
+	jNATs, err := a.getResourceGroupRawData("Microsoft.Network/natGateways", API_VERSION_NETWORK, nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for i := range jNATs {
+		jNAT := jNATs[i]
+		name := jNAT.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jNAT, []string{"id", "name", "location", "properties"}) {
+			log.Infof("exclude nat_gateway: %s, missing attr", name)
+			continue
+		}
+		jProps := jNAT.Get("properties")
+		var vpcLcuuid string
+		jSubnets := jProps.Get("subnets")
+		for j := range jSubnets.MustArray() {
+			network, ok := a.toolDataSet.subnetIDToNetwork[strings.ToLower(jSubnets.GetIndex(j).Get("id").MustString())]
+			if ok {
+				vpcLcuuid = network.VPCLcuuid
+				break
+			}
+		}
+		if vpcLcuuid == "" {
+			log.Infof("exclude nat_gateway: %s, not associated with subnet", name)
+			continue
+		}
+		var floatingIPs []string
+		jPublicIPs := jProps.Get("publicIpAddresses")
+		for j := range jPublicIPs.MustArray() {
+			if ip, ok := a.toolDataSet.publicIPIDToIP[strings.ToLower(jPublicIPs.GetIndex(j).Get("id").MustString())]; ok {
+				floatingIPs = append(floatingIPs, ip)
+			}
+		}
+
+		natLcuuid := a.getLcuuid(jNAT.Get("id").MustString())
+		regionLcuuid := a.toolDataSet.locationToRegionLcuuid[jNAT.Get("location").MustString()]
+		natGateways = append(
+			natGateways,
+			model.NATGateway{
+				Lcuuid:       natLcuuid,
+				Name:         name,
+				Label:        jProps.Get("resourceGuid").MustString(),
+				FloatingIPs:  strings.Join(floatingIPs, ","),
+				VPCLcuuid:    vpcLcuuid,
+				RegionLcuuid: regionLcuuid,
+			},
+		)
+		a.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+
+		vifLcuuid := common.GenerateUUID(natLcuuid)
+		vifs = append(
+			vifs,
+			model.VInterface{
+				Lcuuid:        vifLcuuid,
+				Type:          common.VIF_TYPE_WAN,
+				Mac:           common.VIF_DEFAULT_MAC,
+				DeviceLcuuid:  natLcuuid,
+				DeviceType:    common.VIF_DEVICE_TYPE_NAT_GATEWAY,
+				NetworkLcuuid: common.NETWORK_ISP_LCUUID,
+				VPCLcuuid:     vpcLcuuid,
+				RegionLcuuid:  regionLcuuid,
+			},
+		)
+		for _, ip := range floatingIPs {
+			ips = append(
+				ips,
+				model.IP{
+					Lcuuid:           common.GenerateUUID(vifLcuuid + ip),
+					VInterfaceLcuuid: vifLcuuid,
+					IP:               ip,
+					RegionLcuuid:     regionLcuuid,
+				},
+			)
+		}
+	}
+	return natGateways, vifs, ips, nil
+}
@@ -0,0 +1,39 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"strings"
+)
+
+// getPublicIPs 获取已分配地址的公网IP，供网卡、NAT网关及负载均衡器使用
+func (a *Azure) getPublicIPs() error {
+	jPublicIPs, err := a.getResourceGroupRawData("Microsoft.Network/publicIPAddresses", API_VERSION_NETWORK, nil)
+	if err != nil {
+		return err
+	}
+	for i := range jPublicIPs {
+		jIP := jPublicIPs[i]
+		ip := jIP.GetPath("properties", "ipAddress").MustString()
+		if ip == "" {
+			log.Debugf("exclude public ip: %s, not allocated", jIP.Get("name").MustString())
+			continue
+		}
+		a.toolDataSet.publicIPIDToIP[strings.ToLower(jIP.Get("id").MustString())] = ip
+	}
+	return nil
+}
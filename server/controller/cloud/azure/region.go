@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"fmt"
+
+	"github.com/bitly/go-simplejson"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+func (a *Azure) getRegions() ([]model.Region, error) {
+	var regions []model.Region
+	jLocations, err := a.getRawData("locations", a.getURL("/locations", API_VERSION_SUBSCRIPTION, nil))
+	if err != nil {
+		return nil, err
+	}
+	for i := range jLocations {
+		jl := jLocations[i]
+		name := jl.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jl, []string{"name", "displayName"}) {
+			log.Infof("exclude region: %s, missing attr", name)
+			continue
+		}
+		// 逻辑区域（如地理区域）中没有资源
+		if jl.GetPath("metadata", "regionType").MustString() == "Logical" {
+			continue
+		}
+		if len(a.config.IncludeRegions) > 0 && !common.Contains(a.config.IncludeRegions, name) {
+			log.Infof("region (%s) not in include_regions", name)
+			continue
+		}
+		if common.Contains(a.config.ExcludeRegions, name) {
+			log.Infof("region (%s) in exclude_regions", name)
+			continue
+		}
+		regions = append(
+			regions,
+			model.Region{
+				Lcuuid: common.GenerateUUID(name + "_" + a.lcuuidGenerate),
+				Label:  name,
+				Name:   jl.Get("displayName").MustString(),
+			},
+		)
+		a.toolDataSet.locationToRegionLcuuid[name] = a.locationToRegionLcuuid(name)
+		a.toolDataSet.locationToDisplayName[name] = jl.Get("displayName").MustString()
+	}
+	return regions, nil
+}
+
+// 配置了region_uuid时，所有资源均属于该区域
+func (a *Azure) locationToRegionLcuuid(location string) string {
+	if a.config.RegionLcuuid != "" {
+		return a.config.RegionLcuuid
+	}
+	return common.GenerateUUID(location + "_" + a.lcuuidGenerate)
+}
+
+// getAZLcuuid 返回资源zones中第一个可用区的lcuuid，非可用区资源属于区域的默认可用区
+func (a *Azure) getAZLcuuid(location string, jZones *simplejson.Json) string {
+	var zone string
+	if len(jZones.MustArray()) > 0 {
+		zone = jZones.GetIndex(0).MustString()
+	}
+	key := LocationZoneKey{Location: location, Zone: zone}
+	if azLcuuid, ok := a.toolDataSet.keyToAZLcuuid[key]; ok {
+		return azLcuuid
+	}
+	az := model.AZ{
+		Lcuuid:       common.GenerateUUID(location + "_" + zone + "_" + a.lcuuidGenerate),
+		Label:        location,
+		Name:         a.toolDataSet.locationToDisplayName[location],
+		RegionLcuuid: a.toolDataSet.locationToRegionLcuuid[location],
+	}
+	if zone != "" {
+		az.Label = fmt.Sprintf("%s-%s", location, zone)
+		az.Name = fmt.Sprintf("%s %s", az.Name, zone)
+	}
+	a.toolDataSet.azs = append(a.toolDataSet.azs, az)
+	a.toolDataSet.keyToAZLcuuid[key] = az.Lcuuid
+	return az.Lcuuid
+}
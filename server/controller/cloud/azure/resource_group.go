@@ -0,0 +1,43 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"sort"
+)
+
+// getResourceGroups 获取订阅下的资源组，其他资源按资源组逐个获取
+func (a *Azure) getResourceGroups() error {
+	jRGs, err := a.getRawData("resourcegroups", a.getURL("/resourcegroups", API_VERSION_RESOURCE_GROUP, nil))
+	if err != nil {
+		return err
+	}
+	for i := range jRGs {
+		jRG := jRGs[i]
+		name := jRG.Get("name").MustString()
+		if name == "" {
+			continue
+		}
+		if jRG.GetPath("properties", "provisioningState").MustString() == "Deleting" {
+			log.Infof("exclude resource group: %s, deleting", name)
+			continue
+		}
+		a.toolDataSet.resourceGroups = append(a.toolDataSet.resourceGroups, name)
+	}
+	sort.Strings(a.toolDataSet.resourceGroups)
+	return nil
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"strings"
+
+	"github.com/bitly/go-simplejson"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+)
+
+func (a *Azure) getSecurityGroups() ([]model.SecurityGroup, []model.SecurityGroupRule, error) {
+	var securityGroups []model.SecurityGroup
+	var sgRules []model.SecurityGroupRule
+
+	jNSGs, err := a.getResourceGroupRawData("Microsoft.Network/networkSecurityGroups", API_VERSION_NETWORK, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range jNSGs {
+		jNSG := jNSGs[i]
+		name := jNSG.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jNSG, []string{"id", "name", "location", "properties"}) {
+			log.Infof("exclude security_group: %s, missing attr", name)
+			continue
+		}
+		sgLcuuid := a.getLcuuid(jNSG.Get("id").MustString())
+		regionLcuuid := a.toolDataSet.locationToRegionLcuuid[jNSG.Get("location").MustString()]
+		securityGroups = append(
+			securityGroups,
+			model.SecurityGroup{
+				Lcuuid:       sgLcuuid,
+				Name:         name,
+				Label:        jNSG.GetPath("properties", "resourceGuid").MustString(),
+				RegionLcuuid: regionLcuuid,
+			},
+		)
+		a.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+
+		// 默认规则（如DenyAllInBound）由平台返回，无需额外生成拒绝规则
+		for _, key := range []string{"securityRules", "defaultSecurityRules"} {
+			jRules := jNSG.GetPath("properties", key)
+			for j := range jRules.MustArray() {
+				rule, ok := a.formatSecurityGroupRule(jRules.GetIndex(j), sgLcuuid)
+				if ok {
+					sgRules = append(sgRules, rule)
+				}
+			}
+		}
+	}
+	return securityGroups, sgRules, nil
+}
+
+func (a *Azure) formatSecurityGroupRule(jRule *simplejson.Json, sgLcuuid string) (model.SecurityGroupRule, bool) {
+	id := jRule.Get("id").MustString()
+	if !cloudcommon.CheckJsonAttributes(jRule, []string{"id", "properties"}) {
+		log.Infof("exclude security_group_rule: %s, missing attr", id)
+		return model.SecurityGroupRule{}, false
+	}
+	jProps := jRule.Get("properties")
+	rule := model.SecurityGroupRule{
+		Lcuuid:              a.getLcuuid(id),
+		SecurityGroupLcuuid: sgLcuuid,
+		EtherType:           cloudcommon.SECURITY_GROUP_IPV4,
+		Protocol:            cloudcommon.PROTOCOL_ALL,
+		Priority:            jProps.Get("priority").MustInt(),
+		Action:              cloudcommon.SECURITY_GROUP_RULE_DROP,
+	}
+	if jProps.Get("access").MustString() == "Allow" {
+		rule.Action = cloudcommon.SECURITY_GROUP_RULE_ACCEPT
+	}
+	if protocol := jProps.Get("protocol").MustString(); protocol != "" && protocol != "*" {
+		rule.Protocol = strings.ToUpper(protocol)
+	}
+
+	srcPrefixes := getRuleValues(jProps, "sourceAddressPrefix", "sourceAddressPrefixes")
+	dstPrefixes := getRuleValues(jProps, "destinationAddressPrefix", "destinationAddressPrefixes")
+	if strings.Contains(srcPrefixes+dstPrefixes, ":") {
+		rule.EtherType = cloudcommon.SECURITY_GROUP_IPV6
+	}
+	src := formatRulePrefixes(srcPrefixes, rule.EtherType)
+	dst := formatRulePrefixes(dstPrefixes, rule.EtherType)
+	srcPorts := formatRulePorts(getRuleValues(jProps, "sourcePortRange", "sourcePortRanges"))
+	dstPorts := formatRulePorts(getRuleValues(jProps, "destinationPortRange", "destinationPortRanges"))
+	if jProps.Get("direction").MustString() == "Inbound" {
+		rule.Direction = cloudcommon.SECURITY_GROUP_RULE_INGRESS
+		rule.Local, rule.LocalPortRange = dst, dstPorts
+		rule.Remote, rule.RemotePortRange = src, srcPorts
+	} else {
+		rule.Direction = cloudcommon.SECURITY_GROUP_RULE_EGRESS
+		rule.Local, rule.LocalPortRange = src, srcPorts
+		rule.Remote, rule.RemotePortRange = dst, dstPorts
+	}
+	return rule, true
+}
+
+// getRuleValues 规则的地址及端口可以是单个值或列表，返回以逗号分隔的值
+func getRuleValues(jProps *simplejson.Json, singleKey, listKey string) string {
+	if value := jProps.Get(singleKey).MustString(); value != "" {
+		return value
+	}
+	var values []string
+	jValues := jProps.Get(listKey)
+	for i := range jValues.MustArray() {
+		values = append(values, jValues.GetIndex(i).MustString())
+	}
+	return strings.Join(values, ",")
+}
+
+// formatRulePrefixes 将任意地址转换为默认网段，服务标记（如VirtualNetwork）保持不变
+func formatRulePrefixes(prefixes string, etherType int) string {
+	if prefixes == "" || prefixes == "*" || prefixes == "Internet" {
+		if etherType == cloudcommon.SECURITY_GROUP_IPV6 {
+			return cloudcommon.SUBNET_DEFAULT_CIDR_IPV6
+		}
+		return cloudcommon.SUBNET_DEFAULT_CIDR_IPV4
+	}
+	return prefixes
+}
+
+// formatRulePorts 将任意端口转换为0-65535，单个端口转换为范围
+func formatRulePorts(ports string) string {
+	if ports == "" || ports == "*" {
+		return cloudcommon.PORT_RANGE_ALL
+	}
+	portRanges := strings.Split(ports, ",")
+	for i, port := range portRanges {
+		if !strings.Contains(port, "-") {
+			portRanges[i] = port + "-" + port
+		}
+	}
+	return strings.Join(portRanges, ",")
+}
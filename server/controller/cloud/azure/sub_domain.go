@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"encoding/json"
+	"strings"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+// getSubDomains 将AKS集群作为附属容器集群
+func (a *Azure) getSubDomains() ([]model.SubDomain, error) {
+	var subDomains []model.SubDomain
+
+	log.Debug("get sub_domains starting")
+	jClusters, err := a.getResourceGroupRawData("Microsoft.ContainerService/managedClusters", API_VERSION_CONTAINER_SERVICE, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i := range jClusters {
+		jCluster := jClusters[i]
+		name := jCluster.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jCluster, []string{"id", "name", "location", "properties"}) {
+			log.Infof("exclude sub_domain: %s, missing attr", name)
+			continue
+		}
+		jProps := jCluster.Get("properties")
+		// 使用自定义子网的集群由节点池子网确定VPC，否则使用节点资源组中托管的VPC
+		var vpcLcuuid string
+		jPools := jProps.Get("agentPoolProfiles")
+		for j := range jPools.MustArray() {
+			network, ok := a.toolDataSet.subnetIDToNetwork[strings.ToLower(jPools.GetIndex(j).Get("vnetSubnetID").MustString())]
+			if ok {
+				vpcLcuuid = network.VPCLcuuid
+				break
+			}
+		}
+		if vpcLcuuid == "" {
+			vpcLcuuid = a.toolDataSet.resourceGroupToVPCLcuuid[strings.ToLower(jProps.Get("nodeResourceGroup").MustString())]
+		}
+		if vpcLcuuid == "" {
+			log.Infof("exclude sub_domain: %s, missing vpc info", name)
+			continue
+		}
+		config := map[string]interface{}{
+			"cluster_id":                 name,
+			"region_uuid":                a.toolDataSet.locationToRegionLcuuid[jCluster.Get("location").MustString()],
+			"vpc_uuid":                   vpcLcuuid,
+			"port_name_regex":            common.DEFAULT_PORT_NAME_REGEX,
+			"pod_net_ipv4_cidr_max_mask": common.K8S_POD_IPV4_NETMASK,
+			"pod_net_ipv6_cidr_max_mask": common.K8S_POD_IPV6_NETMASK,
+		}
+		configJson, _ := json.Marshal(config)
+		subDomains = append(subDomains, model.SubDomain{
+			Lcuuid:      a.getLcuuid(jCluster.Get("id").MustString()),
+			Name:        name,
+			DisplayName: name,
+			ClusterID:   name,
+			VpcUUID:     vpcLcuuid,
+			Config:      string(configJson),
+		})
+	}
+	log.Debug("get sub_domains complete")
+	return subDomains, nil
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Token struct {
+	token     string
+	expiresAt time.Time
+}
+
+// 检查token是否过期
+// 离失效时间小于5m时认为已过期，需重新申请
+func (t *Token) isExpired() bool {
+	return t.expiresAt.Sub(time.Now()).Minutes() < 5
+}
+
+func (a *Azure) getToken() (*Token, error) {
+	if a.token == nil || a.token.isExpired() {
+		token, err := a.createToken()
+		if err != nil {
+			return nil, err
+		}
+		a.token = token
+	}
+	return a.token, nil
+}
+
+// createToken 使用service principal的client credentials申请ARM的access token
+func (a *Azure) createToken() (*Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", a.config.ClientID)
+	form.Set("client_secret", a.config.ClientSecret)
+	form.Set("scope", a.config.ManagementURL+"/.default")
+	tokenURL := fmt.Sprintf("%s/%s/oauth2/v2.0/token", a.config.LoginURL, url.PathEscape(a.config.TenantID))
+	resp, err := a.request(http.MethodPost, tokenURL, "", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	// expires_in在部分环境中返回字符串
+	expiresIn := resp.Get("expires_in").MustInt()
+	if expiresIn == 0 {
+		expiresIn, _ = strconv.Atoi(resp.Get("expires_in").MustString())
+	}
+	token := &Token{
+		token:     resp.Get("access_token").MustString(),
+		expiresAt: time.Now().Add(time.Duration(expiresIn) * time.Second),
+	}
+	if token.token == "" {
+		return nil, errors.New("get token failed, missing access_token")
+	}
+	return token, nil
+}
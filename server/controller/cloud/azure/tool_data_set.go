@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+)
+
+// 以ARM资源ID为key的map，key均为小写
+type ToolDataSet struct {
+	locationToRegionLcuuid    map[string]string
+	locationToDisplayName     map[string]string
+	keyToAZLcuuid             map[LocationZoneKey]string
+	azs                       []model.AZ
+	resourceGroups            []string
+	resourceGroupToVPCLcuuid  map[string]string // key: resource group name
+	subnetIDToNetwork         map[string]model.Network
+	networkLcuuidToSubnets    map[string][]model.Subnet
+	subnetIDToNSGID           map[string]string
+	publicIPIDToIP            map[string]string
+	nicIDToNIC                map[string]NIC
+	ipConfigIDToIPConfig      map[string]IPConfig
+	regionLcuuidToResourceNum map[string]int
+	azLcuuidToResourceNum     map[string]int
+}
+
+func NewToolDataSet() *ToolDataSet {
+	return &ToolDataSet{
+		locationToRegionLcuuid:    make(map[string]string),
+		locationToDisplayName:     make(map[string]string),
+		keyToAZLcuuid:             make(map[LocationZoneKey]string),
+		resourceGroupToVPCLcuuid:  make(map[string]string),
+		subnetIDToNetwork:         make(map[string]model.Network),
+		networkLcuuidToSubnets:    make(map[string][]model.Subnet),
+		subnetIDToNSGID:           make(map[string]string),
+		publicIPIDToIP:            make(map[string]string),
+		nicIDToNIC:                make(map[string]NIC),
+		ipConfigIDToIPConfig:      make(map[string]IPConfig),
+		regionLcuuidToResourceNum: make(map[string]int),
+		azLcuuidToResourceNum:     make(map[string]int),
+	}
+}
+
+type LocationZoneKey struct {
+	Location string
+	Zone     string // 非可用区资源为空
+}
+
+// 网卡的部分信息，供虚拟机使用
+type NIC struct {
+	VMLcuuid  string
+	VPCLcuuid string
+	IP        string
+	Primary   bool
+}
+
+// 网卡IP配置的部分信息，供负载均衡器后端使用
+type IPConfig struct {
+	VMLcuuid  string
+	VPCLcuuid string
+	IP        string
+}
@@ -0,0 +1,182 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"strings"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+// getVInterfaces 获取虚拟机网卡及IP，网卡IP配置关联的公网IP作为虚拟机的浮动IP
+func (a *Azure) getVInterfaces() ([]model.VInterface, []model.IP, []model.FloatingIP, []model.VMSecurityGroup, error) {
+	var vifs []model.VInterface
+	var ips []model.IP
+	var floatingIPs []model.FloatingIP
+	var vmSGs []model.VMSecurityGroup
+	vmSGKeys := make(map[string]bool)
+
+	jNICs, err := a.getResourceGroupRawData("Microsoft.Network/networkInterfaces", API_VERSION_NETWORK, nil)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	for i := range jNICs {
+		jNIC := jNICs[i]
+		id := jNIC.Get("id").MustString()
+		if !cloudcommon.CheckJsonAttributes(jNIC, []string{"id", "location", "properties"}) {
+			log.Infof("exclude vinterface: %s, missing attr", id)
+			continue
+		}
+		jProps := jNIC.Get("properties")
+		// 私有终结点等资源的网卡不属于虚拟机
+		vmID := jProps.GetPath("virtualMachine", "id").MustString()
+		if vmID == "" {
+			log.Debugf("exclude vinterface: %s, not attached to vm", id)
+			continue
+		}
+		vmLcuuid := a.getLcuuid(vmID)
+		regionLcuuid := a.toolDataSet.locationToRegionLcuuid[jNIC.Get("location").MustString()]
+		vifLcuuid := a.getLcuuid(id)
+		// 网卡未运行时没有MAC地址
+		mac := strings.ToLower(strings.ReplaceAll(jProps.Get("macAddress").MustString(), "-", ":"))
+		if mac == "" {
+			mac = common.VIF_DEFAULT_MAC
+		}
+
+		var vif model.VInterface
+		var wanVIF model.VInterface
+		nic := NIC{VMLcuuid: vmLcuuid, Primary: jProps.Get("primary").MustBool()}
+		var subnetNSGIDs []string
+		jIPConfigs := jProps.Get("ipConfigurations")
+		for j := range jIPConfigs.MustArray() {
+			jIPConfig := jIPConfigs.GetIndex(j)
+			subnetID := jIPConfig.GetPath("properties", "subnet", "id").MustString()
+			network, ok := a.toolDataSet.subnetIDToNetwork[strings.ToLower(subnetID)]
+			if !ok {
+				log.Infof("exclude vinterface (%s) ip configuration: %s, missing network info", id, jIPConfig.Get("name").MustString())
+				continue
+			}
+			if vif.Lcuuid == "" {
+				vif = model.VInterface{
+					Lcuuid:        vifLcuuid,
+					Type:          common.VIF_TYPE_LAN,
+					Mac:           mac,
+					DeviceLcuuid:  vmLcuuid,
+					DeviceType:    common.VIF_DEVICE_TYPE_VM,
+					NetworkLcuuid: network.Lcuuid,
+					VPCLcuuid:     network.VPCLcuuid,
+					RegionLcuuid:  regionLcuuid,
+				}
+				vifs = append(vifs, vif)
+				nic.VPCLcuuid = network.VPCLcuuid
+			}
+			if nsgID, ok := a.toolDataSet.subnetIDToNSGID[strings.ToLower(subnetID)]; ok {
+				subnetNSGIDs = append(subnetNSGIDs, nsgID)
+			}
+
+			privateIP := jIPConfig.GetPath("properties", "privateIPAddress").MustString()
+			if privateIP == "" {
+				continue
+			}
+			ips = append(
+				ips,
+				model.IP{
+					Lcuuid:           common.GenerateUUID(vifLcuuid + privateIP),
+					VInterfaceLcuuid: vifLcuuid,
+					IP:               privateIP,
+					SubnetLcuuid:     a.getSubnetLcuuid(network.Lcuuid, privateIP),
+					RegionLcuuid:     regionLcuuid,
+				},
+			)
+			if nic.IP == "" || jIPConfig.GetPath("properties", "primary").MustBool() {
+				nic.IP = privateIP
+			}
+			a.toolDataSet.ipConfigIDToIPConfig[strings.ToLower(jIPConfig.Get("id").MustString())] = IPConfig{
+				VMLcuuid:  vmLcuuid,
+				VPCLcuuid: network.VPCLcuuid,
+				IP:        privateIP,
+			}
+
+			publicIP, ok := a.toolDataSet.publicIPIDToIP[strings.ToLower(jIPConfig.GetPath("properties", "publicIPAddress", "id").MustString())]
+			if !ok {
+				continue
+			}
+			if wanVIF.Lcuuid == "" {
+				wanVIF = model.VInterface{
+					Lcuuid:        common.GenerateUUID(vifLcuuid),
+					Type:          common.VIF_TYPE_WAN,
+					Mac:           cloudcommon.GenerateWANVInterfaceMac(mac),
+					DeviceLcuuid:  vmLcuuid,
+					DeviceType:    common.VIF_DEVICE_TYPE_VM,
+					NetworkLcuuid: common.NETWORK_ISP_LCUUID,
+					VPCLcuuid:     network.VPCLcuuid,
+					RegionLcuuid:  regionLcuuid,
+				}
+				vifs = append(vifs, wanVIF)
+			}
+			ips = append(
+				ips,
+				model.IP{
+					Lcuuid:           common.GenerateUUID(wanVIF.Lcuuid + publicIP),
+					VInterfaceLcuuid: wanVIF.Lcuuid,
+					IP:               publicIP,
+					RegionLcuuid:     regionLcuuid,
+				},
+			)
+			floatingIPs = append(
+				floatingIPs,
+				model.FloatingIP{
+					Lcuuid:        common.GenerateUUID(vifLcuuid + publicIP),
+					IP:            publicIP,
+					VMLcuuid:      vmLcuuid,
+					NetworkLcuuid: common.NETWORK_ISP_LCUUID,
+					VPCLcuuid:     network.VPCLcuuid,
+					RegionLcuuid:  regionLcuuid,
+				},
+			)
+		}
+		if vif.Lcuuid == "" {
+			continue
+		}
+		a.toolDataSet.nicIDToNIC[strings.ToLower(id)] = nic
+
+		// 网卡及子网均可关联安全组，网卡的安全组优先
+		nsgIDs := subnetNSGIDs
+		if nsgID := jProps.GetPath("networkSecurityGroup", "id").MustString(); nsgID != "" {
+			nsgIDs = append([]string{nsgID}, nsgIDs...)
+		}
+		for priority, nsgID := range nsgIDs {
+			sgLcuuid := a.getLcuuid(nsgID)
+			if vmSGKeys[vmLcuuid+sgLcuuid] {
+				continue
+			}
+			vmSGKeys[vmLcuuid+sgLcuuid] = true
+			vmSGs = append(
+				vmSGs,
+				model.VMSecurityGroup{
+					Lcuuid:              common.GenerateUUID(vmLcuuid + sgLcuuid),
+					SecurityGroupLcuuid: sgLcuuid,
+					VMLcuuid:            vmLcuuid,
+					Priority:            priority,
+				},
+			)
+		}
+	}
+	return vifs, ips, floatingIPs, vmSGs, nil
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+var STATE_CONVERTION = map[string]int{
+	"PowerState/running":     common.VM_STATE_RUNNING,
+	"PowerState/stopped":     common.VM_STATE_STOPPED,
+	"PowerState/deallocated": common.VM_STATE_STOPPED,
+}
+
+func (a *Azure) getVMs() ([]model.VM, error) {
+	var vms []model.VM
+	// instanceView中包含虚拟机的电源状态
+	params := url.Values{"$expand": []string{"instanceView"}}
+	jVMs, err := a.getResourceGroupRawData("Microsoft.Compute/virtualMachines", API_VERSION_COMPUTE, params)
+	if err != nil {
+		return nil, err
+	}
+	for i := range jVMs {
+		jVM := jVMs[i]
+		name := jVM.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jVM, []string{"id", "name", "location", "properties"}) {
+			log.Infof("exclude vm: %s, missing attr", name)
+			continue
+		}
+		jProps := jVM.Get("properties")
+		nic, ok := a.getVMNIC(jProps.GetPath("networkProfile", "networkInterfaces").MustArray())
+		if !ok {
+			log.Infof("exclude vm: %s, missing vinterface info", name)
+			continue
+		}
+		location := jVM.Get("location").MustString()
+		regionLcuuid := a.toolDataSet.locationToRegionLcuuid[location]
+		azLcuuid := a.getAZLcuuid(location, jVM.Get("zones"))
+		state := common.VM_STATE_EXCEPTION
+		jStatuses := jProps.GetPath("instanceView", "statuses")
+		for j := range jStatuses.MustArray() {
+			code := jStatuses.GetIndex(j).Get("code").MustString()
+			if strings.HasPrefix(code, "PowerState/") {
+				if s, ok := STATE_CONVERTION[code]; ok {
+					state = s
+				}
+				break
+			}
+		}
+		vm := model.VM{
+			Lcuuid:       a.getLcuuid(jVM.Get("id").MustString()),
+			Name:         name,
+			Label:        jProps.Get("vmId").MustString(),
+			IP:           nic.IP,
+			Hostname:     jProps.GetPath("osProfile", "computerName").MustString(),
+			HType:        common.VM_HTYPE_VM_C,
+			State:        state,
+			VPCLcuuid:    nic.VPCLcuuid,
+			AZLcuuid:     azLcuuid,
+			RegionLcuuid: regionLcuuid,
+			CloudTags:    make(map[string]string),
+		}
+		for k, v := range jVM.Get("tags").MustMap() {
+			if value, ok := v.(string); ok {
+				vm.CloudTags[k] = value
+			}
+		}
+		created := jProps.Get("timeCreated").MustString()
+		if created != "" {
+			createdAt, err := time.Parse(time.RFC3339, created)
+			if err != nil {
+				log.Errorf("parse timeCreated failed: %s", created)
+			} else {
+				vm.CreatedAt = createdAt
+			}
+		}
+		vms = append(vms, vm)
+		a.toolDataSet.azLcuuidToResourceNum[azLcuuid]++
+		a.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+	}
+	return vms, nil
+}
+
+// getVMNIC 返回虚拟机的主网卡，虚拟机的VPC及IP由主网卡确定
+func (a *Azure) getVMNIC(nicRefs []interface{}) (NIC, bool) {
+	var found bool
+	var primaryNIC NIC
+	for _, ref := range nicRefs {
+		mRef, ok := ref.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id, _ := mRef["id"].(string)
+		nic, ok := a.toolDataSet.nicIDToNIC[strings.ToLower(id)]
+		if !ok {
+			continue
+		}
+		if !found || nic.Primary {
+			primaryNIC = nic
+			found = true
+		}
+		if nic.Primary {
+			break
+		}
+	}
+	return primaryNIC, found
+}
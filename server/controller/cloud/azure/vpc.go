@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package azure
+
+import (
+	"strings"
+
+	"github.com/bitly/go-simplejson"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+// getVPCs 虚拟网络对应VPC，虚拟网络的每个子网对应一个网络，子网的每个地址前缀对应一个网段
+func (a *Azure) getVPCs() ([]model.VPC, []model.Network, []model.Subnet, error) {
+	var vpcs []model.VPC
+	var networks []model.Network
+	var subnets []model.Subnet
+
+	jVNets, err := a.getResourceGroupRawData("Microsoft.Network/virtualNetworks", API_VERSION_NETWORK, nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for i := range jVNets {
+		jVNet := jVNets[i]
+		name := jVNet.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jVNet, []string{"id", "name", "location", "properties"}) {
+			log.Infof("exclude vpc: %s, missing attr", name)
+			continue
+		}
+		id := jVNet.Get("id").MustString()
+		location := jVNet.Get("location").MustString()
+		regionLcuuid := a.toolDataSet.locationToRegionLcuuid[location]
+		azLcuuid := a.getAZLcuuid(location, jVNet.Get("zones"))
+		jProps := jVNet.Get("properties")
+		vpc := model.VPC{
+			Lcuuid:       a.getLcuuid(id),
+			Name:         name,
+			Label:        jProps.Get("resourceGuid").MustString(),
+			RegionLcuuid: regionLcuuid,
+		}
+		jPrefixes := jProps.GetPath("addressSpace", "addressPrefixes")
+		if len(jPrefixes.MustArray()) > 0 {
+			vpc.CIDR = jPrefixes.GetIndex(0).MustString()
+		}
+		vpcs = append(vpcs, vpc)
+		a.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+		rgKey := strings.ToLower(getResourceGroupName(id))
+		if _, ok := a.toolDataSet.resourceGroupToVPCLcuuid[rgKey]; !ok {
+			a.toolDataSet.resourceGroupToVPCLcuuid[rgKey] = vpc.Lcuuid
+		}
+
+		jSubnets := jProps.Get("subnets")
+		for j := range jSubnets.MustArray() {
+			jSubnet := jSubnets.GetIndex(j)
+			subnetName := jSubnet.Get("name").MustString()
+			if !cloudcommon.CheckJsonAttributes(jSubnet, []string{"id", "name", "properties"}) {
+				log.Infof("exclude network: %s, missing attr", subnetName)
+				continue
+			}
+			subnetID := jSubnet.Get("id").MustString()
+			network := model.Network{
+				Lcuuid:         a.getLcuuid(subnetID),
+				Name:           subnetName,
+				SegmentationID: 1,
+				NetType:        common.NETWORK_TYPE_LAN,
+				VPCLcuuid:      vpc.Lcuuid,
+				AZLcuuid:       azLcuuid,
+				RegionLcuuid:   regionLcuuid,
+			}
+			networks = append(networks, network)
+			a.toolDataSet.subnetIDToNetwork[strings.ToLower(subnetID)] = network
+			a.toolDataSet.azLcuuidToResourceNum[azLcuuid]++
+			a.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+			if nsgID := jSubnet.GetPath("properties", "networkSecurityGroup", "id").MustString(); nsgID != "" {
+				a.toolDataSet.subnetIDToNSGID[strings.ToLower(subnetID)] = nsgID
+			}
+
+			for _, cidr := range getSubnetPrefixes(jSubnet.Get("properties")) {
+				subnet := model.Subnet{
+					Lcuuid:        common.GenerateUUID(network.Lcuuid + "_" + cidr),
+					Name:          subnetName,
+					CIDR:          cidr,
+					NetworkLcuuid: network.Lcuuid,
+					VPCLcuuid:     vpc.Lcuuid,
+				}
+				subnets = append(subnets, subnet)
+				a.toolDataSet.networkLcuuidToSubnets[network.Lcuuid] = append(a.toolDataSet.networkLcuuidToSubnets[network.Lcuuid], subnet)
+			}
+		}
+	}
+	return vpcs, networks, subnets, nil
+}
+
+// 子网的地址前缀为addressPrefix，包含多个前缀（如IPv4及IPv6双栈）时为addressPrefixes
+func getSubnetPrefixes(jProps *simplejson.Json) []string {
+	if prefix := jProps.Get("addressPrefix").MustString(); prefix != "" {
+		return []string{prefix}
+	}
+	var prefixes []string
+	jPrefixes := jProps.Get("addressPrefixes")
+	for i := range jPrefixes.MustArray() {
+		prefixes = append(prefixes, jPrefixes.GetIndex(i).MustString())
+	}
+	return prefixes
+}
+
+// getSubnetLcuuid 返回网络中包含ip的网段，未找到时使用第一个网段
+func (a *Azure) getSubnetLcuuid(networkLcuuid, ip string) string {
+	subnets := a.toolDataSet.networkLcuuidToSubnets[networkLcuuid]
+	for _, subnet := range subnets {
+		if cloudcommon.IsIPInCIDR(ip, subnet.CIDR) {
+			return subnet.Lcuuid
+		}
+	}
+	if len(subnets) > 0 {
+		return subnets[0].Lcuuid
+	}
+	return ""
+}
@@ -48,6 +48,7 @@ type Cloud struct {
 	basicInfo               model.BasicInfo
 	resource                model.Resource
 	platform                platform.Platform
+	platformMutex           sync.Mutex // 定时任务与预演均会调用 platform 获取数据，需保证同一时间只有一个 goroutine 调用
 	taskCost                statsd.CloudTaskStatsd
 	kubernetesGatherTaskMap map[string]*KubernetesGatherTask
 }
@@ -104,7 +105,16 @@ func (c *Cloud) GetBasicInfo() model.BasicInfo {
 }
 
 func (c *Cloud) GetResource() model.Resource {
-	cResource := c.resource
+	return c.completeResource(c.resource)
+}
+
+// GetFreshResource 立即从云平台获取一次数据，不替换定时任务获取的数据，用于 recorder 预演
+func (c *Cloud) GetFreshResource() model.Resource {
+	cResource, _ := c.assembleCloudData()
+	return c.completeResource(cResource)
+}
+
+func (c *Cloud) completeResource(cResource model.Resource) model.Resource {
 	if c.basicInfo.Type != common.KUBERNETES {
 		if cResource.ErrorState == common.RESOURCE_STATE_CODE_SUCCESS && cResource.Verified && len(cResource.VMs) > 0 {
 			cResource.SubDomainResources = c.getSubDomainData(cResource)
@@ -153,12 +163,20 @@ func (c *Cloud) getCloudGatherInterval() int {
 }
 
 func (c *Cloud) getCloudData() {
+	cResource, cloudCost := c.assembleCloudData()
+	c.resource = cResource
+	c.sendStatsd(cloudCost)
+}
+
+func (c *Cloud) assembleCloudData() (model.Resource, float64) {
 	var cResource model.Resource
 	var cloudCost float64
 	if c.basicInfo.Type != common.KUBERNETES {
 		var err error
 		startTime := time.Now()
+		c.platformMutex.Lock()
 		cResource, err = c.platform.GetCloudData()
+		c.platformMutex.Unlock()
 		cloudCost = time.Now().Sub(startTime).Seconds()
 		// 这里因为任务内部没有对成功的状态赋值状态码，在这里统一处理了
 		if err == nil {
@@ -187,8 +205,7 @@ func (c *Cloud) getCloudData() {
 	}
 
 	cResource.SyncAt = time.Now()
-	c.resource = cResource
-	c.sendStatsd(cloudCost)
+	return cResource, cloudCost
 }
 
 func (c *Cloud) sendStatsd(cloudCost float64) {
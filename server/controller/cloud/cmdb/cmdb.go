@@ -0,0 +1,242 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bitly/go-simplejson"
+	"github.com/op/go-logging"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/config"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/statsd"
+)
+
+var log = logging.MustGetLogger("cloud.cmdb")
+
+// 数据校验失败时，ErrorMessage中最多展示的错误数量
+const MAX_ERROR_MESSAGE_NUM = 10
+
+type CMDB struct {
+	lcuuid         string
+	lcuuidGenerate string
+	name           string
+	httpTimeout    int
+	config         *Config
+	// 上次同步成功的数据及其ETag，CMDB返回304时直接使用
+	etag        string
+	resource    model.Resource
+	toolDataSet *ToolDataSet       // 处理资源数据时，构建的需要提供给其他资源使用的工具数据
+	cloudStatsd statsd.CloudStatsd // 性能监控
+	debugger    *cloudcommon.Debugger
+}
+
+func NewCMDB(domain mysql.Domain, globalCloudCfg config.CloudConfig) (*CMDB, error) {
+	conf := &Config{}
+	err := conf.LoadFromString(domain.Config)
+	if err != nil {
+		return nil, err
+	}
+	return newCMDB(domain, globalCloudCfg, conf), nil
+}
+
+func newCMDB(domain mysql.Domain, globalCloudCfg config.CloudConfig, conf *Config) *CMDB {
+	return &CMDB{
+		lcuuid: domain.Lcuuid,
+		// TODO: display_name后期需要修改为uuid_generate
+		lcuuidGenerate: domain.DisplayName,
+		name:           domain.Name,
+		httpTimeout:    globalCloudCfg.HTTPTimeout,
+		config:         conf,
+		debugger:       cloudcommon.NewDebugger(domain.Name),
+	}
+}
+
+func (c *CMDB) ClearDebugLog() {
+	c.debugger.Clear()
+}
+
+func (c *CMDB) CheckAuth() error {
+	_, _, err := c.getData("")
+	return err
+}
+
+func (c *CMDB) GetCloudData() (model.Resource, error) {
+	c.cloudStatsd = statsd.NewCloudStatsd()
+
+	statsdAPIStartTime := time.Now()
+	body, etag, err := c.getData(c.etag)
+	if err != nil {
+		return model.Resource{}, err
+	}
+	if body == nil {
+		log.Infof("cmdb (%s) data not modified (etag: %s)", c.name, c.etag)
+		c.cloudStatsd.RefreshAPIMoniter("cmdb", 0, statsdAPIStartTime)
+		c.cloudStatsd.ResCount = statsd.GetResCount(c.resource)
+		statsd.MetaStatsd.RegisterStatsdTable(c)
+		return c.resource, nil
+	}
+	c.cloudStatsd.RefreshAPIMoniter("cmdb", len(body), statsdAPIStartTime)
+	if jBody, err := simplejson.NewJson(body); err == nil {
+		c.debugger.WriteJson("cmdb", c.config.URL, []*simplejson.Json{jBody})
+	}
+
+	var doc Document
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return c.invalidResource([]string{err.Error()})
+	}
+	c.toolDataSet = NewToolDataSet()
+	resource := c.convert(&doc)
+	if len(c.toolDataSet.errs) > 0 {
+		return c.invalidResource(c.toolDataSet.errs)
+	}
+
+	c.etag = etag
+	c.resource = resource
+	c.cloudStatsd.ResCount = statsd.GetResCount(resource)
+	statsd.MetaStatsd.RegisterStatsdTable(c)
+	c.debugger.Refresh()
+	return resource, nil
+}
+
+func (c *CMDB) GetStatter() statsd.StatsdStatter {
+	globalTags := map[string]string{
+		"domain_name": c.name,
+		"domain":      c.lcuuid,
+		"platform":    common.CMDB_EN,
+	}
+
+	return statsd.StatsdStatter{
+		GlobalTags: globalTags,
+		Element:    statsd.GetCloudStatsd(c.cloudStatsd),
+	}
+}
+
+// convert 按照依赖顺序转换各类资源，校验错误记录在toolDataSet.errs中
+func (c *CMDB) convert(doc *Document) model.Resource {
+	var resource model.Resource
+	resource.Regions = c.getRegions(doc.Regions)
+	resource.AZs = c.getAZs(doc.AZs)
+	resource.VPCs = c.getVPCs(doc.VPCs)
+	resource.Networks = c.getNetworks(doc.Networks)
+	resource.Subnets = c.getSubnets(doc.Subnets)
+	resource.Hosts = c.getHosts(doc.Hosts)
+	resource.VMs = c.getVMs(doc.VMs)
+	resource.LBs = c.getLBs(doc.LBs)
+	resource.LBListeners = c.getLBListeners(doc.LBListeners)
+	resource.LBTargetServers = c.getLBTargetServers(doc.LBTargetServers)
+	resource.VInterfaces = c.getVInterfaces(doc.VInterfaces)
+	resource.IPs = c.getIPs(doc.IPs)
+	return resource
+}
+
+// invalidResource CMDB数据不符合格式时不同步任何资源，错误信息通过ErrorMessage展示
+func (c *CMDB) invalidResource(errs []string) (model.Resource, error) {
+	msgs := errs
+	if len(msgs) > MAX_ERROR_MESSAGE_NUM {
+		msgs = append(msgs[:MAX_ERROR_MESSAGE_NUM:MAX_ERROR_MESSAGE_NUM], fmt.Sprintf("and %d more errors", len(errs)-MAX_ERROR_MESSAGE_NUM))
+	}
+	msg := fmt.Sprintf("cmdb data invalid: %s", strings.Join(msgs, "; "))
+	log.Error(msg)
+	return model.Resource{
+		ErrorState:   common.RESOURCE_STATE_CODE_WARNING,
+		ErrorMessage: msg,
+	}, errors.New(msg)
+}
+
+// getData 获取CMDB数据，etag不为空且数据未变化时返回的body为nil
+func (c *CMDB) getData(etag string) ([]byte, string, error) {
+	req, err := http.NewRequest(http.MethodGet, c.config.URL, nil)
+	if err != nil {
+		log.Errorf("new request (%s) failed: %s", c.config.URL, err.Error())
+		return nil, "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range c.config.Headers {
+		req.Header.Set(k, v)
+	}
+	if c.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	client := cloudcommon.GetUnverifyHTTPClient(time.Second * time.Duration(c.httpTimeout))
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Errorf("request (%s) failed: %s", c.config.URL, err.Error())
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if etag != "" && resp.StatusCode == http.StatusNotModified {
+		return nil, etag, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = errors.New(fmt.Sprintf("request (%s) failed: %s", c.config.URL, resp.Status))
+		log.Error(err.Error())
+		return nil, "", err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Errorf("read response (%s) failed: %s", c.config.URL, err.Error())
+		return nil, "", err
+	}
+	return body, resp.Header.Get("ETag"), nil
+}
+
+func (c *CMDB) generateLcuuid(resourceType, id string) string {
+	return common.GenerateUUID(c.lcuuidGenerate + "_" + resourceType + "_" + id)
+}
+
+func (c *CMDB) addError(format string, a ...interface{}) {
+	c.toolDataSet.errs = append(c.toolDataSet.errs, fmt.Sprintf(format, a...))
+}
+
+// checkRequired 检查必填字段，kvs依次为字段名及字段值
+func (c *CMDB) checkRequired(path string, kvs ...string) bool {
+	ok := true
+	for i := 0; i+1 < len(kvs); i += 2 {
+		if kvs[i+1] == "" {
+			c.addError("%s.%s: required", path, kvs[i])
+			ok = false
+		}
+	}
+	return ok
+}
+
+// 配置了region_uuid时，所有资源均属于该区域
+func (c *CMDB) getRegionLcuuid(path, regionID string) (string, bool) {
+	if c.config.RegionLcuuid != "" {
+		return c.config.RegionLcuuid, true
+	}
+	regionLcuuid, ok := c.toolDataSet.regionIDToLcuuid[regionID]
+	if !ok {
+		c.addError("%s.region_id: region (%s) not found", path, regionID)
+	}
+	return regionLcuuid, ok
+}
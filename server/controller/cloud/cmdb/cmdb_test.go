@@ -0,0 +1,194 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdb
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	cloudconfig "github.com/deepflowio/deepflow/server/controller/cloud/config"
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/statsd"
+	statsdconfig "github.com/deepflowio/deepflow/server/controller/statsd/config"
+)
+
+const TEST_TOKEN = "test-token"
+
+// cmdbServer 模拟CMDB接口，按照ETag返回304
+type cmdbServer struct {
+	*httptest.Server
+	etag         string
+	data         []byte
+	requestCount int
+	notModified  int
+}
+
+func newCMDBServer(t *testing.T) *cmdbServer {
+	data, err := ioutil.ReadFile("./cmdb_data_sample.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &cmdbServer{etag: `"v1"`, data: data}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requestCount++
+		if r.Header.Get("Authorization") != "Bearer "+TEST_TOKEN || r.Header.Get("X-Tenant") != "default" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("If-None-Match") == s.etag {
+			s.notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", s.etag)
+		w.Write(s.data)
+	}))
+	return s
+}
+
+func newTestCMDB(serverURL, token, regionLcuuid string) *CMDB {
+	domain := mysql.Domain{Name: "test_cmdb", DisplayName: "test_cmdb"}
+	conf := &Config{
+		RegionLcuuid: regionLcuuid,
+		URL:          serverURL + "/resources",
+		Token:        token,
+		Headers:      map[string]string{"X-Tenant": "default"},
+	}
+	return newCMDB(domain, cloudconfig.CloudConfig{HTTPTimeout: 30}, conf)
+}
+
+func TestCMDB(t *testing.T) {
+	Convey("TestCMDB", t, func() {
+		cloudconfig.SetCloudGlobalConfig(cloudconfig.CloudConfig{HTTPTimeout: 30})
+		statsd.NewStatsdMonitor(statsdconfig.StatsdConfig{})
+		server := newCMDBServer(t)
+		defer server.Close()
+
+		Convey("wrong token should fail to auth", func() {
+			So(newTestCMDB(server.URL, "wrong", "").CheckAuth(), ShouldNotBeNil)
+		})
+
+		cmdb := newTestCMDB(server.URL, TEST_TOKEN, "")
+		So(cmdb.CheckAuth(), ShouldBeNil)
+
+		data, err := cmdb.GetCloudData()
+		So(err, ShouldBeNil)
+
+		Convey("resources should be converted with references resolved", func() {
+			So(len(data.Regions), ShouldEqual, 1)
+			So(len(data.AZs), ShouldEqual, 1)
+			So(data.AZs[0].RegionLcuuid, ShouldEqual, data.Regions[0].Lcuuid)
+			So(len(data.VPCs), ShouldEqual, 2)
+			So(len(data.Networks), ShouldEqual, 2)
+			So(data.Networks[1].NetType, ShouldEqual, common.NETWORK_TYPE_WAN)
+			So(len(data.Subnets), ShouldEqual, 2)
+			So(data.Subnets[1].Name, ShouldEqual, "203.0.113.0/24")
+			So(len(data.Hosts), ShouldEqual, 1)
+			So(data.Hosts[0].HType, ShouldEqual, common.HOST_HTYPE_KVM)
+			So(data.Hosts[0].RegionLcuuid, ShouldEqual, data.Regions[0].Lcuuid)
+		})
+
+		Convey("vms should be synced with launch server and tags", func() {
+			So(len(data.VMs), ShouldEqual, 2)
+			So(data.VMs[0].LaunchServer, ShouldEqual, "192.168.0.11")
+			So(data.VMs[0].State, ShouldEqual, common.VM_STATE_RUNNING)
+			So(data.VMs[0].CloudTags, ShouldResemble, map[string]string{"app": "order"})
+			So(data.VMs[0].VPCLcuuid, ShouldEqual, data.VPCs[0].Lcuuid)
+			So(data.VMs[1].State, ShouldEqual, common.VM_STATE_STOPPED)
+		})
+
+		Convey("ips without subnet should use the subnet containing the ip", func() {
+			So(len(data.VInterfaces), ShouldEqual, 4)
+			So(data.VInterfaces[2].DeviceType, ShouldEqual, common.VIF_DEVICE_TYPE_HOST)
+			So(data.VInterfaces[3].Type, ShouldEqual, common.VIF_TYPE_WAN)
+			So(len(data.IPs), ShouldEqual, 4)
+			for _, ip := range data.IPs[:3] {
+				So(ip.SubnetLcuuid, ShouldEqual, data.Subnets[0].Lcuuid)
+			}
+			So(data.IPs[3].SubnetLcuuid, ShouldEqual, data.Subnets[1].Lcuuid)
+		})
+
+		Convey("lbs should be synced with listeners and target servers", func() {
+			So(len(data.LBs), ShouldEqual, 1)
+			So(data.LBs[0].Model, ShouldEqual, common.LB_MODEL_EXTERNAL)
+			So(len(data.LBListeners), ShouldEqual, 1)
+			So(data.LBListeners[0].Name, ShouldEqual, "TCP : 80")
+			So(data.LBListeners[0].IPs, ShouldEqual, "203.0.113.10")
+			So(len(data.LBTargetServers), ShouldEqual, 2)
+			So(data.LBTargetServers[0].Type, ShouldEqual, common.LB_SERVER_TYPE_VM)
+			So(data.LBTargetServers[0].VMLcuuid, ShouldEqual, data.VMs[0].Lcuuid)
+			So(data.LBTargetServers[1].Type, ShouldEqual, common.LB_SERVER_TYPE_IP)
+			So(data.LBTargetServers[1].VPCLcuuid, ShouldEqual, data.LBs[0].VPCLcuuid)
+		})
+
+		Convey("unchanged data should be reused by etag", func() {
+			cached, err := cmdb.GetCloudData()
+			So(err, ShouldBeNil)
+			So(server.notModified, ShouldEqual, 1)
+			So(cached, ShouldResemble, data)
+		})
+
+		Convey("invalid data should be reported through error message", func() {
+			server.etag = `"v2"`
+			server.data = []byte(`{
+				"regions": [{"id": "r-1", "name": "Beijing"}, {"id": "r-1", "name": "Shanghai"}],
+				"vpcs": [{"id": "vpc-1", "name": "prod", "region_id": "r-2"}],
+				"vms": [{"id": "vm-1", "vpc_id": "vpc-1", "az_id": "az-1"}]
+			}`)
+			invalid, err := cmdb.GetCloudData()
+			So(err, ShouldNotBeNil)
+			So(invalid.ErrorState, ShouldEqual, common.RESOURCE_STATE_CODE_WARNING)
+			So(invalid.ErrorMessage, ShouldContainSubstring, "regions[1].id: duplicate id (r-1)")
+			So(invalid.ErrorMessage, ShouldContainSubstring, "vpcs[0].region_id: region (r-2) not found")
+			So(invalid.ErrorMessage, ShouldContainSubstring, "vms[0].name: required")
+			So(cmdb.etag, ShouldEqual, `"v1"`)
+
+			server.data = []byte(`{"vms": {}}`)
+			invalid, err = cmdb.GetCloudData()
+			So(err, ShouldNotBeNil)
+			So(invalid.ErrorMessage, ShouldContainSubstring, "cannot unmarshal object")
+		})
+
+		Convey("all resources should belong to region_uuid if specified", func() {
+			regionLcuuid := "ffffffff-ffff-ffff-ffff-ffffffffffff"
+			data, err := newTestCMDB(server.URL, TEST_TOKEN, regionLcuuid).GetCloudData()
+			So(err, ShouldBeNil)
+			So(len(data.Regions), ShouldEqual, 0)
+			So(data.AZs[0].RegionLcuuid, ShouldEqual, regionLcuuid)
+			So(data.VMs[0].RegionLcuuid, ShouldEqual, regionLcuuid)
+		})
+	})
+}
+
+func TestLoadConfig(t *testing.T) {
+	Convey("TestLoadConfig", t, func() {
+		var config Config
+		err := config.LoadFromString(`{"url": "https://cmdb.example.com", "headers": {"X-Tenant": "default"}}`)
+		So(err, ShouldBeNil)
+		So(config.Headers, ShouldResemble, map[string]string{"X-Tenant": "default"})
+
+		Convey("authorization header should be rejected", func() {
+			err := config.LoadFromString(`{"url": "https://cmdb.example.com", "headers": {"authorization": "Basic xxx"}}`)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdb
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bitly/go-simplejson"
+
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+type Config struct {
+	RegionLcuuid string
+	URL          string
+	// token不为空时，以Authorization: Bearer <token>的方式认证
+	Token string
+	// headers以明文保存在云平台配置中，不可包含认证信息，认证信息只能通过token配置
+	Headers map[string]string
+}
+
+func (c *Config) LoadFromString(sConf string) (err error) {
+	jConf, err := simplejson.NewJson([]byte(sConf))
+	if err != nil {
+		log.Errorf("convert config string: %s to json failed: %v", sConf, err)
+		return
+	}
+	c.URL, err = jConf.Get("url").String()
+	if err != nil {
+		log.Error("url must be specified")
+		return
+	}
+	if token := jConf.Get("token").MustString(); token != "" {
+		c.Token, err = common.DecryptSecretKey(token)
+		if err != nil {
+			log.Error("decrypt token failed")
+			return
+		}
+	}
+	c.Headers = make(map[string]string)
+	for k, v := range jConf.Get("headers").MustMap() {
+		if strings.EqualFold(k, "Authorization") {
+			err = errors.New("authorization header is not allowed, use token instead")
+			log.Error(err)
+			return
+		}
+		if value, ok := v.(string); ok {
+			c.Headers[k] = value
+		}
+	}
+	c.RegionLcuuid = jConf.Get("region_uuid").MustString()
+	return
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdb
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+var HTYPE_CONVERTION = map[string]int{
+	"":        common.HOST_HTYPE_KVM,
+	"kvm":     common.HOST_HTYPE_KVM,
+	"esxi":    common.HOST_HTYPE_ESXI,
+	"hyper_v": common.HOST_HTYPE_HYPER_V,
+	"gateway": common.HOST_HTYPE_GATEWAY,
+}
+
+func (c *CMDB) getHosts(cHosts []Host) []model.Host {
+	var hosts []model.Host
+	for i, cHost := range cHosts {
+		path := fmt.Sprintf("hosts[%d]", i)
+		if !c.checkRequired(path, "id", cHost.ID, "ip", cHost.IP, "az_id", cHost.AZID) {
+			continue
+		}
+		if _, ok := c.toolDataSet.hostIDToHost[cHost.ID]; ok {
+			c.addError("%s.id: duplicate id (%s)", path, cHost.ID)
+			continue
+		}
+		if net.ParseIP(cHost.IP) == nil {
+			c.addError("%s.ip: invalid ip (%s)", path, cHost.IP)
+			continue
+		}
+		htype, ok := HTYPE_CONVERTION[cHost.HType]
+		if !ok {
+			c.addError("%s.htype: invalid htype (%s)", path, cHost.HType)
+			continue
+		}
+		az, ok := c.toolDataSet.azIDToAZ[cHost.AZID]
+		if !ok {
+			c.addError("%s.az_id: az (%s) not found", path, cHost.AZID)
+			continue
+		}
+		name := cHost.Name
+		if name == "" {
+			name = cHost.IP
+		}
+		host := model.Host{
+			Lcuuid:       c.generateLcuuid("host", cHost.ID),
+			Name:         name,
+			IP:           cHost.IP,
+			Hostname:     cHost.Hostname,
+			Type:         common.HOST_TYPE_VM,
+			HType:        htype,
+			VCPUNum:      cHost.VCPUNum,
+			MemTotal:     cHost.MemTotal,
+			AZLcuuid:     az.Lcuuid,
+			RegionLcuuid: az.RegionLcuuid,
+		}
+		hosts = append(hosts, host)
+		c.toolDataSet.hostIDToHost[cHost.ID] = host
+	}
+	return hosts
+}
@@ -0,0 +1,189 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdb
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+var LB_MODEL_CONVERTION = map[string]int{
+	"":         common.LB_MODEL_INTERNAL,
+	"internal": common.LB_MODEL_INTERNAL,
+	"external": common.LB_MODEL_EXTERNAL,
+}
+
+func (c *CMDB) getLBs(cLBs []LB) []model.LB {
+	var lbs []model.LB
+	for i, cLB := range cLBs {
+		path := fmt.Sprintf("lbs[%d]", i)
+		if !c.checkRequired(path, "id", cLB.ID, "name", cLB.Name, "vpc_id", cLB.VPCID) {
+			continue
+		}
+		if _, ok := c.toolDataSet.lbIDToLB[cLB.ID]; ok {
+			c.addError("%s.id: duplicate id (%s)", path, cLB.ID)
+			continue
+		}
+		lbModel, ok := LB_MODEL_CONVERTION[cLB.Model]
+		if !ok {
+			c.addError("%s.model: invalid model (%s)", path, cLB.Model)
+			continue
+		}
+		if !c.checkIPs(path+".vips", cLB.VIPs) {
+			continue
+		}
+		vpc, ok := c.toolDataSet.vpcIDToVPC[cLB.VPCID]
+		if !ok {
+			c.addError("%s.vpc_id: vpc (%s) not found", path, cLB.VPCID)
+			continue
+		}
+		lb := model.LB{
+			Lcuuid:       c.generateLcuuid("lb", cLB.ID),
+			Name:         cLB.Name,
+			Label:        cLB.Label,
+			Model:        lbModel,
+			VIP:          cLB.VIPs,
+			VPCLcuuid:    vpc.Lcuuid,
+			RegionLcuuid: vpc.RegionLcuuid,
+		}
+		lbs = append(lbs, lb)
+		c.toolDataSet.lbIDToLB[cLB.ID] = lb
+		c.toolDataSet.lbLcuuidToVPCLcuuid[lb.Lcuuid] = lb.VPCLcuuid
+	}
+	return lbs
+}
+
+func (c *CMDB) getLBListeners(cListeners []LBListener) []model.LBListener {
+	var listeners []model.LBListener
+	for i, cListener := range cListeners {
+		path := fmt.Sprintf("lb_listeners[%d]", i)
+		if !c.checkRequired(path, "id", cListener.ID, "lb_id", cListener.LBID, "protocol", cListener.Protocol) {
+			continue
+		}
+		if _, ok := c.toolDataSet.lbListenerIDToListener[cListener.ID]; ok {
+			c.addError("%s.id: duplicate id (%s)", path, cListener.ID)
+			continue
+		}
+		if !c.checkPort(path, cListener.Port) || !c.checkIPs(path+".ips", cListener.IPs) {
+			continue
+		}
+		lb, ok := c.toolDataSet.lbIDToLB[cListener.LBID]
+		if !ok {
+			c.addError("%s.lb_id: lb (%s) not found", path, cListener.LBID)
+			continue
+		}
+		protocol := strings.ToUpper(cListener.Protocol)
+		name := cListener.Name
+		if name == "" {
+			name = protocol + " : " + strconv.Itoa(cListener.Port)
+		}
+		ips := cListener.IPs
+		if ips == "" {
+			ips = lb.VIP
+		}
+		listener := model.LBListener{
+			Lcuuid:   c.generateLcuuid("lb_listener", cListener.ID),
+			LBLcuuid: lb.Lcuuid,
+			Name:     name,
+			IPs:      ips,
+			Protocol: protocol,
+			Port:     cListener.Port,
+		}
+		listeners = append(listeners, listener)
+		c.toolDataSet.lbListenerIDToListener[cListener.ID] = listener
+	}
+	return listeners
+}
+
+func (c *CMDB) getLBTargetServers(cServers []LBTargetServer) []model.LBTargetServer {
+	var servers []model.LBTargetServer
+	serverLcuuids := make(map[string]bool)
+	for i, cServer := range cServers {
+		path := fmt.Sprintf("lb_target_servers[%d]", i)
+		if !c.checkRequired(path, "lb_listener_id", cServer.LBListenerID, "ip", cServer.IP) {
+			continue
+		}
+		if net.ParseIP(cServer.IP) == nil {
+			c.addError("%s.ip: invalid ip (%s)", path, cServer.IP)
+			continue
+		}
+		if !c.checkPort(path, cServer.Port) {
+			continue
+		}
+		listener, ok := c.toolDataSet.lbListenerIDToListener[cServer.LBListenerID]
+		if !ok {
+			c.addError("%s.lb_listener_id: lb_listener (%s) not found", path, cServer.LBListenerID)
+			continue
+		}
+		lcuuid := common.GenerateUUID(listener.Lcuuid + cServer.IP + strconv.Itoa(cServer.Port))
+		if serverLcuuids[lcuuid] {
+			c.addError("%s: duplicate target server (%s:%d) in lb_listener (%s)", path, cServer.IP, cServer.Port, cServer.LBListenerID)
+			continue
+		}
+		server := model.LBTargetServer{
+			Lcuuid:           lcuuid,
+			LBLcuuid:         listener.LBLcuuid,
+			LBListenerLcuuid: listener.Lcuuid,
+			Type:             common.LB_SERVER_TYPE_IP,
+			IP:               cServer.IP,
+			Protocol:         listener.Protocol,
+			Port:             cServer.Port,
+		}
+		if cServer.VMID != "" {
+			vm, ok := c.toolDataSet.vmIDToVM[cServer.VMID]
+			if !ok {
+				c.addError("%s.vm_id: vm (%s) not found", path, cServer.VMID)
+				continue
+			}
+			server.Type = common.LB_SERVER_TYPE_VM
+			server.VMLcuuid = vm.Lcuuid
+			server.VPCLcuuid = vm.VPCLcuuid
+		} else {
+			server.VPCLcuuid = c.toolDataSet.lbLcuuidToVPCLcuuid[listener.LBLcuuid]
+		}
+		servers = append(servers, server)
+		serverLcuuids[lcuuid] = true
+	}
+	return servers
+}
+
+// checkIPs 检查以逗号分隔的IP列表，允许为空
+func (c *CMDB) checkIPs(path, ips string) bool {
+	if ips == "" {
+		return true
+	}
+	for _, ip := range strings.Split(ips, ",") {
+		if net.ParseIP(ip) == nil {
+			c.addError("%s: invalid ip (%s)", path, ip)
+			return false
+		}
+	}
+	return true
+}
+
+func (c *CMDB) checkPort(path string, port int) bool {
+	if port <= 0 || port > 65535 {
+		c.addError("%s.port: invalid port (%d)", path, port)
+		return false
+	}
+	return true
+}
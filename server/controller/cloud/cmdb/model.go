@@ -0,0 +1,151 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdb
+
+// Document CMDB接口返回的数据格式，与model.Resource对应，示例见cmdb_data_sample.json
+// 资源之间通过CMDB中的id关联，id在同类资源中唯一，lcuuid由id生成
+type Document struct {
+	Regions         []Region         `json:"regions"`
+	AZs             []AZ             `json:"azs"`
+	VPCs            []VPC            `json:"vpcs"`
+	Networks        []Network        `json:"networks"`
+	Subnets         []Subnet         `json:"subnets"`
+	Hosts           []Host           `json:"hosts"`
+	VMs             []VM             `json:"vms"`
+	VInterfaces     []VInterface     `json:"vinterfaces"`
+	IPs             []IP             `json:"ips"`
+	LBs             []LB             `json:"lbs"`
+	LBListeners     []LBListener     `json:"lb_listeners"`
+	LBTargetServers []LBTargetServer `json:"lb_target_servers"`
+}
+
+// 配置了region_uuid时忽略regions，其他资源的region_id可以为空
+type Region struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Label string `json:"label"`
+}
+
+type AZ struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Label    string `json:"label"`
+	RegionID string `json:"region_id"`
+}
+
+type VPC struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Label    string `json:"label"`
+	CIDR     string `json:"cidr"`
+	RegionID string `json:"region_id"`
+}
+
+// NetType: lan（默认）或wan
+type Network struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Label          string `json:"label"`
+	NetType        string `json:"net_type"`
+	SegmentationID int    `json:"segmentation_id"`
+	External       bool   `json:"external"`
+	Shared         bool   `json:"shared"`
+	VPCID          string `json:"vpc_id"`
+	AZID           string `json:"az_id"`
+	RegionID       string `json:"region_id"`
+}
+
+type Subnet struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Label     string `json:"label"`
+	CIDR      string `json:"cidr"`
+	GatewayIP string `json:"gateway_ip"`
+	NetworkID string `json:"network_id"`
+}
+
+// HType: kvm（默认）、esxi、hyper_v或gateway，宿主机的区域与可用区一致
+type Host struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	IP       string `json:"ip"`
+	Hostname string `json:"hostname"`
+	HType    string `json:"htype"`
+	VCPUNum  int    `json:"vcpu_num"`
+	MemTotal int    `json:"mem_total"` // MB
+	AZID     string `json:"az_id"`
+}
+
+// State: running（默认）、stopped或exception，虚拟机的区域与可用区一致
+type VM struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Label     string            `json:"label"`
+	IP        string            `json:"ip"`
+	Hostname  string            `json:"hostname"`
+	State     string            `json:"state"`
+	HostID    string            `json:"host_id"`
+	CreatedAt string            `json:"created_at"` // RFC3339
+	Tags      map[string]string `json:"tags"`
+	VPCID     string            `json:"vpc_id"`
+	AZID      string            `json:"az_id"`
+}
+
+// DeviceType: vm、host或lb，DeviceID为对应资源的id
+type VInterface struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Mac        string `json:"mac"`
+	DeviceType string `json:"device_type"`
+	DeviceID   string `json:"device_id"`
+	NetworkID  string `json:"network_id"`
+}
+
+// SubnetID为空时，使用网络中包含该IP的子网
+type IP struct {
+	IP           string `json:"ip"`
+	VInterfaceID string `json:"vinterface_id"`
+	SubnetID     string `json:"subnet_id"`
+}
+
+// Model: internal（默认）或external，VIPs中的多个IP以逗号分隔，负载均衡器的区域与VPC一致
+type LB struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Model string `json:"model"`
+	VIPs  string `json:"vips"`
+	VPCID string `json:"vpc_id"`
+}
+
+// Name为空时使用"协议 : 端口"，IPs为空时使用负载均衡器的VIPs
+type LBListener struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	LBID     string `json:"lb_id"`
+	IPs      string `json:"ips"`
+	Protocol string `json:"protocol"`
+	Port     int    `json:"port"`
+}
+
+// VMID不为空时，后端为虚拟机，否则为IP
+type LBTargetServer struct {
+	LBListenerID string `json:"lb_listener_id"`
+	IP           string `json:"ip"`
+	Port         int    `json:"port"`
+	VMID         string `json:"vm_id"`
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdb
+
+import (
+	"fmt"
+
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+)
+
+func (c *CMDB) getRegions(cRegions []Region) []model.Region {
+	var regions []model.Region
+	if c.config.RegionLcuuid != "" {
+		return regions
+	}
+	for i, cRegion := range cRegions {
+		path := fmt.Sprintf("regions[%d]", i)
+		if !c.checkRequired(path, "id", cRegion.ID, "name", cRegion.Name) {
+			continue
+		}
+		if _, ok := c.toolDataSet.regionIDToLcuuid[cRegion.ID]; ok {
+			c.addError("%s.id: duplicate id (%s)", path, cRegion.ID)
+			continue
+		}
+		region := model.Region{
+			Lcuuid: c.generateLcuuid("region", cRegion.ID),
+			Name:   cRegion.Name,
+			Label:  cRegion.Label,
+		}
+		regions = append(regions, region)
+		c.toolDataSet.regionIDToLcuuid[cRegion.ID] = region.Lcuuid
+	}
+	return regions
+}
+
+func (c *CMDB) getAZs(cAZs []AZ) []model.AZ {
+	var azs []model.AZ
+	for i, cAZ := range cAZs {
+		path := fmt.Sprintf("azs[%d]", i)
+		if !c.checkRequired(path, "id", cAZ.ID, "name", cAZ.Name) {
+			continue
+		}
+		if _, ok := c.toolDataSet.azIDToAZ[cAZ.ID]; ok {
+			c.addError("%s.id: duplicate id (%s)", path, cAZ.ID)
+			continue
+		}
+		regionLcuuid, ok := c.getRegionLcuuid(path, cAZ.RegionID)
+		if !ok {
+			continue
+		}
+		az := model.AZ{
+			Lcuuid:       c.generateLcuuid("az", cAZ.ID),
+			Name:         cAZ.Name,
+			Label:        cAZ.Label,
+			RegionLcuuid: regionLcuuid,
+		}
+		azs = append(azs, az)
+		c.toolDataSet.azIDToAZ[cAZ.ID] = az
+	}
+	return azs
+}
+
+// getAZ 返回资源所属的可用区，可用区与资源须属于同一区域
+func (c *CMDB) getAZ(path, azID, regionLcuuid string) (model.AZ, bool) {
+	az, ok := c.toolDataSet.azIDToAZ[azID]
+	if !ok {
+		c.addError("%s.az_id: az (%s) not found", path, azID)
+		return az, false
+	}
+	if az.RegionLcuuid != regionLcuuid {
+		c.addError("%s.az_id: az (%s) not in region", path, azID)
+		return az, false
+	}
+	return az, true
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdb
+
+import (
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+)
+
+// 以CMDB中资源id为key的map
+type ToolDataSet struct {
+	regionIDToLcuuid       map[string]string
+	azIDToAZ               map[string]model.AZ
+	vpcIDToVPC             map[string]model.VPC
+	networkIDToNetwork     map[string]model.Network
+	subnetIDToSubnet       map[string]model.Subnet
+	networkLcuuidToSubnets map[string][]model.Subnet
+	hostIDToHost           map[string]model.Host
+	vmIDToVM               map[string]model.VM
+	vinterfaceIDToVIF      map[string]model.VInterface
+	lbIDToLB               map[string]model.LB
+	lbListenerIDToListener map[string]model.LBListener
+	lbLcuuidToVPCLcuuid    map[string]string
+	errs                   []string
+}
+
+func NewToolDataSet() *ToolDataSet {
+	return &ToolDataSet{
+		regionIDToLcuuid:       make(map[string]string),
+		azIDToAZ:               make(map[string]model.AZ),
+		vpcIDToVPC:             make(map[string]model.VPC),
+		networkIDToNetwork:     make(map[string]model.Network),
+		subnetIDToSubnet:       make(map[string]model.Subnet),
+		networkLcuuidToSubnets: make(map[string][]model.Subnet),
+		hostIDToHost:           make(map[string]model.Host),
+		vmIDToVM:               make(map[string]model.VM),
+		vinterfaceIDToVIF:      make(map[string]model.VInterface),
+		lbIDToLB:               make(map[string]model.LB),
+		lbListenerIDToListener: make(map[string]model.LBListener),
+		lbLcuuidToVPCLcuuid:    make(map[string]string),
+	}
+}
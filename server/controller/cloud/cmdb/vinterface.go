@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdb
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+var DEVICE_TYPE_CONVERTION = map[string]int{
+	"vm":   common.VIF_DEVICE_TYPE_VM,
+	"host": common.VIF_DEVICE_TYPE_HOST,
+	"lb":   common.VIF_DEVICE_TYPE_LB,
+}
+
+func (c *CMDB) getVInterfaces(cVIFs []VInterface) []model.VInterface {
+	var vifs []model.VInterface
+	for i, cVIF := range cVIFs {
+		path := fmt.Sprintf("vinterfaces[%d]", i)
+		if !c.checkRequired(path, "id", cVIF.ID, "device_type", cVIF.DeviceType, "device_id", cVIF.DeviceID, "network_id", cVIF.NetworkID) {
+			continue
+		}
+		if _, ok := c.toolDataSet.vinterfaceIDToVIF[cVIF.ID]; ok {
+			c.addError("%s.id: duplicate id (%s)", path, cVIF.ID)
+			continue
+		}
+		mac := common.VIF_DEFAULT_MAC
+		if cVIF.Mac != "" {
+			hwAddr, err := net.ParseMAC(cVIF.Mac)
+			if err != nil {
+				c.addError("%s.mac: invalid mac (%s)", path, cVIF.Mac)
+				continue
+			}
+			mac = hwAddr.String()
+		}
+		deviceType, ok := DEVICE_TYPE_CONVERTION[cVIF.DeviceType]
+		if !ok {
+			c.addError("%s.device_type: invalid device_type (%s)", path, cVIF.DeviceType)
+			continue
+		}
+		deviceLcuuid, ok := c.getDeviceLcuuid(deviceType, cVIF.DeviceID)
+		if !ok {
+			c.addError("%s.device_id: %s (%s) not found", path, cVIF.DeviceType, cVIF.DeviceID)
+			continue
+		}
+		network, ok := c.toolDataSet.networkIDToNetwork[cVIF.NetworkID]
+		if !ok {
+			c.addError("%s.network_id: network (%s) not found", path, cVIF.NetworkID)
+			continue
+		}
+		vif := model.VInterface{
+			Lcuuid:        c.generateLcuuid("vinterface", cVIF.ID),
+			Name:          cVIF.Name,
+			Type:          network.NetType,
+			Mac:           mac,
+			DeviceLcuuid:  deviceLcuuid,
+			DeviceType:    deviceType,
+			NetworkLcuuid: network.Lcuuid,
+			VPCLcuuid:     network.VPCLcuuid,
+			RegionLcuuid:  network.RegionLcuuid,
+		}
+		vifs = append(vifs, vif)
+		c.toolDataSet.vinterfaceIDToVIF[cVIF.ID] = vif
+	}
+	return vifs
+}
+
+func (c *CMDB) getDeviceLcuuid(deviceType int, deviceID string) (string, bool) {
+	switch deviceType {
+	case common.VIF_DEVICE_TYPE_VM:
+		vm, ok := c.toolDataSet.vmIDToVM[deviceID]
+		return vm.Lcuuid, ok
+	case common.VIF_DEVICE_TYPE_HOST:
+		host, ok := c.toolDataSet.hostIDToHost[deviceID]
+		return host.Lcuuid, ok
+	case common.VIF_DEVICE_TYPE_LB:
+		lb, ok := c.toolDataSet.lbIDToLB[deviceID]
+		return lb.Lcuuid, ok
+	}
+	return "", false
+}
+
+func (c *CMDB) getIPs(cIPs []IP) []model.IP {
+	var ips []model.IP
+	ipLcuuids := make(map[string]bool)
+	for i, cIP := range cIPs {
+		path := fmt.Sprintf("ips[%d]", i)
+		if !c.checkRequired(path, "ip", cIP.IP, "vinterface_id", cIP.VInterfaceID) {
+			continue
+		}
+		if net.ParseIP(cIP.IP) == nil {
+			c.addError("%s.ip: invalid ip (%s)", path, cIP.IP)
+			continue
+		}
+		vif, ok := c.toolDataSet.vinterfaceIDToVIF[cIP.VInterfaceID]
+		if !ok {
+			c.addError("%s.vinterface_id: vinterface (%s) not found", path, cIP.VInterfaceID)
+			continue
+		}
+		lcuuid := common.GenerateUUID(vif.Lcuuid + cIP.IP)
+		if ipLcuuids[lcuuid] {
+			c.addError("%s.ip: duplicate ip (%s) in vinterface (%s)", path, cIP.IP, cIP.VInterfaceID)
+			continue
+		}
+		// 未指定子网时，使用网卡所在网络中包含该IP的子网
+		subnetLcuuid := c.getSubnetLcuuid(vif.NetworkLcuuid, cIP.IP)
+		if cIP.SubnetID != "" {
+			subnet, ok := c.toolDataSet.subnetIDToSubnet[cIP.SubnetID]
+			if !ok || subnet.NetworkLcuuid != vif.NetworkLcuuid {
+				c.addError("%s.subnet_id: subnet (%s) not found in network of vinterface (%s)", path, cIP.SubnetID, cIP.VInterfaceID)
+				continue
+			}
+			subnetLcuuid = subnet.Lcuuid
+		}
+		ips = append(ips, model.IP{
+			Lcuuid:           lcuuid,
+			VInterfaceLcuuid: vif.Lcuuid,
+			IP:               cIP.IP,
+			SubnetLcuuid:     subnetLcuuid,
+			RegionLcuuid:     vif.RegionLcuuid,
+		})
+		ipLcuuids[lcuuid] = true
+	}
+	return ips
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdb
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+var STATE_CONVERTION = map[string]int{
+	"":          common.VM_STATE_RUNNING,
+	"running":   common.VM_STATE_RUNNING,
+	"stopped":   common.VM_STATE_STOPPED,
+	"exception": common.VM_STATE_EXCEPTION,
+}
+
+func (c *CMDB) getVMs(cVMs []VM) []model.VM {
+	var vms []model.VM
+	for i, cVM := range cVMs {
+		path := fmt.Sprintf("vms[%d]", i)
+		if !c.checkRequired(path, "id", cVM.ID, "name", cVM.Name, "vpc_id", cVM.VPCID, "az_id", cVM.AZID) {
+			continue
+		}
+		if _, ok := c.toolDataSet.vmIDToVM[cVM.ID]; ok {
+			c.addError("%s.id: duplicate id (%s)", path, cVM.ID)
+			continue
+		}
+		if cVM.IP != "" && net.ParseIP(cVM.IP) == nil {
+			c.addError("%s.ip: invalid ip (%s)", path, cVM.IP)
+			continue
+		}
+		state, ok := STATE_CONVERTION[cVM.State]
+		if !ok {
+			c.addError("%s.state: invalid state (%s)", path, cVM.State)
+			continue
+		}
+		vpc, ok := c.toolDataSet.vpcIDToVPC[cVM.VPCID]
+		if !ok {
+			c.addError("%s.vpc_id: vpc (%s) not found", path, cVM.VPCID)
+			continue
+		}
+		az, ok := c.getAZ(path, cVM.AZID, vpc.RegionLcuuid)
+		if !ok {
+			continue
+		}
+		var launchServer string
+		if cVM.HostID != "" {
+			host, ok := c.toolDataSet.hostIDToHost[cVM.HostID]
+			if !ok {
+				c.addError("%s.host_id: host (%s) not found", path, cVM.HostID)
+				continue
+			}
+			launchServer = host.IP
+		}
+		var createdAt time.Time
+		if cVM.CreatedAt != "" {
+			var err error
+			createdAt, err = time.Parse(time.RFC3339, cVM.CreatedAt)
+			if err != nil {
+				c.addError("%s.created_at: invalid time (%s)", path, cVM.CreatedAt)
+				continue
+			}
+		}
+		vm := model.VM{
+			Lcuuid:       c.generateLcuuid("vm", cVM.ID),
+			Name:         cVM.Name,
+			Label:        cVM.Label,
+			IP:           cVM.IP,
+			Hostname:     cVM.Hostname,
+			HType:        common.VM_HTYPE_VM_C,
+			State:        state,
+			LaunchServer: launchServer,
+			CreatedAt:    createdAt,
+			VPCLcuuid:    vpc.Lcuuid,
+			AZLcuuid:     az.Lcuuid,
+			RegionLcuuid: az.RegionLcuuid,
+			CloudTags:    make(map[string]string),
+		}
+		for k, v := range cVM.Tags {
+			vm.CloudTags[k] = v
+		}
+		vms = append(vms, vm)
+		c.toolDataSet.vmIDToVM[cVM.ID] = vm
+	}
+	return vms
+}
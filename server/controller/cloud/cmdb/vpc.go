@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmdb
+
+import (
+	"fmt"
+	"net"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+var NET_TYPE_CONVERTION = map[string]int{
+	"":    common.NETWORK_TYPE_LAN,
+	"lan": common.NETWORK_TYPE_LAN,
+	"wan": common.NETWORK_TYPE_WAN,
+}
+
+func (c *CMDB) getVPCs(cVPCs []VPC) []model.VPC {
+	var vpcs []model.VPC
+	for i, cVPC := range cVPCs {
+		path := fmt.Sprintf("vpcs[%d]", i)
+		if !c.checkRequired(path, "id", cVPC.ID, "name", cVPC.Name) {
+			continue
+		}
+		if _, ok := c.toolDataSet.vpcIDToVPC[cVPC.ID]; ok {
+			c.addError("%s.id: duplicate id (%s)", path, cVPC.ID)
+			continue
+		}
+		if cVPC.CIDR != "" {
+			if _, _, err := net.ParseCIDR(cVPC.CIDR); err != nil {
+				c.addError("%s.cidr: invalid cidr (%s)", path, cVPC.CIDR)
+				continue
+			}
+		}
+		regionLcuuid, ok := c.getRegionLcuuid(path, cVPC.RegionID)
+		if !ok {
+			continue
+		}
+		vpc := model.VPC{
+			Lcuuid:       c.generateLcuuid("vpc", cVPC.ID),
+			Name:         cVPC.Name,
+			Label:        cVPC.Label,
+			CIDR:         cVPC.CIDR,
+			RegionLcuuid: regionLcuuid,
+		}
+		vpcs = append(vpcs, vpc)
+		c.toolDataSet.vpcIDToVPC[cVPC.ID] = vpc
+	}
+	return vpcs
+}
+
+func (c *CMDB) getNetworks(cNetworks []Network) []model.Network {
+	var networks []model.Network
+	for i, cNetwork := range cNetworks {
+		path := fmt.Sprintf("networks[%d]", i)
+		if !c.checkRequired(path, "id", cNetwork.ID, "name", cNetwork.Name, "vpc_id", cNetwork.VPCID) {
+			continue
+		}
+		if _, ok := c.toolDataSet.networkIDToNetwork[cNetwork.ID]; ok {
+			c.addError("%s.id: duplicate id (%s)", path, cNetwork.ID)
+			continue
+		}
+		netType, ok := NET_TYPE_CONVERTION[cNetwork.NetType]
+		if !ok {
+			c.addError("%s.net_type: invalid net_type (%s)", path, cNetwork.NetType)
+			continue
+		}
+		vpc, ok := c.toolDataSet.vpcIDToVPC[cNetwork.VPCID]
+		if !ok {
+			c.addError("%s.vpc_id: vpc (%s) not found", path, cNetwork.VPCID)
+			continue
+		}
+		// 网络的区域与VPC一致
+		var azLcuuid string
+		if cNetwork.AZID != "" {
+			az, ok := c.getAZ(path, cNetwork.AZID, vpc.RegionLcuuid)
+			if !ok {
+				continue
+			}
+			azLcuuid = az.Lcuuid
+		}
+		network := model.Network{
+			Lcuuid:         c.generateLcuuid("network", cNetwork.ID),
+			Name:           cNetwork.Name,
+			Label:          cNetwork.Label,
+			SegmentationID: cNetwork.SegmentationID,
+			Shared:         cNetwork.Shared,
+			External:       cNetwork.External,
+			NetType:        netType,
+			VPCLcuuid:      vpc.Lcuuid,
+			AZLcuuid:       azLcuuid,
+			RegionLcuuid:   vpc.RegionLcuuid,
+		}
+		networks = append(networks, network)
+		c.toolDataSet.networkIDToNetwork[cNetwork.ID] = network
+	}
+	return networks
+}
+
+func (c *CMDB) getSubnets(cSubnets []Subnet) []model.Subnet {
+	var subnets []model.Subnet
+	for i, cSubnet := range cSubnets {
+		path := fmt.Sprintf("subnets[%d]", i)
+		if !c.checkRequired(path, "id", cSubnet.ID, "cidr", cSubnet.CIDR, "network_id", cSubnet.NetworkID) {
+			continue
+		}
+		if _, ok := c.toolDataSet.subnetIDToSubnet[cSubnet.ID]; ok {
+			c.addError("%s.id: duplicate id (%s)", path, cSubnet.ID)
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(cSubnet.CIDR)
+		if err != nil {
+			c.addError("%s.cidr: invalid cidr (%s)", path, cSubnet.CIDR)
+			continue
+		}
+		if cSubnet.GatewayIP != "" && !ipNet.Contains(net.ParseIP(cSubnet.GatewayIP)) {
+			c.addError("%s.gateway_ip: ip (%s) not in cidr (%s)", path, cSubnet.GatewayIP, cSubnet.CIDR)
+			continue
+		}
+		network, ok := c.toolDataSet.networkIDToNetwork[cSubnet.NetworkID]
+		if !ok {
+			c.addError("%s.network_id: network (%s) not found", path, cSubnet.NetworkID)
+			continue
+		}
+		name := cSubnet.Name
+		if name == "" {
+			name = cSubnet.CIDR
+		}
+		subnet := model.Subnet{
+			Lcuuid:        c.generateLcuuid("subnet", cSubnet.ID),
+			Name:          name,
+			Label:         cSubnet.Label,
+			CIDR:          ipNet.String(),
+			GatewayIP:     cSubnet.GatewayIP,
+			NetworkLcuuid: network.Lcuuid,
+			VPCLcuuid:     network.VPCLcuuid,
+		}
+		subnets = append(subnets, subnet)
+		c.toolDataSet.subnetIDToSubnet[cSubnet.ID] = subnet
+		c.toolDataSet.networkLcuuidToSubnets[network.Lcuuid] = append(c.toolDataSet.networkLcuuidToSubnets[network.Lcuuid], subnet)
+	}
+	return subnets
+}
+
+// getSubnetLcuuid 返回网络中包含ip的子网
+func (c *CMDB) getSubnetLcuuid(networkLcuuid, ip string) string {
+	for _, subnet := range c.toolDataSet.networkLcuuidToSubnets[networkLcuuid] {
+		if cloudcommon.IsIPInCIDR(ip, subnet.CIDR) {
+			return subnet.Lcuuid
+		}
+	}
+	return ""
+}
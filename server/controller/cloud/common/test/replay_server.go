@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// ReplayEndpoint 在回放的响应中会被替换为服务的URL
+const ReplayEndpoint = "{{endpoint}}"
+
+// AuthChecker 在回放响应前校验请求，校验失败时需自行写入响应并返回false；
+// 校验通过时也可以设置响应头和状态码，回放的响应体随后写入
+type AuthChecker func(w http.ResponseWriter, r *http.Request) bool
+
+// NewReplayServer 返回按请求URI回放文件中记录的API响应的服务，文件内容为请求URI到响应体的JSON对象，
+// 未记录的URI返回404
+func NewReplayServer(t *testing.T, file string, checkAuth AuthChecker) *httptest.Server {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var responses map[string]json.RawMessage
+	if err := json.Unmarshal(data, &responses); err != nil {
+		t.Fatal(err)
+	}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, ok := responses[r.URL.RequestURI()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if checkAuth != nil && !checkAuth(w, r) {
+			return
+		}
+		w.Write([]byte(strings.ReplaceAll(string(resp), ReplayEndpoint, server.URL)))
+	}))
+	return server
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+// nova内部服务所在的可用区，不包含计算节点
+const INTERNAL_AZ_NAME = "internal"
+
+func (o *OpenStack) getAZs(regionID, regionLcuuid, computeURL, token string) ([]model.AZ, error) {
+	var azs []model.AZ
+	jAZs, err := o.getRawData(computeURL+"/os-availability-zone/detail", token, "availabilityZoneInfo")
+	if err != nil {
+		return nil, err
+	}
+	for i := range jAZs {
+		ja := jAZs[i]
+		zname := ja.Get("zoneName").MustString()
+		if !cloudcommon.CheckJsonAttributes(ja, []string{"zoneName"}) || zname == INTERNAL_AZ_NAME {
+			log.Infof("exclude az: %s", zname)
+			continue
+		}
+		lcuuid := common.GenerateUUID(regionID + "_" + zname + "_" + o.lcuuidGenerate)
+		azs = append(
+			azs,
+			model.AZ{
+				Lcuuid:       lcuuid,
+				Name:         zname,
+				RegionLcuuid: regionLcuuid,
+			},
+		)
+		o.toolDataSet.keyToAZLcuuid[RegionNameKey{regionID, zname}] = lcuuid
+		for host := range ja.Get("hosts").MustMap() {
+			o.toolDataSet.keyToHostAZLcuuid[RegionNameKey{regionID, host}] = lcuuid
+		}
+	}
+	return azs, nil
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	"strings"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+)
+
+func (o *OpenStack) getFloatingIPs(regionLcuuid, networkURL, token string) ([]model.FloatingIP, error) {
+	var floatingIPs []model.FloatingIP
+	jFIPs, err := o.getRawData(networkURL+"/v2.0/floatingips", token, "floatingips")
+	if err != nil {
+		return nil, err
+	}
+	for i := range jFIPs {
+		jf := jFIPs[i]
+		id := jf.Get("id").MustString()
+		if !cloudcommon.CheckJsonAttributes(jf, []string{"id", "floating_ip_address", "floating_network_id", "port_id"}) {
+			log.Infof("exclude floating_ip: %s, missing attr", id)
+			continue
+		}
+		ip := jf.Get("floating_ip_address").MustString()
+		portID := jf.Get("port_id").MustString()
+		port, ok := o.toolDataSet.portIDToPort[portID]
+		if !ok {
+			log.Infof("exclude floating_ip: %s, not associated", ip)
+			continue
+		}
+		o.toolDataSet.portIDToFloatingIP[portID] = ip
+		// 只记录虚拟机的浮动IP，负载均衡器的浮动IP用于确定其类型
+		if !strings.HasPrefix(port.DeviceOwner, DEVICE_OWNER_COMPUTE_PREFIX) {
+			continue
+		}
+		network, ok := o.toolDataSet.lcuuidToNetwork[jf.Get("floating_network_id").MustString()]
+		if !ok {
+			log.Infof("exclude floating_ip: %s, missing network info", ip)
+			continue
+		}
+		floatingIPs = append(
+			floatingIPs,
+			model.FloatingIP{
+				Lcuuid:        id,
+				IP:            ip,
+				VMLcuuid:      port.DeviceID,
+				NetworkLcuuid: network.Lcuuid,
+				VPCLcuuid:     o.toolDataSet.lcuuidToNetwork[port.NetworkLcuuid].VPCLcuuid,
+				RegionLcuuid:  regionLcuuid,
+			},
+		)
+	}
+	return floatingIPs, nil
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	"strings"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+var HYPERVISOR_TYPE_CONVERTION = map[string]int{
+	"QEMU":                  common.HOST_HTYPE_KVM,
+	"KVM":                   common.HOST_HTYPE_KVM,
+	"VMWARE VCENTER SERVER": common.HOST_HTYPE_ESXI,
+	"HYPERV":                common.HOST_HTYPE_HYPER_V,
+}
+
+func (o *OpenStack) getHosts(regionID, regionLcuuid, computeURL, token string) ([]model.Host, error) {
+	var hosts []model.Host
+	jHosts, err := o.getRawData(computeURL+"/os-hypervisors/detail", token, "hypervisors")
+	if err != nil {
+		return nil, err
+	}
+	for i := range jHosts {
+		jh := jHosts[i]
+		hostname := jh.Get("hypervisor_hostname").MustString()
+		if !cloudcommon.CheckJsonAttributes(jh, []string{"hypervisor_hostname", "host_ip"}) {
+			log.Infof("exclude host: %s, missing attr", hostname)
+			continue
+		}
+		ip := jh.Get("host_ip").MustString()
+		// 计算节点的可用区由nova service host确定
+		azLcuuid := o.toolDataSet.keyToHostAZLcuuid[RegionNameKey{regionID, jh.Get("service").Get("host").MustString()}]
+		if azLcuuid == "" {
+			azLcuuid = o.toolDataSet.keyToHostAZLcuuid[RegionNameKey{regionID, strings.Split(hostname, ".")[0]}]
+		}
+		htype, ok := HYPERVISOR_TYPE_CONVERTION[strings.ToUpper(jh.Get("hypervisor_type").MustString())]
+		if !ok {
+			htype = common.HOST_HTYPE_KVM
+		}
+		hosts = append(
+			hosts,
+			model.Host{
+				Lcuuid:       common.GenerateUUID(regionID + "_" + hostname + "_" + o.lcuuidGenerate),
+				Name:         hostname,
+				IP:           ip,
+				Hostname:     hostname,
+				Type:         common.HOST_TYPE_VM,
+				HType:        htype,
+				VCPUNum:      jh.Get("vcpus").MustInt(),
+				MemTotal:     jh.Get("memory_mb").MustInt(),
+				AZLcuuid:     azLcuuid,
+				RegionLcuuid: regionLcuuid,
+			},
+		)
+		o.toolDataSet.keyToHostIP[RegionNameKey{regionID, hostname}] = ip
+		o.toolDataSet.azLcuuidToResourceNum[azLcuuid]++
+		o.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+	}
+	return hosts, nil
+}
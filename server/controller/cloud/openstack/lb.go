@@ -0,0 +1,180 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	"fmt"
+	"strings"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+func (o *OpenStack) getLBs(regionLcuuid, lbURL, token string) (
+	lbs []model.LB, lbListeners []model.LBListener, lbTargetServers []model.LBTargetServer, vifs []model.VInterface, ips []model.IP, err error,
+) {
+	jLBs, err := o.getRawData(lbURL+"/v2/lbaas/loadbalancers", token, "loadbalancers")
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+	for i := range jLBs {
+		jLB := jLBs[i]
+		name := jLB.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jLB, []string{"id", "name", "vip_address", "vip_port_id", "vip_subnet_id", "vip_network_id"}) {
+			log.Infof("exclude lb: %s, missing attr", name)
+			continue
+		}
+		network, ok := o.toolDataSet.lcuuidToNetwork[jLB.Get("vip_network_id").MustString()]
+		if !ok {
+			log.Infof("exclude lb: %s, missing network info", name)
+			continue
+		}
+		id := jLB.Get("id").MustString()
+		vipPortID := jLB.Get("vip_port_id").MustString()
+		ip := jLB.Get("vip_address").MustString()
+		lbModel := cloudcommon.LB_MODEL_INTERNAL
+		if _, ok := o.toolDataSet.portIDToFloatingIP[vipPortID]; ok {
+			lbModel = cloudcommon.LB_MODEL_EXTERNAL
+		}
+		lb := model.LB{
+			Lcuuid:       id,
+			Name:         name,
+			Label:        id,
+			Model:        lbModel,
+			VIP:          ip,
+			VPCLcuuid:    network.VPCLcuuid,
+			RegionLcuuid: regionLcuuid,
+		}
+		lbs = append(lbs, lb)
+		o.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+		o.toolDataSet.lbLcuuidToIP[id] = ip
+		o.toolDataSet.lbLcuuidToVPCLcuuid[id] = lb.VPCLcuuid
+
+		mac := common.VIF_DEFAULT_MAC
+		if port, ok := o.toolDataSet.portIDToPort[vipPortID]; ok {
+			mac = port.Mac
+		}
+		vifs = append(
+			vifs,
+			model.VInterface{
+				Lcuuid:        vipPortID,
+				Type:          common.VIF_TYPE_LAN,
+				Mac:           mac,
+				DeviceType:    common.VIF_DEVICE_TYPE_LB,
+				DeviceLcuuid:  id,
+				NetworkLcuuid: network.Lcuuid,
+				VPCLcuuid:     network.VPCLcuuid,
+				RegionLcuuid:  regionLcuuid,
+			},
+		)
+		ips = append(
+			ips,
+			model.IP{
+				Lcuuid:           common.GenerateUUID(vipPortID + ip),
+				VInterfaceLcuuid: vipPortID,
+				IP:               ip,
+				SubnetLcuuid:     jLB.Get("vip_subnet_id").MustString(),
+				RegionLcuuid:     regionLcuuid,
+			},
+		)
+	}
+
+	lbListeners, lbTargetServers, err = o.getListenersAndTargetServers(lbURL, token)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+	return
+}
+
+func (o *OpenStack) getListenersAndTargetServers(lbURL, token string) (lbListeners []model.LBListener, lbTargetServers []model.LBTargetServer, err error) {
+	jLs, err := o.getRawData(lbURL+"/v2/lbaas/listeners", token, "listeners")
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range jLs {
+		jL := jLs[i]
+		name := jL.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jL, []string{"id", "name", "loadbalancers", "protocol", "protocol_port"}) {
+			log.Infof("exclude lb_listener: %s, missing attr", name)
+			continue
+		}
+		var lbLcuuid string
+		jLBs := jL.Get("loadbalancers")
+		if len(jLBs.MustArray()) > 0 {
+			lbLcuuid = jLBs.GetIndex(0).Get("id").MustString()
+		}
+		if _, ok := o.toolDataSet.lbLcuuidToIP[lbLcuuid]; !ok {
+			log.Infof("exclude lb_listener: %s, missing lb info", name)
+			continue
+		}
+		listenerID := jL.Get("id").MustString()
+		protocol := jL.Get("protocol").MustString()
+		if strings.Contains(protocol, "HTTPS") {
+			protocol = "HTTPS"
+		}
+		lbListeners = append(
+			lbListeners,
+			model.LBListener{
+				Lcuuid:   listenerID,
+				Name:     name,
+				LBLcuuid: lbLcuuid,
+				IPs:      o.toolDataSet.lbLcuuidToIP[lbLcuuid],
+				Protocol: protocol,
+				Port:     jL.Get("protocol_port").MustInt(),
+			},
+		)
+
+		poolID := jL.Get("default_pool_id").MustString()
+		if poolID == "" {
+			continue
+		}
+		jMembers, err := o.getRawData(fmt.Sprintf("%s/v2/lbaas/pools/%s/members", lbURL, poolID), token, "members")
+		if err != nil {
+			return nil, nil, err
+		}
+		for j := range jMembers {
+			jm := jMembers[j]
+			memberID := jm.Get("id").MustString()
+			if !cloudcommon.CheckJsonAttributes(jm, []string{"id", "address", "protocol_port", "subnet_id"}) {
+				log.Infof("exclude lb_target_server: %s, missing attr", memberID)
+				continue
+			}
+			ip := jm.Get("address").MustString()
+			vmLcuuid, ok := o.toolDataSet.keyToVMLcuuid[SubnetIPKey{jm.Get("subnet_id").MustString(), ip}]
+			if !ok {
+				log.Infof("exclude lb_target_server: %s, missing vm info", memberID)
+				continue
+			}
+			lbTargetServers = append(
+				lbTargetServers,
+				model.LBTargetServer{
+					Lcuuid:           memberID,
+					LBLcuuid:         lbLcuuid,
+					LBListenerLcuuid: listenerID,
+					Type:             common.LB_SERVER_TYPE_VM,
+					IP:               ip,
+					VMLcuuid:         vmLcuuid,
+					Protocol:         protocol,
+					Port:             jm.Get("protocol_port").MustInt(),
+					VPCLcuuid:        o.toolDataSet.lbLcuuidToVPCLcuuid[lbLcuuid],
+				},
+			)
+		}
+	}
+	return
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+func (o *OpenStack) getNetworks(regionID, regionLcuuid, networkURL, token string) ([]model.Network, []model.Subnet, error) {
+	var networks []model.Network
+	var subnets []model.Subnet
+
+	jNetworks, err := o.getRawData(networkURL+"/v2.0/networks", token, "networks")
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range jNetworks {
+		jn := jNetworks[i]
+		name := jn.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jn, []string{"id", "name", "project_id"}) {
+			log.Infof("exclude network: %s, missing attr", name)
+			continue
+		}
+		id := jn.Get("id").MustString()
+		var azLcuuid string
+		jAZs := jn.Get("availability_zones")
+		if len(jAZs.MustArray()) > 0 {
+			azLcuuid = o.toolDataSet.keyToAZLcuuid[RegionNameKey{regionID, jAZs.GetIndex(0).MustString()}]
+		}
+		external := jn.Get("router:external").MustBool()
+		netType := common.NETWORK_TYPE_LAN
+		if external {
+			netType = common.NETWORK_TYPE_WAN
+		}
+		network := model.Network{
+			Lcuuid:         id,
+			Name:           name,
+			SegmentationID: jn.Get("provider:segmentation_id").MustInt(),
+			Shared:         jn.Get("shared").MustBool(),
+			External:       external,
+			NetType:        netType,
+			VPCLcuuid:      o.getVPCLcuuid(regionLcuuid, jn.Get("project_id").MustString()),
+			AZLcuuid:       azLcuuid,
+			RegionLcuuid:   regionLcuuid,
+		}
+		networks = append(networks, network)
+		o.toolDataSet.lcuuidToNetwork[id] = network
+		o.toolDataSet.azLcuuidToResourceNum[azLcuuid]++
+		o.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+	}
+
+	jSubnets, err := o.getRawData(networkURL+"/v2.0/subnets", token, "subnets")
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range jSubnets {
+		js := jSubnets[i]
+		id := js.Get("id").MustString()
+		if !cloudcommon.CheckJsonAttributes(js, []string{"id", "name", "cidr", "network_id"}) {
+			log.Infof("exclude subnet: %s, missing attr", id)
+			continue
+		}
+		network, ok := o.toolDataSet.lcuuidToNetwork[js.Get("network_id").MustString()]
+		if !ok {
+			log.Infof("exclude subnet: %s, missing network info", id)
+			continue
+		}
+		name := js.Get("name").MustString()
+		if name == "" {
+			name = id
+		}
+		subnets = append(
+			subnets,
+			model.Subnet{
+				Lcuuid:        id,
+				Name:          name,
+				CIDR:          js.Get("cidr").MustString(),
+				GatewayIP:     js.Get("gateway_ip").MustString(),
+				NetworkLcuuid: network.Lcuuid,
+				VPCLcuuid:     network.VPCLcuuid,
+			},
+		)
+	}
+	return networks, subnets, nil
+}
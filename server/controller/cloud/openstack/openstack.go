@@ -0,0 +1,262 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bitly/go-simplejson"
+	"github.com/op/go-logging"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/config"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/statsd"
+)
+
+var log = logging.MustGetLogger("cloud.openstack")
+
+type OpenStack struct {
+	lcuuid         string
+	lcuuidGenerate string
+	name           string
+	httpTimeout    int
+	config         *Config
+	token          *Token
+	toolDataSet    *ToolDataSet       // 处理资源数据时，构建的需要提供给其他资源使用的工具数据
+	cloudStatsd    statsd.CloudStatsd // 性能监控
+	debugger       *cloudcommon.Debugger
+}
+
+func NewOpenStack(domain mysql.Domain, globalCloudCfg config.CloudConfig) (*OpenStack, error) {
+	conf := &Config{}
+	err := conf.LoadFromString(domain.Config)
+	if err != nil {
+		return nil, err
+	}
+	return newOpenStack(domain, globalCloudCfg, conf), nil
+}
+
+func newOpenStack(domain mysql.Domain, globalCloudCfg config.CloudConfig, conf *Config) *OpenStack {
+	return &OpenStack{
+		lcuuid: domain.Lcuuid,
+		// TODO: display_name后期需要修改为uuid_generate
+		lcuuidGenerate: domain.DisplayName,
+		name:           domain.Name,
+		httpTimeout:    globalCloudCfg.HTTPTimeout,
+		config:         conf,
+		debugger:       cloudcommon.NewDebugger(domain.Name),
+	}
+}
+
+func (o *OpenStack) ClearDebugLog() {
+	o.debugger.Clear()
+}
+
+func (o *OpenStack) CheckAuth() error {
+	_, err := o.createToken()
+	return err
+}
+
+func (o *OpenStack) GetCloudData() (model.Resource, error) {
+	o.cloudStatsd = statsd.NewCloudStatsd()
+	o.toolDataSet = NewToolDataSet()
+	var resource model.Resource
+	token, err := o.getToken()
+	if err != nil {
+		return resource, err
+	}
+	o.getProjects(token.token)
+
+	regionIDs := []string{}
+	for regionID := range token.endpoints {
+		regionIDs = append(regionIDs, regionID)
+	}
+	sort.Strings(regionIDs)
+	var regions []model.Region
+	var azs []model.AZ
+	for _, regionID := range regionIDs {
+		endpoints := token.endpoints[regionID]
+		computeURL, networkURL := endpoints[SERVICE_TYPE_COMPUTE], endpoints[SERVICE_TYPE_NETWORK]
+		if computeURL == "" || networkURL == "" {
+			log.Infof("exclude region: %s, missing compute or network endpoint", regionID)
+			continue
+		}
+		regions = append(regions, model.Region{Lcuuid: o.getRegionLcuuid(regionID), Name: regionID})
+		regionLcuuid := o.regionIDToLcuuid(regionID)
+
+		rAZs, err := o.getAZs(regionID, regionLcuuid, computeURL, token.token)
+		if err != nil {
+			return resource, err
+		}
+		azs = append(azs, rAZs...)
+
+		hosts, err := o.getHosts(regionID, regionLcuuid, computeURL, token.token)
+		if err != nil {
+			return resource, err
+		}
+		resource.Hosts = append(resource.Hosts, hosts...)
+
+		networks, subnets, err := o.getNetworks(regionID, regionLcuuid, networkURL, token.token)
+		if err != nil {
+			return resource, err
+		}
+		resource.Networks = append(resource.Networks, networks...)
+		resource.Subnets = append(resource.Subnets, subnets...)
+
+		vrouters, routingTables, err := o.getRouters(regionLcuuid, networkURL, token.token)
+		if err != nil {
+			return resource, err
+		}
+		resource.VRouters = append(resource.VRouters, vrouters...)
+		resource.RoutingTables = append(resource.RoutingTables, routingTables...)
+
+		sgs, sgRules, err := o.getSecurityGroups(regionLcuuid, networkURL, token.token)
+		if err != nil {
+			return resource, err
+		}
+		resource.SecurityGroups = append(resource.SecurityGroups, sgs...)
+		resource.SecurityGroupRules = append(resource.SecurityGroupRules, sgRules...)
+
+		dhcpPorts, vifs, ips, vmSGs, err := o.getPorts(regionID, regionLcuuid, networkURL, token.token)
+		if err != nil {
+			return resource, err
+		}
+		resource.DHCPPorts = append(resource.DHCPPorts, dhcpPorts...)
+		resource.VInterfaces = append(resource.VInterfaces, vifs...)
+		resource.IPs = append(resource.IPs, ips...)
+		resource.VMSecurityGroups = append(resource.VMSecurityGroups, vmSGs...)
+
+		vms, err := o.getVMs(regionID, regionLcuuid, computeURL, token.token)
+		if err != nil {
+			return resource, err
+		}
+		resource.VMs = append(resource.VMs, vms...)
+
+		fIPs, err := o.getFloatingIPs(regionLcuuid, networkURL, token.token)
+		if err != nil {
+			return resource, err
+		}
+		resource.FloatingIPs = append(resource.FloatingIPs, fIPs...)
+
+		lbURL, ok := endpoints[SERVICE_TYPE_LOAD_BALANCER]
+		if !ok {
+			continue
+		}
+		lbs, listeners, targetServers, vifs, ips, err := o.getLBs(regionLcuuid, lbURL, token.token)
+		if err != nil {
+			return resource, err
+		}
+		resource.LBs = append(resource.LBs, lbs...)
+		resource.LBListeners = append(resource.LBListeners, listeners...)
+		resource.LBTargetServers = append(resource.LBTargetServers, targetServers...)
+		resource.VInterfaces = append(resource.VInterfaces, vifs...)
+		resource.IPs = append(resource.IPs, ips...)
+	}
+	resource.VPCs = o.getVPCs()
+
+	log.Debugf("region resource num info: %v", o.toolDataSet.regionLcuuidToResourceNum)
+	log.Debugf("az resource num info: %v", o.toolDataSet.azLcuuidToResourceNum)
+	resource.Regions = cloudcommon.EliminateEmptyRegions(regions, o.toolDataSet.regionLcuuidToResourceNum)
+	resource.AZs = cloudcommon.EliminateEmptyAZs(azs, o.toolDataSet.azLcuuidToResourceNum)
+
+	o.cloudStatsd.ResCount = statsd.GetResCount(resource)
+	statsd.MetaStatsd.RegisterStatsdTable(o)
+
+	o.debugger.Refresh()
+	return resource, nil
+}
+
+func (o *OpenStack) GetStatter() statsd.StatsdStatter {
+	globalTags := map[string]string{
+		"domain_name": o.name,
+		"domain":      o.lcuuid,
+		"platform":    common.OPENSTACK_EN,
+	}
+
+	return statsd.StatsdStatter{
+		GlobalTags: globalTags,
+		Element:    statsd.GetCloudStatsd(o.cloudStatsd),
+	}
+}
+
+// getRawData 获取resultKey对应的资源列表，并按照响应中`${resultKey}_links`的next链接翻页
+func (o *OpenStack) getRawData(url, token, resultKey string) (jsonList []*simplejson.Json, err error) {
+	statsdAPIStartTime := time.Now()
+	requestURL := url
+	for requestURL != "" {
+		resp, err := cloudcommon.RequestGet(requestURL, token, time.Duration(o.httpTimeout))
+		if err != nil {
+			return []*simplejson.Json{}, err
+		}
+		jData := resp.Get(resultKey)
+		for i := range jData.MustArray() {
+			jsonList = append(jsonList, jData.GetIndex(i))
+		}
+
+		nextURL := ""
+		if len(jData.MustArray()) > 0 {
+			jLinks := resp.Get(resultKey + "_links")
+			for i := range jLinks.MustArray() {
+				if jLinks.GetIndex(i).Get("rel").MustString() == "next" {
+					nextURL = jLinks.GetIndex(i).Get("href").MustString()
+					break
+				}
+			}
+		}
+		if nextURL == requestURL {
+			break
+		}
+		requestURL = nextURL
+	}
+	o.cloudStatsd.RefreshAPIMoniter(resultKey, len(jsonList), statsdAPIStartTime)
+
+	o.debugger.WriteJson(resultKey, url, jsonList)
+	return
+}
+
+func (o *OpenStack) getRegionLcuuid(regionID string) string {
+	return common.GenerateUUID(regionID + "_" + o.lcuuidGenerate)
+}
+
+// 配置了region_uuid时，所有资源均属于该区域
+func (o *OpenStack) regionIDToLcuuid(regionID string) string {
+	if o.config.RegionLcuuid != "" {
+		return o.config.RegionLcuuid
+	}
+	return o.getRegionLcuuid(regionID)
+}
+
+// getProjects 获取项目名称用于VPC命名，获取失败时使用项目ID
+func (o *OpenStack) getProjects(token string) {
+	jProjects, err := o.getRawData(fmt.Sprintf("%s/projects", o.config.AuthURL), token, "projects")
+	if err != nil {
+		log.Warningf("get projects failed, use project id as vpc name: %s", err.Error())
+		return
+	}
+	for i := range jProjects {
+		jp := jProjects[i]
+		if !cloudcommon.CheckJsonAttributes(jp, []string{"id", "name"}) {
+			continue
+		}
+		o.toolDataSet.projectIDToName[jp.Get("id").MustString()] = jp.Get("name").MustString()
+	}
+}
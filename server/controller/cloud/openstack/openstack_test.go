@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	cloudtest "github.com/deepflowio/deepflow/server/controller/cloud/common/test"
+	cloudconfig "github.com/deepflowio/deepflow/server/controller/cloud/config"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/statsd"
+	statsdconfig "github.com/deepflowio/deepflow/server/controller/statsd/config"
+)
+
+const TEST_TOKEN = "test-token"
+
+// newOpenStackServer 返回按请求URI回放testfiles中记录的API响应的服务
+func newOpenStackServer(t *testing.T) *httptest.Server {
+	return cloudtest.NewReplayServer(t, "./testfiles/openstack-api.json", func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/v3/auth/tokens" {
+			body, _ := ioutil.ReadAll(r.Body)
+			if r.Method != "POST" || !strings.Contains(string(body), `"password":"secret"`) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return false
+			}
+			w.Header().Set("X-Subject-Token", TEST_TOKEN)
+			w.WriteHeader(http.StatusCreated)
+			return true
+		}
+		if r.Header.Get("X-Auth-Token") != TEST_TOKEN {
+			w.WriteHeader(http.StatusUnauthorized)
+			return false
+		}
+		return true
+	})
+}
+
+func TestOpenStack(t *testing.T) {
+	Convey("TestOpenStack", t, func() {
+		cloudconfig.SetCloudGlobalConfig(cloudconfig.CloudConfig{HTTPTimeout: 30})
+		statsd.NewStatsdMonitor(statsdconfig.StatsdConfig{})
+		server := newOpenStackServer(t)
+		defer server.Close()
+
+		domain := mysql.Domain{Name: "test_openstack", DisplayName: "test_openstack"}
+		conf := &Config{
+			AuthURL:           server.URL + "/v3",
+			UserName:          "admin",
+			Password:          "secret",
+			UserDomainName:    DEFAULT_DOMAIN_NAME,
+			ProjectName:       "admin",
+			ProjectDomainName: DEFAULT_DOMAIN_NAME,
+			EndpointInterface: DEFAULT_ENDPOINT_INTERFACE,
+		}
+		openstack := newOpenStack(domain, cloudconfig.CloudConfig{HTTPTimeout: 30}, conf)
+		So(openstack.CheckAuth(), ShouldBeNil)
+
+		data, err := openstack.GetCloudData()
+		So(err, ShouldBeNil)
+
+		Convey("only regions with compute and network endpoints should be synced", func() {
+			So(len(data.Regions), ShouldEqual, 1)
+			So(data.Regions[0].Name, ShouldEqual, "RegionOne")
+			So(len(data.AZs), ShouldEqual, 1)
+		})
+
+		Convey("hypervisors should be synced as hosts", func() {
+			So(len(data.Hosts), ShouldEqual, 2)
+			So(data.Hosts[0].IP, ShouldEqual, "10.0.0.11")
+			So(data.Hosts[0].HType, ShouldEqual, common.HOST_HTYPE_KVM)
+			So(data.Hosts[0].AZLcuuid, ShouldEqual, data.AZs[0].Lcuuid)
+		})
+
+		Convey("servers of all pages should be synced", func() {
+			So(len(data.VMs), ShouldEqual, 2)
+			So(data.VMs[0].State, ShouldEqual, common.VM_STATE_RUNNING)
+			So(data.VMs[0].LaunchServer, ShouldEqual, "10.0.0.11")
+			So(data.VMs[0].CloudTags, ShouldResemble, map[string]string{"app": "web"})
+			So(data.VMs[1].State, ShouldEqual, common.VM_STATE_STOPPED)
+		})
+
+		Convey("each project should be a vpc", func() {
+			So(len(data.VPCs), ShouldEqual, 2)
+			vpcNames := map[string]string{}
+			for _, vpc := range data.VPCs {
+				vpcNames[vpc.Lcuuid] = vpc.Name
+			}
+			So(vpcNames[data.VMs[0].VPCLcuuid], ShouldEqual, "demo")
+			So(len(data.Networks), ShouldEqual, 2)
+			So(data.Networks[0].External, ShouldBeTrue)
+			So(vpcNames[data.Networks[0].VPCLcuuid], ShouldEqual, "admin")
+			So(len(data.Subnets), ShouldEqual, 2)
+			So(len(data.VRouters), ShouldEqual, 1)
+			So(len(data.RoutingTables), ShouldEqual, 1)
+		})
+
+		Convey("ports should be synced as vinterfaces of devices", func() {
+			deviceTypes := map[string]int{}
+			for _, vif := range data.VInterfaces {
+				deviceTypes[vif.Lcuuid] = vif.DeviceType
+			}
+			So(deviceTypes, ShouldResemble, map[string]int{
+				"port-vm-1":      common.VIF_DEVICE_TYPE_VM,
+				"port-vm-2":      common.VIF_DEVICE_TYPE_VM,
+				"port-router-if": common.VIF_DEVICE_TYPE_VROUTER,
+				"port-router-gw": common.VIF_DEVICE_TYPE_VROUTER,
+				"port-dhcp":      common.VIF_DEVICE_TYPE_DHCP_PORT,
+				"port-lb-vip":    common.VIF_DEVICE_TYPE_LB,
+			})
+			So(len(data.IPs), ShouldEqual, 6)
+			So(len(data.DHCPPorts), ShouldEqual, 1)
+			So(data.DHCPPorts[0].AZLcuuid, ShouldEqual, data.AZs[0].Lcuuid)
+		})
+
+		Convey("security groups should be synced with default drop rules", func() {
+			So(len(data.SecurityGroups), ShouldEqual, 1)
+			So(len(data.SecurityGroupRules), ShouldEqual, 6)
+			So(len(data.VMSecurityGroups), ShouldEqual, 2)
+		})
+
+		Convey("only floating ips of vms should be synced", func() {
+			So(data.FloatingIPs, ShouldResemble, []model.FloatingIP{{
+				Lcuuid:        "fip-1",
+				IP:            "172.24.4.20",
+				VMLcuuid:      "vm-1",
+				NetworkLcuuid: "net-public",
+				VPCLcuuid:     data.VMs[0].VPCLcuuid,
+				RegionLcuuid:  data.Regions[0].Lcuuid,
+			}})
+		})
+
+		Convey("load balancers with a floating ip should be external", func() {
+			So(len(data.LBs), ShouldEqual, 1)
+			So(data.LBs[0].VIP, ShouldEqual, "10.10.0.100")
+			So(data.LBs[0].Model, ShouldEqual, 2)
+			So(len(data.LBListeners), ShouldEqual, 1)
+			So(data.LBTargetServers, ShouldHaveLength, 1)
+			So(data.LBTargetServers[0].VMLcuuid, ShouldEqual, "vm-1")
+		})
+	})
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	"strings"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+const (
+	DEVICE_OWNER_COMPUTE_PREFIX = "compute:"
+	DEVICE_OWNER_DHCP           = "network:dhcp"
+	DEVICE_OWNER_ROUTER_GATEWAY = "network:router_gateway"
+)
+
+var ROUTER_DEVICE_OWNERS = []string{
+	DEVICE_OWNER_ROUTER_GATEWAY,
+	"network:router_interface",
+	"network:router_interface_distributed",
+	"network:ha_router_replicated_interface",
+	"network:router_centralized_snat",
+}
+
+func (o *OpenStack) getPorts(regionID, regionLcuuid, networkURL, token string) (
+	dhcpPorts []model.DHCPPort, vifs []model.VInterface, ips []model.IP, vmSGs []model.VMSecurityGroup, err error,
+) {
+	jPorts, err := o.getRawData(networkURL+"/v2.0/ports", token, "ports")
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	for i := range jPorts {
+		jp := jPorts[i]
+		id := jp.Get("id").MustString()
+		if !cloudcommon.CheckJsonAttributes(jp, []string{"id", "network_id", "mac_address", "device_id", "device_owner"}) {
+			log.Infof("exclude port: %s, missing attr", id)
+			continue
+		}
+		network, ok := o.toolDataSet.lcuuidToNetwork[jp.Get("network_id").MustString()]
+		if !ok {
+			log.Infof("exclude port: %s, missing network info", id)
+			continue
+		}
+		port := Port{
+			ID:            id,
+			Mac:           jp.Get("mac_address").MustString(),
+			DeviceID:      jp.Get("device_id").MustString(),
+			DeviceOwner:   jp.Get("device_owner").MustString(),
+			NetworkLcuuid: network.Lcuuid,
+		}
+		o.toolDataSet.portIDToPort[id] = port
+		if port.DeviceID == "" {
+			continue
+		}
+
+		// 负载均衡器的port在获取负载均衡器时处理，其他类型的port不对应设备
+		var deviceType int
+		deviceLcuuid := port.DeviceID
+		if strings.HasPrefix(port.DeviceOwner, DEVICE_OWNER_COMPUTE_PREFIX) {
+			deviceType = common.VIF_DEVICE_TYPE_VM
+		} else if common.Contains(ROUTER_DEVICE_OWNERS, port.DeviceOwner) {
+			deviceType = common.VIF_DEVICE_TYPE_VROUTER
+		} else if port.DeviceOwner == DEVICE_OWNER_DHCP {
+			deviceType = common.VIF_DEVICE_TYPE_DHCP_PORT
+			deviceLcuuid = id
+			azLcuuid := o.toolDataSet.keyToHostAZLcuuid[RegionNameKey{regionID, jp.Get("binding:host_id").MustString()}]
+			dhcpPorts = append(
+				dhcpPorts,
+				model.DHCPPort{
+					Lcuuid:       id,
+					Name:         "dhcp-" + network.Name,
+					VPCLcuuid:    network.VPCLcuuid,
+					AZLcuuid:     azLcuuid,
+					RegionLcuuid: regionLcuuid,
+				},
+			)
+			o.toolDataSet.azLcuuidToResourceNum[azLcuuid]++
+			o.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+		} else {
+			continue
+		}
+
+		vifType := common.VIF_TYPE_LAN
+		mac := port.Mac
+		if network.External {
+			vifType = common.VIF_TYPE_WAN
+			mac = cloudcommon.GenerateWANVInterfaceMac(mac)
+		}
+		vifs = append(
+			vifs,
+			model.VInterface{
+				Lcuuid:        id,
+				Name:          jp.Get("name").MustString(),
+				Type:          vifType,
+				Mac:           mac,
+				DeviceLcuuid:  deviceLcuuid,
+				DeviceType:    deviceType,
+				NetworkLcuuid: network.Lcuuid,
+				VPCLcuuid:     network.VPCLcuuid,
+				RegionLcuuid:  regionLcuuid,
+			},
+		)
+
+		jIPs := jp.Get("fixed_ips")
+		for j := range jIPs.MustArray() {
+			jIP := jIPs.GetIndex(j)
+			ip := jIP.Get("ip_address").MustString()
+			subnetID := jIP.Get("subnet_id").MustString()
+			if ip == "" || subnetID == "" {
+				continue
+			}
+			ips = append(
+				ips,
+				model.IP{
+					Lcuuid:           common.GenerateUUID(id + ip),
+					VInterfaceLcuuid: id,
+					IP:               ip,
+					SubnetLcuuid:     subnetID,
+					RegionLcuuid:     regionLcuuid,
+				},
+			)
+			if deviceType == common.VIF_DEVICE_TYPE_VM {
+				o.toolDataSet.keyToVMLcuuid[SubnetIPKey{subnetID, ip}] = deviceLcuuid
+			}
+		}
+
+		if deviceType == common.VIF_DEVICE_TYPE_VM {
+			jSGs := jp.Get("security_groups")
+			for j := range jSGs.MustArray() {
+				sgLcuuid := jSGs.GetIndex(j).MustString()
+				vmSGs = append(
+					vmSGs,
+					model.VMSecurityGroup{
+						Lcuuid:              common.GenerateUUID(deviceLcuuid + sgLcuuid),
+						VMLcuuid:            deviceLcuuid,
+						SecurityGroupLcuuid: sgLcuuid,
+						Priority:            j,
+					},
+				)
+			}
+		}
+	}
+	return dhcpPorts, vifs, ips, uniqueVMSecurityGroups(vmSGs), nil
+}
+
+// 虚拟机的多个port可能关联相同的安全组
+func uniqueVMSecurityGroups(vmSGs []model.VMSecurityGroup) []model.VMSecurityGroup {
+	set := make(map[string]bool)
+	var result []model.VMSecurityGroup
+	for _, vmSG := range vmSGs {
+		if set[vmSG.Lcuuid] {
+			continue
+		}
+		set[vmSG.Lcuuid] = true
+		result = append(result, vmSG)
+	}
+	return result
+}
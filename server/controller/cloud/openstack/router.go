@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+func (o *OpenStack) getRouters(regionLcuuid, networkURL, token string) ([]model.VRouter, []model.RoutingTable, error) {
+	var vrouters []model.VRouter
+	var routingTables []model.RoutingTable
+
+	jRouters, err := o.getRawData(networkURL+"/v2.0/routers", token, "routers")
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range jRouters {
+		jr := jRouters[i]
+		name := jr.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jr, []string{"id", "name", "project_id"}) {
+			log.Infof("exclude router: %s, missing attr", name)
+			continue
+		}
+		id := jr.Get("id").MustString()
+		vrouters = append(
+			vrouters,
+			model.VRouter{
+				Lcuuid:       id,
+				Name:         name,
+				VPCLcuuid:    o.getVPCLcuuid(regionLcuuid, jr.Get("project_id").MustString()),
+				RegionLcuuid: regionLcuuid,
+			},
+		)
+		o.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+
+		jRoutes := jr.Get("routes")
+		for j := range jRoutes.MustArray() {
+			jRoute := jRoutes.GetIndex(j)
+			destination := jRoute.Get("destination").MustString()
+			nexthop := jRoute.Get("nexthop").MustString()
+			if destination == "" || nexthop == "" {
+				continue
+			}
+			routingTables = append(
+				routingTables,
+				model.RoutingTable{
+					Lcuuid:        common.GenerateUUID(id + destination + nexthop),
+					VRouterLcuuid: id,
+					Destination:   destination,
+					NexthopType:   common.ROUTING_TABLE_TYPE_IP,
+					Nexthop:       nexthop,
+				},
+			)
+		}
+	}
+	return vrouters, routingTables, nil
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bitly/go-simplejson"
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+func (o *OpenStack) getSecurityGroups(regionLcuuid, networkURL, token string) ([]model.SecurityGroup, []model.SecurityGroupRule, error) {
+	var securityGroups []model.SecurityGroup
+	var sgRules []model.SecurityGroupRule
+
+	jSecurityGroups, err := o.getRawData(networkURL+"/v2.0/security-groups", token, "security_groups")
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range jSecurityGroups {
+		jSG := jSecurityGroups[i]
+		name := jSG.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jSG, []string{"id", "name"}) {
+			log.Infof("exclude security_group: %s, missing attr", name)
+			continue
+		}
+		id := jSG.Get("id").MustString()
+		securityGroups = append(
+			securityGroups,
+			model.SecurityGroup{
+				Lcuuid:       id,
+				Name:         name,
+				RegionLcuuid: regionLcuuid,
+			},
+		)
+		o.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+
+		jRules, ok := jSG.CheckGet("security_group_rules")
+		if ok {
+			sgRules = append(sgRules, o.formatSecurityGroupRules(jRules, id)...)
+		}
+	}
+	return securityGroups, sgRules, nil
+}
+
+// neutron安全组规则均为允许规则，未匹配的流量默认拒绝
+func (o *OpenStack) formatSecurityGroupRules(jRules *simplejson.Json, sgLcuuid string) []model.SecurityGroupRule {
+	var rules []model.SecurityGroupRule
+	var ingressPriority, egressPriority int
+	for i := range jRules.MustArray() {
+		jRule := jRules.GetIndex(i)
+		id := jRule.Get("id").MustString()
+		if !cloudcommon.CheckJsonAttributes(jRule, []string{"id", "direction", "ethertype"}) {
+			log.Infof("exclude security_group_rule: %s, missing attr", id)
+			continue
+		}
+		rule := model.SecurityGroupRule{
+			Lcuuid:              id,
+			SecurityGroupLcuuid: sgLcuuid,
+			LocalPortRange:      cloudcommon.PORT_RANGE_ALL,
+			Action:              cloudcommon.SECURITY_GROUP_RULE_ACCEPT,
+		}
+
+		var local, remote string
+		if jRule.Get("ethertype").MustString() == "IPv6" {
+			rule.EtherType = cloudcommon.SECURITY_GROUP_IPV6
+			local = cloudcommon.SUBNET_DEFAULT_CIDR_IPV6
+			remote = cloudcommon.SUBNET_DEFAULT_CIDR_IPV6
+		} else {
+			rule.EtherType = cloudcommon.SECURITY_GROUP_IPV4
+			local = cloudcommon.SUBNET_DEFAULT_CIDR_IPV4
+			remote = cloudcommon.SUBNET_DEFAULT_CIDR_IPV4
+		}
+		remoteGID := jRule.Get("remote_group_id").MustString()
+		remoteIP := jRule.Get("remote_ip_prefix").MustString()
+		if remoteIP != "" {
+			remote = remoteIP
+		} else if remoteGID != "" {
+			remote = remoteGID
+		}
+
+		if jRule.Get("direction").MustString() == "ingress" {
+			rule.Direction = cloudcommon.SECURITY_GROUP_RULE_INGRESS
+			rule.Priority = ingressPriority
+			local, remote = remote, local
+			ingressPriority++
+		} else {
+			rule.Direction = cloudcommon.SECURITY_GROUP_RULE_EGRESS
+			rule.Priority = egressPriority
+			egressPriority++
+		}
+		rule.Local = local
+		rule.Remote = remote
+
+		protocol := jRule.Get("protocol").MustString()
+		if protocol != "" {
+			rule.Protocol = strings.ToUpper(protocol)
+		} else {
+			rule.Protocol = cloudcommon.PROTOCOL_ALL
+		}
+		minPort := jRule.Get("port_range_min").MustInt()
+		maxPort := jRule.Get("port_range_max").MustInt()
+		if minPort != 0 && maxPort != 0 {
+			rule.RemotePortRange = fmt.Sprintf("%d-%d", minPort, maxPort)
+		} else {
+			rule.RemotePortRange = cloudcommon.PORT_RANGE_ALL
+		}
+		rules = append(rules, rule)
+	}
+
+	directions := []int{cloudcommon.SECURITY_GROUP_RULE_EGRESS, cloudcommon.SECURITY_GROUP_RULE_INGRESS}
+	etherTypeToRemote := map[int]string{cloudcommon.SECURITY_GROUP_IPV4: cloudcommon.SUBNET_DEFAULT_CIDR_IPV4, cloudcommon.SECURITY_GROUP_IPV6: cloudcommon.SUBNET_DEFAULT_CIDR_IPV6}
+	for _, direction := range directions {
+		for _, etherType := range []int{cloudcommon.SECURITY_GROUP_IPV4, cloudcommon.SECURITY_GROUP_IPV6} {
+			remote := etherTypeToRemote[etherType]
+			rules = append(
+				rules,
+				model.SecurityGroupRule{
+					Lcuuid:              common.GenerateUUID(sgLcuuid + strconv.Itoa(direction) + remote),
+					SecurityGroupLcuuid: sgLcuuid,
+					Action:              cloudcommon.SECURITY_GROUP_RULE_DROP,
+					Direction:           direction,
+					EtherType:           etherType,
+					Protocol:            cloudcommon.PROTOCOL_ALL,
+					Local:               remote,
+					Remote:              remote,
+					LocalPortRange:      cloudcommon.PORT_RANGE_ALL,
+					RemotePortRange:     cloudcommon.PORT_RANGE_ALL,
+					Priority:            1000,
+				},
+			)
+		}
+	}
+	return rules
+}
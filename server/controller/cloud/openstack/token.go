@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bitly/go-simplejson"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+const (
+	SERVICE_TYPE_IDENTITY      = "identity"
+	SERVICE_TYPE_COMPUTE       = "compute"
+	SERVICE_TYPE_NETWORK       = "network"
+	SERVICE_TYPE_LOAD_BALANCER = "load-balancer"
+)
+
+type Token struct {
+	token     string
+	expiresAt string
+	// region id -> service type -> endpoint url
+	endpoints map[string]map[string]string
+}
+
+// 检查token是否过期
+// 离失效时间小于5m时认为已过期，需重新申请
+func (t *Token) isExpired() bool {
+	expire, err := time.Parse(time.RFC3339, t.expiresAt)
+	if err != nil {
+		log.Errorf("parse expire time error: %s, %v", t.expiresAt, err)
+		return true
+	}
+	return expire.Sub(time.Now().UTC()).Minutes() < 5
+}
+
+func (o *OpenStack) getToken() (*Token, error) {
+	if o.token == nil || o.token.isExpired() {
+		token, err := o.createToken()
+		if err != nil {
+			return nil, err
+		}
+		o.token = token
+	}
+	return o.token, nil
+}
+
+// keystone v3 password认证，token作用域为配置的project，需要有admin角色以获取所有项目的资源
+func (o *OpenStack) createToken() (*Token, error) {
+	authBody := map[string]interface{}{
+		"auth": map[string]interface{}{
+			"identity": map[string]interface{}{
+				"methods": []string{"password"},
+				"password": map[string]interface{}{
+					"user": map[string]interface{}{
+						"domain": map[string]interface{}{
+							"name": o.config.UserDomainName,
+						},
+						"name":     o.config.UserName,
+						"password": o.config.Password,
+					},
+				},
+			},
+			"scope": map[string]interface{}{
+				"project": map[string]interface{}{
+					"domain": map[string]interface{}{
+						"name": o.config.ProjectDomainName,
+					},
+					"name": o.config.ProjectName,
+				},
+			},
+		},
+	}
+	resp, err := cloudcommon.RequestPost(fmt.Sprintf("%s/auth/tokens", o.config.AuthURL), time.Duration(o.httpTimeout), authBody)
+	if err != nil {
+		return nil, err
+	}
+	token := &Token{
+		token:     resp.Get("X-Subject-Token").MustString(),
+		expiresAt: resp.Get("token").Get("expires_at").MustString(),
+		endpoints: o.formatEndpoints(resp.Get("token").Get("catalog")),
+	}
+	if token.token == "" {
+		return nil, errors.New("get token failed, missing X-Subject-Token")
+	}
+	return token, nil
+}
+
+func (o *OpenStack) formatEndpoints(jCatalog *simplejson.Json) map[string]map[string]string {
+	endpoints := make(map[string]map[string]string)
+	for i := range jCatalog.MustArray() {
+		jService := jCatalog.GetIndex(i)
+		serviceType := jService.Get("type").MustString()
+		jEndpoints := jService.Get("endpoints")
+		for j := range jEndpoints.MustArray() {
+			jEndpoint := jEndpoints.GetIndex(j)
+			if !cloudcommon.CheckJsonAttributes(jEndpoint, []string{"interface", "region_id", "url"}) {
+				continue
+			}
+			if jEndpoint.Get("interface").MustString() != o.config.EndpointInterface {
+				continue
+			}
+			regionID := jEndpoint.Get("region_id").MustString()
+			if len(o.config.IncludeRegions) > 0 && !common.Contains(o.config.IncludeRegions, regionID) {
+				continue
+			}
+			if common.Contains(o.config.ExcludeRegions, regionID) {
+				continue
+			}
+			if _, ok := endpoints[regionID]; !ok {
+				endpoints[regionID] = make(map[string]string)
+			}
+			endpoints[regionID][serviceType] = strings.TrimSuffix(jEndpoint.Get("url").MustString(), "/")
+		}
+	}
+	return endpoints
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+)
+
+type ToolDataSet struct {
+	projectIDToName           map[string]string
+	keyToAZLcuuid             map[RegionNameKey]string // key: region id, az name
+	keyToHostAZLcuuid         map[RegionNameKey]string // key: region id, nova service host
+	keyToHostIP               map[RegionNameKey]string // key: region id, hypervisor hostname
+	lcuuidToVPC               map[string]model.VPC
+	lcuuidToNetwork           map[string]model.Network
+	portIDToPort              map[string]Port
+	keyToVMLcuuid             map[SubnetIPKey]string
+	portIDToFloatingIP        map[string]string
+	lbLcuuidToIP              map[string]string
+	lbLcuuidToVPCLcuuid       map[string]string
+	regionLcuuidToResourceNum map[string]int
+	azLcuuidToResourceNum     map[string]int
+}
+
+func NewToolDataSet() *ToolDataSet {
+	return &ToolDataSet{
+		projectIDToName:           make(map[string]string),
+		keyToAZLcuuid:             make(map[RegionNameKey]string),
+		keyToHostAZLcuuid:         make(map[RegionNameKey]string),
+		keyToHostIP:               make(map[RegionNameKey]string),
+		lcuuidToVPC:               make(map[string]model.VPC),
+		lcuuidToNetwork:           make(map[string]model.Network),
+		portIDToPort:              make(map[string]Port),
+		keyToVMLcuuid:             make(map[SubnetIPKey]string),
+		portIDToFloatingIP:        make(map[string]string),
+		lbLcuuidToIP:              make(map[string]string),
+		lbLcuuidToVPCLcuuid:       make(map[string]string),
+		regionLcuuidToResourceNum: make(map[string]int),
+		azLcuuidToResourceNum:     make(map[string]int),
+	}
+}
+
+type RegionNameKey struct {
+	RegionID string
+	Name     string
+}
+
+type SubnetIPKey struct {
+	SubnetLcuuid string
+	IP           string
+}
+
+// neutron port的部分信息，供虚拟机、负载均衡器等资源使用
+type Port struct {
+	ID            string
+	Mac           string
+	DeviceID      string
+	DeviceOwner   string
+	NetworkLcuuid string
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	"fmt"
+	"time"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+var STATE_CONVERTION = map[string]int{
+	"ACTIVE":  common.VM_STATE_RUNNING,
+	"SHUTOFF": common.VM_STATE_STOPPED,
+	"ERROR":   common.VM_STATE_EXCEPTION,
+}
+
+func (o *OpenStack) getVMs(regionID, regionLcuuid, computeURL, token string) ([]model.VM, error) {
+	var vms []model.VM
+	jVMs, err := o.getRawData(fmt.Sprintf("%s/servers/detail?all_tenants=1", computeURL), token, "servers")
+	if err != nil {
+		return nil, err
+	}
+	for i := range jVMs {
+		jVM := jVMs[i]
+		name := jVM.Get("name").MustString()
+		if !cloudcommon.CheckJsonAttributes(jVM, []string{"id", "name", "status", "tenant_id"}) {
+			log.Infof("exclude vm: %s, missing attr", name)
+			continue
+		}
+		id := jVM.Get("id").MustString()
+		azLcuuid := o.toolDataSet.keyToAZLcuuid[RegionNameKey{regionID, jVM.Get("OS-EXT-AZ:availability_zone").MustString()}]
+		state, ok := STATE_CONVERTION[jVM.Get("status").MustString()]
+		if !ok {
+			state = common.VM_STATE_EXCEPTION
+		}
+		vm := model.VM{
+			Lcuuid:       id,
+			Name:         name,
+			Label:        id,
+			HType:        common.VM_HTYPE_VM_C,
+			State:        state,
+			LaunchServer: o.toolDataSet.keyToHostIP[RegionNameKey{regionID, jVM.Get("OS-EXT-SRV-ATTR:hypervisor_hostname").MustString()}],
+			VPCLcuuid:    o.getVPCLcuuid(regionLcuuid, jVM.Get("tenant_id").MustString()),
+			AZLcuuid:     azLcuuid,
+			RegionLcuuid: regionLcuuid,
+			CloudTags:    make(map[string]string),
+		}
+		for k, v := range jVM.Get("metadata").MustMap() {
+			if value, ok := v.(string); ok {
+				vm.CloudTags[k] = value
+			}
+		}
+		created := jVM.Get("created").MustString()
+		if created != "" {
+			createdAt, err := time.Parse(time.RFC3339, created)
+			if err != nil {
+				log.Errorf("parse created failed: %s", created)
+			} else {
+				vm.CreatedAt = createdAt
+			}
+		}
+		vms = append(vms, vm)
+		o.toolDataSet.azLcuuidToResourceNum[azLcuuid]++
+		o.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+	}
+	return vms, nil
+}
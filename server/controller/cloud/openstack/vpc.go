@@ -0,0 +1,52 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package openstack
+
+import (
+	"sort"
+
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+// OpenStack没有VPC的概念，每个区域中的每个项目对应一个VPC
+func (o *OpenStack) getVPCLcuuid(regionLcuuid, projectID string) string {
+	lcuuid := common.GenerateUUID(regionLcuuid + "_" + projectID + "_" + o.lcuuidGenerate)
+	if _, ok := o.toolDataSet.lcuuidToVPC[lcuuid]; ok {
+		return lcuuid
+	}
+	name, ok := o.toolDataSet.projectIDToName[projectID]
+	if !ok {
+		name = projectID
+	}
+	o.toolDataSet.lcuuidToVPC[lcuuid] = model.VPC{
+		Lcuuid:       lcuuid,
+		Name:         name,
+		RegionLcuuid: regionLcuuid,
+	}
+	o.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+	return lcuuid
+}
+
+func (o *OpenStack) getVPCs() []model.VPC {
+	vpcs := make([]model.VPC, 0, len(o.toolDataSet.lcuuidToVPC))
+	for _, vpc := range o.toolDataSet.lcuuidToVPC {
+		vpcs = append(vpcs, vpc)
+	}
+	sort.Slice(vpcs, func(i, j int) bool { return vpcs[i].Lcuuid < vpcs[j].Lcuuid })
+	return vpcs
+}
@@ -24,15 +24,19 @@ import (
 
 	"github.com/deepflowio/deepflow/server/controller/cloud/aliyun"
 	"github.com/deepflowio/deepflow/server/controller/cloud/aws"
+	"github.com/deepflowio/deepflow/server/controller/cloud/azure"
 	"github.com/deepflowio/deepflow/server/controller/cloud/baidubce"
+	"github.com/deepflowio/deepflow/server/controller/cloud/cmdb"
 	"github.com/deepflowio/deepflow/server/controller/cloud/config"
 	"github.com/deepflowio/deepflow/server/controller/cloud/filereader"
 	"github.com/deepflowio/deepflow/server/controller/cloud/genesis"
 	"github.com/deepflowio/deepflow/server/controller/cloud/huawei"
 	"github.com/deepflowio/deepflow/server/controller/cloud/kubernetes"
 	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/cloud/openstack"
 	"github.com/deepflowio/deepflow/server/controller/cloud/qingcloud"
 	"github.com/deepflowio/deepflow/server/controller/cloud/tencent"
+	"github.com/deepflowio/deepflow/server/controller/cloud/vsphere"
 	"github.com/deepflowio/deepflow/server/controller/common"
 	"github.com/deepflowio/deepflow/server/controller/db/mysql"
 )
@@ -68,6 +72,14 @@ func NewPlatform(domain mysql.Domain, cfg config.CloudConfig) (Platform, error)
 		platform, err = huawei.NewHuaWei(domain, cfg)
 	case common.FILEREADER:
 		platform, err = filereader.NewFileReader(domain)
+	case common.OPENSTACK:
+		platform, err = openstack.NewOpenStack(domain, cfg)
+	case common.VSPHERE:
+		platform, err = vsphere.NewVSphere(domain, cfg)
+	case common.AZURE:
+		platform, err = azure.NewAzure(domain, cfg)
+	case common.CMDB:
+		platform, err = cmdb.NewCMDB(domain, cfg)
 	// TODO: other platform
 	default:
 		return nil, errors.New(fmt.Sprintf("domain type (%d) not supported", domain.Type))
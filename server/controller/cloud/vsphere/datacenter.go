@@ -0,0 +1,47 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vsphere
+
+import (
+	"github.com/vmware/govmomi/vim25/mo"
+
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+// getDatacenterResources 每个数据中心对应一个区域、一个同名可用区和一个VPC
+func (v *VSphere) getDatacenterResources(dc mo.Datacenter) (model.Region, model.AZ, model.VPC) {
+	regionLcuuid := v.datacenterToRegionLcuuid(dc)
+	region := model.Region{
+		Lcuuid: common.GenerateUUID(dc.Self.Value + "_" + v.lcuuidGenerate),
+		Label:  dc.Self.Value,
+		Name:   dc.Name,
+	}
+	az := model.AZ{
+		Lcuuid:       common.GenerateUUID(dc.Self.Value + "_az_" + v.lcuuidGenerate),
+		Label:        dc.Self.Value,
+		Name:         dc.Name,
+		RegionLcuuid: regionLcuuid,
+	}
+	vpc := model.VPC{
+		Lcuuid:       common.GenerateUUID(dc.Self.Value + "_vpc_" + v.lcuuidGenerate),
+		Label:        dc.Self.Value,
+		Name:         dc.Name,
+		RegionLcuuid: regionLcuuid,
+	}
+	return region, az, vpc
+}
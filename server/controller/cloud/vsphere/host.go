@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vsphere
+
+import (
+	"context"
+	"net"
+
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+func (v *VSphere) getHosts(ctx context.Context, client *vim25.Client, dc mo.Datacenter, regionLcuuid, azLcuuid string) ([]model.Host, error) {
+	var hosts []model.Host
+	var hostSystems []mo.HostSystem
+	err := v.retrieve(ctx, client, dc.Self, "HostSystem", []string{"name", "summary.hardware", "config.network.vnic"}, &hostSystems)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range hostSystems {
+		ip := getHostIP(h)
+		if ip == "" {
+			log.Infof("exclude host: %s, missing ip", h.Name)
+			continue
+		}
+		host := model.Host{
+			Lcuuid:       common.GenerateUUID(h.Self.Value + "_" + v.lcuuidGenerate),
+			Name:         h.Name,
+			IP:           ip,
+			Hostname:     h.Name,
+			Type:         common.HOST_TYPE_VM,
+			HType:        common.HOST_HTYPE_ESXI,
+			AZLcuuid:     azLcuuid,
+			RegionLcuuid: regionLcuuid,
+		}
+		if hw := h.Summary.Hardware; hw != nil {
+			host.VCPUNum = int(hw.NumCpuThreads)
+			host.MemTotal = int(hw.MemorySize / 1024 / 1024)
+			host.ExtraInfo = hw.Vendor + " " + hw.Model
+		}
+		hosts = append(hosts, host)
+		v.toolDataSet.hostRefToIP[h.Self.Value] = ip
+		v.toolDataSet.azLcuuidToResourceNum[azLcuuid]++
+		v.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+	}
+	return hosts, nil
+}
+
+// getHostIP 优先使用vmkernel网卡的IP，宿主机以IP添加到vCenter时也可使用名称
+func getHostIP(h mo.HostSystem) string {
+	if h.Config != nil && h.Config.Network != nil {
+		for _, vnic := range h.Config.Network.Vnic {
+			if vnic.Spec.Ip != nil && net.ParseIP(vnic.Spec.Ip.IpAddress) != nil {
+				return vnic.Spec.Ip.IpAddress
+			}
+		}
+	}
+	if net.ParseIP(h.Name) != nil {
+		return h.Name
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vsphere
+
+import (
+	"context"
+	"net"
+
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+func (v *VSphere) getNetworks(ctx context.Context, client *vim25.Client, dc mo.Datacenter, regionLcuuid, azLcuuid, vpcLcuuid string) ([]model.Network, error) {
+	var networks []model.Network
+	var portgroups []mo.DistributedVirtualPortgroup
+	err := v.retrieve(ctx, client, dc.Self, "DistributedVirtualPortgroup", []string{"name", "key", "config"}, &portgroups)
+	if err != nil {
+		return nil, err
+	}
+	for _, pg := range portgroups {
+		// uplink端口组用于连接物理网卡，不承载虚拟机网络
+		if pg.Config.Uplink != nil && *pg.Config.Uplink {
+			log.Debugf("exclude port group: %s, uplink", pg.Name)
+			continue
+		}
+		network := model.Network{
+			Lcuuid:         common.GenerateUUID(pg.Key + "_" + v.lcuuidGenerate),
+			Name:           pg.Name,
+			Label:          pg.Key,
+			SegmentationID: getPortgroupVLAN(pg),
+			NetType:        common.NETWORK_TYPE_LAN,
+			VPCLcuuid:      vpcLcuuid,
+			AZLcuuid:       azLcuuid,
+			RegionLcuuid:   regionLcuuid,
+		}
+		networks = append(networks, network)
+		v.toolDataSet.portgroupKeyToNetwork[pg.Key] = network
+		v.toolDataSet.azLcuuidToResourceNum[azLcuuid]++
+		v.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+	}
+	return networks, nil
+}
+
+// getPortgroupVLAN 只处理单个VLAN ID的端口组，trunk及私有VLAN返回0
+func getPortgroupVLAN(pg mo.DistributedVirtualPortgroup) int {
+	setting, ok := pg.Config.DefaultPortConfig.(*types.VMwareDVSPortSetting)
+	if !ok || setting.Vlan == nil {
+		return 0
+	}
+	if vlan, ok := setting.Vlan.(*types.VmwareDistributedVirtualSwitchVlanIdSpec); ok {
+		return int(vlan.VlanId)
+	}
+	return 0
+}
+
+// getSubnet 端口组没有子网信息，按虚拟机IP的掩码生成子网，无掩码的IP归属到默认子网
+func (v *VSphere) getSubnet(network model.Network, ip string, prefixLength int) model.Subnet {
+	cidr := ""
+	if prefixLength > 0 {
+		var err error
+		cidr, err = cloudcommon.IPAndMaskToCIDR(ip, prefixLength)
+		if err != nil {
+			log.Infof("ip (%s/%d) to cidr failed: %s", ip, prefixLength, err.Error())
+		}
+	}
+	if cidr == "" {
+		cidr = cloudcommon.SUBNET_DEFAULT_CIDR_IPV4
+		if net.ParseIP(ip).To4() == nil {
+			cidr = cloudcommon.SUBNET_DEFAULT_CIDR_IPV6
+		}
+	}
+	key := NetworkCIDRKey{NetworkLcuuid: network.Lcuuid, CIDR: cidr}
+	if subnet, ok := v.toolDataSet.keyToSubnet[key]; ok {
+		return subnet
+	}
+	subnet := model.Subnet{
+		Lcuuid:        common.GenerateUUID(network.Lcuuid + "_" + cidr),
+		Name:          network.Name + "_" + cidr,
+		CIDR:          cidr,
+		NetworkLcuuid: network.Lcuuid,
+		VPCLcuuid:     network.VPCLcuuid,
+	}
+	v.toolDataSet.keyToSubnet[key] = subnet
+	v.toolDataSet.subnets = append(v.toolDataSet.subnets, subnet)
+	return subnet
+}
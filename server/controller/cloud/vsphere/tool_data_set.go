@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vsphere
+
+import (
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+)
+
+type ToolDataSet struct {
+	hostRefToIP               map[string]string        // key: host moref value
+	portgroupKeyToNetwork     map[string]model.Network // key: distributed port group key
+	keyToSubnet               map[NetworkCIDRKey]model.Subnet
+	subnets                   []model.Subnet
+	regionLcuuidToResourceNum map[string]int
+	azLcuuidToResourceNum     map[string]int
+}
+
+func NewToolDataSet() *ToolDataSet {
+	return &ToolDataSet{
+		hostRefToIP:               make(map[string]string),
+		portgroupKeyToNetwork:     make(map[string]model.Network),
+		keyToSubnet:               make(map[NetworkCIDRKey]model.Subnet),
+		regionLcuuidToResourceNum: make(map[string]int),
+		azLcuuidToResourceNum:     make(map[string]int),
+	}
+}
+
+type NetworkCIDRKey struct {
+	NetworkLcuuid string
+	CIDR          string
+}
@@ -0,0 +1,184 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vsphere
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+)
+
+var STATE_CONVERTION = map[types.VirtualMachinePowerState]int{
+	types.VirtualMachinePowerStatePoweredOn:  common.VM_STATE_RUNNING,
+	types.VirtualMachinePowerStatePoweredOff: common.VM_STATE_STOPPED,
+	types.VirtualMachinePowerStateSuspended:  common.VM_STATE_STOPPED,
+}
+
+func (v *VSphere) getVMs(ctx context.Context, client *vim25.Client, dc mo.Datacenter, regionLcuuid, azLcuuid, vpcLcuuid string) ([]model.VM, []model.VInterface, []model.IP, error) {
+	var vms []model.VM
+	var vifs []model.VInterface
+	var ips []model.IP
+	var virtualMachines []mo.VirtualMachine
+	err := v.retrieve(ctx, client, dc.Self, "VirtualMachine", []string{"name", "config", "runtime", "guest"}, &virtualMachines)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, vmo := range virtualMachines {
+		// 无法访问的虚拟机没有config属性
+		if vmo.Config == nil {
+			log.Infof("exclude vm: %s, missing config", vmo.Name)
+			continue
+		}
+		if vmo.Config.Template {
+			log.Debugf("exclude vm: %s, template", vmo.Name)
+			continue
+		}
+		lcuuid := vmo.Config.InstanceUuid
+		if lcuuid == "" {
+			lcuuid = common.GenerateUUID(vmo.Config.Uuid + "_" + v.lcuuidGenerate)
+		}
+		state, ok := STATE_CONVERTION[vmo.Runtime.PowerState]
+		if !ok {
+			state = common.VM_STATE_EXCEPTION
+		}
+		var launchServer string
+		if vmo.Runtime.Host != nil {
+			launchServer = v.toolDataSet.hostRefToIP[vmo.Runtime.Host.Value]
+		}
+		vm := model.VM{
+			Lcuuid:       lcuuid,
+			Name:         vmo.Name,
+			Label:        vmo.Self.Value,
+			HType:        common.VM_HTYPE_VM_C,
+			State:        state,
+			LaunchServer: launchServer,
+			VPCLcuuid:    vpcLcuuid,
+			AZLcuuid:     azLcuuid,
+			RegionLcuuid: regionLcuuid,
+		}
+		if vmo.Guest != nil {
+			vm.Hostname = vmo.Guest.HostName
+			vm.IP = vmo.Guest.IpAddress
+		}
+		if vmo.Config.CreateDate != nil {
+			vm.CreatedAt = *vmo.Config.CreateDate
+		}
+		vms = append(vms, vm)
+		v.toolDataSet.azLcuuidToResourceNum[azLcuuid]++
+		v.toolDataSet.regionLcuuidToResourceNum[regionLcuuid]++
+
+		vmVIFs, vmIPs := v.getVMInterfaces(vmo, lcuuid, regionLcuuid)
+		vifs = append(vifs, vmVIFs...)
+		ips = append(ips, vmIPs...)
+	}
+	return vms, vifs, ips, nil
+}
+
+// getVMInterfaces 只处理连接到分布式端口组的网卡，IP从VMware Tools上报的guest.net中按MAC匹配
+func (v *VSphere) getVMInterfaces(vmo mo.VirtualMachine, vmLcuuid, regionLcuuid string) ([]model.VInterface, []model.IP) {
+	var vifs []model.VInterface
+	var ips []model.IP
+	macToGuestNic := make(map[string]types.GuestNicInfo)
+	if vmo.Guest != nil {
+		for _, nic := range vmo.Guest.Net {
+			macToGuestNic[strings.ToLower(nic.MacAddress)] = nic
+		}
+	}
+	for _, device := range vmo.Config.Hardware.Device {
+		card, ok := device.(types.BaseVirtualEthernetCard)
+		if !ok {
+			continue
+		}
+		ethernetCard := card.GetVirtualEthernetCard()
+		mac := strings.ToLower(ethernetCard.MacAddress)
+		backing, ok := ethernetCard.Backing.(*types.VirtualEthernetCardDistributedVirtualPortBackingInfo)
+		if !ok || mac == "" {
+			log.Debugf("exclude vm (%s) nic: %s, not connected to distributed port group", vmo.Name, mac)
+			continue
+		}
+		network, ok := v.toolDataSet.portgroupKeyToNetwork[backing.Port.PortgroupKey]
+		if !ok {
+			log.Infof("exclude vm (%s) nic: %s, missing network info", vmo.Name, mac)
+			continue
+		}
+		vifLcuuid := common.GenerateUUID(vmLcuuid + "_" + mac)
+		vifs = append(
+			vifs,
+			model.VInterface{
+				Lcuuid:        vifLcuuid,
+				Type:          common.VIF_TYPE_LAN,
+				Mac:           mac,
+				DeviceLcuuid:  vmLcuuid,
+				DeviceType:    common.VIF_DEVICE_TYPE_VM,
+				NetworkLcuuid: network.Lcuuid,
+				VPCLcuuid:     network.VPCLcuuid,
+				RegionLcuuid:  regionLcuuid,
+			},
+		)
+
+		ipToPrefixLength := getGuestNicIPs(macToGuestNic[mac])
+		nicIPs := make([]string, 0, len(ipToPrefixLength))
+		for ip := range ipToPrefixLength {
+			nicIPs = append(nicIPs, ip)
+		}
+		sort.Strings(nicIPs)
+		for _, ip := range nicIPs {
+			subnet := v.getSubnet(network, ip, ipToPrefixLength[ip])
+			ips = append(
+				ips,
+				model.IP{
+					Lcuuid:           common.GenerateUUID(vifLcuuid + ip),
+					VInterfaceLcuuid: vifLcuuid,
+					IP:               ip,
+					SubnetLcuuid:     subnet.Lcuuid,
+					RegionLcuuid:     regionLcuuid,
+				},
+			)
+		}
+	}
+	return vifs, ips
+}
+
+// getGuestNicIPs 返回网卡IP及其掩码长度，忽略链路本地地址
+func getGuestNicIPs(nic types.GuestNicInfo) map[string]int {
+	ipToPrefixLength := make(map[string]int)
+	if nic.IpConfig != nil {
+		for _, addr := range nic.IpConfig.IpAddress {
+			ipToPrefixLength[addr.IpAddress] = int(addr.PrefixLength)
+		}
+	}
+	for _, ip := range nic.IpAddress {
+		if _, ok := ipToPrefixLength[ip]; !ok {
+			ipToPrefixLength[ip] = 0
+		}
+	}
+	for ip := range ipToPrefixLength {
+		netIP := net.ParseIP(ip)
+		if netIP == nil || netIP.IsLinkLocalUnicast() {
+			delete(ipToPrefixLength, ip)
+		}
+	}
+	return ipToPrefixLength
+}
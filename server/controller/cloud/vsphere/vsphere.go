@@ -0,0 +1,234 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vsphere
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/bitly/go-simplejson"
+	"github.com/op/go-logging"
+	"github.com/vmware/govmomi/session"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+
+	cloudcommon "github.com/deepflowio/deepflow/server/controller/cloud/common"
+	"github.com/deepflowio/deepflow/server/controller/cloud/config"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/statsd"
+)
+
+var log = logging.MustGetLogger("cloud.vsphere")
+
+type VSphere struct {
+	lcuuid         string
+	lcuuidGenerate string
+	name           string
+	httpTimeout    int
+	config         *Config
+	toolDataSet    *ToolDataSet       // 处理资源数据时，构建的需要提供给其他资源使用的工具数据
+	cloudStatsd    statsd.CloudStatsd // 性能监控
+	debugger       *cloudcommon.Debugger
+}
+
+func NewVSphere(domain mysql.Domain, globalCloudCfg config.CloudConfig) (*VSphere, error) {
+	conf := &Config{}
+	err := conf.LoadFromString(domain.Config)
+	if err != nil {
+		return nil, err
+	}
+	return newVSphere(domain, globalCloudCfg, conf), nil
+}
+
+func newVSphere(domain mysql.Domain, globalCloudCfg config.CloudConfig, conf *Config) *VSphere {
+	return &VSphere{
+		lcuuid: domain.Lcuuid,
+		// TODO: display_name后期需要修改为uuid_generate
+		lcuuidGenerate: domain.DisplayName,
+		name:           domain.Name,
+		httpTimeout:    globalCloudCfg.HTTPTimeout,
+		config:         conf,
+		debugger:       cloudcommon.NewDebugger(domain.Name),
+	}
+}
+
+func (v *VSphere) ClearDebugLog() {
+	v.debugger.Clear()
+}
+
+func (v *VSphere) CheckAuth() error {
+	ctx := context.Background()
+	_, sm, err := v.login(ctx)
+	if err != nil {
+		return err
+	}
+	return sm.Logout(ctx)
+}
+
+func (v *VSphere) GetCloudData() (model.Resource, error) {
+	v.cloudStatsd = statsd.NewCloudStatsd()
+	v.toolDataSet = NewToolDataSet()
+	var resource model.Resource
+
+	ctx := context.Background()
+	client, sm, err := v.login(ctx)
+	if err != nil {
+		return resource, err
+	}
+	defer sm.Logout(ctx)
+
+	var datacenters []mo.Datacenter
+	err = v.retrieve(ctx, client, client.ServiceContent.RootFolder, "Datacenter", []string{"name"}, &datacenters)
+	if err != nil {
+		return resource, err
+	}
+	sort.Slice(datacenters, func(i, j int) bool { return datacenters[i].Name < datacenters[j].Name })
+
+	var regions []model.Region
+	var azs []model.AZ
+	for _, dc := range datacenters {
+		region, az, vpc := v.getDatacenterResources(dc)
+		regions = append(regions, region)
+		azs = append(azs, az)
+		resource.VPCs = append(resource.VPCs, vpc)
+
+		hosts, err := v.getHosts(ctx, client, dc, az.RegionLcuuid, az.Lcuuid)
+		if err != nil {
+			return resource, err
+		}
+		resource.Hosts = append(resource.Hosts, hosts...)
+
+		networks, err := v.getNetworks(ctx, client, dc, az.RegionLcuuid, az.Lcuuid, vpc.Lcuuid)
+		if err != nil {
+			return resource, err
+		}
+		resource.Networks = append(resource.Networks, networks...)
+
+		vms, vifs, ips, err := v.getVMs(ctx, client, dc, az.RegionLcuuid, az.Lcuuid, vpc.Lcuuid)
+		if err != nil {
+			return resource, err
+		}
+		resource.VMs = append(resource.VMs, vms...)
+		resource.VInterfaces = append(resource.VInterfaces, vifs...)
+		resource.IPs = append(resource.IPs, ips...)
+	}
+	resource.Subnets = v.toolDataSet.subnets
+
+	log.Debugf("region resource num info: %v", v.toolDataSet.regionLcuuidToResourceNum)
+	log.Debugf("az resource num info: %v", v.toolDataSet.azLcuuidToResourceNum)
+	resource.Regions = cloudcommon.EliminateEmptyRegions(regions, v.toolDataSet.regionLcuuidToResourceNum)
+	resource.AZs = cloudcommon.EliminateEmptyAZs(azs, v.toolDataSet.azLcuuidToResourceNum)
+
+	v.cloudStatsd.ResCount = statsd.GetResCount(resource)
+	statsd.MetaStatsd.RegisterStatsdTable(v)
+
+	v.debugger.Refresh()
+	return resource, nil
+}
+
+func (v *VSphere) GetStatter() statsd.StatsdStatter {
+	globalTags := map[string]string{
+		"domain_name": v.name,
+		"domain":      v.lcuuid,
+		"platform":    common.VSPHERE_EN,
+	}
+
+	return statsd.StatsdStatter{
+		GlobalTags: globalTags,
+		Element:    statsd.GetCloudStatsd(v.cloudStatsd),
+	}
+}
+
+// login 登录vCenter，调用方需负责登出会话
+func (v *VSphere) login(ctx context.Context) (*vim25.Client, *session.Manager, error) {
+	u, err := soap.ParseURL(v.config.URL)
+	if err != nil {
+		log.Errorf("parse url (%s) failed: %s", v.config.URL, err.Error())
+		return nil, nil, err
+	}
+	soapClient := soap.NewClient(u, true)
+	soapClient.Timeout = time.Duration(v.httpTimeout) * time.Second
+	client, err := vim25.NewClient(ctx, soapClient)
+	if err != nil {
+		log.Errorf("connect vsphere (%s) failed: %s", u.Host, err.Error())
+		return nil, nil, err
+	}
+	sm := session.NewManager(client)
+	err = sm.Login(ctx, url.UserPassword(v.config.UserName, v.config.Password))
+	if err != nil {
+		log.Errorf("login vsphere (%s) failed: %s", u.Host, err.Error())
+		return nil, nil, err
+	}
+	return client, sm, nil
+}
+
+// retrieve 通过container view获取container下所有kind类型对象的props属性，dst为mo对象切片的指针
+func (v *VSphere) retrieve(ctx context.Context, client *vim25.Client, container types.ManagedObjectReference, kind string, props []string, dst interface{}) error {
+	statsdAPIStartTime := time.Now()
+	cv, err := view.NewManager(client).CreateContainerView(ctx, container, []string{kind}, true)
+	if err != nil {
+		log.Errorf("create %s container view of %s failed: %s", kind, container.Value, err.Error())
+		return err
+	}
+	defer cv.Destroy(ctx)
+	err = cv.Retrieve(ctx, []string{kind}, props, dst)
+	if err != nil {
+		log.Errorf("retrieve %s of %s failed: %s", kind, container.Value, err.Error())
+		return err
+	}
+	count := reflect.ValueOf(dst).Elem().Len()
+	v.cloudStatsd.RefreshAPIMoniter(kind, count, statsdAPIStartTime)
+
+	v.writeDebugJson(kind, fmt.Sprintf("%s %s", container.Type, container.Value), dst)
+	return nil
+}
+
+func (v *VSphere) writeDebugJson(kind, dividingLine string, data interface{}) {
+	bytes, err := json.Marshal(data)
+	if err != nil {
+		log.Debugf("marshal %s failed: %s", kind, err.Error())
+		return
+	}
+	jData, err := simplejson.NewJson(bytes)
+	if err != nil {
+		log.Debugf("convert %s to json failed: %s", kind, err.Error())
+		return
+	}
+	var jsonList []*simplejson.Json
+	for i := range jData.MustArray() {
+		jsonList = append(jsonList, jData.GetIndex(i))
+	}
+	v.debugger.WriteJson(kind, dividingLine, jsonList)
+}
+
+// 配置了region_uuid时，所有资源均属于该区域
+func (v *VSphere) datacenterToRegionLcuuid(dc mo.Datacenter) string {
+	if v.config.RegionLcuuid != "" {
+		return v.config.RegionLcuuid
+	}
+	return common.GenerateUUID(dc.Self.Value + "_" + v.lcuuidGenerate)
+}
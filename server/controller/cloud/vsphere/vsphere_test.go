@@ -0,0 +1,150 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vsphere
+
+import (
+	"crypto/tls"
+	"net/url"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+
+	cloudconfig "github.com/deepflowio/deepflow/server/controller/cloud/config"
+	"github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/statsd"
+	statsdconfig "github.com/deepflowio/deepflow/server/controller/statsd/config"
+)
+
+func TestVSphere(t *testing.T) {
+	Convey("TestVSphere", t, func() {
+		cloudconfig.SetCloudGlobalConfig(cloudconfig.CloudConfig{HTTPTimeout: 30})
+		statsd.NewStatsdMonitor(statsdconfig.StatsdConfig{})
+
+		// 1个数据中心，1个独立宿主机及3个集群宿主机，每个宿主机/集群2个虚拟机，虚拟机网卡连接到分布式端口组
+		vcsim := simulator.VPX()
+		So(vcsim.Create(), ShouldBeNil)
+		defer vcsim.Remove()
+		vcsim.Service.TLS = new(tls.Config)
+		vcsim.Service.Listen = &url.URL{User: url.UserPassword("admin", "secret")}
+		server := vcsim.Service.NewServer()
+		defer server.Close()
+
+		// vcsim没有标记uplink端口组，也不会上报虚拟机的guest IP
+		for _, e := range simulator.Map.All("DistributedVirtualPortgroup") {
+			pg := e.(*simulator.DistributedVirtualPortgroup)
+			pg.Config.Uplink = types.NewBool(strings.Contains(pg.Name, "DVUplinks"))
+		}
+		var vmo *simulator.VirtualMachine
+		for _, e := range simulator.Map.All("VirtualMachine") {
+			if e.Entity().Name == "DC0_H0_VM0" {
+				vmo = e.(*simulator.VirtualMachine)
+			}
+		}
+		So(vmo, ShouldNotBeNil)
+		So(len(vmo.Guest.Net), ShouldBeGreaterThan, 0)
+		vmo.Guest.IpAddress = "10.1.0.10"
+		vmo.Guest.Net[0].IpAddress = []string{"10.1.0.10", "fe80::1", "2001:db8::10"}
+		vmo.Guest.Net[0].IpConfig = &types.NetIpConfigInfo{
+			IpAddress: []types.NetIpConfigInfoIpAddress{{IpAddress: "10.1.0.10", PrefixLength: 24}},
+		}
+
+		conf := &Config{
+			URL:      "https://" + server.URL.Host,
+			UserName: "admin",
+			Password: "secret",
+		}
+		domain := mysql.Domain{Name: "test_vsphere", DisplayName: "test_vsphere"}
+		vsphere := newVSphere(domain, cloudconfig.CloudConfig{HTTPTimeout: 30}, conf)
+
+		Convey("CheckAuth", func() {
+			So(vsphere.CheckAuth(), ShouldBeNil)
+
+			wrongConf := *conf
+			wrongConf.Password = "wrong"
+			So(newVSphere(domain, cloudconfig.CloudConfig{HTTPTimeout: 30}, &wrongConf).CheckAuth(), ShouldNotBeNil)
+		})
+
+		Convey("GetCloudData", func() {
+			resource, err := vsphere.GetCloudData()
+			So(err, ShouldBeNil)
+
+			So(len(resource.Regions), ShouldEqual, 1)
+			So(resource.Regions[0].Name, ShouldEqual, "DC0")
+			So(len(resource.AZs), ShouldEqual, 1)
+			So(resource.AZs[0].RegionLcuuid, ShouldEqual, resource.Regions[0].Lcuuid)
+			So(len(resource.VPCs), ShouldEqual, 1)
+
+			So(len(resource.Hosts), ShouldEqual, 4)
+			hostNames := []string{}
+			for _, host := range resource.Hosts {
+				hostNames = append(hostNames, host.Name)
+				So(host.HType, ShouldEqual, common.HOST_HTYPE_ESXI)
+				So(host.IP, ShouldNotBeEmpty)
+				So(host.VCPUNum, ShouldBeGreaterThan, 0)
+				So(host.MemTotal, ShouldBeGreaterThan, 0)
+				So(host.AZLcuuid, ShouldEqual, resource.AZs[0].Lcuuid)
+			}
+			So(hostNames, ShouldContain, "DC0_H0")
+			So(hostNames, ShouldContain, "DC0_C0_H0")
+
+			So(len(resource.Networks), ShouldEqual, 1)
+			network := resource.Networks[0]
+			So(network.Name, ShouldEqual, "DC0_DVPG0")
+			So(network.VPCLcuuid, ShouldEqual, resource.VPCs[0].Lcuuid)
+
+			So(len(resource.VMs), ShouldEqual, 4)
+			var vm0 model.VM
+			for _, vm := range resource.VMs {
+				So(vm.State, ShouldEqual, common.VM_STATE_RUNNING)
+				So(vm.LaunchServer, ShouldNotBeEmpty)
+				So(vm.VPCLcuuid, ShouldEqual, resource.VPCs[0].Lcuuid)
+				if vm.Name == "DC0_H0_VM0" {
+					vm0 = vm
+				}
+			}
+			So(vm0.Lcuuid, ShouldEqual, vmo.Config.InstanceUuid)
+			So(vm0.Label, ShouldEqual, vmo.Self.Value)
+			So(vm0.IP, ShouldEqual, "10.1.0.10")
+
+			So(len(resource.VInterfaces), ShouldEqual, 4)
+			var vm0VIF model.VInterface
+			for _, vif := range resource.VInterfaces {
+				So(vif.NetworkLcuuid, ShouldEqual, network.Lcuuid)
+				if vif.DeviceLcuuid == vm0.Lcuuid {
+					vm0VIF = vif
+				}
+			}
+			So(vm0VIF.DeviceType, ShouldEqual, common.VIF_DEVICE_TYPE_VM)
+
+			// 链路本地地址被忽略，无掩码的IPv6地址归属到默认子网
+			So(len(resource.IPs), ShouldEqual, 2)
+			So(resource.IPs[0].IP, ShouldEqual, "10.1.0.10")
+			So(resource.IPs[0].VInterfaceLcuuid, ShouldEqual, vm0VIF.Lcuuid)
+			So(resource.IPs[1].IP, ShouldEqual, "2001:db8::10")
+			So(len(resource.Subnets), ShouldEqual, 2)
+			So(resource.Subnets[0].CIDR, ShouldEqual, "10.1.0.0/24")
+			So(resource.Subnets[0].NetworkLcuuid, ShouldEqual, network.Lcuuid)
+			So(resource.Subnets[1].CIDR, ShouldEqual, "::/0")
+			So(resource.IPs[0].SubnetLcuuid, ShouldEqual, resource.Subnets[0].Lcuuid)
+		})
+	})
+}
@@ -282,6 +282,7 @@ const (
 	ESHORE            = 26
 	CLOUD_TOWER       = 27
 	NFVO              = 28
+	CMDB              = 29
 
 	OPENSTACK_EN         = "openstack"
 	VSPHERE_EN           = "vsphere"
@@ -311,6 +312,7 @@ const (
 	BAIDU_BCE_EN         = "baidu_bce"
 	CLOUD_TOWER_EN       = "cloudtower"
 	NFVO_EN              = "nfvo"
+	CMDB_EN              = "cmdb"
 
 	TENCENT_CH          = "腾讯云"
 	PINGAN_CH           = "平安云"
@@ -40,6 +40,7 @@ func (d *Debug) RegisterTo(e *gin.Engine) {
 	e.GET("/v1/tasks/", getCloudBasicInfos(d.m))
 	e.GET("/v1/tasks/:lcuuid/", getCloudBasicInfo(d.m))
 	e.GET("/v1/info/:lcuuid/", getCloudResource(d.m))
+	e.GET("/v1/dry-run/:lcuuid/", dryRunDomain(d.m))
 	e.GET("/v1/genesis/:type/", getGenesisSyncData(d.g, true))
 	e.GET("/v1/sync/:type/", getGenesisSyncData(d.g, false))
 	e.GET("/v1/agent-stats/:ipOrID/", getAgentStats(d.g))
@@ -80,6 +81,13 @@ func getCloudResource(m *manager.Manager) gin.HandlerFunc {
 	})
 }
 
+func dryRunDomain(m *manager.Manager) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		data, err := service.DryRunDomain(c.Param("lcuuid"), m)
+		JsonResponse(c, data, err)
+	})
+}
+
 func getKubernetesGatherBasicInfos(m *manager.Manager) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		data, err := service.GetKubernetesGatherBasicInfos(c.Param("lcuuid"), m)
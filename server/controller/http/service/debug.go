@@ -29,6 +29,7 @@ import (
 	. "github.com/deepflowio/deepflow/server/controller/http/service/common"
 	"github.com/deepflowio/deepflow/server/controller/manager"
 	"github.com/deepflowio/deepflow/server/controller/model"
+	"github.com/deepflowio/deepflow/server/controller/recorder"
 	"github.com/deepflowio/deepflow/server/controller/recorder/cache"
 	"github.com/deepflowio/deepflow/server/controller/recorder/cache/diffbase"
 	"github.com/deepflowio/deepflow/server/controller/recorder/cache/tool"
@@ -127,6 +128,17 @@ func GetRecorderToolMapByField(domainLcuuid, subDomainLcuuid, field string, m *m
 	}
 }
 
+func DryRunDomain(lcuuid string, m *manager.Manager) (resp recorder.DryRunResult, err error) {
+	if _, err := m.GetCloudInfo(lcuuid); err != nil {
+		return recorder.DryRunResult{}, NewError(httpcommon.RESOURCE_NOT_FOUND, err.Error())
+	}
+	resp, err = m.DryRunDomain(lcuuid)
+	if err != nil {
+		return recorder.DryRunResult{}, NewError(httpcommon.SERVER_ERROR, err.Error())
+	}
+	return resp, nil
+}
+
 func GetGenesisData(g *genesis.Genesis) (genesis.GenesisSyncData, error) {
 	return g.GetGenesisSyncData(), nil
 }
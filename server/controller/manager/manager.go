@@ -133,6 +133,17 @@ func (m *Manager) GetRecorder(domainLcuuid string) (recorder.Recorder, error) {
 	return *task.Recorder, nil
 }
 
+// DryRunDomain 立即获取云平台数据，并预演 recorder 同步结果
+func (m *Manager) DryRunDomain(domainLcuuid string) (recorder.DryRunResult, error) {
+	m.mutex.RLock()
+	task, ok := m.taskMap[domainLcuuid]
+	m.mutex.RUnlock()
+	if !ok {
+		return recorder.DryRunResult{}, errors.New(fmt.Sprintf("task of domain (lcuuid: %s) not found", domainLcuuid))
+	}
+	return task.Recorder.DryRun(task.Cloud.GetFreshResource())
+}
+
 func (m *Manager) run(ctx context.Context) {
 	// 获取所在控制器的IP
 	var controller mysql.Controller
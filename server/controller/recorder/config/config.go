@@ -25,7 +25,8 @@ type RecorderConfig struct {
 	ResourceMaxID0               int    `default:"64000" yaml:"resource_max_id_0"`
 	ResourceMaxID1               int    `default:"499999" yaml:"resource_max_id_1"`
 
-	LogDebug LogDebugConfig `yaml:"log_debug"`
+	LogDebug             LogDebugConfig             `yaml:"log_debug"`
+	SyncDeleteProtection SyncDeleteProtectionConfig `yaml:"sync_delete_protection"`
 }
 
 func Get() *RecorderConfig {
@@ -41,3 +42,10 @@ type LogDebugConfig struct {
 	DetailEnabled bool     `default:"false" yaml:"detail_enabled"`
 	ResourceTypes []string `default:"" yaml:"resource_type"`
 }
+
+// 同步删除保护：若一次同步将删除某类资源超过一定比例，则阻止本次同步
+type SyncDeleteProtectionConfig struct {
+	Enabled          bool `default:"false" yaml:"enabled"`
+	MaxDeletePercent int  `default:"50" yaml:"max_delete_percent"`
+	MinResourceCount int  `default:"10" yaml:"min_resource_count"` // 已存在资源数量小于此值时不做检查
+}
@@ -0,0 +1,172 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package recorder
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	cloudmodel "github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/recorder/cache"
+	"github.com/deepflowio/deepflow/server/controller/recorder/updater"
+)
+
+// 预演等待正在进行的同步或 cache 刷新完成的最长时间
+const DRY_RUN_WAIT_TIMEOUT = time.Second * 30
+
+// DryRunResult 预演结果，展示使用指定 cloud 数据同步时，各类资源将会发生的变化
+type DryRunResult struct {
+	DomainLcuuid       string
+	DomainName         string
+	SyncAt             time.Time
+	ErrorState         int
+	ErrorMessage       string
+	SkipReason         string   // cloud 数据不满足同步条件时，不会进行同步的原因
+	Blocked            bool     // 是否会被同步删除保护阻止
+	BlockedReasons     []string `json:",omitempty"`
+	Resources          []*updater.DryRunResult
+	SubDomainResources map[string][]*updater.DryRunResult
+}
+
+// DryRun 使用 cloud 数据进行预演，不写数据库、不修改 cache
+func (r *Recorder) DryRun(cloudData cloudmodel.Resource) (DryRunResult, error) {
+	select {
+	case <-r.canRefresh:
+		defer func() { r.canRefresh <- true }()
+	case <-time.After(DRY_RUN_WAIT_TIMEOUT):
+		return DryRunResult{}, fmt.Errorf("recorder (domain lcuuid: %s) is busy, please try again later", r.domainLcuuid)
+	}
+
+	if reason := getCloudDataSkipReason(cloudData); reason != "" {
+		return DryRunResult{
+			DomainLcuuid: r.domainLcuuid,
+			DomainName:   r.domainName,
+			SyncAt:       cloudData.SyncAt,
+			ErrorState:   cloudData.ErrorState,
+			ErrorMessage: cloudData.ErrorMessage,
+			SkipReason:   fmt.Sprintf("domain %s", reason),
+		}, nil
+	}
+
+	result := r.dryRun(cloudData)
+	if r.cfg.SyncDeleteProtection.Enabled {
+		result.BlockedReasons = r.checkSyncDeleteProtection(result)
+		result.Blocked = len(result.BlockedReasons) > 0
+	}
+	return result, nil
+}
+
+// 与 refreshDomain、refreshSubDomains 使用相同的 updater 及处理范围，仅获取比对结果
+func (r *Recorder) dryRun(cloudData cloudmodel.Resource) DryRunResult {
+	result := DryRunResult{
+		DomainLcuuid:       r.domainLcuuid,
+		DomainName:         r.domainName,
+		SyncAt:             cloudData.SyncAt,
+		ErrorState:         cloudData.ErrorState,
+		ErrorMessage:       cloudData.ErrorMessage,
+		Resources:          dryRunUpdaters(r.getDomainUpdatersInOrder(cloudData)),
+		SubDomainResources: make(map[string][]*updater.DryRunResult),
+	}
+
+	for subDomainLcuuid, subDomainResource := range cloudData.SubDomainResources {
+		if !r.shouldRefreshSubDomain(subDomainLcuuid, subDomainResource) {
+			continue
+		}
+		// 预演不创建 sub domain cache，cache 不存在时使用空 cache，所有资源均为新增
+		subDomainCache, ok := r.cacheMng.SubDomainCacheMap[subDomainLcuuid]
+		if !ok {
+			subDomainCache = cache.NewCache(r.domainLcuuid)
+			subDomainCache.SubDomainLcuuid = subDomainLcuuid
+		}
+		result.SubDomainResources[subDomainLcuuid] = dryRunUpdaters(
+			r.getSubDomainUpdatersInOrder(subDomainLcuuid, subDomainResource, subDomainCache, nil))
+	}
+	for subDomainLcuuid, subDomainCache := range r.cacheMng.SubDomainCacheMap {
+		if _, ok := cloudData.SubDomainResources[subDomainLcuuid]; !ok {
+			result.SubDomainResources[subDomainLcuuid] = dryRunUpdaters(
+				r.getSubDomainUpdatersInOrder(subDomainLcuuid, cloudmodel.SubDomainResource{}, subDomainCache, r.cacheMng.DomainCache.ToolDataSet))
+		}
+	}
+	return result
+}
+
+func dryRunUpdaters(updatersInUpdateOrder []updater.ResourceUpdater) []*updater.DryRunResult {
+	results := make([]*updater.DryRunResult, 0, len(updatersInUpdateOrder))
+	for _, u := range updatersInUpdateOrder {
+		results = append(results, u.DryRun())
+	}
+	return results
+}
+
+// 检查预演结果中各类资源的待删除比例，返回超出阈值的原因，未超出时返回空
+func (r *Recorder) checkSyncDeleteProtection(result DryRunResult) []string {
+	var reasons []string
+	for _, rscResult := range result.Resources {
+		if r.exceedSyncDeleteThreshold(rscResult) {
+			reasons = append(reasons, r.formatSyncDeleteThresholdExceeded("", rscResult))
+		}
+	}
+	for subDomainLcuuid, rscResults := range result.SubDomainResources {
+		for _, rscResult := range rscResults {
+			if r.exceedSyncDeleteThreshold(rscResult) {
+				reasons = append(reasons, r.formatSyncDeleteThresholdExceeded(subDomainLcuuid, rscResult))
+			}
+		}
+	}
+	return reasons
+}
+
+func (r *Recorder) exceedSyncDeleteThreshold(rscResult *updater.DryRunResult) bool {
+	cfg := r.cfg.SyncDeleteProtection
+	if rscResult.DeleteCount == 0 || rscResult.ExistingCount < cfg.MinResourceCount {
+		return false
+	}
+	return rscResult.DeletePercent() > float64(cfg.MaxDeletePercent)
+}
+
+func (r *Recorder) formatSyncDeleteThresholdExceeded(subDomainLcuuid string, rscResult *updater.DryRunResult) string {
+	msg := fmt.Sprintf(
+		"%s %d/%d (%.2f%%) to delete, exceeds %d%%",
+		rscResult.ResourceType, rscResult.DeleteCount, rscResult.ExistingCount, rscResult.DeletePercent(), r.cfg.SyncDeleteProtection.MaxDeletePercent,
+	)
+	if subDomainLcuuid != "" {
+		msg = fmt.Sprintf("sub_domain (lcuuid: %s) %s", subDomainLcuuid, msg)
+	}
+	return msg
+}
+
+// 同步被删除保护阻止时，将 domain 状态置为警告，并记录原因
+func (r *Recorder) updateStateInfoOnSyncBlocked(reasons []string) {
+	var domain mysql.Domain
+	err := mysql.Db.Where("lcuuid = ?", r.domainLcuuid).First(&domain).Error
+	if err != nil {
+		log.Errorf("get domain (lcuuid: %s) from db failed: %s", r.domainLcuuid, err)
+		return
+	}
+	if domain.State == common.RESOURCE_STATE_CODE_SUCCESS {
+		domain.State = common.RESOURCE_STATE_CODE_WARNING
+	}
+	if domain.ErrorMsg != "" {
+		domain.ErrorMsg += "\n\n"
+	}
+	domain.ErrorMsg += fmt.Sprintf("sync blocked by delete protection: %s", strings.Join(reasons, "; "))
+	mysql.Db.Save(&domain)
+	log.Debugf("update domain (%+v)", domain)
+}
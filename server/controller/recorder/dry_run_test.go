@@ -0,0 +1,49 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package recorder
+
+import (
+	"github.com/stretchr/testify/assert"
+
+	"github.com/deepflowio/deepflow/server/controller/recorder/config"
+	"github.com/deepflowio/deepflow/server/controller/recorder/updater"
+)
+
+func (t *SuiteTest) TestCheckSyncDeleteProtection() {
+	r := &Recorder{cfg: config.RecorderConfig{SyncDeleteProtection: config.SyncDeleteProtectionConfig{
+		Enabled: true, MaxDeletePercent: 50, MinResourceCount: 10,
+	}}}
+	result := DryRunResult{
+		Resources: []*updater.DryRunResult{
+			{ResourceType: "vm", ExistingCount: 100, DeleteCount: 51},
+			{ResourceType: "host", ExistingCount: 100, DeleteCount: 50},
+			{ResourceType: "vpc", ExistingCount: 9, DeleteCount: 9},
+		},
+		SubDomainResources: map[string][]*updater.DryRunResult{
+			"sub-domain-lcuuid": {{ResourceType: "pod", ExistingCount: 10, DeleteCount: 10}},
+		},
+	}
+	reasons := r.checkSyncDeleteProtection(result)
+	assert.Equal(t.T(), []string{
+		"vm 51/100 (51.00%) to delete, exceeds 50%",
+		"sub_domain (lcuuid: sub-domain-lcuuid) pod 10/10 (100.00%) to delete, exceeds 50%",
+	}, reasons)
+
+	result.Resources[0].DeleteCount = 50
+	delete(result.SubDomainResources, "sub-domain-lcuuid")
+	assert.Empty(t.T(), r.checkSyncDeleteProtection(result))
+}
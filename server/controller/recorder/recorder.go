@@ -124,22 +124,27 @@ func (r *Recorder) shouldRefresh(cloudData cloudmodel.Resource) bool {
 	}
 	r.domainName = domain.Name
 
-	if cloudData.Verified {
-		if len(cloudData.Networks) == 0 || len(cloudData.VInterfaces) == 0 {
-			log.Infof("domain (lcuuid: %s, name: %s) has no networks or vinterfaces, does nothing", r.domainLcuuid, r.domainName)
-			return false
-		}
-		if len(cloudData.VMs) == 0 && len(cloudData.Pods) == 0 {
-			log.Infof("domain (lcuuid: %s, name: %s) has no vms and pods, does nothing", r.domainLcuuid, r.domainName)
-			return false
-		}
-	} else {
-		log.Infof("domain (lcuuid: %s, name: %s) is not verified, does nothing", r.domainLcuuid, r.domainName)
+	if reason := getCloudDataSkipReason(cloudData); reason != "" {
+		log.Infof("domain (lcuuid: %s, name: %s) %s, does nothing", r.domainLcuuid, r.domainName, reason)
 		return false
 	}
 	return true
 }
 
+// 返回 cloud 数据不满足同步条件的原因，满足条件时返回空字符串
+func getCloudDataSkipReason(cloudData cloudmodel.Resource) string {
+	if !cloudData.Verified {
+		return "is not verified"
+	}
+	if len(cloudData.Networks) == 0 || len(cloudData.VInterfaces) == 0 {
+		return "has no networks or vinterfaces"
+	}
+	if len(cloudData.VMs) == 0 && len(cloudData.Pods) == 0 {
+		return "has no vms and pods"
+	}
+	return ""
+}
+
 func (r *Recorder) runNewRefreshWhole(cloudData cloudmodel.Resource) {
 	go func() {
 		// 无论是否会更新资源，需先更新domain及subdomain状态
@@ -149,6 +154,14 @@ func (r *Recorder) runNewRefreshWhole(cloudData cloudmodel.Resource) {
 			r.canRefresh <- true
 			return
 		}
+		if r.cfg.SyncDeleteProtection.Enabled {
+			if reasons := r.checkSyncDeleteProtection(r.dryRun(cloudData)); len(reasons) > 0 {
+				log.Errorf("domain (lcuuid: %s, name: %s) sync blocked by delete protection: %s", r.domainLcuuid, r.domainName, strings.Join(reasons, "; "))
+				r.updateStateInfoOnSyncBlocked(reasons)
+				r.canRefresh <- true
+				return
+			}
+		}
 
 		log.Infof("recorder (domain lcuuid: %s, name: %s) sync refresh started", r.domainLcuuid, r.domainName)
 
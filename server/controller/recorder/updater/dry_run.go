@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package updater
+
+// DryRunResult 资源预演结果，记录本次同步将会新增、更新、删除的资源
+type DryRunResult struct {
+	ResourceType  string
+	ExistingCount int // diff base 中已存在的资源数量
+	AddCount      int
+	UpdateCount   int
+	DeleteCount   int
+	ToAdd         []DryRunItem
+	ToUpdate      []DryRunItem
+	ToDelete      []DryRunItem
+}
+
+type DryRunItem struct {
+	Lcuuid string
+	Data   interface{}            `json:",omitempty"` // 待新增资源的 cloud 数据，或待删除资源的 diff base 数据
+	Fields map[string]interface{} `json:",omitempty"` // 待更新资源变化的字段及新值
+}
+
+func newDryRunResult(resourceType string, existingCount int) *DryRunResult {
+	return &DryRunResult{
+		ResourceType:  resourceType,
+		ExistingCount: existingCount,
+		ToAdd:         []DryRunItem{},
+		ToUpdate:      []DryRunItem{},
+		ToDelete:      []DryRunItem{},
+	}
+}
+
+// DeletePercent 待删除资源数量占已存在资源数量的百分比
+func (r *DryRunResult) DeletePercent() float64 {
+	if r.ExistingCount == 0 {
+		return 0
+	}
+	return float64(r.DeleteCount) * 100 / float64(r.ExistingCount)
+}
+
+func (r *DryRunResult) appendToAdd(lcuuid string, cloudItem interface{}) {
+	r.ToAdd = append(r.ToAdd, DryRunItem{Lcuuid: lcuuid, Data: cloudItem})
+	r.AddCount++
+}
+
+func (r *DryRunResult) appendToUpdate(lcuuid string, mapInfo map[string]interface{}) {
+	fields := make(map[string]interface{}, len(mapInfo))
+	for k, v := range mapInfo {
+		// 部分字段（如 cloud_tags）以 json 字节串形式更新，转为字符串便于阅读
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		fields[k] = v
+	}
+	r.ToUpdate = append(r.ToUpdate, DryRunItem{Lcuuid: lcuuid, Fields: fields})
+	r.UpdateCount++
+}
+
+func (r *DryRunResult) appendToDelete(lcuuid string, diffBase interface{}) {
+	r.ToDelete = append(r.ToDelete, DryRunItem{Lcuuid: lcuuid, Data: diffBase})
+	r.DeleteCount++
+}
+
+func (r *DryRunResult) merge(other *DryRunResult) {
+	r.ExistingCount += other.ExistingCount
+	r.AddCount += other.AddCount
+	r.UpdateCount += other.UpdateCount
+	r.DeleteCount += other.DeleteCount
+	r.ToAdd = append(r.ToAdd, other.ToAdd...)
+	r.ToUpdate = append(r.ToUpdate, other.ToUpdate...)
+	r.ToDelete = append(r.ToDelete, other.ToDelete...)
+}
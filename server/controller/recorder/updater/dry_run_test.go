@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package updater
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	cloudmodel "github.com/deepflowio/deepflow/server/controller/cloud/model"
+	"github.com/deepflowio/deepflow/server/controller/db/mysql"
+	"github.com/deepflowio/deepflow/server/controller/recorder/cache/diffbase"
+)
+
+func (t *SuiteTest) TestDryRunVM() {
+	cache, cloudItemToUpdate := t.getVMMock(false)
+	cloudItemToUpdate.Name = "vm-update"
+	cache.DiffBaseDataSet.VMs[cloudItemToUpdate.Lcuuid] = &diffbase.VM{
+		DiffBase: diffbase.DiffBase{Lcuuid: cloudItemToUpdate.Lcuuid}, Name: "vm", VPCLcuuid: cloudItemToUpdate.VPCLcuuid,
+		Label: cloudItemToUpdate.Label, HType: cloudItemToUpdate.HType, State: cloudItemToUpdate.State, LaunchServer: cloudItemToUpdate.LaunchServer,
+		RegionLcuuid: cloudItemToUpdate.RegionLcuuid, AZLcuuid: cloudItemToUpdate.AZLcuuid,
+	}
+	lcuuidToDelete := uuid.NewString()
+	cache.DiffBaseDataSet.VMs[lcuuidToDelete] = &diffbase.VM{DiffBase: diffbase.DiffBase{Lcuuid: lcuuidToDelete}, Name: "vm-delete"}
+	cloudItemToAdd := newCloudVM()
+	sequence := cache.GetSequence()
+
+	updater := NewVM(cache, []cloudmodel.VM{cloudItemToAdd, cloudItemToUpdate})
+	result := updater.DryRun()
+
+	assert.Equal(t.T(), 2, result.ExistingCount)
+	assert.Equal(t.T(), 1, result.AddCount)
+	assert.Equal(t.T(), cloudItemToAdd.Lcuuid, result.ToAdd[0].Lcuuid)
+	assert.Equal(t.T(), 1, result.UpdateCount)
+	assert.Equal(t.T(), cloudItemToUpdate.Lcuuid, result.ToUpdate[0].Lcuuid)
+	assert.Equal(t.T(), map[string]interface{}{"name": "vm-update"}, result.ToUpdate[0].Fields)
+	assert.Equal(t.T(), 1, result.DeleteCount)
+	assert.Equal(t.T(), lcuuidToDelete, result.ToDelete[0].Lcuuid)
+	assert.Equal(t.T(), float64(50), result.DeletePercent())
+
+	// 预演不修改 cache 及数据库
+	assert.Equal(t.T(), sequence, cache.GetSequence())
+	assert.Equal(t.T(), 2, len(cache.DiffBaseDataSet.VMs))
+	assert.Equal(t.T(), "vm", cache.DiffBaseDataSet.VMs[cloudItemToUpdate.Lcuuid].Name)
+	assert.NotEqual(t.T(), sequence, cache.DiffBaseDataSet.VMs[cloudItemToUpdate.Lcuuid].GetSequence())
+	var count int64
+	t.db.Model(&mysql.VM{}).Count(&count)
+	assert.Equal(t.T(), int64(0), count)
+}
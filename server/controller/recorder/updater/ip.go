@@ -62,6 +62,16 @@ func (i *IP) HandleDelete() {
 	i.lanIPUpdater.HandleDelete()
 }
 
+func (i *IP) DryRun() *DryRunResult {
+	wanCloudData, lanCloudData := i.splitToWANAndLANForDryRun(i.cloudData)
+	i.wanIPUpdater.SetCloudData(wanCloudData)
+	i.lanIPUpdater.SetCloudData(lanCloudData)
+	result := newDryRunResult(i.GetResourceType(), 0)
+	result.merge(i.wanIPUpdater.DryRun())
+	result.merge(i.lanIPUpdater.DryRun())
+	return result
+}
+
 func (i *IP) GetChanged() bool {
 	return i.wanIPUpdater.Changed || i.lanIPUpdater.Changed
 }
@@ -94,3 +104,18 @@ func (i *IP) splitToWANAndLAN(cloudData []cloudmodel.IP) ([]cloudmodel.IP, []clo
 	}
 	return wanCloudData, lanCloudData
 }
+
+// 预演时，新增的 vinterface 尚未写入 cache，无法获取其类型，此类 IP 均视为 LAN IP
+func (i *IP) splitToWANAndLANForDryRun(cloudData []cloudmodel.IP) ([]cloudmodel.IP, []cloudmodel.IP) {
+	wanCloudData := []cloudmodel.IP{}
+	lanCloudData := []cloudmodel.IP{}
+	for _, cloudItem := range cloudData {
+		vt, exists := i.cache.ToolDataSet.GetVInterfaceTypeByLcuuid(cloudItem.VInterfaceLcuuid)
+		if exists && vt == ctrlrcommon.VIF_TYPE_WAN {
+			wanCloudData = append(wanCloudData, cloudItem)
+		} else {
+			lanCloudData = append(lanCloudData, cloudItem)
+		}
+	}
+	return wanCloudData, lanCloudData
+}
@@ -37,6 +37,8 @@ type ResourceUpdater interface {
 	HandleAddAndUpdate()
 	// 逐一检查 diff base 中的资源，若 sequence 不等于 cache 中的 sequence，则删除
 	HandleDelete()
+	// 预演：与 HandleAddAndUpdate、HandleDelete 使用相同的比对逻辑，但不操作数据库、不修改 cache，仅返回比对结果
+	DryRun() *DryRunResult
 
 	Publisher
 }
@@ -175,6 +177,29 @@ func (u *UpdaterBase[CT, MT, BT, MAPT, MAT, MUPT, MUT, MFUPT, MFUT, MDPT, MDT])
 	}
 }
 
+func (u *UpdaterBase[CT, MT, BT, MAPT, MAT, MUPT, MUT, MFUPT, MFUT, MDPT, MDT]) DryRun() *DryRunResult {
+	result := newDryRunResult(u.resourceType, len(u.diffBaseData))
+	// 不更新 diff base 的 sequence，通过记录命中的 diff base 判断待删除资源
+	matchedLcuuids := make(map[string]struct{})
+	for _, cloudItem := range u.cloudData {
+		diffBase, exists := u.dataGenerator.getDiffBaseByCloudItem(&cloudItem)
+		if !exists {
+			result.appendToAdd(getCloudItemLcuuid(cloudItem), cloudItem)
+			continue
+		}
+		matchedLcuuids[diffBase.GetLcuuid()] = struct{}{}
+		if _, mapInfo, ok := u.dataGenerator.generateUpdateInfo(diffBase, &cloudItem); ok {
+			result.appendToUpdate(diffBase.GetLcuuid(), mapInfo)
+		}
+	}
+	for lcuuid, diffBase := range u.diffBaseData {
+		if _, ok := matchedLcuuids[lcuuid]; !ok {
+			result.appendToDelete(lcuuid, diffBase)
+		}
+	}
+	return result
+}
+
 func (u *UpdaterBase[CT, MT, BT, MAPT, MAT, MUPT, MUT, MFUPT, MFUT, MDPT, MDT]) GetResourceType() string {
 	return u.resourceType
 }
@@ -54,6 +54,12 @@ const (
 	DefaultStatsInterval            = 10      // s
 	DefaultFlowTagCacheFlushTimeout = 1800    // s
 	DefaultFlowTagCacheMaxSize      = 1 << 18 // 256k
+	DefaultCKWriterSpillDir         = "/var/lib/deepflow/ckwriter-spill"
+	DefaultCKWriterSpillMaxSize     = 1024  // MB
+	DefaultCKWriterSpillMaxAge      = 86400 // s
+	DefaultCKWriterSpillReplay      = 10    // s
+	DefaultReceiverTLSListenPort    = 20034
+	DefaultReceiverTLSReload        = 60 // s
 	IndexTypeHash                   = "hash"
 	IndexTypeIncremetalIdLocation   = "incremental-id"
 	FormatHex                       = "hex"
@@ -114,6 +120,32 @@ type CKWriterConfig struct {
 	FlushTimeout int `yaml:"flush-timeout"`
 }
 
+// CKWriterSpill saves the blocks which failed to be written to ClickHouse on disk, and replays them
+// when ClickHouse is available again.
+type CKWriterSpill struct {
+	Enabled        bool     `yaml:"enabled"`
+	Dir            string   `yaml:"dir"`
+	Tables         []string `yaml:"tables,flow"`     // 'database.table' of local table, empty means all tables
+	MaxSize        int      `yaml:"max-size"`        // MB, for each table
+	MaxAge         int      `yaml:"max-age"`         // s
+	ReplayInterval int      `yaml:"replay-interval"` // s
+}
+
+func (s *CKWriterSpill) IsTableEnabled(database, table string) bool {
+	if !s.Enabled {
+		return false
+	}
+	if len(s.Tables) == 0 {
+		return true
+	}
+	for _, t := range s.Tables {
+		if t == database+"."+table {
+			return true
+		}
+	}
+	return false
+}
+
 type CKDB struct {
 	External            bool   `yaml:"external"`
 	Host                string `yaml:"host"`
@@ -126,6 +158,19 @@ type CKDB struct {
 	TimeZone            string `yaml:"time-zone"`
 }
 
+// ReceiverTLS enables a TLS listener for the data sent by agents, and the agents are authenticated
+// by their certificates if 'ca-file' is set.
+type ReceiverTLS struct {
+	Enabled           bool   `yaml:"enabled"`
+	ListenPort        uint16 `yaml:"listen-port"`
+	CertFile          string `yaml:"cert-file"`
+	KeyFile           string `yaml:"key-file"`
+	CAFile            string `yaml:"ca-file"`
+	RequireClientCert bool   `yaml:"require-client-cert"`
+	PlainTCPDisabled  bool   `yaml:"plain-tcp-disabled"` // only receive TCP data by TLS listener
+	ReloadInterval    int    `yaml:"reload-interval"`    // s
+}
+
 type Config struct {
 	IsRunningModeStandalone  bool
 	StorageDisabled          bool            `yaml:"storage-disabled"`
@@ -139,6 +184,8 @@ type Config struct {
 	TCPReadBuffer            int             `yaml:"tcp-read-buffer"`
 	TCPReaderBuffer          int             `yaml:"tcp-reader-buffer"`
 	CKDiskMonitor            CKDiskMonitor   `yaml:"ck-disk-monitor"`
+	CKWriterSpill            CKWriterSpill   `yaml:"ckwriter-spill"`
+	ReceiverTLS              ReceiverTLS     `yaml:"receiver-tls"`
 	ColdStorage              CKDBColdStorage `yaml:"ckdb-cold-storage"`
 	ckdbColdStorages         map[string]*ckdb.ColdStorage
 	NodeIP                   string `yaml:"node-ip"`
@@ -235,6 +282,40 @@ func (c *Config) Validate() error {
 		c.GrpcBufferSize = DefaultGrpcBufferSize
 	}
 
+	if c.CKWriterSpill.Dir == "" {
+		c.CKWriterSpill.Dir = DefaultCKWriterSpillDir
+	}
+	if c.CKWriterSpill.MaxSize <= 0 {
+		c.CKWriterSpill.MaxSize = DefaultCKWriterSpillMaxSize
+	}
+	if c.CKWriterSpill.MaxAge <= 0 {
+		c.CKWriterSpill.MaxAge = DefaultCKWriterSpillMaxAge
+	}
+	if c.CKWriterSpill.ReplayInterval <= 0 {
+		c.CKWriterSpill.ReplayInterval = DefaultCKWriterSpillReplay
+	}
+
+	if c.ReceiverTLS.Enabled {
+		if c.ReceiverTLS.CertFile == "" || c.ReceiverTLS.KeyFile == "" {
+			log.Error("'cert-file' and 'key-file' of 'receiver-tls' are required when enabled")
+			sleepAndExit()
+		}
+		if c.ReceiverTLS.RequireClientCert && c.ReceiverTLS.CAFile == "" {
+			log.Error("'ca-file' of 'receiver-tls' is required when 'require-client-cert' is enabled")
+			sleepAndExit()
+		}
+		if c.ReceiverTLS.ListenPort == 0 {
+			c.ReceiverTLS.ListenPort = DefaultReceiverTLSListenPort
+		}
+		if c.ReceiverTLS.ListenPort == c.ListenPort {
+			log.Errorf("'listen-port'(%d) of 'receiver-tls' conflicts with 'listen-port'", c.ReceiverTLS.ListenPort)
+			sleepAndExit()
+		}
+		if c.ReceiverTLS.ReloadInterval <= 0 {
+			c.ReceiverTLS.ReloadInterval = DefaultReceiverTLSReload
+		}
+	}
+
 	if c.ServiceLabelerLruCap <= 0 {
 		c.ServiceLabelerLruCap = DefaultServiceLabelerLruCap
 	}
@@ -33,6 +33,7 @@ var log = logging.MustGetLogger("config")
 const (
 	DefaultESHostPort      = "elasticsearch:20042"
 	DefaultSyslogDirectory = "/var/log/deepflow-agent"
+	DefaultAgentLogTTL     = 168 // hour
 )
 
 type ESAuth struct {
@@ -52,6 +53,10 @@ type Config struct {
 	AgentLogToFile  bool          `yaml:"agent-log-to-file"`
 	SyslogDirectory string        `yaml:"syslog-directory"`
 	ESSyslog        bool          `yaml:"es-syslog"`
+
+	AgentLogToCK     bool                  `yaml:"agent-log-to-ck"`
+	AgentLogTTL      int                   `yaml:"agent-log-ttl-hour"`
+	AgentLogCKWriter config.CKWriterConfig `yaml:"agent-log-ck-writer"`
 }
 
 type DropletConfig struct {
@@ -157,6 +162,9 @@ func (c *Config) Validate() error {
 	if c.SyslogDirectory == "" {
 		c.SyslogDirectory = DefaultSyslogDirectory
 	}
+	if c.AgentLogTTL <= 0 {
+		c.AgentLogTTL = DefaultAgentLogTTL
+	}
 	return nil
 }
 
@@ -168,6 +176,10 @@ func Load(base *config.Config, path string) *Config {
 			ESHostPorts: []string{DefaultESHostPort},
 			RpcTimeout:  8,
 			ESSyslog:    true,
+
+			AgentLogToCK:     true,
+			AgentLogTTL:      DefaultAgentLogTTL,
+			AgentLogCKWriter: config.CKWriterConfig{QueueCount: 1, QueueSize: 50000, BatchSize: 4096, FlushTimeout: 5},
 		},
 	}
 	if err != nil {
@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package syslog
+
+import (
+	"fmt"
+	"net"
+
+	basecommon "github.com/deepflowio/deepflow/server/ingester/common"
+	"github.com/deepflowio/deepflow/server/libs/ckdb"
+	"github.com/deepflowio/deepflow/server/libs/pool"
+	"github.com/deepflowio/deepflow/server/libs/utils"
+)
+
+const (
+	AGENT_LOG_DB    = "event"
+	AGENT_LOG_TABLE = "agent_log"
+
+	DefaultPartition = ckdb.TimeFuncTwelveHour
+)
+
+type AgentLog struct {
+	pool.ReferenceCount
+
+	Time      uint32 // s
+	Timestamp int64  // us
+
+	VtapID uint16
+	Host   string
+	IsIPv4 bool
+	IP4    uint32
+	IP6    net.IP
+
+	Severity uint8 // syslog severity, 0: emergency ... 7: debug
+	Facility uint8
+	AppName  string // the program in the syslog tag, e.g.: deepflow-agent
+	Module   string // the source file of the agent log, e.g.: src/sender/uniform_sender.rs
+	Message  string
+}
+
+func AgentLogColumns() []*ckdb.Column {
+	return []*ckdb.Column{
+		ckdb.NewColumn("time", ckdb.DateTime).SetComment("精度: 秒"),
+		ckdb.NewColumn("timestamp", ckdb.DateTime64us).SetComment("精度: 微秒"),
+		ckdb.NewColumn("vtap_id", ckdb.UInt16).SetIndex(ckdb.IndexSet),
+		ckdb.NewColumn("hostname", ckdb.LowCardinalityString).SetComment("采集器主机名"),
+		ckdb.NewColumn("is_ipv4", ckdb.UInt8).SetIndex(ckdb.IndexMinmax),
+		ckdb.NewColumn("ip4", ckdb.IPv4).SetComment("采集器IP"),
+		ckdb.NewColumn("ip6", ckdb.IPv6).SetComment("采集器IP"),
+		ckdb.NewColumn("severity", ckdb.UInt8).SetIndex(ckdb.IndexMinmax).SetComment("日志级别, 0:EMERG, 1:ALERT, 2:CRIT, 3:ERR, 4:WARNING, 5:NOTICE, 6:INFO, 7:DEBUG"),
+		ckdb.NewColumn("facility", ckdb.UInt8).SetIndex(ckdb.IndexNone),
+		ckdb.NewColumn("app_name", ckdb.LowCardinalityString),
+		ckdb.NewColumn("module", ckdb.LowCardinalityString).SetComment("日志所在的源文件"),
+		ckdb.NewColumn("message", ckdb.String).SetComment("日志内容"),
+	}
+}
+
+func (l *AgentLog) WriteBlock(block *ckdb.Block) {
+	block.WriteDateTime(l.Time)
+	block.Write(
+		l.Timestamp,
+		l.VtapID,
+		l.Host,
+	)
+	block.WriteBool(l.IsIPv4)
+	block.WriteIPv4(l.IP4)
+	block.WriteIPv6(l.IP6)
+	block.Write(
+		l.Severity,
+		l.Facility,
+		l.AppName,
+		l.Module,
+		l.Message,
+	)
+}
+
+func (l *AgentLog) SetIP(ip net.IP) {
+	if ip4 := ip.To4(); ip4 != nil {
+		l.IsIPv4 = true
+		l.IP4 = utils.IpToUint32(ip4)
+	} else {
+		l.IsIPv4 = false
+		l.IP6 = append(l.IP6[:0], ip...)
+	}
+}
+
+func (l *AgentLog) IP() net.IP {
+	if l.IsIPv4 {
+		return utils.IpFromUint32(l.IP4)
+	}
+	return l.IP6
+}
+
+func (l *AgentLog) Release() {
+	ReleaseAgentLog(l)
+}
+
+func (l *AgentLog) String() string {
+	return fmt.Sprintf("AgentLog: %+v\n", *l)
+}
+
+func GenAgentLogCKTable(cluster, storagePolicy string, ttl int, coldStorage *ckdb.ColdStorage) *ckdb.Table {
+	timeKey := "time"
+	orderKeys := []string{timeKey, "vtap_id", "hostname", "severity"}
+	return &ckdb.Table{
+		Version:         basecommon.CK_VERSION,
+		Database:        AGENT_LOG_DB,
+		LocalName:       AGENT_LOG_TABLE + ckdb.LOCAL_SUBFFIX,
+		GlobalName:      AGENT_LOG_TABLE,
+		Columns:         AgentLogColumns(),
+		TimeKey:         timeKey,
+		TTL:             ttl,
+		PartitionFunc:   DefaultPartition,
+		Engine:          ckdb.MergeTree,
+		Cluster:         cluster,
+		StoragePolicy:   storagePolicy,
+		ColdStorage:     *coldStorage,
+		OrderKeys:       orderKeys,
+		PrimaryKeyCount: len(orderKeys),
+	}
+}
+
+var poolAgentLog = pool.NewLockFreePool(func() interface{} {
+	return &AgentLog{}
+})
+
+func AcquireAgentLog() *AgentLog {
+	l := poolAgentLog.Get().(*AgentLog)
+	l.ReferenceCount.Reset()
+	return l
+}
+
+func ReleaseAgentLog(l *AgentLog) {
+	if l == nil {
+		return
+	}
+	if l.SubReferenceCount() {
+		return
+	}
+	*l = AgentLog{}
+	poolAgentLog.Put(l)
+}
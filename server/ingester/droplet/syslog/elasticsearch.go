@@ -17,7 +17,11 @@
 package syslog
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"log/syslog"
+	"strconv"
 	"strings"
 	"time"
 
@@ -43,6 +47,37 @@ type ESLog struct {
 	Message   string `json:"message"`
 }
 
+func parseESLog(bs []byte) (*ESLog, error) {
+	// example log
+	// 2020-11-23T16:56:35+08:00 dfi-153 trident[8642]: [INFO] synchronizer.go:397 update FlowAcls version  1605685133 to 1605685134
+	columns := bytes.SplitN(bs, []byte{' '}, 6)
+	if len(columns) != 6 {
+		return nil, errors.New("not enough columns in log")
+	}
+	esLog := ESLog{Type: LOG_TYPE, Module: LOG_MODULE}
+	datetime, err := time.Parse(time.RFC3339, string(columns[0]))
+	if err != nil {
+		return nil, err
+	}
+	esLog.Timestamp = uint32(datetime.Unix())
+	esLog.Host = string(columns[1])
+	severity := syslog.Priority(0)
+	switch string(columns[3]) {
+	case "[INFO]":
+		severity = syslog.LOG_INFO
+	case "[WARN]":
+		severity = syslog.LOG_WARNING
+	case "[ERRO]", "[ERROR]":
+		severity = syslog.LOG_ERR
+	default:
+		return nil, errors.New("ignored log level: " + string(columns[3]))
+	}
+	esLog.Severity = strconv.Itoa(int(severity))
+	esLog.SyslogTag = string(columns[4])
+	esLog.Message = string(columns[5])
+	return &esLog, nil
+}
+
 type ESLogger struct {
 	addresses []string
 	username  string
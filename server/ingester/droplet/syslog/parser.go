@@ -0,0 +1,215 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package syslog
+
+import (
+	"errors"
+	"fmt"
+	"log/syslog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	NILVALUE        = "-"
+	RFC3164_TIME    = "Jan _2 15:04:05"
+	RFC5424_VERSION = "1 "
+	UTF8_BOM        = "\xef\xbb\xbf"
+)
+
+var (
+	errEmptyLog       = errors.New("empty log")
+	errNotEnoughField = errors.New("not enough fields in log")
+)
+
+// the levels written by deepflow-agent in the beginning of messages, e.g.: [INFO]
+var agentLevels = map[string]syslog.Priority{
+	"[TRACE]": syslog.LOG_DEBUG,
+	"[DEBUG]": syslog.LOG_DEBUG,
+	"[INFO]":  syslog.LOG_INFO,
+	"[WARN]":  syslog.LOG_WARNING,
+	"[ERRO]":  syslog.LOG_ERR,
+	"[ERROR]": syslog.LOG_ERR,
+}
+
+// nextField returns the field before the first space and the rest after it
+func nextField(s string) (string, string) {
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+func (l *AgentLog) setTime(t time.Time) {
+	l.Timestamp = t.UnixMicro()
+	l.Time = uint32(t.Unix())
+}
+
+// ParseSyslog parses the log in one of the formats:
+//   - RFC5424: <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+//   - RFC3164: <PRI>Mmm dd hh:mm:ss HOSTNAME TAG: MSG
+//   - deepflow-agent without PRI: TIMESTAMP HOSTNAME TAG: MSG, e.g.:
+//     2020-11-23T16:56:35+08:00 dfi-153 deepflow-agent[8642]: [INFO] src/sender/uniform_sender.rs:397 update config
+//
+// the level and the source file written by deepflow-agent at the beginning of MSG are parsed as severity and module
+func ParseSyslog(bs []byte, l *AgentLog) error {
+	s := strings.TrimRight(string(bs), "\r\n\x00")
+	if len(s) == 0 {
+		return errEmptyLog
+	}
+	var err error
+	if s[0] == '<' {
+		end := strings.IndexByte(s, '>')
+		if end < 2 || end > 4 {
+			return fmt.Errorf("invalid PRI in log: %s", s)
+		}
+		pri, e := strconv.Atoi(s[1:end])
+		if e != nil || pri > 191 {
+			return fmt.Errorf("invalid PRI in log: %s", s)
+		}
+		l.Facility, l.Severity = uint8(pri>>3), uint8(pri&0x7)
+		s = s[end+1:]
+		if strings.HasPrefix(s, RFC5424_VERSION) {
+			err = l.parseRFC5424(s[len(RFC5424_VERSION):])
+		} else {
+			err = l.parseRFC3164(s)
+		}
+	} else {
+		l.Facility, l.Severity = uint8(syslog.LOG_DAEMON>>3), uint8(syslog.LOG_INFO)
+		err = l.parseRFC3164(s)
+	}
+	if err != nil {
+		return err
+	}
+	l.parseAgentMessage()
+	return nil
+}
+
+func (l *AgentLog) parseRFC5424(s string) error {
+	var timestamp, msgID string
+	timestamp, s = nextField(s)
+	l.Host, s = nextField(s)
+	l.AppName, s = nextField(s)
+	_, s = nextField(s) // PROCID
+	msgID, s = nextField(s)
+	if msgID == "" {
+		return errNotEnoughField
+	}
+	if timestamp == NILVALUE {
+		l.setTime(time.Now())
+	} else {
+		t, err := time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			return err
+		}
+		l.setTime(t)
+	}
+	if l.Host == NILVALUE {
+		l.Host = ""
+	}
+	if l.AppName == NILVALUE {
+		l.AppName = ""
+	}
+
+	// skip STRUCTURED-DATA, which is NILVALUE or one or more [SD-ID PARAM-NAME="PARAM-VALUE" ...]
+	if strings.HasPrefix(s, NILVALUE) {
+		s = s[len(NILVALUE):]
+	} else {
+		for len(s) > 0 && s[0] == '[' {
+			i, inQuote := 1, false
+			for ; i < len(s); i++ {
+				if s[i] == '\\' {
+					i++
+				} else if s[i] == '"' {
+					inQuote = !inQuote
+				} else if s[i] == ']' && !inQuote {
+					break
+				}
+			}
+			if i >= len(s) {
+				return fmt.Errorf("unterminated structured data in log: %s", s)
+			}
+			s = s[i+1:]
+		}
+	}
+	s = strings.TrimPrefix(s, " ")
+	l.Message = strings.TrimPrefix(s, UTF8_BOM)
+	return nil
+}
+
+func (l *AgentLog) parseRFC3164(s string) error {
+	// the timestamp is RFC3339 if it is sent by deepflow-agent or forwarded by rsyslog with high precision timestamps
+	timestamp, rest := nextField(s)
+	if t, err := time.Parse(time.RFC3339Nano, timestamp); err == nil {
+		l.setTime(t)
+		s = rest
+	} else {
+		if len(s) <= len(RFC3164_TIME) {
+			return errNotEnoughField
+		}
+		t, err := time.ParseInLocation(RFC3164_TIME, s[:len(RFC3164_TIME)], time.Local)
+		if err != nil {
+			return err
+		}
+		// the year is not in the timestamp, it is the last year if the time is later than now, e.g.: Dec 31 received on Jan 1
+		now := time.Now()
+		t = t.AddDate(now.Year(), 0, 0)
+		if t.After(now.Add(24 * time.Hour)) {
+			t = t.AddDate(-1, 0, 0)
+		}
+		l.setTime(t)
+		s = strings.TrimPrefix(s[len(RFC3164_TIME):], " ")
+	}
+
+	l.Host, s = nextField(s)
+	if s == "" {
+		return errNotEnoughField
+	}
+	// TAG is optional, it is the program name with an optional pid, e.g.: deepflow-agent[8642]:
+	tag, rest := nextField(s)
+	if strings.HasSuffix(tag, ":") {
+		tag = tag[:len(tag)-1]
+		if i := strings.IndexByte(tag, '['); i >= 0 {
+			tag = tag[:i]
+		}
+		l.AppName = tag
+		s = rest
+	}
+	l.Message = s
+	return nil
+}
+
+// parseAgentMessage parses the message written by deepflow-agent, e.g.: [INFO] src/sender/uniform_sender.rs:397 update config
+func (l *AgentLog) parseAgentMessage() {
+	level, rest := nextField(l.Message)
+	severity, ok := agentLevels[level]
+	if !ok {
+		return
+	}
+	// the level written by the agent is more accurate than the priority set by the relays
+	l.Severity = uint8(severity)
+	l.Message = rest
+
+	location, rest := nextField(l.Message)
+	if i := strings.LastIndexByte(location, ':'); i > 0 {
+		if _, err := strconv.Atoi(location[i+1:]); err == nil {
+			l.Module = location[:i]
+			l.Message = rest
+		}
+	}
+}
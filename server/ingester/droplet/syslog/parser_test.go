@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package syslog
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSyslog(t *testing.T) {
+	testCases := []struct {
+		log      string
+		expected AgentLog
+		time     string
+	}{
+		{
+			log:      "2020-11-23T16:56:35+08:00 dfi-153 trident[8642]: [INFO] synchronizer.go:397 update FlowAcls version  1605685133 to 1605685134\n",
+			expected: AgentLog{Host: "dfi-153", Severity: 6, Facility: 3, AppName: "trident", Module: "synchronizer.go", Message: "update FlowAcls version  1605685133 to 1605685134"},
+			time:     "2020-11-23T16:56:35+08:00",
+		},
+		{
+			log:      "2024-01-02T03:04:05.123456+00:00 node-1 deepflow-agent[1]: [WARN] src/sender/uniform_sender.rs:12 connect failed",
+			expected: AgentLog{Host: "node-1", Severity: 4, Facility: 3, AppName: "deepflow-agent", Module: "src/sender/uniform_sender.rs", Message: "connect failed"},
+			time:     "2024-01-02T03:04:05.123456Z",
+		},
+		{
+			log:      "<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut=\"3\" eventSource=\"Application\" eventID=\"1011\"][examplePriority@32473 class=\"high\\]\"] \xef\xbb\xbfAn application event log entry...",
+			expected: AgentLog{Host: "mymachine.example.com", Severity: 5, Facility: 20, AppName: "evntslog", Message: "An application event log entry..."},
+			time:     "2003-10-11T22:14:15.003Z",
+		},
+		{
+			log:      "<27>1 2003-08-24T05:14:15.000003-07:00 192.0.2.1 deepflow-agent 8710 - - [ERROR] src/main.rs:1 exit",
+			expected: AgentLog{Host: "192.0.2.1", Severity: 3, Facility: 3, AppName: "deepflow-agent", Module: "src/main.rs", Message: "exit"},
+			time:     "2003-08-24T05:14:15.000003-07:00",
+		},
+		{
+			log:      "<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8",
+			expected: AgentLog{Host: "mymachine", Severity: 2, Facility: 4, AppName: "su", Message: "'su root' failed for lonvick on /dev/pts/8"},
+		},
+		{
+			log:      "<13>Feb  5 17:32:18 10.0.0.99 Use the BFG!",
+			expected: AgentLog{Host: "10.0.0.99", Severity: 5, Facility: 1, Message: "Use the BFG!"},
+		},
+	}
+	for _, tc := range testCases {
+		l := &AgentLog{}
+		if err := ParseSyslog([]byte(tc.log), l); err != nil {
+			t.Errorf("parse %s failed: %s", tc.log, err)
+			continue
+		}
+		if tc.time != "" {
+			expectedTime, _ := time.Parse(time.RFC3339Nano, tc.time)
+			if l.Timestamp != expectedTime.UnixMicro() || l.Time != uint32(expectedTime.Unix()) {
+				t.Errorf("parse %s time %d, expected %d", tc.log, l.Timestamp, expectedTime.UnixMicro())
+			}
+		} else if l.Time == 0 || int64(l.Time) > time.Now().Add(24*time.Hour).Unix() {
+			t.Errorf("parse %s invalid time %d", tc.log, l.Time)
+		}
+		l.Time, l.Timestamp = 0, 0
+		if !reflect.DeepEqual(*l, tc.expected) {
+			t.Errorf("parse %s\n got %+v\nwant %+v", tc.log, *l, tc.expected)
+		}
+	}
+}
+
+func TestParseSyslogInvalid(t *testing.T) {
+	for _, log := range []string{
+		"",
+		"\n",
+		"<999>1 2003-10-11T22:14:15.003Z host app - ID47 - msg",
+		"<165>1 2003-10-11T22:14:15.003Z host",
+		"<165>1 2003-10-11T22:14:15.003Z host app - ID47 [unterminated",
+		"not a syslog",
+	} {
+		if err := ParseSyslog([]byte(log), &AgentLog{}); err == nil {
+			t.Errorf("parse %q should fail", log)
+		}
+	}
+}
+
+func TestParseESLog(t *testing.T) {
+	esLog, err := parseESLog([]byte("2020-11-23T16:56:35+08:00 dfi-153 trident[8642]: [WARN] synchronizer.go:397 update FlowAcls version  1605685133 to 1605685134"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &ESLog{
+		Timestamp: 1606121795,
+		Type:      LOG_TYPE,
+		Host:      "dfi-153",
+		Module:    LOG_MODULE,
+		Severity:  "4",
+		SyslogTag: "synchronizer.go:397",
+		Message:   "update FlowAcls version  1605685133 to 1605685134",
+	}
+	if !reflect.DeepEqual(esLog, expected) {
+		t.Errorf("got %+v, expected %+v", esLog, expected)
+	}
+
+	for _, log := range []string{
+		"2020-11-23T16:56:35+08:00 dfi-153 trident[8642]: [DEBUG] synchronizer.go:397 msg",
+		"2020-11-23T16:56:35+08:00 dfi-153 trident[8642]: [INFO]",
+		"<14>1 2020-11-23T16:56:35+08:00 dfi-153 trident 8642 - - [INFO] synchronizer.go:397 msg",
+	} {
+		if _, err := parseESLog([]byte(log)); err == nil {
+			t.Errorf("parse %q should fail", log)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package syslog
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/deepflowio/deepflow/server/ingester/droplet/config"
+	"github.com/deepflowio/deepflow/server/ingester/pkg/ckwriter"
+	"github.com/deepflowio/deepflow/server/libs/ckdb"
+	"github.com/deepflowio/deepflow/server/libs/utils"
+)
+
+// Sink is an output of the agent logs, all of the sinks are called in the same goroutine
+type Sink interface {
+	// Put is called with the raw log and the parsed one, which is nil if the raw log can not be parsed.
+	// The sink should call AddReferenceCount if it keeps the parsed log after returning.
+	Put(ip net.IP, raw []byte, l *AgentLog)
+	// Flush is called on every flush tick of the queue
+	Flush()
+}
+
+// FileSink writes the raw logs to the daily rotated files named by the agent ips
+type FileSink struct {
+	directory string
+	fileMap   map[uint32]*fileWriter
+}
+
+func NewFileSink(directory string) (*FileSink, error) {
+	if err := os.MkdirAll(directory, os.ModePerm); err != nil {
+		return nil, err
+	}
+	return &FileSink{
+		directory: directory,
+		fileMap:   make(map[uint32]*fileWriter, 8),
+	}, nil
+}
+
+func (s *FileSink) create(ip net.IP) *fileWriter {
+	fileName := filepath.Join(s.directory, ip.String()+".log")
+	return &fileWriter{NewRotateWriter(fileName), _FILE_FEED}
+}
+
+func (s *FileSink) Put(ip net.IP, raw []byte, l *AgentLog) {
+	hash := utils.GetIpHash(ip)
+	writer, in := s.fileMap[hash]
+	if !in {
+		writer = s.create(ip)
+		s.fileMap[hash] = writer
+	}
+	writer.fileBuffer.Write(raw)
+	writer.feed = _FILE_FEED
+}
+
+func (s *FileSink) Flush() {
+	for key, value := range s.fileMap {
+		value.fileBuffer.Flush()
+		value.feed--
+		if value.feed == 0 {
+			value.fileBuffer.Close()
+			delete(s.fileMap, key)
+		}
+	}
+}
+
+// ESSink writes the logs of level INFO, WARN and ERROR to elasticsearch, the raw logs are parsed by parseESLog
+// instead of ParseSyslog to keep the documents the same as before
+type ESSink struct {
+	esLogger *ESLogger
+}
+
+func NewESSink(addresses []string, username, password string) *ESSink {
+	return &ESSink{esLogger: NewESLogger(addresses, username, password)}
+}
+
+func (s *ESSink) Put(ip net.IP, raw []byte, l *AgentLog) {
+	if esLog, err := parseESLog(raw); err == nil {
+		s.esLogger.Log(esLog)
+	} else {
+		log.Debug("invalid log message for es:", err)
+	}
+}
+
+func (s *ESSink) Flush() {
+	s.esLogger.Flush()
+}
+
+// CKSink writes the parsed logs to the clickhouse table event.agent_log
+type CKSink struct {
+	ckwriter *ckwriter.CKWriter
+}
+
+func NewCKSink(cfg *config.Config) (*CKSink, error) {
+	base := cfg.Base
+	table := GenAgentLogCKTable(base.CKDB.ClusterName, base.CKDB.StoragePolicy, cfg.AgentLogTTL,
+		ckdb.GetColdStorage(base.GetCKDBColdStorages(), AGENT_LOG_DB, AGENT_LOG_TABLE))
+	writerConfig := cfg.AgentLogCKWriter
+	w, err := ckwriter.NewCKWriter(base.CKDB.ActualAddrs, base.CKDBAuth.Username, base.CKDBAuth.Password,
+		AGENT_LOG_TABLE, base.CKDB.TimeZone, table, writerConfig.QueueCount, writerConfig.QueueSize, writerConfig.BatchSize, writerConfig.FlushTimeout)
+	if err != nil {
+		return nil, err
+	}
+	w.Run()
+	return &CKSink{ckwriter: w}, nil
+}
+
+func (s *CKSink) Put(ip net.IP, raw []byte, l *AgentLog) {
+	if l == nil {
+		return
+	}
+	l.AddReferenceCount()
+	s.ckwriter.Put(l)
+}
+
+func (s *CKSink) Flush() {}
@@ -17,18 +17,13 @@
 package syslog
 
 import (
-	"bytes"
-	"errors"
-	"log/syslog"
 	"net"
-	"os"
-	"path/filepath"
-	"strconv"
-	"time"
 
-	"github.com/deepflowio/deepflow/server/libs/codec"
 	logging "github.com/op/go-logging"
 
+	"github.com/deepflowio/deepflow/server/ingester/common"
+	"github.com/deepflowio/deepflow/server/ingester/droplet/config"
+	"github.com/deepflowio/deepflow/server/libs/codec"
 	"github.com/deepflowio/deepflow/server/libs/queue"
 	"github.com/deepflowio/deepflow/server/libs/receiver"
 	"github.com/deepflowio/deepflow/server/libs/utils"
@@ -53,115 +48,76 @@ type fileWriter struct {
 	feed int
 }
 
-type syslogWriter struct {
-	directory        string
-	logToFileEnabled bool
-
-	index   int
-	fileMap map[uint32]*fileWriter
-	in      queue.QueueReader
-
-	esLogger *ESLogger
+type Counter struct {
+	InCount       int64 `statsd:"in-count"`
+	ParseErrCount int64 `statsd:"parse-err-count"`
 }
 
-func (w *syslogWriter) create(ip net.IP) *fileWriter {
-	fileName := filepath.Join(w.directory, ip.String()+".log")
-	return &fileWriter{NewRotateWriter(fileName), _FILE_FEED}
-}
+type syslogWriter struct {
+	in    queue.QueueReader
+	sinks []Sink
 
-func (w *syslogWriter) write(writer *fileWriter, bytes []byte) {
-	writer.fileBuffer.Write(bytes)
-	writer.feed = _FILE_FEED
+	counter *Counter
+	utils.Closable
 }
 
-func (w *syslogWriter) writeFile(ip net.IP, bytes []byte) {
-	if !w.logToFileEnabled {
-		return
-	}
-	if bytes == nil {
-		// tick
-		for key, value := range w.fileMap {
-			value.fileBuffer.Flush()
-			value.feed--
-			if value.feed == 0 {
-				value.fileBuffer.Close()
-				delete(w.fileMap, key)
-			}
-		}
-		return
-	}
-	hash := utils.GetIpHash(ip)
-	if _, in := w.fileMap[hash]; !in {
-		w.fileMap[hash] = w.create(ip)
-	}
-	w.write(w.fileMap[hash], bytes)
+func (w *syslogWriter) GetCounter() interface{} {
+	var counter *Counter
+	counter, w.counter = w.counter, &Counter{}
+	return counter
 }
 
-func (w *syslogWriter) writeES(bytes []byte) {
-	if w.esLogger == nil {
-		return
+func (w *syslogWriter) put(ip net.IP, vtapID uint16, bytes []byte) {
+	w.counter.InCount++
+	l := AcquireAgentLog()
+	if err := ParseSyslog(bytes, l); err != nil {
+		if w.counter.ParseErrCount == 0 {
+			log.Debugf("invalid agent log from %s: %s", ip, err)
+		}
+		w.counter.ParseErrCount++
+		l.Release()
+		l = nil
+	} else {
+		l.VtapID = vtapID
+		l.SetIP(ip)
 	}
-	if bytes == nil {
-		// tick
-		w.esLogger.Flush()
-		return
+	for _, sink := range w.sinks {
+		sink.Put(ip, bytes, l)
 	}
-	if esLog, err := parseSyslog(bytes); err == nil {
-		w.esLogger.Log(esLog)
-	} else {
-		log.Debug("invalid log message for es:", err)
+	if l != nil {
+		l.Release()
 	}
 }
 
-func parseSyslog(bs []byte) (*ESLog, error) {
-	// example log
-	// 2020-11-23T16:56:35+08:00 dfi-153 trident[8642]: [INFO] synchronizer.go:397 update FlowAcls version  1605685133 to 1605685134
-	columns := bytes.SplitN(bs, []byte{' '}, 6)
-	if len(columns) != 6 {
-		return nil, errors.New("not enough columns in log")
-	}
-	esLog := ESLog{Type: LOG_TYPE, Module: LOG_MODULE}
-	datetime, err := time.Parse(time.RFC3339, string(columns[0]))
-	if err != nil {
-		return nil, err
+func (w *syslogWriter) flush() {
+	for _, sink := range w.sinks {
+		sink.Flush()
 	}
-	esLog.Timestamp = uint32(datetime.Unix())
-	esLog.Host = string(columns[1])
-	severity := syslog.Priority(0)
-	switch string(columns[3]) {
-	case "[INFO]":
-		severity = syslog.LOG_INFO
-	case "[WARN]":
-		severity = syslog.LOG_WARNING
-	case "[ERRO]", "[ERROR]":
-		severity = syslog.LOG_ERR
-	default:
-		return nil, errors.New("ignored log level: " + string(columns[3]))
-	}
-	esLog.Severity = strconv.Itoa(int(severity))
-	esLog.SyslogTag = string(columns[4])
-	esLog.Message = string(columns[5])
-	return &esLog, nil
 }
 
-func NewSyslogWriter(in queue.QueueReader, logToFileEnabled, esEnabled bool, directory string, esAddresses []string, esUsername, esPassword string) *syslogWriter {
-	if logToFileEnabled {
-		if err := os.MkdirAll(directory, os.ModePerm); err != nil {
-			log.Warningf("cannot output syslog to directory %s: %v", directory, err)
-			return &syslogWriter{}
+func NewSyslogWriter(in queue.QueueReader, cfg *config.Config) *syslogWriter {
+	writer := &syslogWriter{
+		in:      in,
+		counter: &Counter{},
+	}
+	if cfg.AgentLogToFile {
+		if sink, err := NewFileSink(cfg.SyslogDirectory); err != nil {
+			log.Warningf("cannot output syslog to directory %s: %v", cfg.SyslogDirectory, err)
+		} else {
+			writer.sinks = append(writer.sinks, sink)
 		}
 	}
-	var esLogger *ESLogger
-	if esEnabled {
-		esLogger = NewESLogger(esAddresses, esUsername, esPassword)
+	if cfg.ESSyslog {
+		writer.sinks = append(writer.sinks, NewESSink(cfg.ESHostPorts, cfg.ESAuth.User, cfg.ESAuth.Password))
 	}
-	writer := &syslogWriter{
-		logToFileEnabled: logToFileEnabled,
-		directory:        directory,
-		fileMap:          make(map[uint32]*fileWriter, 8),
-		in:               in,
-		esLogger:         esLogger,
+	if cfg.AgentLogToCK && !cfg.Base.StorageDisabled {
+		if sink, err := NewCKSink(cfg); err != nil {
+			log.Warningf("cannot output syslog to clickhouse: %v", err)
+		} else {
+			writer.sinks = append(writer.sinks, sink)
+		}
 	}
+	common.RegisterCountableForIngester("syslog", writer)
 
 	go writer.run()
 	return writer
@@ -178,22 +134,19 @@ func (w *syslogWriter) run() {
 			if receiveBuffer, ok := value.(*receiver.RecvBuffer); ok {
 				bytes := receiveBuffer.Buffer[receiveBuffer.Begin:receiveBuffer.End]
 				if receiveBuffer.SocketType == receiver.UDP {
-					w.writeFile(receiveBuffer.IP, bytes)
-					w.writeES(bytes)
+					w.put(receiveBuffer.IP, receiveBuffer.VtapID, bytes)
 				} else {
 					decoder.Init(bytes)
 					for !decoder.IsEnd() {
 						syslog := decoder.ReadBytes()
 						if syslog != nil {
-							w.writeFile(receiveBuffer.IP, syslog)
-							w.writeES(syslog)
+							w.put(receiveBuffer.IP, receiveBuffer.VtapID, syslog)
 						}
 					}
 				}
 				receiver.ReleaseRecvBuffer(receiveBuffer)
 			} else if value == nil { // flush ticker
-				w.writeFile(nil, nil)
-				w.writeES(nil)
+				w.flush()
 			} else {
 				log.Warning("get queue data type wrong")
 			}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/influxdata/influxdb/models"
 	logging "github.com/op/go-logging"
+	metricsv1 "go.opentelemetry.io/proto/otlp/metrics/v1"
 
 	"github.com/deepflowio/deepflow/server/ingester/common"
 	"github.com/deepflowio/deepflow/server/ingester/ext_metrics/config"
@@ -53,6 +54,7 @@ type Counter struct {
 	ErrorCount             int64 `statsd:"err-count"`
 	ErrMetrics             int64 `statsd:"err-metrics"`
 	DropUnsupportedMetrics int64 `statsd:"drop-unsupported-metrics"`
+	DropNoRecordedValue    int64 `statsd:"drop-no-recorded-value"`
 }
 
 type Decoder struct {
@@ -111,6 +113,7 @@ func (d *Decoder) Run() {
 	d.initMetricsTable()
 	buffer := make([]interface{}, BUFFER_SIZE)
 	decoder := &codec.SimpleDecoder{}
+	pbMetricsData := &metricsv1.MetricsData{}
 	for {
 		n := d.inQueue.Gets(buffer)
 		for i := 0; i < n; i++ {
@@ -128,6 +131,8 @@ func (d *Decoder) Run() {
 				d.handleTelegraf(recvBytes.VtapID, decoder)
 			} else if d.msgType == datatype.MESSAGE_TYPE_DFSTATS {
 				d.handleDeepflowStats(recvBytes.VtapID, decoder)
+			} else if d.msgType == datatype.MESSAGE_TYPE_OPENTELEMETRY_METRICS {
+				d.handleOpenTelemetryMetrics(recvBytes.VtapID, decoder, pbMetricsData)
 			}
 			receiver.ReleaseRecvBuffer(recvBytes)
 		}
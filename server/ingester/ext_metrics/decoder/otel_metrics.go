@@ -0,0 +1,317 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package decoder
+
+import (
+	"encoding/hex"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	v11 "go.opentelemetry.io/proto/otlp/common/v1"
+	metricsv1 "go.opentelemetry.io/proto/otlp/metrics/v1"
+	v1 "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"github.com/deepflowio/deepflow/server/ingester/ext_metrics/dbwriter"
+	"github.com/deepflowio/deepflow/server/libs/codec"
+	"github.com/deepflowio/deepflow/server/libs/datatype"
+)
+
+const (
+	VTABLE_PREFIX_OTEL = "otel."
+	OTEL_POD_NAME      = "k8s.pod.name"
+
+	// the metrics names of the OTel data points, histogram buckets and summary quantiles are named by their bounds,
+	// which is the same as the Prometheus metrics converted by Telegraf
+	OTEL_METRICS_VALUE      = "value"
+	OTEL_METRICS_COUNT      = "count"
+	OTEL_METRICS_SUM        = "sum"
+	OTEL_METRICS_MIN        = "min"
+	OTEL_METRICS_MAX        = "max"
+	OTEL_METRICS_ZERO_COUNT = "zero_count"
+	OTEL_METRICS_INF_BUCKET = "+Inf"
+)
+
+type otelResource struct {
+	tagNames  []string
+	tagValues []string
+	podName   string
+}
+
+func (r *otelResource) reset(resource *v1.Resource) {
+	r.tagNames = r.tagNames[:0]
+	r.tagValues = r.tagValues[:0]
+	r.podName = ""
+	for _, attr := range resource.GetAttributes() {
+		value := otelValueString(attr.GetValue())
+		r.tagNames = append(r.tagNames, attr.GetKey())
+		r.tagValues = append(r.tagValues, value)
+		if attr.GetKey() == OTEL_POD_NAME {
+			r.podName = value
+		}
+	}
+}
+
+func otelValueString(value *v11.AnyValue) string {
+	switch v := value.GetValue().(type) {
+	case *v11.AnyValue_StringValue:
+		return v.StringValue
+	case *v11.AnyValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue)
+	case *v11.AnyValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10)
+	case *v11.AnyValue_DoubleValue:
+		return strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+	case *v11.AnyValue_BytesValue:
+		return hex.EncodeToString(v.BytesValue)
+	case nil:
+		return ""
+	default:
+		// array and kvlist values
+		return value.String()
+	}
+}
+
+func formatBound(bound float64) string {
+	return strconv.FormatFloat(bound, 'g', -1, 64)
+}
+
+func (d *Decoder) handleOpenTelemetryMetrics(vtapID uint16, decoder *codec.SimpleDecoder, pbMetricsData *metricsv1.MetricsData) {
+	for !decoder.IsEnd() {
+		pbMetricsData.Reset()
+		bytes := decoder.ReadBytes()
+		var err error
+		if len(bytes) > 0 {
+			err = proto.Unmarshal(bytes, pbMetricsData)
+		}
+		if decoder.Failed() || err != nil {
+			if d.counter.ErrorCount == 0 {
+				log.Errorf("OpenTelemetry metrics decode failed, offset=%d len=%d err: %s", decoder.Offset(), len(decoder.Bytes()), err)
+			}
+			d.counter.ErrorCount++
+			return
+		}
+		d.sendOpenTelemetryMetrics(vtapID, pbMetricsData)
+	}
+}
+
+func (d *Decoder) sendOpenTelemetryMetrics(vtapID uint16, metricsData *metricsv1.MetricsData) {
+	if d.debugEnabled {
+		log.Debugf("decoder %d vtap %d recv otel metrics: %s", d.index, vtapID, metricsData)
+	}
+	for _, m := range d.OTelMetricsDataToExtMetrics(vtapID, metricsData) {
+		d.extMetricsWriter.Write(m)
+		d.counter.OutCount++
+	}
+}
+
+// OTelMetricsDataToExtMetrics converts each data point to an ExtMetrics, the virtual table name is 'otel.${metric_name}',
+// the tags are the attributes of the data point and the resource.
+// The values are stored as they are reported, the temporality of sum and histogram is not converted.
+func (d *Decoder) OTelMetricsDataToExtMetrics(vtapID uint16, metricsData *metricsv1.MetricsData) []*dbwriter.ExtMetrics {
+	var ms []*dbwriter.ExtMetrics
+	resource := &otelResource{}
+	for _, resourceMetrics := range metricsData.GetResourceMetrics() {
+		resource.reset(resourceMetrics.GetResource())
+		for _, scopeMetrics := range resourceMetrics.GetScopeMetrics() {
+			for _, metric := range scopeMetrics.GetMetrics() {
+				ms = d.appendOTelMetric(ms, vtapID, resource, metric)
+			}
+		}
+	}
+	return ms
+}
+
+func (d *Decoder) appendOTelMetric(ms []*dbwriter.ExtMetrics, vtapID uint16, resource *otelResource, metric *metricsv1.Metric) []*dbwriter.ExtMetrics {
+	vtableName := VTABLE_PREFIX_OTEL + metric.GetName()
+	switch data := metric.GetData().(type) {
+	case *metricsv1.Metric_Gauge:
+		for _, dp := range data.Gauge.GetDataPoints() {
+			if m := d.newOTelExtMetrics(vtapID, vtableName, resource, dp.GetAttributes(), dp.GetTimeUnixNano(), dp.GetFlags()); m != nil {
+				appendNumberDataPoint(m, dp)
+				ms = append(ms, m)
+			}
+		}
+	case *metricsv1.Metric_Sum:
+		for _, dp := range data.Sum.GetDataPoints() {
+			if m := d.newOTelExtMetrics(vtapID, vtableName, resource, dp.GetAttributes(), dp.GetTimeUnixNano(), dp.GetFlags()); m != nil {
+				appendNumberDataPoint(m, dp)
+				ms = append(ms, m)
+			}
+		}
+	case *metricsv1.Metric_Histogram:
+		for _, dp := range data.Histogram.GetDataPoints() {
+			if m := d.newOTelExtMetrics(vtapID, vtableName, resource, dp.GetAttributes(), dp.GetTimeUnixNano(), dp.GetFlags()); m != nil {
+				appendHistogramDataPoint(m, dp)
+				ms = append(ms, m)
+			}
+		}
+	case *metricsv1.Metric_ExponentialHistogram:
+		for _, dp := range data.ExponentialHistogram.GetDataPoints() {
+			if m := d.newOTelExtMetrics(vtapID, vtableName, resource, dp.GetAttributes(), dp.GetTimeUnixNano(), dp.GetFlags()); m != nil {
+				appendExponentialHistogramDataPoint(m, dp)
+				ms = append(ms, m)
+			}
+		}
+	case *metricsv1.Metric_Summary:
+		for _, dp := range data.Summary.GetDataPoints() {
+			if m := d.newOTelExtMetrics(vtapID, vtableName, resource, dp.GetAttributes(), dp.GetTimeUnixNano(), dp.GetFlags()); m != nil {
+				appendSummaryDataPoint(m, dp)
+				ms = append(ms, m)
+			}
+		}
+	default:
+		if d.counter.DropUnsupportedMetrics&0xff == 0 {
+			log.Warningf("drop unsupported otel metrics name: %s type: %T. total drop %d", metric.GetName(), data, d.counter.DropUnsupportedMetrics)
+		}
+		d.counter.DropUnsupportedMetrics++
+	}
+	return ms
+}
+
+// newOTelExtMetrics returns nil if the data point has no recorded value
+func (d *Decoder) newOTelExtMetrics(vtapID uint16, vtableName string, resource *otelResource, attributes []*v11.KeyValue, timeUnixNano uint64, flags uint32) *dbwriter.ExtMetrics {
+	if flags&uint32(metricsv1.DataPointFlags_DATA_POINT_FLAGS_NO_RECORDED_VALUE_MASK) != 0 {
+		d.counter.DropNoRecordedValue++
+		return nil
+	}
+	m := dbwriter.AcquireExtMetrics()
+	if timeUnixNano > 0 {
+		m.Timestamp = uint32(timeUnixNano / uint64(time.Second))
+	} else {
+		m.Timestamp = uint32(time.Now().Unix())
+	}
+	m.MsgType = datatype.MESSAGE_TYPE_OPENTELEMETRY_METRICS
+	m.VTableName = vtableName
+	// the attributes of data point take precedence over the resource attributes with the same name
+	for _, attr := range attributes {
+		appendOTelTag(m, attr.GetKey(), otelValueString(attr.GetValue()), true)
+	}
+	for i, name := range resource.tagNames {
+		appendOTelTag(m, name, resource.tagValues[i], false)
+	}
+	d.fillExtMetricsBase(m, vtapID, resource.podName, true)
+	return m
+}
+
+// appendOTelTag keeps the tag names unique, the value of an existing tag is replaced only if override is true
+func appendOTelTag(m *dbwriter.ExtMetrics, name, value string, override bool) {
+	for i, tagName := range m.TagNames {
+		if tagName == name {
+			if override {
+				m.TagValues[i] = value
+			}
+			return
+		}
+	}
+	m.TagNames = append(m.TagNames, name)
+	m.TagValues = append(m.TagValues, value)
+}
+
+func appendMetrics(m *dbwriter.ExtMetrics, name string, value float64) {
+	m.MetricsFloatNames = append(m.MetricsFloatNames, name)
+	m.MetricsFloatValues = append(m.MetricsFloatValues, value)
+}
+
+func appendNumberDataPoint(m *dbwriter.ExtMetrics, dp *metricsv1.NumberDataPoint) {
+	switch v := dp.GetValue().(type) {
+	case *metricsv1.NumberDataPoint_AsDouble:
+		appendMetrics(m, OTEL_METRICS_VALUE, v.AsDouble)
+	case *metricsv1.NumberDataPoint_AsInt:
+		appendMetrics(m, OTEL_METRICS_VALUE, float64(v.AsInt))
+	}
+}
+
+// appendHistogramDataPoint stores the buckets as cumulative counts named by their upper bounds
+func appendHistogramDataPoint(m *dbwriter.ExtMetrics, dp *metricsv1.HistogramDataPoint) {
+	appendMetrics(m, OTEL_METRICS_COUNT, float64(dp.GetCount()))
+	if dp.Sum != nil {
+		appendMetrics(m, OTEL_METRICS_SUM, dp.GetSum())
+	}
+	if dp.Min != nil {
+		appendMetrics(m, OTEL_METRICS_MIN, dp.GetMin())
+	}
+	if dp.Max != nil {
+		appendMetrics(m, OTEL_METRICS_MAX, dp.GetMax())
+	}
+
+	bounds, counts := dp.GetExplicitBounds(), dp.GetBucketCounts()
+	if len(counts) != len(bounds)+1 {
+		return
+	}
+	cumulative := uint64(0)
+	for i, bound := range bounds {
+		cumulative += counts[i]
+		appendMetrics(m, formatBound(bound), float64(cumulative))
+	}
+	cumulative += counts[len(bounds)]
+	appendMetrics(m, OTEL_METRICS_INF_BUCKET, float64(cumulative))
+}
+
+// appendExponentialHistogramDataPoint converts the exponential buckets to cumulative counts named by their upper bounds,
+// the upper bound of bucket index i is base^(i+1), where base = 2^(2^-scale)
+func appendExponentialHistogramDataPoint(m *dbwriter.ExtMetrics, dp *metricsv1.ExponentialHistogramDataPoint) {
+	appendMetrics(m, OTEL_METRICS_COUNT, float64(dp.GetCount()))
+	if dp.Sum != nil {
+		appendMetrics(m, OTEL_METRICS_SUM, dp.GetSum())
+	}
+	if dp.Min != nil {
+		appendMetrics(m, OTEL_METRICS_MIN, dp.GetMin())
+	}
+	if dp.Max != nil {
+		appendMetrics(m, OTEL_METRICS_MAX, dp.GetMax())
+	}
+	appendMetrics(m, OTEL_METRICS_ZERO_COUNT, float64(dp.GetZeroCount()))
+
+	factor := math.Exp2(-float64(dp.GetScale()))
+	cumulative := uint64(0)
+	// the negative buckets are in ascending order of absolute value, so traverse them backwards
+	negative := dp.GetNegative()
+	negativeCounts := negative.GetBucketCounts()
+	for i := len(negativeCounts) - 1; i >= 0; i-- {
+		if negativeCounts[i] == 0 {
+			continue
+		}
+		cumulative += negativeCounts[i]
+		index := float64(negative.GetOffset()) + float64(i)
+		appendMetrics(m, formatBound(-math.Exp2(index*factor)), float64(cumulative))
+	}
+	cumulative += dp.GetZeroCount()
+	if dp.GetZeroCount() > 0 {
+		appendMetrics(m, formatBound(dp.GetZeroThreshold()), float64(cumulative))
+	}
+	positive := dp.GetPositive()
+	for i, count := range positive.GetBucketCounts() {
+		if count == 0 {
+			continue
+		}
+		cumulative += count
+		index := float64(positive.GetOffset()) + float64(i)
+		appendMetrics(m, formatBound(math.Exp2((index+1)*factor)), float64(cumulative))
+	}
+	appendMetrics(m, OTEL_METRICS_INF_BUCKET, float64(cumulative))
+}
+
+// appendSummaryDataPoint stores the quantiles named by their quantile, e.g.: 0.99
+func appendSummaryDataPoint(m *dbwriter.ExtMetrics, dp *metricsv1.SummaryDataPoint) {
+	appendMetrics(m, OTEL_METRICS_COUNT, float64(dp.GetCount()))
+	appendMetrics(m, OTEL_METRICS_SUM, dp.GetSum())
+	for _, q := range dp.GetQuantileValues() {
+		appendMetrics(m, formatBound(q.GetQuantile()), q.GetValue())
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package decoder
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	v11 "go.opentelemetry.io/proto/otlp/common/v1"
+	metricsv1 "go.opentelemetry.io/proto/otlp/metrics/v1"
+	v1 "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"github.com/deepflowio/deepflow/server/ingester/ext_metrics/dbwriter"
+	"github.com/deepflowio/deepflow/server/libs/datatype"
+	"github.com/deepflowio/deepflow/server/libs/grpc"
+)
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+func stringKeyValue(key, value string) *v11.KeyValue {
+	return &v11.KeyValue{Key: key, Value: &v11.AnyValue{Value: &v11.AnyValue_StringValue{StringValue: value}}}
+}
+
+func metricsString(m *dbwriter.ExtMetrics) string {
+	s := ""
+	for i, name := range m.MetricsFloatNames {
+		s += fmt.Sprintf("%s=%g ", name, m.MetricsFloatValues[i])
+	}
+	return s
+}
+
+func TestOTelMetricsDataToExtMetrics(t *testing.T) {
+	d := NewDecoder(0, datatype.MESSAGE_TYPE_OPENTELEMETRY_METRICS, grpc.NewPlatformInfoTable(nil, 0, 0, 0, "", "", nil, true, nil), nil, nil, nil)
+	timestamp := uint64(1700000000123456789)
+	metricsData := &metricsv1.MetricsData{
+		ResourceMetrics: []*metricsv1.ResourceMetrics{{
+			Resource: &v1.Resource{Attributes: []*v11.KeyValue{stringKeyValue("service.name", "svc-a")}},
+			ScopeMetrics: []*metricsv1.ScopeMetrics{{
+				Metrics: []*metricsv1.Metric{
+					{Name: "queue.size", Data: &metricsv1.Metric_Gauge{Gauge: &metricsv1.Gauge{DataPoints: []*metricsv1.NumberDataPoint{
+						{TimeUnixNano: timestamp, Value: &metricsv1.NumberDataPoint_AsInt{AsInt: 5}, Attributes: []*v11.KeyValue{stringKeyValue("queue", "q1")}},
+						{TimeUnixNano: timestamp, Flags: uint32(metricsv1.DataPointFlags_DATA_POINT_FLAGS_NO_RECORDED_VALUE_MASK)},
+					}}}},
+					{Name: "requests", Data: &metricsv1.Metric_Sum{Sum: &metricsv1.Sum{IsMonotonic: true, DataPoints: []*metricsv1.NumberDataPoint{
+						{TimeUnixNano: timestamp, Value: &metricsv1.NumberDataPoint_AsDouble{AsDouble: 1.5}, Attributes: []*v11.KeyValue{stringKeyValue("service.name", "svc-b"), stringKeyValue("path", "/a")}},
+					}}}},
+					{Name: "latency", Data: &metricsv1.Metric_Histogram{Histogram: &metricsv1.Histogram{DataPoints: []*metricsv1.HistogramDataPoint{
+						{TimeUnixNano: timestamp, Count: 6, Sum: float64Ptr(12), Max: float64Ptr(9), ExplicitBounds: []float64{1, 5}, BucketCounts: []uint64{1, 2, 3}},
+					}}}},
+					{Name: "size", Data: &metricsv1.Metric_ExponentialHistogram{ExponentialHistogram: &metricsv1.ExponentialHistogram{DataPoints: []*metricsv1.ExponentialHistogramDataPoint{
+						{TimeUnixNano: timestamp, Count: 7, Scale: 0, ZeroCount: 1,
+							Negative: &metricsv1.ExponentialHistogramDataPoint_Buckets{Offset: 0, BucketCounts: []uint64{1}},
+							Positive: &metricsv1.ExponentialHistogramDataPoint_Buckets{Offset: 1, BucketCounts: []uint64{2, 0, 3}}},
+					}}}},
+					{Name: "rt", Data: &metricsv1.Metric_Summary{Summary: &metricsv1.Summary{DataPoints: []*metricsv1.SummaryDataPoint{
+						{TimeUnixNano: timestamp, Count: 10, Sum: 20, QuantileValues: []*metricsv1.SummaryDataPoint_ValueAtQuantile{{Quantile: 0.5, Value: 1}, {Quantile: 0.99, Value: 8}}},
+					}}}},
+					{Name: "empty"},
+				},
+			}},
+		}},
+	}
+
+	ms := d.OTelMetricsDataToExtMetrics(1, metricsData)
+	expected := []struct {
+		vtableName string
+		metrics    string
+	}{
+		{"otel.queue.size", "value=5 "},
+		{"otel.requests", "value=1.5 "},
+		{"otel.latency", "count=6 sum=12 max=9 1=1 5=3 +Inf=6 "},
+		{"otel.size", "count=7 zero_count=1 -1=1 0=2 4=4 16=7 +Inf=7 "},
+		{"otel.rt", "count=10 sum=20 0.5=1 0.99=8 "},
+	}
+	if len(ms) != len(expected) {
+		t.Fatalf("got %d metrics, expected %d", len(ms), len(expected))
+	}
+	for i, m := range ms {
+		if m.VTableName != expected[i].vtableName || metricsString(m) != expected[i].metrics {
+			t.Errorf("got %s %s, expected %s %s", m.VTableName, metricsString(m), expected[i].vtableName, expected[i].metrics)
+		}
+		if m.Timestamp != 1700000000 || m.MsgType != datatype.MESSAGE_TYPE_OPENTELEMETRY_METRICS || m.UniversalTag.VTAPID != 1 {
+			t.Errorf("unexpected metrics %+v", m)
+		}
+	}
+	if !reflect.DeepEqual(ms[0].TagNames, []string{"queue", "service.name"}) || !reflect.DeepEqual(ms[0].TagValues, []string{"q1", "svc-a"}) {
+		t.Errorf("unexpected tags %v %v", ms[0].TagNames, ms[0].TagValues)
+	}
+	// the data point attributes override the resource attributes with the same name
+	if !reflect.DeepEqual(ms[1].TagNames, []string{"service.name", "path"}) || !reflect.DeepEqual(ms[1].TagValues, []string{"svc-b", "/a"}) {
+		t.Errorf("unexpected tags %v %v", ms[1].TagNames, ms[1].TagValues)
+	}
+	if d.counter.DropNoRecordedValue != 1 || d.counter.DropUnsupportedMetrics != 1 {
+		t.Errorf("unexpected counter %+v", *d.counter)
+	}
+}
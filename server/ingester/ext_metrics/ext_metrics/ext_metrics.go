@@ -44,6 +44,7 @@ type ExtMetrics struct {
 	Config        *config.Config
 	Telegraf      *Metricsor
 	MetaflowStats *Metricsor
+	OTelMetrics   *Metricsor
 }
 
 type Metricsor struct {
@@ -65,10 +66,15 @@ func NewExtMetrics(config *config.Config, recv *receiver.Receiver, platformDataM
 	if err != nil {
 		return nil, err
 	}
+	otelMetrics, err := NewMetricsor(datatype.MESSAGE_TYPE_OPENTELEMETRY_METRICS, dbwriter.EXT_METRICS_DB, config, platformDataManager, manager, recv, true)
+	if err != nil {
+		return nil, err
+	}
 	return &ExtMetrics{
 		Config:        config,
 		Telegraf:      telegraf,
 		MetaflowStats: deepflowStats,
+		OTelMetrics:   otelMetrics,
 	}, nil
 }
 
@@ -89,7 +95,7 @@ func NewMetricsor(msgType datatype.MessageType, db string, config *config.Config
 		if platformDataEnabled {
 			var err error
 			platformDatas[i], err = platformDataManager.NewPlatformInfoTable("ext-metrics-" + msgType.String() + "-" + strconv.Itoa(i))
-			if i == 0 {
+			if i == 0 && msgType == datatype.MESSAGE_TYPE_TELEGRAF {
 				debug.ServerRegisterSimple(CMD_PLATFORMDATA_EXT_METRICS, platformDatas[i])
 			}
 			if err != nil {
@@ -140,10 +146,12 @@ func (m *Metricsor) Close() {
 func (s *ExtMetrics) Start() {
 	s.Telegraf.Start()
 	s.MetaflowStats.Start()
+	s.OTelMetrics.Start()
 }
 
 func (s *ExtMetrics) Close() error {
 	s.Telegraf.Close()
 	s.MetaflowStats.Close()
+	s.OTelMetrics.Close()
 	return nil
 }
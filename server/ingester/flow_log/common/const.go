@@ -27,6 +27,7 @@ const (
 	L7_FLOW_ID
 	L4_PACKET_ID
 	L7_PACKET_ID
+	APP_LOG_ID
 
 	FLOWLOG_ID_MAX
 )
@@ -36,6 +37,7 @@ var flowLogNames = []string{
 	L7_FLOW_ID:   "l7_flow_log",
 	L4_PACKET_ID: "l4_packet",
 	L7_PACKET_ID: "l7_packet",
+	APP_LOG_ID:   "application_log",
 }
 
 func (l FlowLogID) String() string {
@@ -17,8 +17,10 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
+	"regexp"
 
 	logging "github.com/op/go-logging"
 	yaml "gopkg.in/yaml.v2"
@@ -36,12 +38,70 @@ const (
 	DefaultDecoderQueueSize  = 1 << 14
 	DefaultBrokerQueueSize   = 1 << 14
 	DefaultFlowLogTTL        = 72 // hour
+
+	DefaultTailSamplingSlowPercentile = 99
+	DefaultTailSamplingTraceCacheSize = 1 << 16
+
+	DefaultRedactionMask = "***"
+)
+
+const (
+	RESPONSE_STATUS_SERVER_ERROR = "server-error"
+	RESPONSE_STATUS_CLIENT_ERROR = "client-error"
+	RESPONSE_STATUS_TIMEOUT      = "timeout"
 )
 
+// TailSampling keeps the important l7 flow logs when 'throttle' is exceeded, the others are still reservoir sampled
+type TailSampling struct {
+	Enabled            bool     `yaml:"enabled"`
+	KeepResponseStatus []string `yaml:"keep-response-status"` // server-error, client-error, timeout
+	KeepSlowPercentile float64  `yaml:"keep-slow-percentile"` // keep the rows whose response_duration is above it, 0 means disabled
+	KeepTrace          bool     `yaml:"keep-trace"`           // keep every span of the trace_id which has a span kept by the policies above
+	PriorityThrottle   int      `yaml:"priority-throttle"`    // max rows kept by the policies per second, 0 means the same as throttle
+	TraceCacheSize     int      `yaml:"trace-cache-size"`
+}
+
+const (
+	REDACTION_FIELD_REQUEST_RESOURCE   = "request_resource"
+	REDACTION_FIELD_REQUEST_DOMAIN     = "request_domain"
+	REDACTION_FIELD_ENDPOINT           = "endpoint"
+	REDACTION_FIELD_RESPONSE_RESULT    = "response_result"
+	REDACTION_FIELD_RESPONSE_EXCEPTION = "response_exception"
+	REDACTION_FIELD_ATTRIBUTE          = "attribute" // the values of attribute
+)
+
+var redactionFields = []string{
+	REDACTION_FIELD_REQUEST_RESOURCE,
+	REDACTION_FIELD_REQUEST_DOMAIN,
+	REDACTION_FIELD_ENDPOINT,
+	REDACTION_FIELD_RESPONSE_RESULT,
+	REDACTION_FIELD_RESPONSE_EXCEPTION,
+	REDACTION_FIELD_ATTRIBUTE,
+}
+
+type RedactionRule struct {
+	Protocols   []string `yaml:"protocols"` // l7 protocol names, e.g.: HTTP, MySQL. empty means all protocols
+	Fields      []string `yaml:"fields"`    // empty means request_resource
+	Regex       string   `yaml:"regex"`
+	Replacement string   `yaml:"replacement"` // empty means 'mask', supports $1 to reference the submatches
+}
+
+// Redaction masks the sensitive data of l7 flow logs before they are stored and exported
+type Redaction struct {
+	Enabled             bool            `yaml:"enabled"`
+	Mask                string          `yaml:"mask"`
+	SQLNormalize        bool            `yaml:"sql-normalize"`          // replace the literals in SQL with '?'
+	QueryParams         []string        `yaml:"query-params"`           // mask the values of these query-string parameters in HTTP URLs, '*' means all
+	HTTPHeaderAllowList []string        `yaml:"http-header-allow-list"` // only keep these headers in the attributes of HTTP logs, empty means all
+	RedisAuthMask       bool            `yaml:"redis-auth-mask"`        // mask the passwords of Redis AUTH, HELLO, MIGRATE and CONFIG SET requirepass
+	Rules               []RedactionRule `yaml:"rules"`
+}
+
 type FlowLogTTL struct {
 	L4FlowLog int `yaml:"l4-flow-log"`
 	L7FlowLog int `yaml:"l7-flow-log"`
 	L4Packet  int `yaml:"l4-packet"`
+	AppLog    int `yaml:"application-log"`
 }
 
 type Config struct {
@@ -51,6 +111,8 @@ type Config struct {
 	ThrottleBucket    int                        `yaml:"throttle-bucket"`
 	L4Throttle        int                        `yaml:"l4-throttle"`
 	L7Throttle        int                        `yaml:"l7-throttle"`
+	L7TailSampling    TailSampling               `yaml:"l7-tail-sampling"`
+	L7Redaction       Redaction                  `yaml:"l7-redaction"`
 	FlowLogTTL        FlowLogTTL                 `yaml:"flow-log-ttl-hour"`
 	DecoderQueueCount int                        `yaml:"flow-log-decoder-queue-count"`
 	DecoderQueueSize  int                        `yaml:"flow-log-decoder-queue-size"`
@@ -114,6 +176,18 @@ func (c *Config) Validate() error {
 		c.FlowLogTTL.L4Packet = DefaultFlowLogTTL
 	}
 
+	if c.FlowLogTTL.AppLog == 0 {
+		c.FlowLogTTL.AppLog = DefaultFlowLogTTL
+	}
+
+	if err := c.L7TailSampling.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.L7Redaction.Validate(); err != nil {
+		return err
+	}
+
 	if c.ExportersCfg.Enabled {
 		if err := c.ExportersCfg.Validate(); err != nil {
 			return err
@@ -123,6 +197,61 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+func (t *TailSampling) Validate() error {
+	if !t.Enabled {
+		return nil
+	}
+	for _, status := range t.KeepResponseStatus {
+		switch status {
+		case RESPONSE_STATUS_SERVER_ERROR, RESPONSE_STATUS_CLIENT_ERROR, RESPONSE_STATUS_TIMEOUT:
+		default:
+			return fmt.Errorf("invalid 'keep-response-status' %s of 'l7-tail-sampling', must be one of: %s, %s, %s",
+				status, RESPONSE_STATUS_SERVER_ERROR, RESPONSE_STATUS_CLIENT_ERROR, RESPONSE_STATUS_TIMEOUT)
+		}
+	}
+	if t.KeepSlowPercentile < 0 || t.KeepSlowPercentile >= 100 {
+		return fmt.Errorf("invalid 'keep-slow-percentile' %f of 'l7-tail-sampling', must be in [0, 100)", t.KeepSlowPercentile)
+	}
+	if t.TraceCacheSize <= 0 {
+		t.TraceCacheSize = DefaultTailSamplingTraceCacheSize
+	}
+	return nil
+}
+
+func (r *Redaction) Validate() error {
+	if !r.Enabled {
+		return nil
+	}
+	if r.Mask == "" {
+		r.Mask = DefaultRedactionMask
+	}
+	for i := range r.Rules {
+		rule := &r.Rules[i]
+		if _, err := regexp.Compile(rule.Regex); err != nil {
+			return fmt.Errorf("invalid 'regex' %s of 'l7-redaction' rules: %s", rule.Regex, err)
+		}
+		for _, field := range rule.Fields {
+			valid := false
+			for _, f := range redactionFields {
+				if field == f {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return fmt.Errorf("invalid 'fields' %s of 'l7-redaction' rules, must be in %v", field, redactionFields)
+			}
+		}
+		if len(rule.Fields) == 0 {
+			rule.Fields = []string{REDACTION_FIELD_REQUEST_RESOURCE}
+		}
+		if rule.Replacement == "" {
+			rule.Replacement = r.Mask
+		}
+	}
+	return nil
+}
+
 func Load(base *config.Config, path string) *Config {
 	config := &FlowLogConfig{
 		FlowLog: Config{
@@ -132,9 +261,21 @@ func Load(base *config.Config, path string) *Config {
 			DecoderQueueCount: DefaultDecoderQueueCount,
 			DecoderQueueSize:  DefaultDecoderQueueSize,
 			CKWriterConfig:    config.CKWriterConfig{QueueCount: 1, QueueSize: 1000000, BatchSize: 512000, FlushTimeout: 10},
-			FlowLogTTL:        FlowLogTTL{DefaultFlowLogTTL, DefaultFlowLogTTL, DefaultFlowLogTTL},
-			ExportersCfg:      exporters_cfg.NewDefaultExportersCfg(),
-			OtlpDeprecated:    exporters_cfg.NewOtlpDefaultConfigDeprecated(),
+			FlowLogTTL:        FlowLogTTL{DefaultFlowLogTTL, DefaultFlowLogTTL, DefaultFlowLogTTL, DefaultFlowLogTTL},
+			L7TailSampling: TailSampling{
+				KeepResponseStatus: []string{RESPONSE_STATUS_SERVER_ERROR, RESPONSE_STATUS_TIMEOUT},
+				KeepSlowPercentile: DefaultTailSamplingSlowPercentile,
+				KeepTrace:          true,
+				TraceCacheSize:     DefaultTailSamplingTraceCacheSize,
+			},
+			L7Redaction: Redaction{
+				Mask:          DefaultRedactionMask,
+				SQLNormalize:  true,
+				QueryParams:   []string{"password", "passwd", "pwd", "token", "access_token", "secret", "api_key", "apikey"},
+				RedisAuthMask: true,
+			},
+			ExportersCfg:   exporters_cfg.NewDefaultExportersCfg(),
+			OtlpDeprecated: exporters_cfg.NewOtlpDefaultConfigDeprecated(),
 		},
 	}
 	if _, err := os.Stat(path); os.IsNotExist(err) {
@@ -51,6 +51,8 @@ func newFlowLogTable(id common.FlowLogID, columns []*ckdb.Column, engine ckdb.En
 		orderKeys = append(orderKeys, flowKeys...)
 	case common.L4_PACKET_ID:
 		orderKeys = append(orderKeys, "flow_id", "vtap_id")
+	case common.APP_LOG_ID:
+		orderKeys = append(orderKeys, "app_service", "l3_epc_id", "pod_id")
 	default:
 		panic("unreachalable")
 	}
@@ -74,20 +76,23 @@ func newFlowLogTable(id common.FlowLogID, columns []*ckdb.Column, engine ckdb.En
 	}
 }
 
-func GetFlowLogTables(engine ckdb.EngineType, cluster, storagePolicy string, l4LogTtl, l7LogTtl, l4PacketTtl int, coldStorages map[string]*ckdb.ColdStorage) []*ckdb.Table {
+func GetFlowLogTables(engine ckdb.EngineType, cluster, storagePolicy string, l4LogTtl, l7LogTtl, l4PacketTtl, appLogTtl int, coldStorages map[string]*ckdb.ColdStorage) []*ckdb.Table {
 	return []*ckdb.Table{
 		newFlowLogTable(common.L4_FLOW_ID, logdata.L4FlowLogColumns(), engine, cluster, storagePolicy, l4LogTtl, ckdb.GetColdStorage(coldStorages, common.FLOW_LOG_DB, common.L4_FLOW_ID.String())),
 		newFlowLogTable(common.L7_FLOW_ID, logdata.L7FlowLogColumns(), engine, cluster, storagePolicy, l7LogTtl, ckdb.GetColdStorage(coldStorages, common.FLOW_LOG_DB, common.L7_FLOW_ID.String())),
 		newFlowLogTable(common.L4_PACKET_ID, logdata.L4PacketColumns(), engine, cluster, storagePolicy, l4PacketTtl, ckdb.GetColdStorage(coldStorages, common.FLOW_LOG_DB, common.L4_PACKET_ID.String())),
+		newFlowLogTable(common.APP_LOG_ID, logdata.AppLogColumns(), engine, cluster, storagePolicy, appLogTtl, ckdb.GetColdStorage(coldStorages, common.FLOW_LOG_DB, common.APP_LOG_ID.String())),
 	}
 }
 
 func NewFlowLogWriter(addrs []string, user, password, cluster, storagePolicy, timeZone string, ckWriterCfg config.CKWriterConfig, flowLogTtl flowlogconfig.FlowLogTTL, coldStorages map[string]*ckdb.ColdStorage) (*FlowLogWriter, error) {
 	ckwriters := make([]*ckwriter.CKWriter, common.FLOWLOG_ID_MAX)
 	var err error
-	tables := GetFlowLogTables(ckdb.MergeTree, cluster, storagePolicy, flowLogTtl.L4FlowLog, flowLogTtl.L7FlowLog, flowLogTtl.L4Packet, coldStorages)
-	for i, table := range tables {
+	tables := GetFlowLogTables(ckdb.MergeTree, cluster, storagePolicy, flowLogTtl.L4FlowLog, flowLogTtl.L7FlowLog, flowLogTtl.L4Packet, flowLogTtl.AppLog, coldStorages)
+	for _, table := range tables {
 		counterName := common.FlowLogID(table.ID).String()
+		// indexed by FlowLogID, since not every FlowLogID has a table, e.g.: L7_PACKET_ID
+		i := table.ID
 		ckwriters[i], err = ckwriter.NewCKWriter(addrs, user, password, counterName, timeZone, table,
 			ckWriterCfg.QueueCount, ckWriterCfg.QueueSize, ckWriterCfg.BatchSize, ckWriterCfg.FlushTimeout)
 		if err != nil {
@@ -108,6 +113,9 @@ func (w *FlowLogWriter) Put(index int, items ...interface{}) {
 
 func (w *FlowLogWriter) Close() {
 	for _, ckwriter := range w.ckwriters {
+		if ckwriter == nil {
+			continue
+		}
 		ckwriter.Close()
 	}
 }
@@ -20,19 +20,24 @@ import (
 	"bytes"
 	"compress/zlib"
 	"io/ioutil"
+	"net"
 	"strconv"
 	"time"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/google/gopacket/layers"
 	logging "github.com/op/go-logging"
+	logsv1 "go.opentelemetry.io/proto/otlp/logs/v1"
 	v1 "go.opentelemetry.io/proto/otlp/trace/v1"
 
 	"github.com/deepflowio/deepflow/server/ingester/common"
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/config"
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/exporters"
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/log_data"
+	"github.com/deepflowio/deepflow/server/ingester/flow_log/redaction"
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/throttler"
 	"github.com/deepflowio/deepflow/server/ingester/flow_tag"
+	"github.com/deepflowio/deepflow/server/ingester/ingesterctl/tap"
 	"github.com/deepflowio/deepflow/server/libs/codec"
 	"github.com/deepflowio/deepflow/server/libs/datatype"
 	"github.com/deepflowio/deepflow/server/libs/datatype/pb"
@@ -80,6 +85,8 @@ type Decoder struct {
 	throttler     *throttler.ThrottlingQueue
 	flowTagWriter *flow_tag.FlowTagWriter
 	exporters     *exporters.Exporters
+	redactor      *redaction.Redactor
+	tap           *tap.Tap
 	cfg           *config.Config
 	debugEnabled  bool
 
@@ -97,6 +104,7 @@ func NewDecoder(
 	throttler *throttler.ThrottlingQueue,
 	flowTagWriter *flow_tag.FlowTagWriter,
 	exporters *exporters.Exporters,
+	redactor *redaction.Redactor,
 	cfg *config.Config,
 ) *Decoder {
 	return &Decoder{
@@ -107,6 +115,8 @@ func NewDecoder(
 		throttler:      throttler,
 		flowTagWriter:  flowTagWriter,
 		exporters:      exporters,
+		redactor:       redactor,
+		tap:            tap.GetTap(msgType),
 		cfg:            cfg,
 		debugEnabled:   log.IsEnabledFor(logging.DEBUG),
 		fieldsBuf:      make([]interface{}, 0, 64),
@@ -137,6 +147,7 @@ func (d *Decoder) Run() {
 	decoder := &codec.SimpleDecoder{}
 	pbTaggedFlow := pb.NewTaggedFlow()
 	pbTracesData := &v1.TracesData{}
+	pbLogsData := &logsv1.LogsData{}
 	for {
 		n := d.inQueue.Gets(buffer)
 		start := time.Now()
@@ -161,6 +172,8 @@ func (d *Decoder) Run() {
 				d.handleOpenTelemetry(recvBytes.VtapID, decoder, pbTracesData, false)
 			case datatype.MESSAGE_TYPE_OPENTELEMETRY_COMPRESSED:
 				d.handleOpenTelemetry(recvBytes.VtapID, decoder, pbTracesData, true)
+			case datatype.MESSAGE_TYPE_OPENTELEMETRY_LOG:
+				d.handleOpenTelemetryLog(recvBytes.VtapID, decoder, pbLogsData)
 			case datatype.MESSAGE_TYPE_PACKETSEQUENCE:
 				d.handleL4Packet(recvBytes.VtapID, decoder)
 			default:
@@ -247,6 +260,12 @@ func (d *Decoder) sendOpenMetetry(vtapID uint16, tracesData *v1.TracesData) {
 	d.counter.Count++
 	ls := log_data.OTelTracesDataToL7FlowLogs(vtapID, tracesData, d.platformData, d.cfg)
 	for _, l := range ls {
+		if d.redactor != nil {
+			d.redactor.Redact(l)
+		}
+		if d.tap.Enabled() {
+			d.tapL7(l)
+		}
 		l.AddReferenceCount()
 		if !d.throttler.SendWithThrottling(l) {
 			d.counter.DropCount++
@@ -260,6 +279,45 @@ func (d *Decoder) sendOpenMetetry(vtapID uint16, tracesData *v1.TracesData) {
 	}
 }
 
+func (d *Decoder) handleOpenTelemetryLog(vtapID uint16, decoder *codec.SimpleDecoder, pbLogsData *logsv1.LogsData) {
+	for !decoder.IsEnd() {
+		pbLogsData.Reset()
+		bytes := decoder.ReadBytes()
+		var err error
+		if len(bytes) > 0 {
+			err = proto.Unmarshal(bytes, pbLogsData)
+		}
+		if decoder.Failed() || err != nil {
+			if d.counter.ErrorCount == 0 {
+				log.Errorf("OpenTelemetry application log decode failed, offset=%d len=%d err: %s", decoder.Offset(), len(decoder.Bytes()), err)
+			}
+			d.counter.ErrorCount++
+			return
+		}
+		d.sendOpenTelemetryLog(vtapID, pbLogsData)
+	}
+}
+
+func (d *Decoder) sendOpenTelemetryLog(vtapID uint16, logsData *logsv1.LogsData) {
+	if d.debugEnabled {
+		log.Debugf("decoder %d vtap %d recv otel log: %s", d.index, vtapID, logsData)
+	}
+	ls := log_data.OTelLogsDataToAppLogs(vtapID, logsData, d.platformData, d.cfg)
+	for _, l := range ls {
+		d.counter.Count++
+		if d.tap.Enabled() {
+			d.tapAppLog(l)
+		}
+		if d.flowTagWriter != nil {
+			l.GenerateNewFlowTags(d.flowTagWriter.Cache)
+			d.flowTagWriter.WriteFieldsAndFieldValuesInCache()
+		}
+		if !d.throttler.SendWithThrottling(l) {
+			d.counter.DropCount++
+		}
+	}
+}
+
 func (d *Decoder) handleL4Packet(vtapID uint16, decoder *codec.SimpleDecoder) {
 	for !decoder.IsEnd() {
 		l4Packet, err := log_data.DecodePacketSequence(decoder, vtapID)
@@ -286,6 +344,9 @@ func (d *Decoder) sendFlow(flow *pb.TaggedFlow) {
 	}
 	d.counter.Count++
 	l := log_data.TaggedFlowToL4FlowLog(flow, d.platformData)
+	if d.tap.Enabled() {
+		d.tapL4(l)
+	}
 
 	if l.HitPcapPolicy() {
 		d.throttler.SendWithoutThrottling(l)
@@ -296,6 +357,43 @@ func (d *Decoder) sendFlow(flow *pb.TaggedFlow) {
 	}
 }
 
+// the records are tapped after redaction and before throttling, since the throttler may release them asynchronously
+func (d *Decoder) tapL4(l *log_data.L4FlowLog) {
+	meta := &tap.Meta{
+		VtapID:    l.VtapID,
+		Protocols: []string{layers.IPProtocol(l.Protocol).String(), datatype.L7Protocol(l.L7Protocol).String(false)},
+	}
+	if l.IsIPv4 {
+		meta.IPs = []net.IP{tap.IPv4(l.IP40), tap.IPv4(l.IP41)}
+	} else {
+		meta.IPs = []net.IP{l.IP60, l.IP61}
+	}
+	d.tap.Send(meta, l)
+}
+
+func (d *Decoder) tapL7(l *log_data.L7FlowLog) {
+	meta := &tap.Meta{
+		VtapID:    l.VtapID,
+		Protocols: []string{layers.IPProtocol(l.Protocol).String(), datatype.L7Protocol(l.L7Protocol).String(false), l.L7ProtocolStr},
+	}
+	if l.IsIPv4 {
+		meta.IPs = []net.IP{tap.IPv4(l.IP40), tap.IPv4(l.IP41)}
+	} else {
+		meta.IPs = []net.IP{l.IP60, l.IP61}
+	}
+	d.tap.Send(meta, l)
+}
+
+func (d *Decoder) tapAppLog(l *log_data.AppLog) {
+	meta := &tap.Meta{VtapID: l.VtapID}
+	if l.IsIPv4 {
+		meta.IPs = []net.IP{tap.IPv4(l.IP4)}
+	} else {
+		meta.IPs = []net.IP{l.IP6}
+	}
+	d.tap.Send(meta, l)
+}
+
 func (d *Decoder) export(l *log_data.L7FlowLog) {
 	if d.exporters != nil {
 		d.exporters.Put(l, d.index)
@@ -308,6 +406,12 @@ func (d *Decoder) sendProto(proto *pb.AppProtoLogsData) {
 	}
 
 	l := log_data.ProtoLogToL7FlowLog(proto, d.platformData, d.cfg)
+	if d.redactor != nil {
+		d.redactor.Redact(l)
+	}
+	if d.tap.Enabled() {
+		d.tapL7(l)
+	}
 	l.AddReferenceCount()
 	sent := d.throttler.SendWithThrottling(l)
 	if sent {
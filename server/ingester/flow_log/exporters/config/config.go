@@ -27,6 +27,9 @@ type ExportersCfg struct {
 	// OtlpExporter config for OTLP exporters
 	OtlpExporterCfgs []OtlpExporterConfig `yaml:"otlp-exporters"`
 
+	// KafkaExporter config for Kafka exporters
+	KafkaExporterCfgs []KafkaExporterConfig `yaml:"kafka-exporters"`
+
 	// other exporter configs ...
 }
 
@@ -36,6 +39,11 @@ func (ec *ExportersCfg) Validate() error {
 			return err
 		}
 	}
+	for i := range ec.KafkaExporterCfgs {
+		if err := ec.KafkaExporterCfgs[i].Validate(ec.OverridableCfg); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -49,7 +57,8 @@ func NewDefaultExportersCfg() ExportersCfg {
 			ExportDatas:     DefaultOtlpExportDatas,
 			ExportDataTypes: DefaultOtlpExportDataTypes,
 		},
-		OtlpExporterCfgs: []OtlpExporterConfig{NewOtlpDefaultConfig()},
+		OtlpExporterCfgs:  []OtlpExporterConfig{NewOtlpDefaultConfig()},
+		KafkaExporterCfgs: []KafkaExporterConfig{NewKafkaDefaultConfig()},
 	}
 }
 
@@ -54,6 +54,24 @@ func TestConfig(t *testing.T) {
 						},
 					},
 				},
+				KafkaExporterCfgs: []KafkaExporterConfig{
+					{
+						Enabled:     true,
+						Brokers:     []string{"127.0.0.1:9092"},
+						Topic:       "l7_flow_log",
+						KeySelector: "trace_id",
+						Encoding:    "protobuf",
+						Sasl: KafkaSaslConfig{
+							Enabled:   true,
+							Mechanism: "SCRAM-SHA-512",
+							Username:  "user",
+							Password:  "pass",
+						},
+						OverridableCfg: OverridableCfg{
+							ExportDatas: []string{"otel-app-span"},
+						},
+					},
+				},
 			},
 		},
 	}
@@ -61,3 +79,40 @@ func TestConfig(t *testing.T) {
 		t.Fatalf("yaml unmarshal not equal, expect: %v, got: %v", expect, ingesterCfg)
 	}
 }
+
+func TestKafkaConfigValidate(t *testing.T) {
+	global := OverridableCfg{
+		ExportDatas:     []string{"cbpf-net-span", "ebpf-sys-span"},
+		ExportDataTypes: []string{"service_info", "tracing_info"},
+	}
+	cfg := KafkaExporterConfig{
+		Enabled: true,
+		Brokers: []string{"127.0.0.1:9092"},
+		Topic:   "l7_flow_log",
+	}
+	if err := cfg.Validate(global); err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+	if cfg.KeySelector != KAFKA_KEY_TRACE_ID || cfg.Encoding != KAFKA_ENCODING_JSON {
+		t.Errorf("default key-selector/encoding not set, got %s/%s", cfg.KeySelector, cfg.Encoding)
+	}
+	if cfg.ExportDataBits != CBPF_NET_SPAN|EBPF_SYS_SPAN {
+		t.Errorf("export data bits should be inherited from global config, got %08b", cfg.ExportDataBits)
+	}
+	if cfg.ExportDataTypeBits != SERVICE_INFO|TRACING_INFO {
+		t.Errorf("export data type bits should be inherited from global config, got %08b", cfg.ExportDataTypeBits)
+	}
+
+	invalids := []KafkaExporterConfig{
+		{Enabled: true, Topic: "l7_flow_log"},
+		{Enabled: true, Brokers: []string{"127.0.0.1:9092"}},
+		{Enabled: true, Brokers: []string{"127.0.0.1:9092"}, Topic: "t", KeySelector: "unknown"},
+		{Enabled: true, Brokers: []string{"127.0.0.1:9092"}, Topic: "t", Encoding: "xml"},
+		{Enabled: true, Brokers: []string{"127.0.0.1:9092"}, Topic: "t", Sasl: KafkaSaslConfig{Enabled: true, Mechanism: "GSSAPI"}},
+	}
+	for i := range invalids {
+		if err := invalids[i].Validate(global); err == nil {
+			t.Errorf("config %d should be invalid", i)
+		}
+	}
+}
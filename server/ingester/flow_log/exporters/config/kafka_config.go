@@ -0,0 +1,190 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+)
+
+const (
+	KAFKA_KEY_NONE        = "none"
+	KAFKA_KEY_TRACE_ID    = "trace_id"
+	KAFKA_KEY_VTAP_ID     = "vtap_id"
+	KAFKA_KEY_APP_SERVICE = "app_service"
+
+	KAFKA_ENCODING_JSON     = "json"
+	KAFKA_ENCODING_PROTOBUF = "protobuf"
+
+	KAFKA_SASL_PLAIN         = "PLAIN"
+	KAFKA_SASL_SCRAM_SHA_256 = "SCRAM-SHA-256"
+	KAFKA_SASL_SCRAM_SHA_512 = "SCRAM-SHA-512"
+)
+
+const (
+	DefaultKafkaExportBatchCount  = 64
+	DefaultKafkaExportQueueCount  = 4
+	DefaultKafkaExportQueueSize   = 100000
+	DefaultKafkaFlushTimeoutMs    = 500
+	DefaultKafkaMaxMessageBytes   = 1000000
+	DefaultKafkaDialTimeoutSecond = 10
+)
+
+type KafkaSaslConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	Mechanism string `yaml:"mechanism"`
+	Username  string `yaml:"username"`
+	Password  string `yaml:"password"`
+}
+
+type KafkaExporterConfig struct {
+	Enabled          bool            `yaml:"enabled"`
+	Brokers          []string        `yaml:"brokers"`
+	Topic            string          `yaml:"topic"`
+	KeySelector      string          `yaml:"key-selector"`
+	Encoding         string          `yaml:"encoding"`
+	Compression      string          `yaml:"compression"`
+	QueueCount       int             `yaml:"queue-count"`
+	QueueSize        int             `yaml:"queue-size"`
+	ExportBatchCount int             `yaml:"export-batch-count"`
+	FlushTimeoutMs   int             `yaml:"flush-timeout-ms"`
+	MaxMessageBytes  int             `yaml:"max-message-bytes"`
+	DialTimeout      int             `yaml:"dial-timeout"`
+	Sasl             KafkaSaslConfig `yaml:"sasl"`
+
+	OverridableCfg `yaml:",inline"`
+}
+
+func (cfg *KafkaExporterConfig) Validate(overridableCfg OverridableCfg) error {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	if len(cfg.Brokers) == 0 {
+		return fmt.Errorf("kafka exporter brokers is empty")
+	}
+	if cfg.Topic == "" {
+		return fmt.Errorf("kafka exporter topic is empty")
+	}
+
+	switch cfg.KeySelector {
+	case "":
+		cfg.KeySelector = KAFKA_KEY_TRACE_ID
+	case KAFKA_KEY_NONE, KAFKA_KEY_TRACE_ID, KAFKA_KEY_VTAP_ID, KAFKA_KEY_APP_SERVICE:
+	default:
+		return fmt.Errorf("kafka exporter key-selector(%s) is invalid, should be one of: %s, %s, %s, %s",
+			cfg.KeySelector, KAFKA_KEY_NONE, KAFKA_KEY_TRACE_ID, KAFKA_KEY_VTAP_ID, KAFKA_KEY_APP_SERVICE)
+	}
+
+	switch cfg.Encoding {
+	case "":
+		cfg.Encoding = KAFKA_ENCODING_JSON
+	case KAFKA_ENCODING_JSON, KAFKA_ENCODING_PROTOBUF:
+	default:
+		return fmt.Errorf("kafka exporter encoding(%s) is invalid, should be %s or %s",
+			cfg.Encoding, KAFKA_ENCODING_JSON, KAFKA_ENCODING_PROTOBUF)
+	}
+
+	switch cfg.Compression {
+	case "", "none", "gzip", "snappy", "lz4", "zstd":
+	default:
+		return fmt.Errorf("kafka exporter compression(%s) is invalid, should be one of: none, gzip, snappy, lz4, zstd", cfg.Compression)
+	}
+
+	if cfg.Sasl.Enabled {
+		switch cfg.Sasl.Mechanism {
+		case "":
+			cfg.Sasl.Mechanism = KAFKA_SASL_PLAIN
+		case KAFKA_SASL_PLAIN, KAFKA_SASL_SCRAM_SHA_256, KAFKA_SASL_SCRAM_SHA_512:
+		default:
+			return fmt.Errorf("kafka exporter sasl mechanism(%s) is invalid, should be one of: %s, %s, %s",
+				cfg.Sasl.Mechanism, KAFKA_SASL_PLAIN, KAFKA_SASL_SCRAM_SHA_256, KAFKA_SASL_SCRAM_SHA_512)
+		}
+	}
+
+	if cfg.ExportBatchCount == 0 {
+		cfg.ExportBatchCount = DefaultKafkaExportBatchCount
+	}
+	if cfg.QueueCount == 0 {
+		cfg.QueueCount = DefaultKafkaExportQueueCount
+	}
+	if cfg.QueueSize == 0 {
+		cfg.QueueSize = DefaultKafkaExportQueueSize
+	}
+	if cfg.FlushTimeoutMs == 0 {
+		cfg.FlushTimeoutMs = DefaultKafkaFlushTimeoutMs
+	}
+	if cfg.MaxMessageBytes == 0 {
+		cfg.MaxMessageBytes = DefaultKafkaMaxMessageBytes
+	}
+	if cfg.DialTimeout == 0 {
+		cfg.DialTimeout = DefaultKafkaDialTimeoutSecond
+	}
+
+	// overwritten params
+	if cfg.ExportCustomK8sLabelsRegexp == "" {
+		cfg.ExportCustomK8sLabelsRegexp = overridableCfg.ExportCustomK8sLabelsRegexp
+	}
+
+	if len(cfg.ExportDatas) == 0 {
+		cfg.ExportDatas = overridableCfg.ExportDatas
+	}
+
+	if len(cfg.ExportDataTypes) == 0 {
+		cfg.ExportDataTypes = overridableCfg.ExportDataTypes
+	}
+
+	if cfg.ExportOnlyWithTraceID == nil {
+		cfg.ExportOnlyWithTraceID = overridableCfg.ExportOnlyWithTraceID
+	}
+
+	// calculate after overwritten, so the global 'export-datas' and 'export-data-types' also take effect
+	cfg.calcDataBits()
+	return nil
+}
+
+func (cfg *KafkaExporterConfig) calcDataBits() {
+	cfg.ExportDataBits = 0
+	for _, v := range cfg.ExportDatas {
+		cfg.ExportDataBits |= uint32(StringToExportedData(v))
+	}
+	log.Infof("kafka export data bits: %08b, string: %s", cfg.ExportDataBits, ExportedDataBitsToString(cfg.ExportDataBits))
+
+	cfg.ExportDataTypeBits = 0
+	for _, v := range cfg.ExportDataTypes {
+		cfg.ExportDataTypeBits |= uint32(StringToExportedDataType(v))
+	}
+	if cfg.ExportCustomK8sLabelsRegexp != "" {
+		cfg.ExportDataTypeBits |= K8S_LABEL
+	}
+	log.Infof("kafka export data type bits: %08b, string: %s", cfg.ExportDataTypeBits, ExportedDataTypeBitsToString(cfg.ExportDataTypeBits))
+}
+
+func NewKafkaDefaultConfig() KafkaExporterConfig {
+	return KafkaExporterConfig{
+		Enabled:          false,
+		Brokers:          []string{"127.0.0.1:9092"},
+		Topic:            "deepflow_l7_flow_log",
+		KeySelector:      KAFKA_KEY_TRACE_ID,
+		Encoding:         KAFKA_ENCODING_JSON,
+		QueueCount:       DefaultKafkaExportQueueCount,
+		QueueSize:        DefaultKafkaExportQueueSize,
+		ExportBatchCount: DefaultKafkaExportBatchCount,
+		FlushTimeoutMs:   DefaultKafkaFlushTimeoutMs,
+		MaxMessageBytes:  DefaultKafkaMaxMessageBytes,
+		DialTimeout:      DefaultKafkaDialTimeoutSecond,
+	}
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/config"
 	exporters_cfg "github.com/deepflowio/deepflow/server/ingester/flow_log/exporters/config"
+	"github.com/deepflowio/deepflow/server/ingester/flow_log/exporters/kafka_exporter"
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/exporters/otlp_exporter"
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/exporters/universal_tag"
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/log_data"
@@ -75,7 +76,12 @@ func NewExporters(flowlogCfg *config.Config) *Exporters {
 		}
 	}
 
-	// todo add other exporters....
+	for i := range exportersCfg.KafkaExporterCfgs {
+		if exportersCfg.KafkaExporterCfgs[i].Enabled {
+			kafkaExporter := kafka_exporter.NewKafkaExporter(i, exportersCfg, universalTagManager)
+			exporters = append(exporters, kafkaExporter)
+		}
+	}
 
 	// init caches
 	for i := range putCaches {
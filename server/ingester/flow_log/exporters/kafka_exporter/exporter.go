@@ -0,0 +1,320 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kafka_exporter
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Shopify/sarama"
+	logging "github.com/op/go-logging"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/deepflowio/deepflow/server/ingester/common"
+	exporters_cfg "github.com/deepflowio/deepflow/server/ingester/flow_log/exporters/config"
+	"github.com/deepflowio/deepflow/server/ingester/flow_log/exporters/otlp_exporter"
+	utag "github.com/deepflowio/deepflow/server/ingester/flow_log/exporters/universal_tag"
+	"github.com/deepflowio/deepflow/server/ingester/flow_log/log_data"
+	"github.com/deepflowio/deepflow/server/ingester/ingesterctl"
+	"github.com/deepflowio/deepflow/server/libs/debug"
+	"github.com/deepflowio/deepflow/server/libs/queue"
+	"github.com/deepflowio/deepflow/server/libs/stats"
+	"github.com/deepflowio/deepflow/server/libs/utils"
+)
+
+var log = logging.MustGetLogger("kafka_exporter")
+
+const (
+	QUEUE_BATCH_COUNT = 1024
+)
+
+type KafkaExporter struct {
+	index                int
+	dataQueues           queue.FixedMultiQueue
+	queueCount           int
+	producers            []sarama.SyncProducer
+	saramaConfig         *sarama.Config
+	universalTagsManager *utag.UniversalTagsManager
+	config               *exporters_cfg.KafkaExporterConfig
+	counter              *Counter
+	lastCounter          Counter
+	running              bool
+
+	utils.Closable
+}
+
+type Counter struct {
+	RecvCounter          int64 `statsd:"recv-count"`
+	SendCounter          int64 `statsd:"send-count"`
+	SendBatchCounter     int64 `statsd:"send-batch-count"`
+	ExportUsedTimeNs     int64 `statsd:"export-used-time-ns"`
+	DropCounter          int64 `statsd:"drop-count"`
+	DropBatchCounter     int64 `statsd:"drop-batch-count"`
+	DropNoTraceIDCounter int64 `statsd:"drop-no-traceid-count"`
+	EncodeErrCounter     int64 `statsd:"encode-err-count"`
+}
+
+func (e *KafkaExporter) GetCounter() interface{} {
+	var counter Counter
+	counter, *e.counter = *e.counter, Counter{}
+	e.lastCounter = counter
+	return &counter
+}
+
+type ExportItem interface {
+	Release()
+}
+
+func NewKafkaExporter(index int, config *exporters_cfg.ExportersCfg, universalTagsManager *utag.UniversalTagsManager) *KafkaExporter {
+	kafkaConfig := config.KafkaExporterCfgs[index]
+
+	dataQueues := queue.NewOverwriteQueues(
+		fmt.Sprintf("kafka_exporter_%d", index), queue.HashKey(kafkaConfig.QueueCount), kafkaConfig.QueueSize,
+		queue.OptionFlushIndicator(time.Second),
+		queue.OptionRelease(func(p interface{}) { p.(ExportItem).Release() }),
+		common.QUEUE_STATS_MODULE_INGESTER)
+
+	exporter := &KafkaExporter{
+		index:                index,
+		dataQueues:           dataQueues,
+		queueCount:           kafkaConfig.QueueCount,
+		producers:            make([]sarama.SyncProducer, kafkaConfig.QueueCount),
+		saramaConfig:         newSaramaConfig(&kafkaConfig),
+		universalTagsManager: universalTagsManager,
+		config:               &kafkaConfig,
+		counter:              &Counter{},
+	}
+	debug.ServerRegisterSimple(ingesterctl.CMD_KAFKA_EXPORTER, exporter)
+	common.RegisterCountableForIngester("exporter", exporter, stats.OptionStatTags{
+		"type": "kafka", "index": strconv.Itoa(index)})
+	log.Infof("kafka exporter %d created", index)
+	return exporter
+}
+
+func newSaramaConfig(cfg *exporters_cfg.KafkaExporterConfig) *sarama.Config {
+	c := sarama.NewConfig()
+	c.ClientID = "deepflow-ingester"
+	// SyncProducer requires both of them to be true
+	c.Producer.Return.Successes = true
+	c.Producer.Return.Errors = true
+	c.Producer.RequiredAcks = sarama.WaitForLocal
+	c.Producer.Partitioner = sarama.NewHashPartitioner
+	c.Producer.MaxMessageBytes = cfg.MaxMessageBytes
+	c.Producer.Flush.Messages = cfg.ExportBatchCount
+	c.Producer.Flush.Frequency = time.Duration(cfg.FlushTimeoutMs) * time.Millisecond
+	c.Net.DialTimeout = time.Duration(cfg.DialTimeout) * time.Second
+
+	switch cfg.Compression {
+	case "gzip":
+		c.Producer.Compression = sarama.CompressionGZIP
+	case "snappy":
+		c.Producer.Compression = sarama.CompressionSnappy
+	case "lz4":
+		c.Producer.Compression = sarama.CompressionLZ4
+	case "zstd":
+		c.Producer.Compression = sarama.CompressionZSTD
+		// zstd is supported from kafka 2.1.0
+		c.Version = sarama.V2_1_0_0
+	default:
+		c.Producer.Compression = sarama.CompressionNone
+	}
+
+	if cfg.Sasl.Enabled {
+		c.Net.SASL.Enable = true
+		c.Net.SASL.User = cfg.Sasl.Username
+		c.Net.SASL.Password = cfg.Sasl.Password
+		switch cfg.Sasl.Mechanism {
+		case exporters_cfg.KAFKA_SASL_SCRAM_SHA_256:
+			c.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+			c.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &xdgSCRAMClient{HashGeneratorFcn: SHA256} }
+		case exporters_cfg.KAFKA_SASL_SCRAM_SHA_512:
+			c.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+			c.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &xdgSCRAMClient{HashGeneratorFcn: SHA512} }
+		default:
+			c.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		}
+	}
+	return c
+}
+
+func (e *KafkaExporter) IsExportData(l *log_data.L7FlowLog) bool {
+	if e.config.ExportOnlyWithTraceID != nil && *e.config.ExportOnlyWithTraceID && l.TraceId == "" {
+		e.counter.DropNoTraceIDCounter++
+		return false
+	}
+
+	if (1<<uint32(l.SignalSource))&e.config.ExportDataBits == 0 {
+		return false
+	}
+	return true
+}
+
+func (e *KafkaExporter) Put(items ...interface{}) {
+	e.counter.RecvCounter++
+	e.dataQueues.Put(queue.HashKey(int(e.counter.RecvCounter)%e.queueCount), items...)
+}
+
+func (e *KafkaExporter) Start() {
+	if e.running {
+		log.Warningf("kafka exporter %d already running", e.index)
+		return
+	}
+	e.running = true
+	for i := 0; i < e.queueCount; i++ {
+		go e.queueProcess(int(i))
+	}
+	log.Infof("kafka exporter %d started %d queue", e.index, e.queueCount)
+}
+
+func (e *KafkaExporter) Close() {
+	e.running = false
+	log.Infof("kafka exporter %d stopping", e.index)
+}
+
+func (e *KafkaExporter) queueProcess(queueID int) {
+	messages := make([]*sarama.ProducerMessage, 0, e.config.ExportBatchCount)
+	flows := make([]interface{}, QUEUE_BATCH_COUNT)
+
+	for e.running {
+		n := e.dataQueues.Gets(queue.HashKey(queueID), flows)
+		for _, flow := range flows[:n] {
+			if flow == nil {
+				if len(messages) > 0 {
+					e.send(queueID, messages)
+					messages = messages[:0]
+				}
+				continue
+			}
+			switch t := flow.(type) {
+			case (*log_data.L7FlowLog):
+				f := flow.(*log_data.L7FlowLog)
+				if msg, err := e.encode(f); err == nil {
+					messages = append(messages, msg)
+				} else {
+					if e.counter.EncodeErrCounter == 0 {
+						log.Warningf("kafka exporter %d encode flow log failed. err: %s", e.index, err)
+					}
+					e.counter.EncodeErrCounter++
+				}
+				f.Release()
+
+				if len(messages) >= e.config.ExportBatchCount {
+					e.send(queueID, messages)
+					messages = messages[:0]
+				}
+			default:
+				log.Warningf("flow type(%T) unsupport", t)
+				continue
+			}
+		}
+	}
+
+	if e.producers[queueID] != nil {
+		e.producers[queueID].Close()
+		e.producers[queueID] = nil
+	}
+}
+
+// encode converts a L7FlowLog into an OTLP ResourceSpans, which is the same as the OTLP exporter sends,
+// and wraps it in a kafka message.
+func (e *KafkaExporter) encode(l7 *log_data.L7FlowLog) (*sarama.ProducerMessage, error) {
+	traces := ptrace.NewTraces()
+	otlp_exporter.L7FlowLogToExportResourceSpans(l7, e.universalTagsManager, e.config.ExportDataTypeBits, traces.ResourceSpans().AppendEmpty())
+
+	var value []byte
+	var err error
+	if e.config.Encoding == exporters_cfg.KAFKA_ENCODING_PROTOBUF {
+		value, err = (&ptrace.ProtoMarshaler{}).MarshalTraces(traces)
+	} else {
+		value, err = (&ptrace.JSONMarshaler{}).MarshalTraces(traces)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &sarama.ProducerMessage{
+		Topic: e.config.Topic,
+		Value: sarama.ByteEncoder(value),
+	}
+	if key := e.messageKey(l7); key != "" {
+		msg.Key = sarama.StringEncoder(key)
+	}
+	return msg, nil
+}
+
+// messageKey returns the partition key of the flow log, empty means the message will be sent to a random partition.
+func (e *KafkaExporter) messageKey(l7 *log_data.L7FlowLog) string {
+	switch e.config.KeySelector {
+	case exporters_cfg.KAFKA_KEY_TRACE_ID:
+		return l7.TraceId
+	case exporters_cfg.KAFKA_KEY_VTAP_ID:
+		return strconv.Itoa(int(l7.VtapID))
+	case exporters_cfg.KAFKA_KEY_APP_SERVICE:
+		return l7.AppService
+	}
+	return ""
+}
+
+func (e *KafkaExporter) send(i int, messages []*sarama.ProducerMessage) error {
+	now := time.Now()
+
+	if e.producers[i] == nil {
+		if err := e.newProducer(i); err != nil {
+			if e.counter.DropCounter == 0 {
+				log.Warningf("new kafka producer failed. err: %s", err)
+			}
+			e.counter.DropCounter += int64(len(messages))
+			e.counter.DropBatchCounter++
+			return err
+		}
+	}
+
+	if err := e.producers[i].SendMessages(messages); err != nil {
+		failed := len(messages)
+		if errs, ok := err.(sarama.ProducerErrors); ok {
+			failed = len(errs)
+		}
+		if e.counter.DropCounter == 0 {
+			log.Warningf("kafka exporter %d send messages failed. err: %s", e.index, err)
+		}
+		e.counter.DropCounter += int64(failed)
+		e.counter.DropBatchCounter++
+		e.counter.SendCounter += int64(len(messages) - failed)
+		e.producers[i].Close()
+		e.producers[i] = nil
+		return err
+	}
+	e.counter.SendCounter += int64(len(messages))
+	e.counter.SendBatchCounter++
+	e.counter.ExportUsedTimeNs += int64(time.Since(now))
+	return nil
+}
+
+func (e *KafkaExporter) newProducer(i int) error {
+	producer, err := sarama.NewSyncProducer(e.config.Brokers, e.saramaConfig)
+	if err != nil {
+		return fmt.Errorf("kafka connect to brokers %v failed, err: %s", e.config.Brokers, err)
+	}
+	log.Debugf("new kafka producer: %v", e.config.Brokers)
+	e.producers[i] = producer
+	return nil
+}
+
+func (e *KafkaExporter) HandleSimpleCommand(op uint16, arg string) string {
+	return fmt.Sprintf("kafka exporter %d last 10s counter: %+v", e.index, e.lastCounter)
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kafka_exporter
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	"github.com/deepflowio/deepflow/server/ingester/config"
+	exporters_cfg "github.com/deepflowio/deepflow/server/ingester/flow_log/exporters/config"
+	utag "github.com/deepflowio/deepflow/server/ingester/flow_log/exporters/universal_tag"
+	"github.com/deepflowio/deepflow/server/ingester/flow_log/log_data"
+	"github.com/deepflowio/deepflow/server/libs/datatype"
+)
+
+const testTopic = "l7_flow_log"
+
+func newTestExporter(t *testing.T, broker *sarama.MockBroker, encoding, keySelector string) *KafkaExporter {
+	cfg := &exporters_cfg.ExportersCfg{
+		OverridableCfg: exporters_cfg.OverridableCfg{
+			ExportDatas:     []string{"ebpf-sys-span"},
+			ExportDataTypes: []string{"service_info", "tracing_info"},
+		},
+		KafkaExporterCfgs: []exporters_cfg.KafkaExporterConfig{
+			{
+				Enabled:          true,
+				Brokers:          []string{broker.Addr()},
+				Topic:            testTopic,
+				Encoding:         encoding,
+				KeySelector:      keySelector,
+				QueueCount:       1,
+				QueueSize:        16,
+				ExportBatchCount: 2,
+			},
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("validate config failed: %v", err)
+	}
+	return NewKafkaExporter(0, cfg, utag.NewUniversalTagsManager("", &config.Config{}))
+}
+
+func newMockBroker(t *testing.T) *sarama.MockBroker {
+	broker := sarama.NewMockBroker(t, 1)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(testTopic, 0, broker.BrokerID()),
+		// the default sarama config(kafka 1.0.0) sends produce request of version 3
+		"ProduceRequest": sarama.NewMockProduceResponse(t).SetVersion(3).SetError(testTopic, 0, sarama.ErrNoError),
+	})
+	return broker
+}
+
+func newTestFlowLog(traceID string) *log_data.L7FlowLog {
+	l := &log_data.L7FlowLog{}
+	l.TraceId = traceID
+	l.AppService = "cart"
+	l.SignalSource = uint16(datatype.SIGNAL_SOURCE_EBPF)
+	l.VtapID = 3
+	return l
+}
+
+func TestIsExportData(t *testing.T) {
+	broker := newMockBroker(t)
+	defer broker.Close()
+	e := newTestExporter(t, broker, exporters_cfg.KAFKA_ENCODING_JSON, exporters_cfg.KAFKA_KEY_NONE)
+
+	l := newTestFlowLog("")
+	if !e.IsExportData(l) {
+		t.Errorf("ebpf span should be exported")
+	}
+	l.SignalSource = uint16(datatype.SIGNAL_SOURCE_PACKET)
+	if e.IsExportData(l) {
+		t.Errorf("cbpf span should not be exported")
+	}
+
+	onlyWithTraceID := true
+	e.config.ExportOnlyWithTraceID = &onlyWithTraceID
+	l.SignalSource = uint16(datatype.SIGNAL_SOURCE_EBPF)
+	if e.IsExportData(l) {
+		t.Errorf("span without trace id should not be exported")
+	}
+	if e.counter.DropNoTraceIDCounter != 1 {
+		t.Errorf("drop no trace id counter should be 1, got %d", e.counter.DropNoTraceIDCounter)
+	}
+}
+
+func TestMessageKey(t *testing.T) {
+	broker := newMockBroker(t)
+	defer broker.Close()
+	e := newTestExporter(t, broker, exporters_cfg.KAFKA_ENCODING_JSON, exporters_cfg.KAFKA_KEY_NONE)
+
+	l := newTestFlowLog("abc")
+	cases := map[string]string{
+		exporters_cfg.KAFKA_KEY_NONE:        "",
+		exporters_cfg.KAFKA_KEY_TRACE_ID:    "abc",
+		exporters_cfg.KAFKA_KEY_VTAP_ID:     "3",
+		exporters_cfg.KAFKA_KEY_APP_SERVICE: "cart",
+	}
+	for selector, expected := range cases {
+		e.config.KeySelector = selector
+		if got := e.messageKey(l); got != expected {
+			t.Errorf("messageKey with selector %s == %q, expected %q", selector, got, expected)
+		}
+	}
+}
+
+func TestSendToMockBroker(t *testing.T) {
+	for _, encoding := range []string{exporters_cfg.KAFKA_ENCODING_JSON, exporters_cfg.KAFKA_ENCODING_PROTOBUF} {
+		broker := newMockBroker(t)
+		e := newTestExporter(t, broker, encoding, exporters_cfg.KAFKA_KEY_TRACE_ID)
+
+		messages := make([]*sarama.ProducerMessage, 0, 2)
+		for _, traceID := range []string{"trace-1", "trace-2"} {
+			msg, err := e.encode(newTestFlowLog(traceID))
+			if err != nil {
+				t.Fatalf("encode failed: %v", err)
+			}
+			if key, _ := msg.Key.Encode(); string(key) != traceID {
+				t.Errorf("message key == %q, expected %q", key, traceID)
+			}
+
+			value, _ := msg.Value.Encode()
+			var traces ptrace.Traces
+			if encoding == exporters_cfg.KAFKA_ENCODING_PROTOBUF {
+				traces, err = (&ptrace.ProtoUnmarshaler{}).UnmarshalTraces(value)
+			} else {
+				traces, err = (&ptrace.JSONUnmarshaler{}).UnmarshalTraces(value)
+			}
+			if err != nil {
+				t.Fatalf("unmarshal %s message failed: %v", encoding, err)
+			}
+			if traces.SpanCount() != 1 {
+				t.Errorf("message should contain 1 span, got %d", traces.SpanCount())
+			}
+			messages = append(messages, msg)
+		}
+
+		if err := e.send(0, messages); err != nil {
+			t.Fatalf("send to mock broker failed: %v", err)
+		}
+		if e.counter.SendCounter != 2 || e.counter.SendBatchCounter != 1 {
+			t.Errorf("counter mismatch, got %+v", *e.counter)
+		}
+		e.producers[0].Close()
+		broker.Close()
+	}
+}
+
+func TestSendFailed(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	e := newTestExporter(t, broker, exporters_cfg.KAFKA_ENCODING_JSON, exporters_cfg.KAFKA_KEY_NONE)
+	broker.Close()
+	e.saramaConfig.Metadata.Retry.Max = 0
+
+	msg, _ := e.encode(newTestFlowLog(""))
+	if err := e.send(0, []*sarama.ProducerMessage{msg}); err == nil {
+		t.Fatalf("send to closed broker should fail")
+	}
+	if e.counter.DropCounter != 1 || e.counter.DropBatchCounter != 1 {
+		t.Errorf("drop counter mismatch, got %+v", *e.counter)
+	}
+}
@@ -27,12 +27,14 @@ import (
 	_ "golang.org/x/net/context"
 	_ "google.golang.org/grpc"
 
+	ingestercommon "github.com/deepflowio/deepflow/server/ingester/common"
 	dropletqueue "github.com/deepflowio/deepflow/server/ingester/droplet/queue"
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/common"
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/config"
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/dbwriter"
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/decoder"
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/geo"
+	"github.com/deepflowio/deepflow/server/ingester/flow_log/redaction"
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/throttler"
 	"github.com/deepflowio/deepflow/server/ingester/flow_tag"
 	"github.com/deepflowio/deepflow/server/ingester/ingesterctl"
@@ -42,6 +44,7 @@ import (
 	"github.com/deepflowio/deepflow/server/libs/queue"
 	libqueue "github.com/deepflowio/deepflow/server/libs/queue"
 	"github.com/deepflowio/deepflow/server/libs/receiver"
+	"github.com/deepflowio/deepflow/server/libs/stats"
 	logging "github.com/op/go-logging"
 )
 
@@ -54,6 +57,7 @@ type FlowLog struct {
 	OtelLogger           *Logger
 	OtelCompressedLogger *Logger
 	L4PacketLogger       *Logger
+	AppLogger            *Logger
 	Exporters            *exporters.Exporters
 }
 
@@ -108,6 +112,10 @@ func NewFlowLog(config *config.Config, recv *receiver.Receiver, platformDataMana
 	if err != nil {
 		return nil, err
 	}
+	appLogger, err := NewLogger(datatype.MESSAGE_TYPE_OPENTELEMETRY_LOG, config, platformDataManager, manager, recv, flowLogWriter, common.APP_LOG_ID, nil)
+	if err != nil {
+		return nil, err
+	}
 	return &FlowLog{
 		FlowLogConfig:        config,
 		L4FlowLogger:         l4FlowLogger,
@@ -115,6 +123,7 @@ func NewFlowLog(config *config.Config, recv *receiver.Receiver, platformDataMana
 		OtelLogger:           otelLogger,
 		OtelCompressedLogger: otelCompressedLogger,
 		L4PacketLogger:       l4PacketLogger,
+		AppLogger:            appLogger,
 		Exporters:            exporters,
 	}, nil
 }
@@ -131,11 +140,16 @@ func NewLogger(msgType datatype.MessageType, config *config.Config, platformData
 	recv.RegistHandler(msgType, decodeQueues, queueCount)
 	throttle := config.Throttle / queueCount
 
+	ttl := config.FlowLogTTL.L7FlowLog
+	if flowLogId == common.APP_LOG_ID {
+		ttl = config.FlowLogTTL.AppLog
+	}
+
 	throttlers := make([]*throttler.ThrottlingQueue, queueCount)
 	decoders := make([]*decoder.Decoder, queueCount)
 	platformDatas := make([]*grpc.PlatformInfoTable, queueCount)
 	for i := 0; i < queueCount; i++ {
-		flowTagWriter, err := flow_tag.NewFlowTagWriter(i, msgType.String(), common.FLOW_LOG_DB, config.FlowLogTTL.L7FlowLog, dbwriter.DefaultPartition, config.Base, &config.CKWriterConfig)
+		flowTagWriter, err := flow_tag.NewFlowTagWriter(i, msgType.String(), common.FLOW_LOG_DB, ttl, dbwriter.DefaultPartition, config.Base, &config.CKWriterConfig)
 		if err != nil {
 			return nil, err
 		}
@@ -151,6 +165,12 @@ func NewLogger(msgType datatype.MessageType, config *config.Config, platformData
 				debug.ServerRegisterSimple(ingesterctl.CMD_PLATFORMDATA_FLOW_LOG, platformDatas[i])
 			}
 		}
+		var redactor *redaction.Redactor
+		if flowLogId == common.L7_FLOW_ID {
+			if redactor, err = newRedactor(config, i, msgType); err != nil {
+				return nil, err
+			}
+		}
 		decoders[i] = decoder.NewDecoder(
 			i,
 			msgType,
@@ -159,6 +179,7 @@ func NewLogger(msgType datatype.MessageType, config *config.Config, platformData
 			throttlers[i],
 			flowTagWriter,
 			exporters,
+			redactor,
 			config,
 		)
 	}
@@ -170,6 +191,21 @@ func NewLogger(msgType datatype.MessageType, config *config.Config, platformData
 	}, nil
 }
 
+// newRedactor returns nil if l7 redaction is disabled
+func newRedactor(config *config.Config, index int, msgType datatype.MessageType) (*redaction.Redactor, error) {
+	if !config.L7Redaction.Enabled {
+		return nil, nil
+	}
+	redactor, err := redaction.NewRedactor(&config.L7Redaction)
+	if err != nil {
+		return nil, err
+	}
+	ingestercommon.RegisterCountableForIngester("l7_redaction", redactor, stats.OptionStatTags{
+		"thread":   strconv.Itoa(index),
+		"msg_type": msgType.String()})
+	return redactor, nil
+}
+
 func NewL4FlowLogger(config *config.Config, platformDataManager *grpc.PlatformDataManager, manager *dropletqueue.Manager, recv *receiver.Receiver, flowLogWriter *dbwriter.FlowLogWriter) *Logger {
 	msgType := datatype.MESSAGE_TYPE_TAGGEDFLOW
 	queueCount := config.DecoderQueueCount
@@ -212,6 +248,7 @@ func NewL4FlowLogger(config *config.Config, platformDataManager *grpc.PlatformDa
 			throttlers[i],
 			nil,
 			nil,
+			nil,
 			config,
 		)
 	}
@@ -243,6 +280,11 @@ func NewL7FlowLogger(config *config.Config, platformDataManager *grpc.PlatformDa
 		throttle = config.L7Throttle / queueCount
 	}
 
+	priorityThrottle := throttle
+	if config.L7TailSampling.PriorityThrottle != 0 {
+		priorityThrottle = config.L7TailSampling.PriorityThrottle / queueCount
+	}
+
 	throttlers := make([]*throttler.ThrottlingQueue, queueCount)
 
 	platformDatas := make([]*grpc.PlatformInfoTable, queueCount)
@@ -262,10 +304,20 @@ func NewL7FlowLogger(config *config.Config, platformDataManager *grpc.PlatformDa
 			flowLogWriter,
 			int(common.L7_FLOW_ID),
 		)
+		throttlers[i].EnableTailSampling(&config.L7TailSampling, priorityThrottle)
+		if throttlers[i].TailSamplingEnabled() {
+			ingestercommon.RegisterCountableForIngester("l7_tail_sampling", throttlers[i], stats.OptionStatTags{
+				"thread":   strconv.Itoa(i),
+				"msg_type": msgType.String()})
+		}
 		platformDatas[i], _ = platformDataManager.NewPlatformInfoTable("l7-flow-log-" + strconv.Itoa(i))
 		if i == 0 {
 			debug.ServerRegisterSimple(ingesterctl.CMD_PLATFORMDATA_FLOW_LOG, platformDatas[i])
 		}
+		redactor, err := newRedactor(config, i, msgType)
+		if err != nil {
+			return nil, err
+		}
 		decoders[i] = decoder.NewDecoder(
 			i,
 			msgType,
@@ -274,6 +326,7 @@ func NewL7FlowLogger(config *config.Config, platformDataManager *grpc.PlatformDa
 			throttlers[i],
 			flowTagWriter,
 			exporters,
+			redactor,
 			config,
 		)
 	}
@@ -332,6 +385,9 @@ func (s *FlowLog) Start() {
 	if s.OtelCompressedLogger != nil {
 		s.OtelCompressedLogger.Start()
 	}
+	if s.AppLogger != nil {
+		s.AppLogger.Start()
+	}
 	if s.Exporters != nil {
 		s.Exporters.Start()
 	}
@@ -353,6 +409,9 @@ func (s *FlowLog) Close() error {
 	if s.OtelCompressedLogger != nil {
 		s.OtelCompressedLogger.Close()
 	}
+	if s.AppLogger != nil {
+		s.AppLogger.Close()
+	}
 	if s.Exporters != nil {
 		s.Exporters.Close()
 	}
@@ -0,0 +1,433 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log_data
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"time"
+
+	v11 "go.opentelemetry.io/proto/otlp/common/v1"
+	logsv1 "go.opentelemetry.io/proto/otlp/logs/v1"
+
+	basecommon "github.com/deepflowio/deepflow/server/ingester/common"
+	"github.com/deepflowio/deepflow/server/ingester/flow_log/common"
+	flowlogCfg "github.com/deepflowio/deepflow/server/ingester/flow_log/config"
+	"github.com/deepflowio/deepflow/server/ingester/flow_tag"
+	"github.com/deepflowio/deepflow/server/libs/ckdb"
+	"github.com/deepflowio/deepflow/server/libs/datatype"
+	"github.com/deepflowio/deepflow/server/libs/grpc"
+	"github.com/deepflowio/deepflow/server/libs/pool"
+	"github.com/deepflowio/deepflow/server/libs/utils"
+	"github.com/deepflowio/deepflow/server/libs/zerodoc"
+)
+
+// AppLog is an application log record received by OTLP, stored in flow_log.application_log
+type AppLog struct {
+	_id       uint64
+	Time      uint32 // s
+	Timestamp int64  // us
+	// the time when the log is collected, equals to Timestamp if it is not set
+	ObservedTime int64 // us
+
+	VtapID       uint16
+	SignalSource uint16
+
+	TraceId      string
+	TraceIdIndex uint64
+	SpanId       string
+	TraceFlags   uint32
+
+	SeverityNumber uint8
+	SeverityText   string
+	Body           string
+
+	AppService  string
+	AppInstance string
+
+	GProcessID   uint32
+	RegionID     uint16
+	AZID         uint16
+	L3EpcID      int32
+	HostID       uint16
+	PodID        uint32
+	PodNodeID    uint32
+	PodNSID      uint16
+	PodClusterID uint16
+	PodGroupID   uint32
+	L3DeviceType uint8
+	L3DeviceID   uint32
+	ServiceID    uint32
+	SubnetID     uint16
+	IsIPv4       bool
+	IP4          uint32
+	IP6          net.IP
+
+	AutoInstanceID   uint32
+	AutoInstanceType uint8
+	AutoServiceID    uint32
+	AutoServiceType  uint8
+
+	AttributeNames  []string
+	AttributeValues []string
+}
+
+func AppLogColumns() []*ckdb.Column {
+	return []*ckdb.Column{
+		ckdb.NewColumn("_id", ckdb.UInt64).SetCodec(ckdb.CodecDoubleDelta),
+		ckdb.NewColumn("time", ckdb.DateTime).SetComment("精度: 秒"),
+		ckdb.NewColumn("timestamp", ckdb.DateTime64us).SetComment("精度: 微秒"),
+		ckdb.NewColumn("observed_time", ckdb.DateTime64us).SetComment("精度: 微秒"),
+		ckdb.NewColumn("vtap_id", ckdb.UInt16).SetIndex(ckdb.IndexSet),
+		ckdb.NewColumn("signal_source", ckdb.UInt16).SetIndex(ckdb.IndexNone),
+
+		ckdb.NewColumn("trace_id", ckdb.String).SetIndex(ckdb.IndexBloomfilter).SetComment("TraceID"),
+		ckdb.NewColumn("trace_id_index", ckdb.UInt64).SetIndex(ckdb.IndexMinmax).SetComment("TraceIDIndex"),
+		ckdb.NewColumn("span_id", ckdb.String).SetComment("SpanID"),
+		ckdb.NewColumn("trace_flags", ckdb.UInt32),
+
+		ckdb.NewColumn("severity_number", ckdb.UInt8).SetIndex(ckdb.IndexMinmax).SetComment("日志级别, 1-4:TRACE, 5-8:DEBUG, 9-12:INFO, 13-16:WARN, 17-20:ERROR, 21-24:FATAL"),
+		ckdb.NewColumn("severity_text", ckdb.LowCardinalityString).SetComment("日志级别的原始值"),
+		ckdb.NewColumn("body", ckdb.String).SetComment("日志内容"),
+
+		ckdb.NewColumn("app_service", ckdb.LowCardinalityString).SetComment("app service"),
+		ckdb.NewColumn("app_instance", ckdb.String).SetComment("app instance"),
+
+		ckdb.NewColumn("gprocess_id", ckdb.UInt32).SetComment("全局进程ID"),
+		ckdb.NewColumn("region_id", ckdb.UInt16).SetComment("云平台区域ID"),
+		ckdb.NewColumn("az_id", ckdb.UInt16).SetComment("可用区ID"),
+		ckdb.NewColumn("l3_epc_id", ckdb.Int32).SetComment("ip对应的EPC ID"),
+		ckdb.NewColumn("host_id", ckdb.UInt16).SetComment("宿主机ID"),
+		ckdb.NewColumn("pod_id", ckdb.UInt32).SetComment("容器ID"),
+		ckdb.NewColumn("pod_node_id", ckdb.UInt32).SetComment("容器节点ID"),
+		ckdb.NewColumn("pod_ns_id", ckdb.UInt16).SetComment("容器命名空间ID"),
+		ckdb.NewColumn("pod_cluster_id", ckdb.UInt16).SetComment("容器集群ID"),
+		ckdb.NewColumn("pod_group_id", ckdb.UInt32).SetComment("容器组ID"),
+		ckdb.NewColumn("l3_device_type", ckdb.UInt8).SetComment("资源类型"),
+		ckdb.NewColumn("l3_device_id", ckdb.UInt32).SetComment("资源ID"),
+		ckdb.NewColumn("service_id", ckdb.UInt32).SetComment("服务ID"),
+		ckdb.NewColumn("subnet_id", ckdb.UInt16),
+		ckdb.NewColumn("is_ipv4", ckdb.UInt8).SetIndex(ckdb.IndexMinmax),
+		ckdb.NewColumn("ip4", ckdb.IPv4),
+		ckdb.NewColumn("ip6", ckdb.IPv6),
+
+		ckdb.NewColumn("auto_instance_id", ckdb.UInt32),
+		ckdb.NewColumn("auto_instance_type", ckdb.UInt8),
+		ckdb.NewColumn("auto_service_id", ckdb.UInt32),
+		ckdb.NewColumn("auto_service_type", ckdb.UInt8),
+
+		ckdb.NewColumn("attribute_names", ckdb.ArrayLowCardinalityString).SetComment("额外的属性"),
+		ckdb.NewColumn("attribute_values", ckdb.ArrayString).SetComment("额外的属性对应的值"),
+	}
+}
+
+func (l *AppLog) WriteBlock(block *ckdb.Block) {
+	block.Write(l._id)
+	block.WriteDateTime(l.Time)
+	block.Write(
+		l.Timestamp,
+		l.ObservedTime,
+		l.VtapID,
+		l.SignalSource,
+
+		l.TraceId,
+		l.TraceIdIndex,
+		l.SpanId,
+		l.TraceFlags,
+
+		l.SeverityNumber,
+		l.SeverityText,
+		l.Body,
+
+		l.AppService,
+		l.AppInstance,
+
+		l.GProcessID,
+		l.RegionID,
+		l.AZID,
+		l.L3EpcID,
+		l.HostID,
+		l.PodID,
+		l.PodNodeID,
+		l.PodNSID,
+		l.PodClusterID,
+		l.PodGroupID,
+		l.L3DeviceType,
+		l.L3DeviceID,
+		l.ServiceID,
+		l.SubnetID)
+	block.WriteBool(l.IsIPv4)
+	block.WriteIPv4(l.IP4)
+	block.WriteIPv6(l.IP6)
+
+	block.Write(
+		l.AutoInstanceID,
+		l.AutoInstanceType,
+		l.AutoServiceID,
+		l.AutoServiceType,
+
+		l.AttributeNames,
+		l.AttributeValues,
+	)
+}
+
+func (l *AppLog) Release() {
+	ReleaseAppLog(l)
+}
+
+func (l *AppLog) String() string {
+	return fmt.Sprintf("AppLog: %+v\n", *l)
+}
+
+var appLogFieldNamesNeedWriteFlowTag = [2]string{"app_service", "app_instance"}
+
+func (l *AppLog) GenerateNewFlowTags(cache *flow_tag.FlowTagCache) {
+	// reset temporary buffers
+	flowTagInfo := &cache.FlowTagInfoBuffer
+	*flowTagInfo = flow_tag.FlowTagInfo{
+		Table:   common.APP_LOG_ID.String(),
+		VpcId:   l.L3EpcID,
+		PodNsId: l.PodNSID,
+	}
+	cache.Fields = cache.Fields[:0]
+	cache.FieldValues = cache.FieldValues[:0]
+
+	extraFieldValuesNeedWriteFlowTag := [2]string{l.AppService, l.AppInstance}
+	attributeNames := append(l.AttributeNames, appLogFieldNamesNeedWriteFlowTag[:]...)
+	attributeValues := append(l.AttributeValues, extraFieldValuesNeedWriteFlowTag[:]...)
+
+	// tags
+	flowTagInfo.FieldType = flow_tag.FieldTag
+	for i, name := range attributeNames {
+		if attributeValues[i] == "" {
+			continue
+		}
+		flowTagInfo.FieldName = name
+
+		// tag + value
+		flowTagInfo.FieldValue = attributeValues[i]
+		if old, ok := cache.FieldValueCache.AddOrGet(*flowTagInfo, l.Time); ok {
+			if old+cache.CacheFlushTimeout >= l.Time {
+				// If there is no new fieldValue, of course there will be no new field.
+				// So we can just skip the rest of the process in the loop.
+				continue
+			} else {
+				cache.FieldValueCache.Add(*flowTagInfo, l.Time)
+			}
+		}
+		tagFieldValue := flow_tag.AcquireFlowTag()
+		tagFieldValue.Timestamp = l.Time
+		tagFieldValue.FlowTagInfo = *flowTagInfo
+		cache.FieldValues = append(cache.FieldValues, tagFieldValue)
+
+		// The tag key in appLogFieldNamesNeedWriteFlowTag does not need to be written into flow_tag.
+		if i >= len(l.AttributeNames) {
+			continue
+		}
+		// only tag
+		flowTagInfo.FieldValue = ""
+		if old, ok := cache.FieldCache.AddOrGet(*flowTagInfo, l.Time); ok {
+			if old+cache.CacheFlushTimeout >= l.Time {
+				continue
+			} else {
+				cache.FieldCache.Add(*flowTagInfo, l.Time)
+			}
+		}
+		tagField := flow_tag.AcquireFlowTag()
+		tagField.Timestamp = l.Time
+		tagField.FlowTagInfo = *flowTagInfo
+		cache.Fields = append(cache.Fields, tagField)
+	}
+}
+
+var poolAppLog = pool.NewLockFreePool(func() interface{} {
+	return &AppLog{
+		AttributeNames:  []string{},
+		AttributeValues: []string{},
+	}
+})
+
+func AcquireAppLog() *AppLog {
+	return poolAppLog.Get().(*AppLog)
+}
+
+func ReleaseAppLog(l *AppLog) {
+	if l == nil {
+		return
+	}
+	attributeNames := l.AttributeNames[:0]
+	attributeValues := l.AttributeValues[:0]
+	*l = AppLog{}
+	l.AttributeNames = attributeNames
+	l.AttributeValues = attributeValues
+	poolAppLog.Put(l)
+}
+
+var AppLogCounter uint32
+
+func OTelLogsDataToAppLogs(vtapID uint16, l *logsv1.LogsData, platformData *grpc.PlatformInfoTable, cfg *flowlogCfg.Config) []*AppLog {
+	ret := []*AppLog{}
+	for _, resourceLog := range l.GetResourceLogs() {
+		var resAttributes []*v11.KeyValue
+		resource := resourceLog.GetResource()
+		if resource != nil {
+			resAttributes = resource.Attributes
+		}
+		for _, scopeLog := range resourceLog.GetScopeLogs() {
+			for _, record := range scopeLog.GetLogRecords() {
+				ret = append(ret, logRecordToAppLog(vtapID, record, resAttributes, platformData, cfg))
+			}
+		}
+	}
+	return ret
+}
+
+func logRecordToAppLog(vtapID uint16, record *logsv1.LogRecord, resAttributes []*v11.KeyValue, platformData *grpc.PlatformInfoTable, cfg *flowlogCfg.Config) *AppLog {
+	l := AcquireAppLog()
+	l.VtapID = vtapID
+	l.SignalSource = uint16(datatype.SIGNAL_SOURCE_OTEL)
+
+	l.ObservedTime = int64(record.ObservedTimeUnixNano / uint64(time.Microsecond))
+	l.Timestamp = int64(record.TimeUnixNano / uint64(time.Microsecond))
+	// the time of the log event is optional, use the observed time if it is not set
+	if l.Timestamp == 0 {
+		l.Timestamp = l.ObservedTime
+	}
+	if l.ObservedTime == 0 {
+		l.ObservedTime = l.Timestamp
+	}
+	if l.Timestamp == 0 {
+		l.Timestamp = time.Now().UnixMicro()
+		l.ObservedTime = l.Timestamp
+	}
+	l.Time = uint32(l.Timestamp / US_TO_S_DEVISOR)
+	l._id = genID(l.Time, &AppLogCounter, platformData.QueryAnalyzerID())
+
+	if len(record.TraceId) > 0 {
+		l.TraceId = hex.EncodeToString(record.TraceId)
+		l.TraceIdIndex = parseTraceIdIndex(l.TraceId, &cfg.Base.TraceIdWithIndex)
+	}
+	if len(record.SpanId) > 0 {
+		l.SpanId = hex.EncodeToString(record.SpanId)
+	}
+	l.TraceFlags = record.Flags
+	l.SeverityNumber = uint8(record.SeverityNumber)
+	l.SeverityText = record.SeverityText
+	if record.Body != nil {
+		l.Body = getValueString(record.Body)
+	}
+
+	l.IsIPv4 = true
+	for _, attr := range record.GetAttributes() {
+		if value := attr.GetValue(); value != nil {
+			l.AttributeNames = append(l.AttributeNames, attr.GetKey())
+			l.AttributeValues = append(l.AttributeValues, getValueString(value))
+		}
+	}
+	for _, attr := range resAttributes {
+		key, value := attr.GetKey(), attr.GetValue()
+		if value == nil {
+			continue
+		}
+		switch key {
+		case "service.name":
+			l.AppService = getValueString(value)
+		case "service.instance.id":
+			l.AppInstance = getValueString(value)
+		// the same as the spans, see L7FlowLog.fillAttributes
+		case "app.host.ip":
+			ip := net.ParseIP(value.GetStringValue())
+			if ip == nil {
+				continue
+			}
+			if ip4 := ip.To4(); ip4 != nil {
+				l.IP4 = utils.IpToUint32(ip4)
+			} else {
+				l.IsIPv4 = false
+				l.IP6 = ip
+			}
+		}
+		l.AttributeNames = append(l.AttributeNames, key)
+		l.AttributeValues = append(l.AttributeValues, getValueString(value))
+	}
+
+	l.fillUniversalTags(platformData)
+	return l
+}
+
+// fillUniversalTags matches the resource by 'app.host.ip', the resource of the vtap is used if it is not set
+func (l *AppLog) fillUniversalTags(platformData *grpc.PlatformInfoTable) {
+	l.L3EpcID = platformData.QueryVtapEpc0(uint32(l.VtapID))
+
+	var info *grpc.Info
+	if l.IP4 != 0 || l.IP6 != nil {
+		if l.IsIPv4 {
+			info = platformData.QueryIPV4Infos(l.L3EpcID, l.IP4)
+		} else {
+			info = platformData.QueryIPV6Infos(l.L3EpcID, l.IP6)
+		}
+	} else if vtapInfo := platformData.QueryVtapInfo(uint32(l.VtapID)); vtapInfo != nil {
+		if vtapIP := net.ParseIP(vtapInfo.Ip); vtapIP != nil {
+			if ip4 := vtapIP.To4(); ip4 != nil {
+				l.IP4 = utils.IpToUint32(ip4)
+				info = platformData.QueryIPV4Infos(vtapInfo.EpcId, l.IP4)
+			} else {
+				l.IsIPv4 = false
+				l.IP6 = vtapIP
+				info = platformData.QueryIPV6Infos(vtapInfo.EpcId, l.IP6)
+			}
+		}
+	}
+
+	podGroupType := uint8(0)
+	if info != nil {
+		l.RegionID = uint16(info.RegionID)
+		l.AZID = uint16(info.AZID)
+		l.L3EpcID = info.EpcID
+		l.HostID = uint16(info.HostID)
+		l.PodID = info.PodID
+		l.PodNodeID = info.PodNodeID
+		l.PodNSID = uint16(info.PodNSID)
+		l.PodClusterID = uint16(info.PodClusterID)
+		l.PodGroupID = info.PodGroupID
+		podGroupType = info.PodGroupType
+		l.L3DeviceType = uint8(info.DeviceType)
+		l.L3DeviceID = info.DeviceID
+		l.SubnetID = uint16(info.SubnetID)
+		// if it is just Pod Node, there is no need to match the service
+		if basecommon.IsPodServiceIP(zerodoc.DeviceType(l.L3DeviceType), l.PodID, 0) {
+			l.ServiceID = platformData.QueryService(
+				l.PodID, l.PodNodeID, uint32(l.PodClusterID), l.PodGroupID, l.L3EpcID, !l.IsIPv4, l.IP4, l.IP6, 0, 0)
+		}
+	} else if baseInfo := platformData.QueryEpcIDBaseInfo(l.L3EpcID); baseInfo != nil {
+		l.RegionID = uint16(baseInfo.RegionID)
+	}
+	// OTel data always not from INTERNET
+	if l.L3EpcID == datatype.EPC_FROM_INTERNET {
+		l.L3EpcID = datatype.EPC_UNKNOWN
+	}
+
+	l.AutoInstanceID, l.AutoInstanceType = basecommon.GetAutoInstance(l.PodID, l.GProcessID, l.PodNodeID, l.L3DeviceID, l.L3DeviceType, l.L3EpcID)
+	l.AutoServiceID, l.AutoServiceType = basecommon.GetAutoService(l.ServiceID, l.PodGroupID, l.GProcessID, l.PodNodeID, l.L3DeviceID, l.L3DeviceType, podGroupType, l.L3EpcID)
+	if l.AutoInstanceType == basecommon.InternetIpType {
+		l.AutoInstanceType = basecommon.IpType
+	}
+	if l.AutoServiceType == basecommon.InternetIpType {
+		l.AutoServiceType = basecommon.IpType
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package log_data
+
+import (
+	"testing"
+
+	v11 "go.opentelemetry.io/proto/otlp/common/v1"
+	logsv1 "go.opentelemetry.io/proto/otlp/logs/v1"
+	v1 "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	baseconfig "github.com/deepflowio/deepflow/server/ingester/config"
+	flowlogCfg "github.com/deepflowio/deepflow/server/ingester/flow_log/config"
+	"github.com/deepflowio/deepflow/server/libs/grpc"
+)
+
+func stringKeyValue(key, value string) *v11.KeyValue {
+	return &v11.KeyValue{Key: key, Value: &v11.AnyValue{Value: &v11.AnyValue_StringValue{StringValue: value}}}
+}
+
+func TestOTelLogsDataToAppLogs(t *testing.T) {
+	pf := grpc.NewPlatformInfoTable(nil, 0, 0, 0, "", "", nil, true, nil)
+	cfg := &flowlogCfg.Config{Base: &baseconfig.Config{}}
+	logsData := &logsv1.LogsData{
+		ResourceLogs: []*logsv1.ResourceLogs{{
+			Resource: &v1.Resource{
+				Attributes: []*v11.KeyValue{
+					stringKeyValue("service.name", "svc-a"),
+					stringKeyValue("service.instance.id", "svc-a-0"),
+					stringKeyValue("app.host.ip", "10.1.2.3"),
+				},
+			},
+			ScopeLogs: []*logsv1.ScopeLogs{{
+				LogRecords: []*logsv1.LogRecord{
+					{
+						TimeUnixNano:   1700000000123456789,
+						SeverityNumber: logsv1.SeverityNumber_SEVERITY_NUMBER_ERROR,
+						SeverityText:   "ERROR",
+						Body:           &v11.AnyValue{Value: &v11.AnyValue_StringValue{StringValue: "connection refused"}},
+						TraceId:        []byte{0x01, 0x02, 0x03, 0x04},
+						SpanId:         []byte{0x0a, 0x0b},
+						Attributes:     []*v11.KeyValue{stringKeyValue("thread", "main")},
+					},
+					{
+						ObservedTimeUnixNano: 1700000001000000000,
+					},
+				},
+			}},
+		}},
+	}
+
+	logs := OTelLogsDataToAppLogs(1, logsData, pf, cfg)
+	if len(logs) != 2 {
+		t.Fatalf("got %d logs, expected 2", len(logs))
+	}
+	l := logs[0]
+	if l.Timestamp != 1700000000123456 || l.ObservedTime != l.Timestamp || l.Time != 1700000000 {
+		t.Errorf("unexpected time %d %d %d", l.Timestamp, l.ObservedTime, l.Time)
+	}
+	if l.SeverityNumber != 17 || l.SeverityText != "ERROR" || l.Body != "connection refused" {
+		t.Errorf("unexpected severity or body %d %s %s", l.SeverityNumber, l.SeverityText, l.Body)
+	}
+	if l.TraceId != "01020304" || l.SpanId != "0a0b" {
+		t.Errorf("unexpected trace id %s or span id %s", l.TraceId, l.SpanId)
+	}
+	if l.AppService != "svc-a" || l.AppInstance != "svc-a-0" {
+		t.Errorf("unexpected app service %s or app instance %s", l.AppService, l.AppInstance)
+	}
+	if !l.IsIPv4 || l.IP4 != 0x0a010203 {
+		t.Errorf("unexpected ip %x", l.IP4)
+	}
+	if len(l.AttributeNames) != 4 || l.AttributeNames[0] != "thread" || l.AttributeValues[0] != "main" {
+		t.Errorf("unexpected attributes %v %v", l.AttributeNames, l.AttributeValues)
+	}
+
+	if logs[1].Timestamp != 1700000001000000 || logs[1].Time != 1700000001 {
+		t.Errorf("observed time should be used if time is not set, got %d", logs[1].Timestamp)
+	}
+	for _, l := range logs {
+		l.Release()
+	}
+}
@@ -514,6 +514,19 @@ func (h *L7FlowLog) ID() uint64 {
 	return h._id
 }
 
+// implement throttler.TailSamplingItem
+func (h *L7FlowLog) GetResponseStatus() uint8 {
+	return h.ResponseStatus
+}
+
+func (h *L7FlowLog) GetResponseDuration() uint64 {
+	return h.ResponseDuration
+}
+
+func (h *L7FlowLog) GetTraceID() string {
+	return h.TraceId
+}
+
 func (b *L7Base) Fill(log *pb.AppProtoLogsData, platformData *grpc.PlatformInfoTable) {
 	l := log.Base
 	// 网络层
@@ -0,0 +1,262 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redaction
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/deepflowio/deepflow/server/ingester/flow_log/config"
+	"github.com/deepflowio/deepflow/server/ingester/flow_log/log_data"
+	"github.com/deepflowio/deepflow/server/libs/datatype"
+	"github.com/deepflowio/deepflow/server/libs/utils"
+)
+
+const QUERY_PARAMS_ALL = "*"
+
+type Counter struct {
+	SQLNormalized    int64 `statsd:"sql-normalized"`
+	QueryParamMasked int64 `statsd:"query-param-masked"`
+	HeaderDropped    int64 `statsd:"header-dropped"`
+	RedisAuthMasked  int64 `statsd:"redis-auth-masked"`
+	RegexMasked      int64 `statsd:"regex-masked"`
+}
+
+type rule struct {
+	protocols   []string
+	fields      []string
+	regex       *regexp.Regexp
+	replacement string
+}
+
+func (r *rule) matchProtocol(l *log_data.L7FlowLog) bool {
+	if len(r.protocols) == 0 {
+		return true
+	}
+	protocol := datatype.L7Protocol(l.L7Protocol).String(false)
+	for _, p := range r.protocols {
+		if strings.EqualFold(p, protocol) || strings.EqualFold(p, l.L7ProtocolStr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redactor is not thread safe, each decoder should have its own
+type Redactor struct {
+	mask                string
+	sqlNormalize        bool
+	maskAllQueryParams  bool
+	queryParams         map[string]bool
+	httpHeaderAllowList map[string]bool
+	redisAuthMask       bool
+	rules               []*rule
+
+	counter *Counter
+	utils.Closable
+}
+
+func NewRedactor(cfg *config.Redaction) (*Redactor, error) {
+	r := &Redactor{
+		mask:          cfg.Mask,
+		sqlNormalize:  cfg.SQLNormalize,
+		queryParams:   make(map[string]bool),
+		redisAuthMask: cfg.RedisAuthMask,
+		counter:       &Counter{},
+	}
+	for _, p := range cfg.QueryParams {
+		if p == QUERY_PARAMS_ALL {
+			r.maskAllQueryParams = true
+		}
+		r.queryParams[strings.ToLower(p)] = true
+	}
+	if len(cfg.HTTPHeaderAllowList) > 0 {
+		r.httpHeaderAllowList = make(map[string]bool)
+		for _, h := range cfg.HTTPHeaderAllowList {
+			r.httpHeaderAllowList[strings.ToLower(h)] = true
+		}
+	}
+	for _, c := range cfg.Rules {
+		regex, err := regexp.Compile(c.Regex)
+		if err != nil {
+			return nil, err
+		}
+		r.rules = append(r.rules, &rule{
+			protocols:   c.Protocols,
+			fields:      c.Fields,
+			regex:       regex,
+			replacement: c.Replacement,
+		})
+	}
+	return r, nil
+}
+
+func (r *Redactor) GetCounter() interface{} {
+	var counter *Counter
+	counter, r.counter = r.counter, &Counter{}
+	return counter
+}
+
+// Redact should be called before the flow log is throttled, since the throttler may write or export it asynchronously
+func (r *Redactor) Redact(l *log_data.L7FlowLog) {
+	switch datatype.L7Protocol(l.L7Protocol) {
+	case datatype.L7_PROTOCOL_MYSQL, datatype.L7_PROTOCOL_POSTGRE, datatype.L7_PROTOCOL_ORACLE:
+		if r.sqlNormalize && l.RequestResource != "" {
+			normalized := NormalizeSQL(l.RequestResource, datatype.L7Protocol(l.L7Protocol) == datatype.L7_PROTOCOL_MYSQL)
+			if normalized != l.RequestResource {
+				l.RequestResource = normalized
+				r.counter.SQLNormalized++
+			}
+		}
+	case datatype.L7_PROTOCOL_HTTP_1, datatype.L7_PROTOCOL_HTTP_2:
+		if len(r.queryParams) > 0 {
+			l.RequestResource = r.maskQueryParams(l.RequestResource)
+			for i, name := range l.AttributeNames {
+				if name == "http_referer" {
+					l.AttributeValues[i] = r.maskQueryParams(l.AttributeValues[i])
+				}
+			}
+		}
+		if r.httpHeaderAllowList != nil && l.SignalSource != uint16(datatype.SIGNAL_SOURCE_OTEL) {
+			r.filterHeaders(l)
+		}
+	case datatype.L7_PROTOCOL_REDIS:
+		if r.redisAuthMask && l.RequestResource != "" {
+			l.RequestResource = r.maskRedisAuth(l.RequestResource)
+		}
+	}
+
+	for _, rule := range r.rules {
+		if !rule.matchProtocol(l) {
+			continue
+		}
+		for _, field := range rule.fields {
+			switch field {
+			case config.REDACTION_FIELD_REQUEST_RESOURCE:
+				l.RequestResource = r.replace(rule, l.RequestResource)
+			case config.REDACTION_FIELD_REQUEST_DOMAIN:
+				l.RequestDomain = r.replace(rule, l.RequestDomain)
+			case config.REDACTION_FIELD_ENDPOINT:
+				l.Endpoint = r.replace(rule, l.Endpoint)
+			case config.REDACTION_FIELD_RESPONSE_RESULT:
+				l.ResponseResult = r.replace(rule, l.ResponseResult)
+			case config.REDACTION_FIELD_RESPONSE_EXCEPTION:
+				l.ResponseException = r.replace(rule, l.ResponseException)
+			case config.REDACTION_FIELD_ATTRIBUTE:
+				for i := range l.AttributeValues {
+					l.AttributeValues[i] = r.replace(rule, l.AttributeValues[i])
+				}
+			}
+		}
+	}
+}
+
+func (r *Redactor) replace(rule *rule, value string) string {
+	if value == "" || !rule.regex.MatchString(value) {
+		return value
+	}
+	r.counter.RegexMasked++
+	return rule.regex.ReplaceAllString(value, rule.replacement)
+}
+
+// maskQueryParams masks the values of the query-string parameters, the others are kept as they are
+func (r *Redactor) maskQueryParams(resource string) string {
+	start := strings.IndexByte(resource, '?')
+	if start < 0 {
+		return resource
+	}
+	end := strings.IndexByte(resource[start:], '#')
+	if end < 0 {
+		end = len(resource)
+	} else {
+		end += start
+	}
+
+	params := strings.Split(resource[start+1:end], "&")
+	masked := false
+	for i, param := range params {
+		eq := strings.IndexByte(param, '=')
+		if eq < 0 {
+			continue
+		}
+		name := param[:eq]
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if r.maskAllQueryParams || r.queryParams[strings.ToLower(name)] {
+			params[i] = param[:eq+1] + r.mask
+			masked = true
+			r.counter.QueryParamMasked++
+		}
+	}
+	if !masked {
+		return resource
+	}
+	return resource[:start+1] + strings.Join(params, "&") + resource[end:]
+}
+
+// filterHeaders drops the attributes of HTTP logs which are not in the allow list
+func (r *Redactor) filterHeaders(l *log_data.L7FlowLog) {
+	n := 0
+	for i, name := range l.AttributeNames {
+		if !r.httpHeaderAllowList[strings.ToLower(name)] {
+			r.counter.HeaderDropped++
+			continue
+		}
+		l.AttributeNames[n] = name
+		l.AttributeValues[n] = l.AttributeValues[i]
+		n++
+	}
+	l.AttributeNames = l.AttributeNames[:n]
+	l.AttributeValues = l.AttributeValues[:n]
+}
+
+// maskRedisAuth masks the passwords of the commands:
+//   - AUTH [username] password
+//   - HELLO protover AUTH username password
+//   - MIGRATE host port key db timeout AUTH password / AUTH2 username password
+//   - CONFIG SET requirepass|masterauth password
+func (r *Redactor) maskRedisAuth(command string) string {
+	args := strings.Fields(command)
+	if len(args) < 2 {
+		return command
+	}
+	maskFrom := -1
+	switch strings.ToUpper(args[0]) {
+	case "AUTH":
+		maskFrom = 1
+	case "HELLO", "MIGRATE":
+		for i := 1; i < len(args); i++ {
+			if arg := strings.ToUpper(args[i]); arg == "AUTH" || arg == "AUTH2" {
+				maskFrom = i + 1
+				break
+			}
+		}
+	case "CONFIG":
+		if len(args) > 3 && strings.EqualFold(args[1], "SET") {
+			if param := strings.ToLower(args[2]); param == "requirepass" || param == "masterauth" {
+				maskFrom = 3
+			}
+		}
+	}
+	if maskFrom < 0 || maskFrom >= len(args) {
+		return command
+	}
+	r.counter.RedisAuthMasked++
+	return strings.Join(args[:maskFrom], " ") + " " + r.mask
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redaction
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/deepflowio/deepflow/server/ingester/flow_log/config"
+	"github.com/deepflowio/deepflow/server/ingester/flow_log/log_data"
+	"github.com/deepflowio/deepflow/server/libs/datatype"
+)
+
+func TestNormalizeSQL(t *testing.T) {
+	cases := []struct {
+		sql                string
+		doubleQuotedString bool
+		expected           string
+	}{
+		{"SELECT * FROM user WHERE name='alice' AND password = 'p''w\\'d'", true, "SELECT * FROM user WHERE name=? AND password = ?"},
+		{`UPDATE t1 SET c2=1.5e-3, c3=0x1F, c4="x" WHERE id IN (1, 2,-3)`, true, `UPDATE t1 SET c2=?, c3=?, c4=? WHERE id IN (?, ?,-?)`},
+		{`SELECT "col1" FROM "t2" WHERE a = $1 AND b = 'x'`, false, `SELECT "col1" FROM "t2" WHERE a = $1 AND b = ?`},
+		{"SELECT `c1` /* 123 */ FROM t -- 'a'\nWHERE x = 10", true, "SELECT `c1` /* 123 */ FROM t -- 'a'\nWHERE x = ?"},
+		{"INSERT INTO t VALUES ('truncat", true, "INSERT INTO t VALUES (?"},
+	}
+	for _, c := range cases {
+		if got := NormalizeSQL(c.sql, c.doubleQuotedString); got != c.expected {
+			t.Errorf("NormalizeSQL(%q) == %q, expected %q", c.sql, got, c.expected)
+		}
+	}
+}
+
+func newTestRedactor(t *testing.T, cfg *config.Redaction) *Redactor {
+	cfg.Enabled = true
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewRedactor(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRedact(t *testing.T) {
+	r := newTestRedactor(t, &config.Redaction{
+		SQLNormalize:        true,
+		QueryParams:         []string{"password", "Token"},
+		HTTPHeaderAllowList: []string{"http_user_agent", "X-Tenant"},
+		RedisAuthMask:       true,
+		Rules: []config.RedactionRule{
+			{Protocols: []string{"http"}, Regex: "(/users/)[0-9]+", Replacement: "${1}{id}"},
+			{Fields: []string{config.REDACTION_FIELD_ATTRIBUTE, config.REDACTION_FIELD_RESPONSE_RESULT}, Regex: "[0-9]{3}-[0-9]{4}"},
+		},
+	})
+
+	l := &log_data.L7FlowLog{
+		L7Protocol:      uint8(datatype.L7_PROTOCOL_HTTP_1),
+		RequestResource: "/users/42/login?user=bob&password=123&token=abc#top",
+		AttributeNames:  []string{"http_user_agent", "http_referer", "cookie", "x-tenant"},
+		AttributeValues: []string{"curl", "/?Password=1", "session=1", "phone 555-1234"},
+	}
+	r.Redact(l)
+	if l.RequestResource != "/users/{id}/login?user=bob&password=***&token=***#top" {
+		t.Errorf("unexpected request_resource %s", l.RequestResource)
+	}
+	if !reflect.DeepEqual(l.AttributeNames, []string{"http_user_agent", "x-tenant"}) ||
+		!reflect.DeepEqual(l.AttributeValues, []string{"curl", "phone ***"}) {
+		t.Errorf("unexpected attributes %v %v", l.AttributeNames, l.AttributeValues)
+	}
+
+	l = &log_data.L7FlowLog{
+		L7Protocol:      uint8(datatype.L7_PROTOCOL_MYSQL),
+		RequestResource: "SELECT * FROM user WHERE phone='555-1234'",
+		ResponseResult:  "555-1234",
+	}
+	r.Redact(l)
+	if l.RequestResource != "SELECT * FROM user WHERE phone=?" || l.ResponseResult != "***" {
+		t.Errorf("unexpected sql %s, result %s", l.RequestResource, l.ResponseResult)
+	}
+
+	for command, expected := range map[string]string{
+		"AUTH secret":                           "AUTH ***",
+		"AUTH default secret":                   "AUTH ***",
+		"HELLO 3 AUTH default secret":           "HELLO 3 AUTH ***",
+		"CONFIG SET requirepass secret":         "CONFIG SET requirepass ***",
+		"CONFIG GET requirepass":                "CONFIG GET requirepass",
+		"MIGRATE host 6379 key 0 5000 AUTH pwd": "MIGRATE host 6379 key 0 5000 AUTH ***",
+		"GET auth":                              "GET auth",
+	} {
+		l = &log_data.L7FlowLog{L7Protocol: uint8(datatype.L7_PROTOCOL_REDIS), RequestResource: command}
+		r.Redact(l)
+		if l.RequestResource != expected {
+			t.Errorf("redact redis command %q got %q, expected %q", command, l.RequestResource, expected)
+		}
+	}
+
+	counter := r.GetCounter().(*Counter)
+	expected := Counter{SQLNormalized: 1, QueryParamMasked: 3, HeaderDropped: 2, RedisAuthMasked: 5, RegexMasked: 3}
+	if *counter != expected {
+		t.Errorf("counter == %+v, expected %+v", *counter, expected)
+	}
+}
+
+func TestRedactionValidate(t *testing.T) {
+	if err := (&config.Redaction{Enabled: true, Rules: []config.RedactionRule{{Regex: "("}}}).Validate(); err == nil {
+		t.Errorf("invalid regex should fail")
+	}
+	if err := (&config.Redaction{Enabled: true, Rules: []config.RedactionRule{{Regex: "a", Fields: []string{"body"}}}}).Validate(); err == nil {
+		t.Errorf("invalid field should fail")
+	}
+}
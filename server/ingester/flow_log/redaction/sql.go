@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redaction
+
+import (
+	"strings"
+)
+
+const SQL_PLACEHOLDER = '?'
+
+func isIdentifierChar(c byte) bool {
+	return c == '_' || c == '$' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// skipQuoted returns the end of the literal quoted by sql[start], the quote is escaped by doubling it or by backslash,
+// the SQL may be truncated by the agent, so an unterminated literal ends with the SQL
+func skipQuoted(sql string, start int) int {
+	quote := sql[start]
+	for i := start + 1; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			i++
+		case quote:
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(sql)
+}
+
+// NormalizeSQL replaces the string and number literals with '?', the identifiers, keywords and comments are kept.
+// doubleQuotedString is true for MySQL, in which "xxx" is a string literal instead of an identifier
+func NormalizeSQL(sql string, doubleQuotedString bool) string {
+	var sb strings.Builder
+	sb.Grow(len(sql))
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' && doubleQuotedString:
+			i = skipQuoted(sql, i)
+			sb.WriteByte(SQL_PLACEHOLDER)
+		case c == '"' || c == '`':
+			// quoted identifiers
+			end := skipQuoted(sql, i)
+			sb.WriteString(sql[i:end])
+			i = end
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-', c == '#':
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			sb.WriteString(sql[i : i+end])
+			i += end
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				end = len(sql)
+			} else {
+				end += i + 4
+			}
+			sb.WriteString(sql[i:end])
+			i = end
+		case isDigit(c):
+			// numbers, including hex numbers like 0x1F and floats like 1.5e10
+			for i < len(sql) && (isIdentifierChar(sql[i]) || (sql[i] == '+' || sql[i] == '-') && (sql[i-1] == 'e' || sql[i-1] == 'E')) {
+				i++
+			}
+			sb.WriteByte(SQL_PLACEHOLDER)
+		case isIdentifierChar(c):
+			start := i
+			for i < len(sql) && isIdentifierChar(sql[i]) {
+				i++
+			}
+			sb.WriteString(sql[start:i])
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return sb.String()
+}
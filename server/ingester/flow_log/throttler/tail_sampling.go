@@ -0,0 +1,273 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package throttler
+
+import (
+	"math/bits"
+
+	"github.com/deepflowio/deepflow/server/ingester/flow_log/config"
+	"github.com/deepflowio/deepflow/server/libs/datatype"
+	"github.com/deepflowio/deepflow/server/libs/lru"
+)
+
+// TailSamplingItem is implemented by the items which can be kept by the tail sampling policies, e.g.: L7FlowLog
+type TailSamplingItem interface {
+	GetResponseStatus() uint8
+	GetResponseDuration() uint64 // us
+	GetTraceID() string
+}
+
+type samplingPolicy uint8
+
+const (
+	POLICY_NONE samplingPolicy = iota
+	POLICY_ERROR
+	POLICY_TIMEOUT
+	POLICY_SLOW
+	POLICY_TRACE
+)
+
+type Counter struct {
+	TotalCount       int64 `statsd:"total-count"`
+	KeepCount        int64 `statsd:"keep-count"` // kept by reservoir sampling
+	KeepErrorCount   int64 `statsd:"keep-error-count"`
+	KeepTimeoutCount int64 `statsd:"keep-timeout-count"`
+	KeepSlowCount    int64 `statsd:"keep-slow-count"`
+	KeepTraceCount   int64 `statsd:"keep-trace-count"`
+
+	DropCount        int64 `statsd:"drop-count"`
+	DropErrorCount   int64 `statsd:"drop-error-count"` // the rows matched the policies but exceeded 'priority-throttle'
+	DropTimeoutCount int64 `statsd:"drop-timeout-count"`
+	DropSlowCount    int64 `statsd:"drop-slow-count"`
+
+	SlowThreshold int64 `statsd:"slow-threshold"` // us
+}
+
+const (
+	// each power of 2 is divided into 4 sub buckets, the error of latency percentile is less than 25%
+	latencySubBucketBits = 2
+	latencyBucketCount   = (64 + 1) << latencySubBucketBits
+)
+
+// latencyHistogram estimates the latency percentile of the last throttle period
+type latencyHistogram struct {
+	buckets [latencyBucketCount]uint32
+	count   uint32
+}
+
+func latencyBucket(d uint64) int {
+	n := bits.Len64(d)
+	if n <= latencySubBucketBits {
+		return int(d)
+	}
+	sub := (d >> uint(n-1-latencySubBucketBits)) & (1<<latencySubBucketBits - 1)
+	return n<<latencySubBucketBits | int(sub)
+}
+
+// latencyBucketLowerBound is the minimum latency of the bucket
+func latencyBucketLowerBound(b int) uint64 {
+	n := b >> latencySubBucketBits
+	if n <= latencySubBucketBits {
+		return uint64(b)
+	}
+	sub := uint64(b & (1<<latencySubBucketBits - 1))
+	return (1<<latencySubBucketBits | sub) << uint(n-1-latencySubBucketBits)
+}
+
+func (h *latencyHistogram) add(d uint64) {
+	h.buckets[latencyBucket(d)]++
+	h.count++
+}
+
+// percentile returns the lower bound of the bucket where the percentile lies, 0 means not enough samples
+func (h *latencyHistogram) percentile(p float64) uint64 {
+	if h.count == 0 {
+		return 0
+	}
+	target := uint32(float64(h.count) * p / 100)
+	sum := uint32(0)
+	for i := range h.buckets {
+		sum += h.buckets[i]
+		if sum > target {
+			return latencyBucketLowerBound(i)
+		}
+	}
+	return 0
+}
+
+func (h *latencyHistogram) reset() {
+	*h = latencyHistogram{}
+}
+
+type tailSampler struct {
+	keepServerError bool
+	keepClientError bool
+	keepTimeout     bool
+	slowPercentile  float64
+	keepTrace       bool
+
+	// the rows kept by the policies in one throttle period
+	priorityThrottle int
+	priorityCount    int
+
+	histogram     latencyHistogram
+	slowThreshold uint64                       // calculated by the histogram of the last period
+	traces        *lru.Cache[string, struct{}] // the traces which have spans kept by policies
+
+	counter *Counter
+}
+
+func newTailSampler(cfg *config.TailSampling, priorityThrottle int) *tailSampler {
+	s := &tailSampler{
+		slowPercentile:   cfg.KeepSlowPercentile,
+		keepTrace:        cfg.KeepTrace,
+		priorityThrottle: priorityThrottle,
+		counter:          &Counter{},
+	}
+	for _, status := range cfg.KeepResponseStatus {
+		switch status {
+		case config.RESPONSE_STATUS_SERVER_ERROR:
+			s.keepServerError = true
+		case config.RESPONSE_STATUS_CLIENT_ERROR:
+			s.keepClientError = true
+		case config.RESPONSE_STATUS_TIMEOUT:
+			s.keepTimeout = true
+		}
+	}
+	if s.keepTrace {
+		s.traces = lru.NewCache[string, struct{}](cfg.TraceCacheSize)
+	}
+	return s
+}
+
+// classify returns the policy which the item matched, the trace policy is not included
+func (s *tailSampler) classify(item TailSamplingItem) samplingPolicy {
+	switch datatype.LogMessageStatus(item.GetResponseStatus()) {
+	case datatype.STATUS_SERVER_ERROR, datatype.STATUS_ERROR:
+		if s.keepServerError {
+			return POLICY_ERROR
+		}
+	case datatype.STATUS_CLIENT_ERROR:
+		if s.keepClientError {
+			return POLICY_ERROR
+		}
+	case datatype.STATUS_NOT_EXIST:
+		// no response is received
+		if s.keepTimeout {
+			return POLICY_TIMEOUT
+		}
+	}
+	if s.slowThreshold > 0 && item.GetResponseDuration() > s.slowThreshold {
+		return POLICY_SLOW
+	}
+	return POLICY_NONE
+}
+
+func (s *tailSampler) isTraceSampled(item TailSamplingItem) bool {
+	if !s.keepTrace {
+		return false
+	}
+	traceID := item.GetTraceID()
+	return traceID != "" && s.traces.Contain(traceID)
+}
+
+// keep checks whether the item should be kept regardless of the reservoir sampling
+func (s *tailSampler) keep(flow interface{}) bool {
+	item, ok := flow.(TailSamplingItem)
+	if !ok {
+		return false
+	}
+	s.counter.TotalCount++
+	if s.slowPercentile > 0 {
+		if d := item.GetResponseDuration(); d > 0 {
+			s.histogram.add(d)
+		}
+	}
+
+	policy := s.classify(item)
+	if policy == POLICY_NONE && s.isTraceSampled(item) {
+		policy = POLICY_TRACE
+	}
+	if policy == POLICY_NONE {
+		return false
+	}
+	if s.priorityCount >= s.priorityThrottle {
+		// fall back to reservoir sampling
+		return false
+	}
+	s.priorityCount++
+	switch policy {
+	case POLICY_ERROR:
+		s.counter.KeepErrorCount++
+	case POLICY_TIMEOUT:
+		s.counter.KeepTimeoutCount++
+	case POLICY_SLOW:
+		s.counter.KeepSlowCount++
+	case POLICY_TRACE:
+		s.counter.KeepTraceCount++
+	}
+	if s.keepTrace && policy != POLICY_TRACE {
+		if traceID := item.GetTraceID(); traceID != "" {
+			s.traces.Add(traceID, struct{}{})
+		}
+	}
+	return true
+}
+
+// rescue is called before an item is discarded by reservoir sampling, the item is kept if its trace
+// has been sampled after it entered the reservoir
+func (s *tailSampler) rescue(flow interface{}) bool {
+	item, ok := flow.(TailSamplingItem)
+	if !ok || s.priorityCount >= s.priorityThrottle || !s.isTraceSampled(item) {
+		return false
+	}
+	s.priorityCount++
+	s.counter.KeepTraceCount++
+	return true
+}
+
+func (s *tailSampler) countDrop(flow interface{}) {
+	s.counter.DropCount++
+	item, ok := flow.(TailSamplingItem)
+	if !ok {
+		return
+	}
+	switch s.classify(item) {
+	case POLICY_ERROR:
+		s.counter.DropErrorCount++
+	case POLICY_TIMEOUT:
+		s.counter.DropTimeoutCount++
+	case POLICY_SLOW:
+		s.counter.DropSlowCount++
+	}
+}
+
+// newPeriod is called when a new throttle period begins
+func (s *tailSampler) newPeriod() {
+	s.priorityCount = 0
+	if s.slowPercentile > 0 {
+		s.slowThreshold = s.histogram.percentile(s.slowPercentile)
+		s.histogram.reset()
+	}
+}
+
+func (s *tailSampler) GetCounter() interface{} {
+	var counter *Counter
+	counter, s.counter = s.counter, &Counter{}
+	counter.SlowThreshold = int64(s.slowThreshold)
+	return counter
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package throttler
+
+import (
+	"testing"
+
+	"github.com/deepflowio/deepflow/server/ingester/flow_log/config"
+	"github.com/deepflowio/deepflow/server/libs/datatype"
+)
+
+type testFlow struct {
+	status   datatype.LogMessageStatus
+	duration uint64
+	traceID  string
+}
+
+func (f *testFlow) GetResponseStatus() uint8    { return uint8(f.status) }
+func (f *testFlow) GetResponseDuration() uint64 { return f.duration }
+func (f *testFlow) GetTraceID() string          { return f.traceID }
+
+func newTestTailSampling() *config.TailSampling {
+	return &config.TailSampling{
+		Enabled:            true,
+		KeepResponseStatus: []string{config.RESPONSE_STATUS_SERVER_ERROR, config.RESPONSE_STATUS_TIMEOUT},
+		KeepSlowPercentile: 90,
+		KeepTrace:          true,
+		TraceCacheSize:     16,
+	}
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	for _, d := range []uint64{0, 1, 3, 4, 5, 7, 8, 100, 1000, 123456, 1 << 40, 1<<64 - 1} {
+		b := latencyBucket(d)
+		if lower := latencyBucketLowerBound(b); lower > d || (d > 8 && float64(d-lower) > float64(d)/4) {
+			t.Errorf("latency %d in bucket %d with lower bound %d", d, b, lower)
+		}
+	}
+
+	h := &latencyHistogram{}
+	for i := uint64(1); i <= 1000; i++ {
+		h.add(i)
+	}
+	if p := h.percentile(99); p < 800 || p > 990 {
+		t.Errorf("p99 == %d, expected about 990", p)
+	}
+	if p := h.percentile(50); p < 400 || p > 500 {
+		t.Errorf("p50 == %d, expected about 500", p)
+	}
+	h.reset()
+	if p := h.percentile(99); p != 0 {
+		t.Errorf("p99 of empty histogram == %d, expected 0", p)
+	}
+}
+
+func TestTailSamplerKeep(t *testing.T) {
+	s := newTailSampler(newTestTailSampling(), 3)
+
+	if s.keep(&testFlow{status: datatype.STATUS_OK, duration: 1000}) {
+		t.Errorf("normal flow should not be kept")
+	}
+	if s.keep(&testFlow{status: datatype.STATUS_CLIENT_ERROR, duration: 1000}) {
+		t.Errorf("client error flow should not be kept")
+	}
+	if !s.keep(&testFlow{status: datatype.STATUS_SERVER_ERROR, duration: 1000, traceID: "t1"}) {
+		t.Errorf("server error flow should be kept")
+	}
+	if !s.keep(&testFlow{status: datatype.STATUS_OK, duration: 1000, traceID: "t1"}) {
+		t.Errorf("flow of sampled trace should be kept")
+	}
+	if !s.keep(&testFlow{status: datatype.STATUS_NOT_EXIST, traceID: "t2"}) {
+		t.Errorf("timeout flow should be kept")
+	}
+	// exceed priority throttle
+	if s.keep(&testFlow{status: datatype.STATUS_SERVER_ERROR}) {
+		t.Errorf("flow exceeded priority throttle should not be kept")
+	}
+
+	// the slow threshold is calculated by the last period
+	for i := uint64(1); i <= 100; i++ {
+		s.keep(&testFlow{status: datatype.STATUS_OK, duration: i * 100})
+	}
+	s.newPeriod()
+	if s.slowThreshold < 8000 || s.slowThreshold > 9000 {
+		t.Errorf("slow threshold == %d, expected about 9000", s.slowThreshold)
+	}
+	if s.keep(&testFlow{status: datatype.STATUS_OK, duration: 5000}) {
+		t.Errorf("fast flow should not be kept")
+	}
+	if !s.keep(&testFlow{status: datatype.STATUS_OK, duration: 20000}) {
+		t.Errorf("slow flow should be kept")
+	}
+
+	counter := s.GetCounter().(*Counter)
+	if counter.TotalCount != 108 || counter.KeepErrorCount != 1 || counter.KeepTimeoutCount != 1 ||
+		counter.KeepTraceCount != 1 || counter.KeepSlowCount != 1 || counter.SlowThreshold != int64(s.slowThreshold) {
+		t.Errorf("unexpected counter %+v", *counter)
+	}
+}
+
+func TestThrottlingQueueTailSampling(t *testing.T) {
+	thq := NewThrottlingQueue(2, 1, nil, 0)
+	thq.EnableTailSampling(newTestTailSampling(), 2)
+	if !thq.TailSamplingEnabled() {
+		t.Fatalf("tail sampling should be enabled")
+	}
+	// never flush in this test
+	thq.throttleBucket = 1 << 40
+
+	// fill the reservoir, the spans of trace t1 enter it before t1 is sampled
+	for i := 0; i < 2; i++ {
+		thq.SendWithThrottling(&testFlow{status: datatype.STATUS_OK, traceID: "t1"})
+	}
+	if !thq.SendWithThrottling(&testFlow{status: datatype.STATUS_SERVER_ERROR, traceID: "t1"}) {
+		t.Errorf("server error flow should be kept")
+	}
+	if len(thq.nonSampleItems) != 1 {
+		t.Fatalf("%d items sent without throttling, expected 1", len(thq.nonSampleItems))
+	}
+
+	// the flows discarded by the reservoir are rescued if their traces are sampled
+	if thq.release(&testFlow{status: datatype.STATUS_OK}) {
+		t.Errorf("flow of unsampled trace should not be rescued")
+	}
+	if !thq.release(&testFlow{status: datatype.STATUS_OK, traceID: "t1"}) {
+		t.Errorf("flow of sampled trace should be rescued")
+	}
+	if len(thq.nonSampleItems) != 2 {
+		t.Errorf("%d items sent without throttling, expected 2", len(thq.nonSampleItems))
+	}
+
+	// priority throttle is exhausted, fall back to reservoir sampling
+	for i := 0; i < 10; i++ {
+		thq.SendWithThrottling(&testFlow{status: datatype.STATUS_SERVER_ERROR})
+	}
+
+	counter := thq.GetCounter().(*Counter)
+	if counter.KeepErrorCount != 1 || counter.KeepTraceCount != 1 || counter.DropCount != 11 || counter.DropErrorCount < 8 {
+		t.Errorf("unexpected counter %+v", *counter)
+	}
+
+	disabled := NewThrottlingQueue(2, 1, nil, 0)
+	disabled.EnableTailSampling(&config.TailSampling{}, 2)
+	if disabled.TailSamplingEnabled() || disabled.GetCounter() != nil {
+		t.Errorf("tail sampling should be disabled")
+	}
+}
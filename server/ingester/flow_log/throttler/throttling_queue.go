@@ -20,7 +20,9 @@ import (
 	"math/rand"
 	"time"
 
+	"github.com/deepflowio/deepflow/server/ingester/flow_log/config"
 	"github.com/deepflowio/deepflow/server/ingester/flow_log/dbwriter"
+	"github.com/deepflowio/deepflow/server/libs/utils"
 )
 
 const (
@@ -32,6 +34,8 @@ type throttleItem interface {
 }
 
 type ThrottlingQueue struct {
+	utils.Closable
+
 	flowLogWriter *dbwriter.FlowLogWriter
 	index         int
 
@@ -43,6 +47,8 @@ type ThrottlingQueue struct {
 
 	sampleItems    []interface{}
 	nonSampleItems []interface{}
+
+	tailSampler *tailSampler
 }
 
 func NewThrottlingQueue(throttle, throttleBucket int, flowLogWriter *dbwriter.FlowLogWriter, index int) *ThrottlingQueue {
@@ -60,11 +66,50 @@ func NewThrottlingQueue(throttle, throttleBucket int, flowLogWriter *dbwriter.Fl
 	return thq
 }
 
+// EnableTailSampling keeps the items matched the policies of cfg instead of reservoir sampling them
+func (thq *ThrottlingQueue) EnableTailSampling(cfg *config.TailSampling, priorityThrottle int) {
+	if thq.SampleDisabled() || !cfg.Enabled {
+		return
+	}
+	thq.tailSampler = newTailSampler(cfg, priorityThrottle*int(thq.throttleBucket))
+}
+
+func (thq *ThrottlingQueue) TailSamplingEnabled() bool {
+	return thq.tailSampler != nil
+}
+
+// GetCounter returns nil if tail sampling is disabled
+func (thq *ThrottlingQueue) GetCounter() interface{} {
+	if thq.tailSampler == nil {
+		return nil
+	}
+	return thq.tailSampler.GetCounter()
+}
+
+// release discards the item dropped by reservoir sampling, returns true if it is kept by tail sampling
+func (thq *ThrottlingQueue) release(flow interface{}) bool {
+	if thq.tailSampler != nil {
+		if thq.tailSampler.rescue(flow) {
+			thq.SendWithoutThrottling(flow)
+			return true
+		}
+		thq.tailSampler.countDrop(flow)
+	}
+	if tItem, ok := flow.(throttleItem); ok {
+		tItem.Release()
+	}
+	return false
+}
+
 func (thq *ThrottlingQueue) SampleDisabled() bool {
 	return thq.Throttle <= 0
 }
 
 func (thq *ThrottlingQueue) flush() {
+	if thq.tailSampler != nil {
+		thq.tailSampler.counter.KeepCount += int64(thq.periodEmitCount)
+		thq.tailSampler.newPeriod()
+	}
 	if thq.periodEmitCount > 0 {
 		if thq.flowLogWriter != nil {
 			thq.flowLogWriter.Put(thq.index, thq.sampleItems[:thq.periodEmitCount]...)
@@ -95,6 +140,11 @@ func (thq *ThrottlingQueue) SendWithThrottling(flow interface{}) bool {
 		return false
 	}
 
+	if thq.tailSampler != nil && thq.tailSampler.keep(flow) {
+		thq.SendWithoutThrottling(flow)
+		return true
+	}
+
 	// Reservoir Sampling
 	thq.periodCount++
 	if thq.periodEmitCount < thq.Throttle {
@@ -104,14 +154,10 @@ func (thq *ThrottlingQueue) SendWithThrottling(flow interface{}) bool {
 	} else {
 		r := rand.Intn(thq.periodCount)
 		if r < thq.Throttle {
-			if tItem, ok := thq.sampleItems[r].(throttleItem); ok {
-				tItem.Release()
-			}
+			thq.release(thq.sampleItems[r])
 			thq.sampleItems[r] = flow
 		} else {
-			if tItem, ok := flow.(throttleItem); ok {
-				tItem.Release()
-			}
+			return thq.release(flow)
 		}
 		return false
 	}
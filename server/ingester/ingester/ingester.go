@@ -51,6 +51,7 @@ import (
 	flowmetrics "github.com/deepflowio/deepflow/server/ingester/flow_metrics/flow_metrics"
 	pcapcfg "github.com/deepflowio/deepflow/server/ingester/pcap/config"
 	"github.com/deepflowio/deepflow/server/ingester/pcap/pcap"
+	"github.com/deepflowio/deepflow/server/ingester/pkg/ckwriter"
 	profilecfg "github.com/deepflowio/deepflow/server/ingester/profile/config"
 	"github.com/deepflowio/deepflow/server/ingester/profile/profile"
 	prometheuscfg "github.com/deepflowio/deepflow/server/ingester/prometheus/config"
@@ -91,11 +92,31 @@ func Start(configPath string, shared *servercommon.ControllerIngesterShared) []i
 	stats.SetRemoteType(stats.REMOTE_TYPE_DFSTATSD)
 	stats.SetDFRemote(net.JoinHostPort("127.0.0.1", strconv.Itoa(int(cfg.ListenPort))))
 
+	ckwriter.SetSpillConfig(&cfg.CKWriterSpill)
+
 	dropletConfig := dropletcfg.Load(cfg, configPath)
 	bytes, _ = yaml.Marshal(dropletConfig)
 	log.Infof("droplet config:\n%s", string(bytes))
 
+	var receiverTLS *receiver.TLSConfig
+	receiverServerType := receiver.BOTH
+	if tlsCfg := cfg.ReceiverTLS; tlsCfg.Enabled {
+		receiverTLS = &receiver.TLSConfig{
+			ListenPort:        int(tlsCfg.ListenPort),
+			CertFile:          tlsCfg.CertFile,
+			KeyFile:           tlsCfg.KeyFile,
+			CAFile:            tlsCfg.CAFile,
+			RequireClientCert: tlsCfg.RequireClientCert,
+			ReloadInterval:    time.Duration(tlsCfg.ReloadInterval) * time.Second,
+		}
+		if tlsCfg.PlainTCPDisabled {
+			receiverServerType = receiver.UDP
+		}
+	}
+
 	receiver := receiver.NewReceiver(int(cfg.ListenPort), cfg.UDPReadBuffer, cfg.TCPReadBuffer, cfg.TCPReaderBuffer)
+	receiver.SetServerType(receiverServerType)
+	receiver.SetTLSConfig(receiverTLS)
 
 	closers := droplet.Start(dropletConfig, receiver)
 
@@ -30,6 +30,7 @@ import (
 	"github.com/deepflowio/deepflow/server/ingester/droplet/queue"
 	"github.com/deepflowio/deepflow/server/ingester/ingesterctl"
 	"github.com/deepflowio/deepflow/server/ingester/ingesterctl/rpc"
+	"github.com/deepflowio/deepflow/server/ingester/ingesterctl/tap"
 	"github.com/deepflowio/deepflow/server/ingester/prometheus/decoder"
 	"github.com/deepflowio/deepflow/server/libs/debug"
 	"github.com/deepflowio/deepflow/server/libs/receiver"
@@ -73,6 +74,7 @@ func RegisterIngesterCommand(root *cobra.Command) {
 	ingesterCmd.AddCommand(profiler.RegisterProfilerCommand())
 	ingesterCmd.AddCommand(debug.RegisterLogLevelCommand())
 	ingesterCmd.AddCommand(RegisterTimeConvertCommand())
+	ingesterCmd.AddCommand(tap.RegisterTapCommand())
 
 	dropletCmd.AddCommand(queue.RegisterCommand(ingesterctl.INGESTERCTL_QUEUE, []string{
 		"1-receiver-to-statsd",
@@ -37,6 +37,7 @@ const (
 	INGESTERCTL_EVENT_QUEUE
 	INGESTERCTL_PROMETHEUS_QUEUE
 	INGESTERCTL_PROFILE_QUEUE
+	INGESTERCTL_TAP
 
 	INGESTERCTL_MAX
 )
@@ -52,6 +53,7 @@ const (
 	CMD_OTLP_EXPORTER
 	CMD_EXPORTER_PLATFORMDATA
 	CMD_PLATFORMDATA_PROFILE
+	CMD_KAFKA_EXPORTER
 )
 
 const (
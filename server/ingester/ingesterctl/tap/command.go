@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tap
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/deepflowio/deepflow/server/ingester/ingesterctl"
+	"github.com/deepflowio/deepflow/server/libs/datatype"
+	"github.com/deepflowio/deepflow/server/libs/debug"
+)
+
+func sendFilter(filter *Filter, operate int) (*net.UDPConn, string, error) {
+	buffer := bytes.Buffer{}
+	if err := gob.NewEncoder(&buffer).Encode(filter); err != nil {
+		return nil, "", err
+	}
+	conn, result, err := debug.SendToServer(ingesterctl.INGESTERCTL_TAP, debug.ModuleOperate(operate), &buffer)
+	if err != nil {
+		return conn, "", err
+	}
+	message := ""
+	if result != nil && result.Len() > 0 {
+		gob.NewDecoder(result).Decode(&message)
+	}
+	return conn, message, nil
+}
+
+func stopTap(filter *Filter) {
+	conn, _, err := sendFilter(filter, TAP_CMD_STOP)
+	if conn != nil {
+		conn.Close()
+	}
+	if err != nil {
+		fmt.Printf("stop tap failed: %v\n", err)
+	}
+}
+
+func recvRecords(conn *net.UDPConn, filter *Filter) {
+	sigs := make(chan os.Signal, 10)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
+	defer conn.Close()
+	// the server stops the session at the latest after the duration, the extra time is for the end message
+	deadline := time.Now().Add(filter.Duration + 10*time.Second)
+	var message string
+	for time.Now().Before(deadline) {
+		select {
+		case sig := <-sigs:
+			stopTap(filter)
+			fmt.Printf("signal %v\n", sig)
+			return
+		default:
+			conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+			buffer, err := debug.RecvFromServer(conn)
+			if err != nil {
+				if !strings.Contains(err.Error(), "timeout") {
+					stopTap(filter)
+					fmt.Printf("ingesterctl.RecvFromServer: %v\n", err)
+					return
+				}
+				break
+			}
+			if err := gob.NewDecoder(buffer).Decode(&message); err != nil {
+				stopTap(filter)
+				fmt.Printf("decoder.Decode: %v\n", err)
+				return
+			}
+			if strings.HasPrefix(message, TAP_END_PREFIX) {
+				fmt.Fprintln(os.Stderr, message)
+				return
+			}
+			fmt.Println(message)
+		}
+	}
+}
+
+func RegisterTapCommand() *cobra.Command {
+	msgTypes := make([]string, 0, datatype.MESSAGE_TYPE_MAX)
+	for i := datatype.MessageType(0); i < datatype.MESSAGE_TYPE_MAX; i++ {
+		msgTypes = append(msgTypes, i.String())
+	}
+
+	filter := Filter{}
+	cmd := &cobra.Command{
+		Use:       "tap {msg_type}",
+		Short:     "print a sample of decoded records as JSON lines",
+		Example:   "tap l7_log --vtap-id 1 --protocol HTTP --ip 10.1.1.1 --rate 10 --duration 1m",
+		ValidArgs: msgTypes,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				fmt.Printf("please run with '{msg_type}', msg_type is one of: %s\n", strings.Join(msgTypes, ", "))
+				return
+			}
+			filter.MsgType = args[0]
+			if _, _, err := filter.Validate(); err != nil {
+				fmt.Println(err)
+				return
+			}
+			conn, message, err := sendFilter(&filter, TAP_CMD_START)
+			if err != nil {
+				if conn != nil {
+					conn.Close()
+				}
+				fmt.Printf("start tap failed: %v\n", err)
+				return
+			}
+			fmt.Fprintln(os.Stderr, message)
+			recvRecords(conn, &filter)
+		},
+	}
+	cmd.Flags().Uint16Var(&filter.VtapID, "vtap-id", 0, "only records of the vtap, 0 means any vtap")
+	cmd.Flags().StringVar(&filter.Protocol, "protocol", "", "only records of the protocol, such as TCP, HTTP, MySQL or the profile language")
+	cmd.Flags().StringVar(&filter.IP, "ip", "", "only records with the client or server ip")
+	cmd.Flags().IntVar(&filter.Rate, "rate", DefaultRate, fmt.Sprintf("max records per second, at most %d", MaxRate))
+	cmd.Flags().DurationVar(&filter.Duration, "duration", DefaultDuration, fmt.Sprintf("stop tapping after the duration, at most %s", MaxDuration))
+	return cmd
+}
@@ -0,0 +1,321 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// tap streams a sample of the records decoded by the running decoders to ingesterctl,
+// the decoders get their Tap by GetTap and call Send only if Enabled returns true.
+package tap
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	logging "github.com/op/go-logging"
+
+	"github.com/deepflowio/deepflow/server/ingester/ingesterctl"
+	"github.com/deepflowio/deepflow/server/libs/datatype"
+	"github.com/deepflowio/deepflow/server/libs/debug"
+	"github.com/deepflowio/deepflow/server/libs/utils"
+)
+
+var log = logging.MustGetLogger("ingesterctl.tap")
+
+const (
+	TAP_CMD_START = iota
+	TAP_CMD_STOP
+)
+
+const (
+	DefaultRate     = 10
+	MaxRate         = 100 // records per second
+	DefaultDuration = time.Minute
+	MaxDuration     = 10 * time.Minute
+
+	SESSION_QUEUE_SIZE = 1024
+	// the message beginning with it is the last message of a session
+	TAP_END_PREFIX = "tap stopped"
+)
+
+type Filter struct {
+	MsgType  string // datatype.MessageType.String()
+	VtapID   uint16 // 0 means any vtap
+	Protocol string // match any of Meta.Protocols case-insensitively, empty means any protocol
+	IP       string // match any of Meta.IPs, empty means any ip
+	Rate     int    // records per second
+	Duration time.Duration
+}
+
+// Validate sets the defaults and caps the rate and duration
+func (f *Filter) Validate() (datatype.MessageType, net.IP, error) {
+	msgType := messageType(f.MsgType)
+	if msgType == datatype.MESSAGE_TYPE_MAX {
+		return msgType, nil, fmt.Errorf("unknown message type '%s'", f.MsgType)
+	}
+	var ip net.IP
+	if f.IP != "" {
+		if ip = net.ParseIP(f.IP); ip == nil {
+			return msgType, nil, fmt.Errorf("invalid ip '%s'", f.IP)
+		}
+	}
+	if f.Rate <= 0 {
+		f.Rate = DefaultRate
+	} else if f.Rate > MaxRate {
+		f.Rate = MaxRate
+	}
+	if f.Duration <= 0 {
+		f.Duration = DefaultDuration
+	} else if f.Duration > MaxDuration {
+		f.Duration = MaxDuration
+	}
+	return msgType, ip, nil
+}
+
+func messageType(name string) datatype.MessageType {
+	for i := datatype.MessageType(0); i < datatype.MESSAGE_TYPE_MAX; i++ {
+		if i.String() == name {
+			return i
+		}
+	}
+	return datatype.MESSAGE_TYPE_MAX
+}
+
+// Meta is the information of a record used for filtering
+type Meta struct {
+	VtapID    uint16
+	Protocols []string
+	IPs       []net.IP
+}
+
+// IPv4 converts the ip4 column of records to net.IP
+func IPv4(ip uint32) net.IP {
+	return utils.IpFromUint32(ip)
+}
+
+type session struct {
+	filter Filter
+	ip     net.IP
+	conn   *net.UDPConn
+	remote *net.UDPAddr
+	ch     chan string
+
+	// rate limit, protected by Tap.Mutex
+	second int64
+	count  int
+
+	sent    int64
+	dropped int64
+	stop    chan struct{}
+}
+
+func (s *session) match(meta *Meta) bool {
+	if s.filter.VtapID != 0 && meta.VtapID != s.filter.VtapID {
+		return false
+	}
+	if s.filter.Protocol != "" {
+		matched := false
+		for _, p := range meta.Protocols {
+			if strings.EqualFold(p, s.filter.Protocol) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if s.ip != nil {
+		matched := false
+		for _, ip := range meta.IPs {
+			if s.ip.Equal(ip) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *session) allow(now int64) bool {
+	if now != s.second {
+		s.second = now
+		s.count = 0
+	}
+	if s.count >= s.filter.Rate {
+		return false
+	}
+	s.count++
+	return true
+}
+
+func sendMessage(conn *net.UDPConn, remote *net.UDPAddr, message string) {
+	if len(message) > debug.MAX_PAYLOAD_LEN-64 {
+		message = message[:debug.MAX_PAYLOAD_LEN-64-3] + "..."
+	}
+	buffer := bytes.Buffer{}
+	if err := gob.NewEncoder(&buffer).Encode(message); err != nil {
+		log.Error(err)
+		return
+	}
+	debug.SendToClient(conn, remote, 0, &buffer)
+}
+
+func (s *session) run(t *Tap) {
+	timer := time.NewTimer(s.filter.Duration)
+	defer timer.Stop()
+	reason := ""
+	for reason == "" {
+		select {
+		case message := <-s.ch:
+			sendMessage(s.conn, s.remote, message)
+			atomic.AddInt64(&s.sent, 1)
+		case <-timer.C:
+			reason = "timeout"
+		case <-s.stop:
+			reason = "stopped by client"
+		}
+	}
+	t.detach(s)
+	log.Infof("tap of %s %s, sent %d records", s.filter.MsgType, reason, atomic.LoadInt64(&s.sent))
+	sendMessage(s.conn, s.remote, fmt.Sprintf("%s for %s, sent %d records, dropped %d records",
+		TAP_END_PREFIX, reason, atomic.LoadInt64(&s.sent), atomic.LoadInt64(&s.dropped)))
+}
+
+// Tap is shared by the decoders of the same message type
+type Tap struct {
+	enabled int32
+	sync.Mutex
+	session *session
+}
+
+func (t *Tap) Enabled() bool {
+	return atomic.LoadInt32(&t.enabled) == 1
+}
+
+// Send marshals the record synchronously, since the records are usually released to pools after being written
+func (t *Tap) Send(meta *Meta, record interface{}) {
+	t.Lock()
+	s := t.session
+	if s == nil || !s.match(meta) || !s.allow(time.Now().Unix()) {
+		t.Unlock()
+		return
+	}
+	t.Unlock()
+
+	bytes, err := json.Marshal(record)
+	if err != nil {
+		bytes = []byte(fmt.Sprintf(`{"error": %q}`, err.Error()))
+	}
+	select {
+	case s.ch <- string(bytes):
+	default:
+		atomic.AddInt64(&s.dropped, 1)
+	}
+}
+
+func (t *Tap) attach(s *session) {
+	t.Lock()
+	old := t.session
+	t.session = s
+	atomic.StoreInt32(&t.enabled, 1)
+	t.Unlock()
+	if old != nil {
+		close(old.stop)
+	}
+	go s.run(t)
+}
+
+func (t *Tap) detach(s *session) {
+	t.Lock()
+	if t.session == s {
+		t.session = nil
+		atomic.StoreInt32(&t.enabled, 0)
+	}
+	t.Unlock()
+}
+
+func (t *Tap) stopSession() {
+	t.Lock()
+	s := t.session
+	t.session = nil
+	atomic.StoreInt32(&t.enabled, 0)
+	t.Unlock()
+	// only the attached session is stopped here or in attach, so stop is closed once
+	if s != nil {
+		close(s.stop)
+	}
+}
+
+type manager struct {
+	taps [datatype.MESSAGE_TYPE_MAX]*Tap
+}
+
+var (
+	tapManager     = &manager{}
+	registerServer sync.Once
+)
+
+// GetTap returns the Tap of the message type, the debug command handler is registered at the first call
+func GetTap(msgType datatype.MessageType) *Tap {
+	registerServer.Do(func() {
+		for i := range tapManager.taps {
+			tapManager.taps[i] = &Tap{}
+		}
+		debug.Register(ingesterctl.INGESTERCTL_TAP, tapManager)
+	})
+	return tapManager.taps[msgType]
+}
+
+func (m *manager) RecvCommand(conn *net.UDPConn, remote *net.UDPAddr, operate uint16, arg *bytes.Buffer) {
+	filter := Filter{}
+	if err := gob.NewDecoder(arg).Decode(&filter); err != nil {
+		log.Error(err)
+		debug.SendToClient(conn, remote, 1, nil)
+		return
+	}
+	msgType, ip, err := filter.Validate()
+	if err != nil {
+		log.Warning(err)
+		debug.SendToClient(conn, remote, 1, nil)
+		return
+	}
+	switch operate {
+	case TAP_CMD_START:
+		log.Infof("start tap of %s by %s, filter: %+v", filter.MsgType, remote, filter)
+		sendMessage(conn, remote, fmt.Sprintf("tap of %s started, rate: %d/s, duration: %s", filter.MsgType, filter.Rate, filter.Duration))
+		m.taps[msgType].attach(&session{
+			filter: filter,
+			ip:     ip,
+			conn:   conn,
+			remote: remote,
+			ch:     make(chan string, SESSION_QUEUE_SIZE),
+			stop:   make(chan struct{}),
+		})
+	case TAP_CMD_STOP:
+		m.taps[msgType].stopSession()
+		debug.SendToClient(conn, remote, 0, nil)
+	default:
+		log.Warningf("tap recv unknown command(%v).", operate)
+	}
+}
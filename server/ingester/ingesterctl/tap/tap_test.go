@@ -0,0 +1,111 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tap
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestFilterValidate(t *testing.T) {
+	f := Filter{MsgType: "l7_log", Rate: 1000, Duration: time.Hour}
+	if _, _, err := f.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if f.Rate != MaxRate || f.Duration != MaxDuration {
+		t.Errorf("rate %d duration %s should be capped", f.Rate, f.Duration)
+	}
+
+	f = Filter{MsgType: "l7_log"}
+	f.Validate()
+	if f.Rate != DefaultRate || f.Duration != DefaultDuration {
+		t.Errorf("rate %d duration %s should be default", f.Rate, f.Duration)
+	}
+
+	for _, f := range []Filter{{MsgType: "unknown"}, {MsgType: "l7_log", IP: "1.1.1"}} {
+		if _, _, err := f.Validate(); err == nil {
+			t.Errorf("filter %+v should be invalid", f)
+		}
+	}
+}
+
+func TestSessionMatch(t *testing.T) {
+	s := &session{
+		filter: Filter{VtapID: 1, Protocol: "http"},
+		ip:     net.ParseIP("10.1.1.1"),
+	}
+	meta := &Meta{VtapID: 1, Protocols: []string{"TCP", "HTTP"}, IPs: []net.IP{IPv4(0x0a010102), IPv4(0x0a010101)}}
+	if !s.match(meta) {
+		t.Error("meta should match")
+	}
+
+	for _, m := range []*Meta{
+		{VtapID: 2, Protocols: meta.Protocols, IPs: meta.IPs},
+		{VtapID: 1, Protocols: []string{"TCP", "DNS"}, IPs: meta.IPs},
+		{VtapID: 1, Protocols: meta.Protocols, IPs: []net.IP{net.ParseIP("10.1.1.2")}},
+		{VtapID: 1, IPs: meta.IPs},
+	} {
+		if s.match(m) {
+			t.Errorf("meta %+v should not match", m)
+		}
+	}
+
+	if !(&session{}).match(&Meta{}) {
+		t.Error("empty filter should match any meta")
+	}
+}
+
+func TestSessionRateLimit(t *testing.T) {
+	s := &session{filter: Filter{Rate: 3}}
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if s.allow(100) {
+			allowed++
+		}
+	}
+	if allowed != 3 {
+		t.Errorf("allowed %d records in a second, expected 3", allowed)
+	}
+	if !s.allow(101) {
+		t.Error("record in the next second should be allowed")
+	}
+}
+
+func TestTapSend(t *testing.T) {
+	tap := &Tap{}
+	s := &session{
+		filter: Filter{Rate: 2, Duration: time.Minute},
+		ch:     make(chan string, 1),
+		stop:   make(chan struct{}),
+	}
+	tap.session = s
+	for i := 0; i < 3; i++ {
+		tap.Send(&Meta{}, map[string]int{"i": i})
+	}
+	if message := <-s.ch; message != `{"i":0}` {
+		t.Errorf("unexpected message %s", message)
+	}
+	if s.dropped != 1 {
+		t.Errorf("dropped %d, expected 1", s.dropped)
+	}
+
+	tap.stopSession()
+	if tap.Enabled() || tap.session != nil {
+		t.Error("tap should be disabled after stopped")
+	}
+}
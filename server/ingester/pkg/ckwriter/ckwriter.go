@@ -19,6 +19,7 @@ package ckwriter
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"sync"
@@ -64,6 +65,9 @@ type CKWriter struct {
 	putCounter   int
 	writeCounter uint64
 
+	spill          *spillQueue // spill the failed blocks to disk, nil if disabled
+	replayInterval time.Duration
+
 	wg   sync.WaitGroup
 	exit bool
 }
@@ -164,6 +168,19 @@ func NewCKWriter(addrs []string, user, password, counterName, timeZone string, t
 		queue.OptionRelease(func(p interface{}) { p.(CKItem).Release() }),
 		common.QUEUE_STATS_MODULE_INGESTER)
 
+	var spill *spillQueue
+	var replayInterval time.Duration
+	if spillConfig != nil && spillConfig.IsTableEnabled(table.Database, table.LocalName) {
+		spillDir := filepath.Join(spillConfig.Dir, name)
+		spill, err = newSpillQueue(spillDir, int64(spillConfig.MaxSize)<<20, time.Duration(spillConfig.MaxAge)*time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("create spill queue in %s failed: %s", spillDir, err)
+		}
+		replayInterval = time.Duration(spillConfig.ReplayInterval) * time.Second
+		common.RegisterCountableForIngester("ckwriter_spill", spill, stats.OptionStatTags{"table": name, "name": counterName})
+		log.Infof("ckwriter %s spill enabled, dir: %s", name, spillDir)
+	}
+
 	return &CKWriter{
 		addrs:        addrs,
 		user:         user,
@@ -182,6 +199,9 @@ func NewCKWriter(addrs []string, user, password, counterName, timeZone string, t
 		connCount:  uint64(len(conns)),
 		dataQueues: dataQueues,
 		counters:   make([]Counter, queueCount),
+
+		spill:          spill,
+		replayInterval: replayInterval,
 	}, nil
 }
 
@@ -189,6 +209,9 @@ func (w *CKWriter) Run() {
 	for i := 0; i < w.queueCount; i++ {
 		go w.queueProcess(i)
 	}
+	if w.spill != nil {
+		go w.replayProcess()
+	}
 }
 
 type Counter struct {
@@ -196,6 +219,7 @@ type Counter struct {
 	WriteFailedCount  int64 `statsd:"write-failed-count"`
 	RetryCount        int64 `statsd:"retry-count"`
 	RetryFailedCount  int64 `statsd:"retry-failed-count"`
+	SpillCount        int64 `statsd:"spill-count"`
 	utils.Closable
 }
 
@@ -272,8 +296,8 @@ func (w *CKWriter) ResetConnection(connID int) error {
 func (w *CKWriter) Write(queueID int, items []CKItem) {
 	connID := int(atomic.AddUint64(&w.writeCounter, 1) % w.connCount)
 	if err := w.writeItems(queueID, connID, items); err != nil {
-		// Prevent frequent log writing
-		logEnabled := w.counters[queueID].WriteFailedCount == 0
+		// Prevent frequent log writing, the failed items are either spilled or dropped
+		logEnabled := w.counters[queueID].WriteFailedCount == 0 && w.counters[queueID].SpillCount == 0
 		if logEnabled {
 			log.Warningf("write table(%s.%s) failed, will retry write(%d) items: %s", w.table.Database, w.table.LocalName, len(items), err)
 		}
@@ -289,18 +313,28 @@ func (w *CKWriter) Write(queueID int, items []CKItem) {
 		w.counters[queueID].RetryCount++
 		// 写失败重连后重试一次, 规避偶尔写失败问题
 		err = w.writeItems(queueID, connID, items)
-		if logEnabled {
-			if err != nil {
-				w.counters[queueID].RetryFailedCount++
-				log.Warningf("retry write table(%s.%s) failed, drop(%d) items: %s", w.table.Database, w.table.LocalName, len(items), err)
-			} else {
+		if err == nil {
+			w.counters[queueID].WriteSuccessCount += int64(len(items))
+			if logEnabled {
 				log.Infof("retry write table(%s.%s) success, write(%d) items", w.table.Database, w.table.LocalName, len(items))
 			}
-		}
-		if err != nil {
-			w.counters[queueID].WriteFailedCount += int64(len(items))
+		} else if w.spill != nil {
+			w.counters[queueID].RetryFailedCount++
+			if logEnabled {
+				log.Warningf("retry write table(%s.%s) failed, spill(%d) items: %s", w.table.Database, w.table.LocalName, len(items), err)
+			}
+			if w.spillItems(items, logEnabled) == nil {
+				// the items have been saved to disk, and will be written by replayProcess
+				w.counters[queueID].SpillCount += int64(len(items))
+			} else {
+				w.counters[queueID].WriteFailedCount += int64(len(items))
+			}
 		} else {
-			w.counters[queueID].WriteSuccessCount += int64(len(items))
+			w.counters[queueID].RetryFailedCount++
+			if logEnabled {
+				log.Warningf("retry write table(%s.%s) failed, drop(%d) items: %s", w.table.Database, w.table.LocalName, len(items), err)
+			}
+			w.counters[queueID].WriteFailedCount += int64(len(items))
 		}
 	} else {
 		w.counters[queueID].WriteSuccessCount += int64(len(items))
@@ -311,6 +345,69 @@ func (w *CKWriter) Write(queueID int, items []CKItem) {
 	}
 }
 
+func (w *CKWriter) spillItems(items []CKItem, logEnabled bool) error {
+	rows, err := itemsToRows(items)
+	if err == nil {
+		err = w.spill.Put(rows)
+	}
+	if logEnabled {
+		if err != nil {
+			log.Warningf("spill table(%s.%s) %d items failed, drop them: %s", w.table.Database, w.table.LocalName, len(items), err)
+		} else {
+			log.Infof("spill table(%s.%s) %d items to disk, will replay later", w.table.Database, w.table.LocalName, len(items))
+		}
+	}
+	return err
+}
+
+func (w *CKWriter) replayProcess() {
+	defer w.wg.Done()
+	w.wg.Add(1)
+
+	ticker := time.NewTicker(w.replayInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if w.exit {
+			return
+		}
+		if w.spill.Len() == 0 {
+			continue
+		}
+		connID := int(atomic.AddUint64(&w.writeCounter, 1) % w.connCount)
+		replayed, err := w.spill.Replay(func(rows [][]interface{}) error {
+			return w.writeRows(connID, rows)
+		})
+		if replayed > 0 {
+			log.Infof("replay table(%s.%s) %d spilled rows", w.table.Database, w.table.LocalName, replayed)
+		}
+		if err != nil {
+			log.Debugf("replay table(%s.%s) failed: %s", w.table.Database, w.table.LocalName, err)
+		}
+	}
+}
+
+// writeRows writes the rows decoded from the spill files, uses a new batch to avoid sharing with queueProcess
+func (w *CKWriter) writeRows(connID int, rows [][]interface{}) error {
+	ck := w.conns[connID]
+	if IsNil(ck) {
+		return fmt.Errorf("clickhouse connection %d is not ready", connID)
+	}
+	if err := ck.Ping(context.Background()); err != nil {
+		return err
+	}
+	batch, err := ck.PrepareBatch(context.Background(), w.prepare)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := batch.Append(row...); err != nil {
+			batch.Abort()
+			return &spillDataError{err}
+		}
+	}
+	return batch.Send()
+}
+
 func IsNil(i interface{}) bool {
 	if i == nil {
 		return true
@@ -378,6 +475,9 @@ func (w *CKWriter) Close() {
 	for _, c := range w.counters {
 		c.Close()
 	}
+	if w.spill != nil {
+		w.spill.Close()
+	}
 
 	for _, q := range w.dataQueues {
 		q.Close()
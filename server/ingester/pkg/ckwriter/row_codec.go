@@ -0,0 +1,316 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ckwriter
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+
+	"github.com/deepflowio/deepflow/server/libs/ckdb"
+	"github.com/deepflowio/deepflow/server/libs/codec"
+)
+
+// value tags of the spilled rows, the values are decoded to the basic go types,
+// which are the same types accepted by clickhouse-go when appending to a batch.
+const (
+	tagNil byte = iota
+	tagBool
+	tagInt8
+	tagInt16
+	tagInt32
+	tagInt64
+	tagInt
+	tagUint8
+	tagUint16
+	tagUint32
+	tagUint64
+	tagUint
+	tagFloat32
+	tagFloat64
+	tagString
+	tagIP
+	tagTime
+
+	tagSlice = 0x40
+	tagPtr   = 0x80
+)
+
+var tagTypes = [...]reflect.Type{
+	tagBool:    reflect.TypeOf(false),
+	tagInt8:    reflect.TypeOf(int8(0)),
+	tagInt16:   reflect.TypeOf(int16(0)),
+	tagInt32:   reflect.TypeOf(int32(0)),
+	tagInt64:   reflect.TypeOf(int64(0)),
+	tagInt:     reflect.TypeOf(int(0)),
+	tagUint8:   reflect.TypeOf(uint8(0)),
+	tagUint16:  reflect.TypeOf(uint16(0)),
+	tagUint32:  reflect.TypeOf(uint32(0)),
+	tagUint64:  reflect.TypeOf(uint64(0)),
+	tagUint:    reflect.TypeOf(uint(0)),
+	tagFloat32: reflect.TypeOf(float32(0)),
+	tagFloat64: reflect.TypeOf(float64(0)),
+	tagString:  reflect.TypeOf(""),
+	tagIP:      reflect.TypeOf(net.IP{}),
+	tagTime:    reflect.TypeOf(time.Time{}),
+}
+
+var (
+	ipType   = reflect.TypeOf(net.IP{})
+	timeType = reflect.TypeOf(time.Time{})
+)
+
+func scalarTag(t reflect.Type) (byte, error) {
+	switch t {
+	case ipType:
+		return tagIP, nil
+	case timeType:
+		return tagTime, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return tagBool, nil
+	case reflect.Int8:
+		return tagInt8, nil
+	case reflect.Int16:
+		return tagInt16, nil
+	case reflect.Int32:
+		return tagInt32, nil
+	case reflect.Int64:
+		return tagInt64, nil
+	case reflect.Int:
+		return tagInt, nil
+	case reflect.Uint8:
+		return tagUint8, nil
+	case reflect.Uint16:
+		return tagUint16, nil
+	case reflect.Uint32:
+		return tagUint32, nil
+	case reflect.Uint64:
+		return tagUint64, nil
+	case reflect.Uint:
+		return tagUint, nil
+	case reflect.Float32:
+		return tagFloat32, nil
+	case reflect.Float64:
+		return tagFloat64, nil
+	case reflect.String:
+		return tagString, nil
+	}
+	return 0, fmt.Errorf("unsupported type %s", t)
+}
+
+func writeScalar(e *codec.SimpleEncoder, tag byte, v reflect.Value) {
+	switch tag {
+	case tagBool:
+		e.WriteBool(v.Bool())
+	case tagInt8, tagInt16, tagInt32, tagInt64, tagInt:
+		e.WriteZigzagU64(uint64(v.Int()))
+	case tagUint8, tagUint16, tagUint32, tagUint64, tagUint:
+		e.WriteVarintU64(v.Uint())
+	case tagFloat32, tagFloat64:
+		e.WriteU64(math.Float64bits(v.Float()))
+	case tagString:
+		e.WriteBytesWithVarintLen([]byte(v.String()))
+	case tagIP:
+		e.WriteBytesWithVarintLen(v.Bytes())
+	case tagTime:
+		e.WriteZigzagU64(uint64(v.Interface().(time.Time).UnixNano()))
+	}
+}
+
+func readScalar(d *codec.SimpleDecoder, tag byte) reflect.Value {
+	v := reflect.New(tagTypes[tag]).Elem()
+	switch tag {
+	case tagBool:
+		v.SetBool(d.ReadBool())
+	case tagInt8, tagInt16, tagInt32, tagInt64, tagInt:
+		v.SetInt(int64(d.ReadZigzagU64()))
+	case tagUint8, tagUint16, tagUint32, tagUint64, tagUint:
+		v.SetUint(d.ReadVarintU64())
+	case tagFloat32, tagFloat64:
+		v.SetFloat(math.Float64frombits(d.ReadU64()))
+	case tagString:
+		v.SetString(string(d.ReadBytesWithVarintLen()))
+	case tagIP:
+		ip := make(net.IP, 0)
+		v.SetBytes(append(ip, d.ReadBytesWithVarintLen()...))
+	case tagTime:
+		v.Set(reflect.ValueOf(time.Unix(0, int64(d.ReadZigzagU64()))))
+	}
+	return v
+}
+
+// encodeValue supports nil, scalars, slices of scalars and pointers to scalars (nullable columns)
+func encodeValue(e *codec.SimpleEncoder, value interface{}) error {
+	if value == nil {
+		e.WriteU8(tagNil)
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	t := v.Type()
+	switch {
+	case t.Kind() == reflect.Ptr:
+		tag, err := scalarTag(t.Elem())
+		if err != nil {
+			return err
+		}
+		e.WriteU8(tagPtr | tag)
+		if v.IsNil() {
+			e.WriteBool(false)
+			return nil
+		}
+		e.WriteBool(true)
+		writeScalar(e, tag, v.Elem())
+	case t.Kind() == reflect.Slice && t != ipType:
+		tag, err := scalarTag(t.Elem())
+		if err != nil {
+			return err
+		}
+		e.WriteU8(tagSlice | tag)
+		e.WriteVarintU32(uint32(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			writeScalar(e, tag, v.Index(i))
+		}
+	default:
+		tag, err := scalarTag(t)
+		if err != nil {
+			return err
+		}
+		e.WriteU8(tag)
+		writeScalar(e, tag, v)
+	}
+	return nil
+}
+
+func decodeValue(d *codec.SimpleDecoder) (interface{}, error) {
+	tag := d.ReadU8()
+	if tag == tagNil {
+		return nil, nil
+	}
+	scalar := tag &^ (tagSlice | tagPtr)
+	if scalar == tagNil || int(scalar) >= len(tagTypes) {
+		return nil, fmt.Errorf("invalid value tag %d", tag)
+	}
+	switch {
+	case tag&tagPtr != 0:
+		if !d.ReadBool() {
+			return reflect.Zero(reflect.PtrTo(tagTypes[scalar])).Interface(), nil
+		}
+		p := reflect.New(tagTypes[scalar])
+		p.Elem().Set(readScalar(d, scalar))
+		return p.Interface(), nil
+	case tag&tagSlice != 0:
+		n := int(d.ReadVarintU32())
+		// each element takes at least 1 byte
+		if d.Failed() || n > remainBytes(d) {
+			return nil, fmt.Errorf("decode slice length failed")
+		}
+		s := reflect.MakeSlice(reflect.SliceOf(tagTypes[scalar]), 0, n)
+		for i := 0; i < n && !d.Failed(); i++ {
+			s = reflect.Append(s, readScalar(d, scalar))
+		}
+		return s.Interface(), nil
+	}
+	return readScalar(d, scalar).Interface(), nil
+}
+
+func remainBytes(d *codec.SimpleDecoder) int {
+	return len(d.Bytes()) - d.Offset()
+}
+
+// encodeRows encodes rows of a block as: rowCount, then columnCount and values of each row
+func encodeRows(e *codec.SimpleEncoder, rows [][]interface{}) error {
+	e.WriteVarintU32(uint32(len(rows)))
+	for _, row := range rows {
+		e.WriteVarintU32(uint32(len(row)))
+		for _, v := range row {
+			if err := encodeValue(e, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func decodeRows(d *codec.SimpleDecoder) ([][]interface{}, error) {
+	rowCount := int(d.ReadVarintU32())
+	if d.Failed() || rowCount > remainBytes(d) {
+		return nil, fmt.Errorf("decode row count failed")
+	}
+	rows := make([][]interface{}, 0, rowCount)
+	for i := 0; i < rowCount; i++ {
+		columnCount := int(d.ReadVarintU32())
+		if d.Failed() || columnCount > remainBytes(d) {
+			return nil, fmt.Errorf("decode column count of row %d failed", i)
+		}
+		row := make([]interface{}, 0, columnCount)
+		for j := 0; j < columnCount; j++ {
+			v, err := decodeValue(d)
+			if err != nil {
+				return nil, err
+			}
+			row = append(row, v)
+		}
+		if d.Failed() {
+			return nil, fmt.Errorf("decode row %d failed", i)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// rowRecorder implements driver.Batch, records the rows appended by CKItem.WriteBlock
+type rowRecorder struct {
+	rows [][]interface{}
+}
+
+func (r *rowRecorder) Abort() error { return nil }
+
+func (r *rowRecorder) Append(v ...interface{}) error {
+	row := make([]interface{}, len(v))
+	copy(row, v)
+	r.rows = append(r.rows, row)
+	return nil
+}
+
+func (r *rowRecorder) AppendStruct(v interface{}) error {
+	return fmt.Errorf("rowRecorder does not support AppendStruct")
+}
+
+func (r *rowRecorder) Column(int) driver.BatchColumn { return nil }
+
+func (r *rowRecorder) Send() error { return nil }
+
+func (r *rowRecorder) Reset() { r.rows = r.rows[:0] }
+
+// itemsToRows gets the rows which would be written to clickhouse by the items
+func itemsToRows(items []CKItem) ([][]interface{}, error) {
+	recorder := &rowRecorder{rows: make([][]interface{}, 0, len(items))}
+	block := ckdb.NewBlock(recorder)
+	for _, item := range items {
+		item.WriteBlock(block)
+		if err := block.WriteAll(); err != nil {
+			return nil, err
+		}
+	}
+	return recorder.rows, nil
+}
@@ -0,0 +1,343 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ckwriter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deepflowio/deepflow/server/ingester/config"
+	"github.com/deepflowio/deepflow/server/libs/codec"
+	"github.com/deepflowio/deepflow/server/libs/utils"
+)
+
+const (
+	SPILL_FILE_SUFFIX  = ".spill"
+	SPILL_MAGIC        = 0x44465350 // 'DFSP'
+	SPILL_VERSION      = 1
+	SPILL_HEADER_LEN   = 4 + 4 + 4 + 8 // magic, version, row count, create time
+	SPILL_REPLAY_BATCH = 8             // max segments replayed in one round
+)
+
+var spillConfig *config.CKWriterSpill
+
+// SetSpillConfig should be called before creating CKWriters, then the failed blocks of the enabled tables
+// will be spilled to disk instead of being dropped.
+func SetSpillConfig(cfg *config.CKWriterSpill) {
+	spillConfig = cfg
+}
+
+type SpillCounter struct {
+	SpillCount        int64 `statsd:"spill-count"`
+	SpillFailedCount  int64 `statsd:"spill-failed-count"`
+	ReplayCount       int64 `statsd:"replay-count"`
+	ReplayFailedCount int64 `statsd:"replay-failed-count"`
+	DiscardCount      int64 `statsd:"discard-count"`
+	PendingRows       int64 `statsd:"pending-rows"`
+	PendingBytes      int64 `statsd:"pending-bytes"`
+}
+
+// spillDataError means the spilled rows are not accepted by ClickHouse, retrying will never succeed
+type spillDataError struct {
+	err error
+}
+
+func (e *spillDataError) Error() string {
+	return fmt.Sprintf("invalid spilled rows: %s", e.err)
+}
+
+type spillSegment struct {
+	seq        uint64
+	path       string
+	size       int64
+	rows       int
+	createTime time.Time
+}
+
+// spillQueue is a disk-backed FIFO queue of rows, each failed block is saved as a segment file
+type spillQueue struct {
+	dir     string
+	maxSize int64
+	maxAge  time.Duration
+
+	mutex    sync.Mutex
+	segments []*spillSegment
+	size     int64
+	rows     int64
+	nextSeq  uint64
+	counter  SpillCounter
+
+	utils.Closable
+}
+
+func newSpillQueue(dir string, maxSize int64, maxAge time.Duration) (*spillQueue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	q := &spillQueue{
+		dir:     dir,
+		maxSize: maxSize,
+		maxAge:  maxAge,
+	}
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *spillQueue) segmentPath(seq uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", seq, SPILL_FILE_SUFFIX))
+}
+
+// load restores the segments left by the last running
+func (q *spillQueue) load() error {
+	files, err := ioutil.ReadDir(q.dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, SPILL_FILE_SUFFIX) {
+			// remove the temp file which has not been renamed
+			if strings.HasSuffix(name, SPILL_FILE_SUFFIX+".tmp") {
+				os.Remove(filepath.Join(q.dir, name))
+			}
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, SPILL_FILE_SUFFIX), 10, 64)
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(q.dir, name)
+		rows, createTime, err := readSpillHeader(path)
+		if err != nil {
+			log.Warningf("remove invalid spill file %s: %s", path, err)
+			os.Remove(path)
+			continue
+		}
+		q.segments = append(q.segments, &spillSegment{seq: seq, path: path, size: f.Size(), rows: rows, createTime: createTime})
+		q.size += f.Size()
+		q.rows += int64(rows)
+		if seq >= q.nextSeq {
+			q.nextSeq = seq + 1
+		}
+	}
+	sort.Slice(q.segments, func(i, j int) bool { return q.segments[i].seq < q.segments[j].seq })
+	if len(q.segments) > 0 {
+		log.Infof("load %d spill files (%d rows, %d bytes) from %s", len(q.segments), q.rows, q.size, q.dir)
+	}
+	return nil
+}
+
+func readSpillHeader(path string) (int, time.Time, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	defer f.Close()
+	header := make([]byte, SPILL_HEADER_LEN)
+	if _, err := f.Read(header); err != nil {
+		return 0, time.Time{}, err
+	}
+	return parseSpillHeader(header)
+}
+
+func parseSpillHeader(header []byte) (int, time.Time, error) {
+	if len(header) < SPILL_HEADER_LEN {
+		return 0, time.Time{}, fmt.Errorf("spill header too short")
+	}
+	if binary.LittleEndian.Uint32(header) != SPILL_MAGIC {
+		return 0, time.Time{}, fmt.Errorf("invalid spill magic")
+	}
+	if version := binary.LittleEndian.Uint32(header[4:]); version != SPILL_VERSION {
+		return 0, time.Time{}, fmt.Errorf("unsupported spill version %d", version)
+	}
+	rows := int(binary.LittleEndian.Uint32(header[8:]))
+	createTime := time.Unix(0, int64(binary.LittleEndian.Uint64(header[12:])))
+	return rows, createTime, nil
+}
+
+// Put saves rows as a new segment, the oldest segments are discarded if the size exceeds the limit
+func (q *spillQueue) Put(rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	encoder := codec.AcquireSimpleEncoder()
+	defer codec.ReleaseSimpleEncoder(encoder)
+	now := time.Now()
+	encoder.WriteU32(SPILL_MAGIC)
+	encoder.WriteU32(SPILL_VERSION)
+	encoder.WriteU32(uint32(len(rows)))
+	encoder.WriteU64(uint64(now.UnixNano()))
+	if err := encodeRows(encoder, rows); err != nil {
+		q.mutex.Lock()
+		q.counter.SpillFailedCount += int64(len(rows))
+		q.mutex.Unlock()
+		return err
+	}
+	data := encoder.Bytes()
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if int64(len(data)) > q.maxSize {
+		q.counter.SpillFailedCount += int64(len(rows))
+		return fmt.Errorf("size of rows(%d) exceeds the spill max size(%d)", len(data), q.maxSize)
+	}
+	for len(q.segments) > 0 && q.size+int64(len(data)) > q.maxSize {
+		q.discardOldest()
+	}
+
+	seq := q.nextSeq
+	path := q.segmentPath(seq)
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		os.Remove(tmpPath)
+		q.counter.SpillFailedCount += int64(len(rows))
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		q.counter.SpillFailedCount += int64(len(rows))
+		return err
+	}
+	q.nextSeq++
+	q.segments = append(q.segments, &spillSegment{seq: seq, path: path, size: int64(len(data)), rows: len(rows), createTime: now})
+	q.size += int64(len(data))
+	q.rows += int64(len(rows))
+	q.counter.SpillCount += int64(len(rows))
+	return nil
+}
+
+// must be called with lock held
+func (q *spillQueue) discardOldest() {
+	s := q.segments[0]
+	q.removeHead()
+	q.counter.DiscardCount += int64(s.rows)
+	log.Warningf("discard spill file %s (%d rows)", s.path, s.rows)
+}
+
+// must be called with lock held
+func (q *spillQueue) removeHead() {
+	s := q.segments[0]
+	os.Remove(s.path)
+	q.segments[0] = nil
+	q.segments = q.segments[1:]
+	q.size -= s.size
+	q.rows -= int64(s.rows)
+}
+
+func (q *spillQueue) expire() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for len(q.segments) > 0 && time.Since(q.segments[0].createTime) > q.maxAge {
+		q.discardOldest()
+	}
+}
+
+func (q *spillQueue) head() *spillSegment {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.segments) == 0 {
+		return nil
+	}
+	return q.segments[0]
+}
+
+// Replay writes the segments in order by 'write', it stops at the first failure and the
+// failed segment will be replayed again next time.
+func (q *spillQueue) Replay(write func(rows [][]interface{}) error) (int, error) {
+	q.expire()
+
+	replayed := 0
+	for i := 0; i < SPILL_REPLAY_BATCH; i++ {
+		s := q.head()
+		if s == nil {
+			break
+		}
+		rows, err := q.readSegment(s)
+		if err != nil {
+			// the file is broken and can never be replayed
+			log.Warningf("read spill file %s failed, discard it: %s", s.path, err)
+			q.mutex.Lock()
+			if len(q.segments) > 0 && q.segments[0] == s {
+				q.discardOldest()
+			}
+			q.mutex.Unlock()
+			continue
+		}
+		if err := write(rows); err != nil {
+			q.mutex.Lock()
+			q.counter.ReplayFailedCount += int64(len(rows))
+			if _, ok := err.(*spillDataError); ok && len(q.segments) > 0 && q.segments[0] == s {
+				// the rows can never be written, e.g. the table schema has changed
+				log.Warningf("replay spill file %s failed, discard it: %s", s.path, err)
+				q.discardOldest()
+				q.mutex.Unlock()
+				continue
+			}
+			q.mutex.Unlock()
+			return replayed, err
+		}
+
+		q.mutex.Lock()
+		// the segment may have been discarded by Put while writing
+		if len(q.segments) > 0 && q.segments[0] == s {
+			q.removeHead()
+		}
+		q.counter.ReplayCount += int64(len(rows))
+		q.mutex.Unlock()
+		replayed += len(rows)
+	}
+	return replayed, nil
+}
+
+func (q *spillQueue) readSegment(s *spillSegment) ([][]interface{}, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := parseSpillHeader(data); err != nil {
+		return nil, err
+	}
+	decoder := &codec.SimpleDecoder{}
+	decoder.Init(data[SPILL_HEADER_LEN:])
+	return decodeRows(decoder)
+}
+
+func (q *spillQueue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.segments)
+}
+
+func (q *spillQueue) GetCounter() interface{} {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	counter := q.counter
+	q.counter = SpillCounter{}
+	counter.PendingRows = q.rows
+	counter.PendingBytes = q.size
+	return &counter
+}
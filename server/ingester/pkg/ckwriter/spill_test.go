@@ -0,0 +1,201 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ckwriter
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/deepflowio/deepflow/server/libs/ckdb"
+	"github.com/deepflowio/deepflow/server/libs/codec"
+)
+
+type testItem struct {
+	id       uint64
+	ip       uint32
+	name     string
+	nullable *int64
+	tags     []string
+	values   []float64
+}
+
+func (i *testItem) WriteBlock(block *ckdb.Block) {
+	block.Write(i.id)
+	block.WriteIPv4(i.ip)
+	block.WriteBool(i.id%2 == 0)
+	block.Write(i.name, i.nullable, i.tags, i.values)
+}
+
+func (i *testItem) Release() {}
+
+func newTestItems(n int) []CKItem {
+	items := make([]CKItem, 0, n)
+	for i := 0; i < n; i++ {
+		v := int64(-i)
+		item := &testItem{id: uint64(i), ip: uint32(i) + 0x0a000000, name: "item", tags: []string{"a", "b"}, values: []float64{1.5}}
+		if i%2 == 0 {
+			item.nullable = &v
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func TestRowCodec(t *testing.T) {
+	v := int64(-3)
+	var nilPtr *uint32
+	row := []interface{}{
+		nil, true, int8(-1), int16(-2), int32(-3), int64(-4), uint8(1), uint16(2), uint32(3), uint64(1 << 60),
+		float32(1.5), float64(-2.5), "abc", net.ParseIP("2001::1"), time.Unix(1700000000, 5),
+		&v, nilPtr, []string{"x", ""}, []uint16{1, 2}, []float64{}, []byte{1, 2, 3},
+	}
+	encoder := &codec.SimpleEncoder{}
+	if err := encodeRows(encoder, [][]interface{}{row, row}); err != nil {
+		t.Fatalf("encode rows failed: %v", err)
+	}
+	decoder := &codec.SimpleDecoder{}
+	decoder.Init(encoder.Bytes())
+	rows, err := decodeRows(decoder)
+	if err != nil {
+		t.Fatalf("decode rows failed: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("decode %d rows, expected 2", len(rows))
+	}
+	for i := range row {
+		if !reflect.DeepEqual(rows[1][i], row[i]) {
+			t.Errorf("column %d: decoded %#v(%T), expected %#v(%T)", i, rows[1][i], rows[1][i], row[i], row[i])
+		}
+	}
+
+	if err := encodeRows(encoder, [][]interface{}{{struct{}{}}}); err == nil {
+		t.Errorf("encode unsupported type should fail")
+	}
+	decoder.Init(encoder.Bytes()[:10])
+	if _, err := decodeRows(decoder); err == nil {
+		t.Errorf("decode truncated data should fail")
+	}
+}
+
+func TestItemsToRows(t *testing.T) {
+	rows, err := itemsToRows(newTestItems(3))
+	if err != nil {
+		t.Fatalf("items to rows failed: %v", err)
+	}
+	if len(rows) != 3 || len(rows[0]) != 7 {
+		t.Fatalf("unexpected rows: %v", rows)
+	}
+	if ip, ok := rows[1][1].(net.IP); !ok || ip.String() != "10.0.0.1" {
+		t.Errorf("unexpected ip column: %v", rows[1][1])
+	}
+}
+
+func TestSpillQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ckwriter_spill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	q, err := newSpillQueue(dir, 1<<20, time.Hour)
+	if err != nil {
+		t.Fatalf("new spill queue failed: %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		rows, _ := itemsToRows(newTestItems(i))
+		if err := q.Put(rows); err != nil {
+			t.Fatalf("put failed: %v", err)
+		}
+	}
+
+	// reload from disk, e.g. after restart
+	q, err = newSpillQueue(dir, 1<<20, time.Hour)
+	if err != nil {
+		t.Fatalf("reload spill queue failed: %v", err)
+	}
+	if q.Len() != 3 || q.rows != 6 {
+		t.Fatalf("reload got %d segments %d rows, expected 3 segments 6 rows", q.Len(), q.rows)
+	}
+
+	// replay stops at the first failure, and the segments are replayed in order
+	replayed := []int{}
+	n, err := q.Replay(func(rows [][]interface{}) error {
+		if len(rows) == 3 {
+			return errors.New("clickhouse unavailable")
+		}
+		replayed = append(replayed, len(rows))
+		return nil
+	})
+	if err == nil || n != 3 || !reflect.DeepEqual(replayed, []int{1, 2}) {
+		t.Fatalf("replay got %d rows %v, err %v", n, replayed, err)
+	}
+	if q.Len() != 1 {
+		t.Fatalf("%d segments left, expected 1", q.Len())
+	}
+
+	// the rows which are never accepted are discarded
+	n, err = q.Replay(func(rows [][]interface{}) error {
+		return &spillDataError{errors.New("schema changed")}
+	})
+	if err != nil || n != 0 || q.Len() != 0 {
+		t.Fatalf("replay invalid rows got %d rows, %d segments left, err %v", n, q.Len(), err)
+	}
+
+	counter := q.GetCounter().(*SpillCounter)
+	if counter.ReplayCount != 3 || counter.ReplayFailedCount != 6 || counter.DiscardCount != 3 || counter.PendingRows != 0 {
+		t.Errorf("unexpected counter %+v", *counter)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) != 0 {
+		t.Errorf("spill files are not removed: %v", files)
+	}
+}
+
+func TestSpillQueueLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ckwriter_spill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rows, _ := itemsToRows(newTestItems(10))
+	q, _ := newSpillQueue(dir, 1<<20, time.Hour)
+	q.Put(rows)
+	segmentSize := q.size
+
+	q, _ = newSpillQueue(dir, segmentSize*2, time.Hour)
+	for i := 0; i < 3; i++ {
+		if err := q.Put(rows); err != nil {
+			t.Fatalf("put failed: %v", err)
+		}
+	}
+	if q.Len() != 2 || q.counter.DiscardCount != 20 {
+		t.Errorf("size limit: got %d segments, discard %d rows", q.Len(), q.counter.DiscardCount)
+	}
+
+	q.maxAge = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	q.expire()
+	if q.Len() != 0 || q.counter.DiscardCount != 40 {
+		t.Errorf("age limit: got %d segments, discard %d rows", q.Len(), q.counter.DiscardCount)
+	}
+}
@@ -19,6 +19,7 @@ package decoder
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/url"
 	"strconv"
 	"strings"
@@ -26,6 +27,7 @@ import (
 	"time"
 
 	"github.com/deepflowio/deepflow/server/ingester/common"
+	"github.com/deepflowio/deepflow/server/ingester/ingesterctl/tap"
 	profile_common "github.com/deepflowio/deepflow/server/ingester/profile/common"
 	"github.com/deepflowio/deepflow/server/ingester/profile/dbwriter"
 	"github.com/deepflowio/deepflow/server/libs/codec"
@@ -41,6 +43,7 @@ import (
 	"github.com/pyroscope-io/pyroscope/pkg/convert/jfr"
 	"github.com/pyroscope-io/pyroscope/pkg/convert/pprof"
 	pprofile "github.com/pyroscope-io/pyroscope/pkg/convert/profile"
+	"github.com/pyroscope-io/pyroscope/pkg/convert/speedscope"
 	"github.com/pyroscope-io/pyroscope/pkg/ingestion"
 	"github.com/pyroscope-io/pyroscope/pkg/storage/metadata"
 	"github.com/pyroscope-io/pyroscope/pkg/storage/segment"
@@ -60,6 +63,12 @@ type Counter struct {
 	GolangProfileCount int64 `statsd:"golang-profile-count"`
 	EBPFProfileCount   int64 `statsd:"EBPF-profile-count"`
 
+	SpeedscopeProfileCount int64 `statsd:"speedscope-profile-count"`
+	TreeProfileCount       int64 `statsd:"tree-profile-count"`
+	TrieProfileCount       int64 `statsd:"trie-profile-count"`
+	LinesProfileCount      int64 `statsd:"lines-profile-count"`
+	ParseFailedCount       int64 `statsd:"parse-failed-count"`
+
 	UncompressSize int64 `statsd:"uncompress-size"`
 	CompressedSize int64 `statsd:"compressed-size"`
 
@@ -91,6 +100,7 @@ type Decoder struct {
 	inQueue         queue.QueueReader
 	profileWriter   *dbwriter.ProfileWriter
 	compressionAlgo string
+	tap             *tap.Tap
 
 	counter *Counter
 	utils.Closable
@@ -107,6 +117,7 @@ func NewDecoder(index int, msgType datatype.MessageType, compressionAlgo string,
 		inQueue:         inQueue,
 		profileWriter:   profileWriter,
 		compressionAlgo: compressionAlgo,
+		tap:             tap.GetTap(msgType),
 		counter:         &Counter{},
 	}
 }
@@ -149,6 +160,19 @@ func (d *Decoder) Run() {
 	}
 }
 
+func (d *Decoder) write(item interface{}) {
+	if p, ok := item.(*dbwriter.InProcessProfile); ok && d.tap.Enabled() {
+		meta := &tap.Meta{VtapID: p.VtapID, Protocols: []string{p.ProfileLanguageType}}
+		if p.IsIPv4 {
+			meta.IPs = []net.IP{tap.IPv4(p.IP4)}
+		} else {
+			meta.IPs = []net.IP{p.IP6}
+		}
+		d.tap.Send(meta, p)
+	}
+	d.profileWriter.Write(item)
+}
+
 func (d *Decoder) handleProfileData(vtapID uint16, decoder *codec.SimpleDecoder) {
 	for !decoder.IsEnd() {
 		profile := &pb.Profile{}
@@ -161,7 +185,7 @@ func (d *Decoder) handleProfileData(vtapID uint16, decoder *codec.SimpleDecoder)
 		parser := &Parser{
 			vtapID:          vtapID,
 			inTimestamp:     time.Now(),
-			callBack:        d.profileWriter.Write,
+			callBack:        d.write,
 			platformData:    d.platformData,
 			IP:              make([]byte, len(profile.Ip)),
 			podID:           profile.PodId,
@@ -171,74 +195,116 @@ func (d *Decoder) handleProfileData(vtapID uint16, decoder *codec.SimpleDecoder)
 		}
 		copy(parser.IP, profile.Ip[:len(profile.Ip)])
 
-		switch profile.Format {
-		case "jfr":
-			atomic.AddInt64(&d.counter.JavaProfileCount, 1)
-			metadata := d.buildMetaData(profile)
-			parser.profileName = metadata.Key.AppName()
-			decompressJfr, err := profile_common.GzipDecompress(profile.Data)
-			if err != nil {
-				log.Errorf("decompress java profile data failed, offset=%d, len=%d, err=%s", decoder.Offset(), len(decoder.Bytes()), err)
-				return
-			}
-			err = d.sendProfileData(&jfr.RawProfile{
-				FormDataContentType: string(profile.ContentType),
-				RawData:             decompressJfr,
-			}, profile.Format, parser, metadata)
+		if err := d.handleProfile(parser, profile); err != nil {
+			log.Errorf("%s, offset=%d, len=%d", err, decoder.Offset(), len(decoder.Bytes()))
+			return
+		}
+	}
+}
 
-			if err != nil {
-				log.Errorf("decode java profile data failed, offset=%d, len=%d, err=%s", decoder.Offset(), len(decoder.Bytes()), err)
-				return
-			}
-		case "pprof":
+// handleProfile parses the profile by its format, the parsed profiles are sent to the callBack of the parser
+func (d *Decoder) handleProfile(parser *Parser, profile *pb.Profile) error {
+	switch profile.Format {
+	case "jfr":
+		atomic.AddInt64(&d.counter.JavaProfileCount, 1)
+		metadata := d.buildMetaData(profile)
+		parser.profileName = metadata.Key.AppName()
+		decompressJfr, err := profile_common.GzipDecompress(profile.Data)
+		if err != nil {
+			return fmt.Errorf("decompress java profile data failed, err=%s", err)
+		}
+		err = d.sendProfileData(&jfr.RawProfile{
+			FormDataContentType: string(profile.ContentType),
+			RawData:             decompressJfr,
+		}, profile.Format, parser, metadata)
+
+		if err != nil {
+			return fmt.Errorf("decode java profile data failed, err=%s", err)
+		}
+	case "pprof":
+		atomic.AddInt64(&d.counter.GolangProfileCount, 1)
+		metadata := d.buildMetaData(profile)
+		parser.profileName = metadata.Key.AppName()
+		err := d.sendProfileData(&pprof.RawProfile{
+			FormDataContentType: string(profile.ContentType),
+			RawData:             profile.Data,
+		}, profile.Format, parser, metadata)
+		if err != nil {
+			return fmt.Errorf("decode golang profile data failed, err=%s", err)
+		}
+	case "":
+		// 如果 format == "" && contentType 有 "multipart/form-data"，默认当作 pprof 来解析，且 StreamingParser&PoolStreamingParser = true
+		// if format == "" && contentType has "multipart/form-data", using pprof parser as default, StreamingParser&PoolStreamingParser = true
+		if strings.Contains(string(profile.ContentType), "multipart/form-data") {
 			atomic.AddInt64(&d.counter.GolangProfileCount, 1)
 			metadata := d.buildMetaData(profile)
 			parser.profileName = metadata.Key.AppName()
 			err := d.sendProfileData(&pprof.RawProfile{
 				FormDataContentType: string(profile.ContentType),
 				RawData:             profile.Data,
+				StreamingParser:     true,
+				PoolStreamingParser: true,
 			}, profile.Format, parser, metadata)
 			if err != nil {
-				log.Errorf("decode golang profile data failed, offset=%d, len=%d, err=%s", decoder.Offset(), len(decoder.Bytes()), err)
-				return
+				return fmt.Errorf("decode golang profile data failed, err=%s", err)
 			}
-		case "":
-			// 如果 format == "" && contentType 有 "multipart/form-data"，默认当作 pprof 来解析，且 StreamingParser&PoolStreamingParser = true
-			// if format == "" && contentType has "multipart/form-data", using pprof parser as default, StreamingParser&PoolStreamingParser = true
-			if strings.Contains(string(profile.ContentType), "multipart/form-data") {
-				atomic.AddInt64(&d.counter.GolangProfileCount, 1)
-				metadata := d.buildMetaData(profile)
-				parser.profileName = metadata.Key.AppName()
-				err := d.sendProfileData(&pprof.RawProfile{
-					FormDataContentType: string(profile.ContentType),
-					RawData:             profile.Data,
-					StreamingParser:     true,
-					PoolStreamingParser: true,
-				}, profile.Format, parser, metadata)
-				if err != nil {
-					log.Errorf("decode golang profile data failed, offset=%d, len=%d, err=%s", decoder.Offset(), len(decoder.Bytes()), err)
-					return
-				}
-			} else {
-				atomic.AddInt64(&d.counter.EBPFProfileCount, 1)
-				profile = d.filleBPFData(profile)
-				metadata := d.buildMetaData(profile)
-				parser.profileName = metadata.Key.AppName()
-				parser.processTracer = &processTracer{value: profile.Count, pid: profile.Pid, stime: int64(profile.Stime), eventType: eBPFEventType[profile.EventType]}
-				err := d.sendProfileData(&pprofile.RawProfile{
-					Format:  ingestion.FormatLines,
-					RawData: profile.Data,
-				}, profile.Format, parser, metadata)
-				if err != nil {
-					log.Errorf("decode ebpf profile data failed, offset=%d, len=%d, err=%s", decoder.Offset(), len(decoder.Bytes()), err)
-					return
-				}
+		} else {
+			atomic.AddInt64(&d.counter.EBPFProfileCount, 1)
+			profile = d.filleBPFData(profile)
+			metadata := d.buildMetaData(profile)
+			parser.profileName = metadata.Key.AppName()
+			parser.processTracer = &processTracer{value: profile.Count, pid: profile.Pid, stime: int64(profile.Stime), eventType: eBPFEventType[profile.EventType]}
+			err := d.sendProfileData(&pprofile.RawProfile{
+				Format:  ingestion.FormatLines,
+				RawData: profile.Data,
+			}, profile.Format, parser, metadata)
+			if err != nil {
+				return fmt.Errorf("decode ebpf profile data failed, err=%s", err)
 			}
-		case "speedscope", "tree", "trie", "lines":
-			// not implemented
-			continue
 		}
+	case "speedscope", "tree", "trie", "lines":
+		// sent by pyroscope clients of python/ruby etc.
+		d.countFormat(profile.Format)
+		metadata := d.buildMetaData(profile)
+		parser.profileName = metadata.Key.AppName()
+		rawProfile, err := newRawProfile(profile.Format, profile.Data)
+		if err == nil {
+			err = d.sendProfileData(rawProfile, profile.Format, parser, metadata)
+		}
+		if err != nil {
+			atomic.AddInt64(&d.counter.ParseFailedCount, 1)
+			return fmt.Errorf("decode %s profile data failed, err=%s", profile.Format, err)
+		}
+	}
+	return nil
+}
+
+func (d *Decoder) countFormat(format string) {
+	switch format {
+	case "speedscope":
+		atomic.AddInt64(&d.counter.SpeedscopeProfileCount, 1)
+	case "tree":
+		atomic.AddInt64(&d.counter.TreeProfileCount, 1)
+	case "trie":
+		atomic.AddInt64(&d.counter.TrieProfileCount, 1)
+	case "lines":
+		atomic.AddInt64(&d.counter.LinesProfileCount, 1)
+	}
+}
+
+// newRawProfile builds the parser of speedscope, tree, trie and lines formats, the data may be gzip compressed
+func newRawProfile(format string, data []byte) (ingestion.RawProfile, error) {
+	data, err := profile_common.GzipDecompress(data)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "speedscope":
+		return &speedscope.RawProfile{RawData: data}, nil
+	case "tree", "trie", "lines":
+		return &pprofile.RawProfile{Format: ingestion.Format(format), RawData: data}, nil
 	}
+	return nil, fmt.Errorf("unsupported profile format %s", format)
 }
 
 func (d *Decoder) filleBPFData(profile *pb.Profile) *pb.Profile {
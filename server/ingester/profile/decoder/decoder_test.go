@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package decoder
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deepflowio/deepflow/server/ingester/profile/dbwriter"
+	"github.com/deepflowio/deepflow/server/libs/grpc"
+	"github.com/deepflowio/deepflow/server/libs/zerodoc/pb"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// parseTestProfile parses the profile by handleProfile, and formats the profiles sent to the callBack as sorted rows
+func parseTestProfile(t *testing.T, d *Decoder, profile *pb.Profile) ([]string, error) {
+	rows := []string{}
+	parser := &Parser{
+		vtapID:       1,
+		inTimestamp:  time.Now(),
+		platformData: d.platformData,
+		IP:           []byte{10, 1, 2, 3},
+		observer:     &observer{},
+		Counter:      d.counter,
+		callBack: func(m interface{}) {
+			p := m.(*dbwriter.InProcessProfile)
+			rows = append(rows, fmt.Sprintf("%s|%s|%s|%s|%d|%s", p.AppService, p.ProfileEventType, p.ProfileLanguageType,
+				p.ProfileValueUnit, p.ProfileValue, p.ProfileLocationStr))
+		},
+	}
+	if err := d.handleProfile(parser, profile); err != nil {
+		return nil, err
+	}
+	sort.Strings(rows)
+	return rows, nil
+}
+
+func TestProfileFormatsGolden(t *testing.T) {
+	d := &Decoder{
+		platformData: grpc.NewPlatformInfoTable(nil, 0, 0, 0, "", "", nil, true, nil),
+		counter:      &Counter{},
+	}
+	cases := []struct {
+		file    string
+		format  string
+		name    string
+		spyName string
+	}{
+		{"pyspy.lines", "lines", "python-app.cpu{env=test}", "pyspy"},
+		{"pyspy.tree", "tree", "python-app.cpu{env=test}", "pyspy"},
+		{"rbspy.trie.gz", "trie", "ruby-app.cpu{env=test}", "rbspy"},
+		{"sampled.speedscope.json", "speedscope", "ruby-app{env=test}", "rbspy"},
+	}
+	for _, c := range cases {
+		data, err := ioutil.ReadFile(filepath.Join("testdata", c.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		profile := &pb.Profile{
+			Name:            c.name,
+			Format:          c.format,
+			Data:            data,
+			SpyName:         c.spyName,
+			From:            1700000000,
+			Until:           1700000010,
+			SampleRate:      100,
+			Units:           "samples",
+			AggregationType: "sum",
+		}
+		rows, err := parseTestProfile(t, d, profile)
+		if err != nil {
+			t.Fatalf("parse %s failed: %v", c.file, err)
+		}
+		got := strings.Join(rows, "\n") + "\n"
+
+		golden := filepath.Join("testdata", c.file+".golden")
+		if *update {
+			if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		expected, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("read golden file failed: %v, run the test with -update to generate it", err)
+		}
+		if got != string(expected) {
+			t.Errorf("parse %s:\ngot:\n%s\nexpected:\n%s", c.file, got, expected)
+		}
+	}
+
+	counter := d.GetCounter().(*Counter)
+	if counter.LinesProfileCount != 1 || counter.TreeProfileCount != 1 || counter.TrieProfileCount != 1 || counter.SpeedscopeProfileCount != 1 {
+		t.Errorf("unexpected counter %+v", *counter)
+	}
+}
+
+func TestProfileFormatsInvalid(t *testing.T) {
+	d := &Decoder{
+		platformData: grpc.NewPlatformInfoTable(nil, 0, 0, 0, "", "", nil, true, nil),
+		counter:      &Counter{},
+	}
+	for _, format := range []string{"speedscope", "tree", "trie"} {
+		profile := &pb.Profile{Name: "app", Format: format, Data: []byte("{invalid")}
+		if _, err := parseTestProfile(t, d, profile); err == nil {
+			t.Errorf("parse invalid %s profile should fail", format)
+		}
+	}
+	if counter := d.GetCounter().(*Counter); counter.ParseFailedCount != 3 {
+		t.Errorf("unexpected counter %+v", *counter)
+	}
+	if _, err := newRawProfile("groups", nil); err == nil {
+		t.Errorf("unsupported format should fail")
+	}
+}
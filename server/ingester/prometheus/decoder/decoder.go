@@ -28,6 +28,7 @@ import (
 	"github.com/prometheus/common/model"
 
 	"github.com/deepflowio/deepflow/server/ingester/common"
+	"github.com/deepflowio/deepflow/server/ingester/ingesterctl/tap"
 	"github.com/deepflowio/deepflow/server/ingester/prometheus/config"
 	"github.com/deepflowio/deepflow/server/ingester/prometheus/dbwriter"
 	"github.com/deepflowio/deepflow/server/libs/codec"
@@ -126,6 +127,13 @@ func NewPrometheusSamplesBuilder(name string, index int, platformData *grpc.Plat
 	return p
 }
 
+type TappedTimeSeries struct {
+	VtapID      uint16          `json:"vtap_id"`
+	Labels      []prompb.Label  `json:"labels"`
+	ExtraLabels []prompb.Label  `json:"extra_labels,omitempty"`
+	Samples     []prompb.Sample `json:"samples"`
+}
+
 type Decoder struct {
 	index            int
 	inQueue          queue.QueueReader
@@ -133,6 +141,7 @@ type Decoder struct {
 	prometheusWriter *dbwriter.PrometheusWriter
 	debugEnabled     bool
 	config           *config.Config
+	tap              *tap.Tap
 
 	samplesBuilder *PrometheusSamplesBuilder
 
@@ -157,6 +166,7 @@ func NewDecoder(
 		debugEnabled:     log.IsEnabledFor(logging.DEBUG),
 		prometheusWriter: prometheusWriter,
 		config:           config,
+		tap:              tap.GetTap(datatype.MESSAGE_TYPE_PROMETHEUS),
 		counter:          &Counter{},
 	}
 }
@@ -268,6 +278,14 @@ func (d *Decoder) sendPrometheus(vtapID uint16, ts *prompb.TimeSeries, extraLabe
 	if d.debugEnabled {
 		log.Debugf("decoder %d vtap %d recv promtheus timeseries: %v", d.index, vtapID, ts)
 	}
+	if d.tap.Enabled() {
+		d.tap.Send(&tap.Meta{VtapID: vtapID}, &TappedTimeSeries{
+			VtapID:      vtapID,
+			Labels:      ts.Labels,
+			ExtraLabels: extraLabels,
+			Samples:     ts.Samples,
+		})
+	}
 
 	epcId, podClusterId, err := d.samplesBuilder.GetEpcPodClusterId(vtapID)
 	if err != nil {
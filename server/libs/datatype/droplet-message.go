@@ -50,6 +50,8 @@ const (
 	MESSAGE_TYPE_PROFILE
 	MESSAGE_TYPE_PROC_EVENT
 	MESSAGE_TYPE_ALARM_EVENT
+	MESSAGE_TYPE_OPENTELEMETRY_LOG
+	MESSAGE_TYPE_OPENTELEMETRY_METRICS
 	MESSAGE_TYPE_MAX
 )
 
@@ -72,6 +74,8 @@ var MessageTypeString = [MESSAGE_TYPE_MAX]string{
 	MESSAGE_TYPE_PROFILE:                  "profile",
 	MESSAGE_TYPE_PROC_EVENT:               "proc_event",
 	MESSAGE_TYPE_ALARM_EVENT:              "alarm_event",
+	MESSAGE_TYPE_OPENTELEMETRY_LOG:        "open_telemetry_log",
+	MESSAGE_TYPE_OPENTELEMETRY_METRICS:    "open_telemetry_metrics",
 }
 
 func (m MessageType) String() string {
@@ -112,6 +116,8 @@ var MessageHeaderTypes = [MESSAGE_TYPE_MAX]MessageHeaderType{
 	MESSAGE_TYPE_PROFILE:                  HEADER_TYPE_LT_VTAP,
 	MESSAGE_TYPE_PROC_EVENT:               HEADER_TYPE_LT_VTAP,
 	MESSAGE_TYPE_ALARM_EVENT:              HEADER_TYPE_LT_VTAP,
+	MESSAGE_TYPE_OPENTELEMETRY_LOG:        HEADER_TYPE_LT_VTAP,
+	MESSAGE_TYPE_OPENTELEMETRY_METRICS:    HEADER_TYPE_LT_VTAP,
 }
 
 func (m MessageType) HeaderType() MessageHeaderType {
@@ -1156,8 +1156,15 @@ func (t *PlatformInfoTable) getCommunicationVtaps() []*trident.CommunicationVtap
 
 func (t *PlatformInfoTable) communicationVtapsString() string {
 	sb := &strings.Builder{}
-	for _, comm := range t.getCommunicationVtaps() {
-		sb.WriteString(fmt.Sprintf("Vtapid: %d  LastActiveTime: %d %s\n", *comm.VtapId, *comm.LastActiveTime, time.Unix(int64(*comm.LastActiveTime), 0)))
+	if t.receiver == nil {
+		return ""
+	}
+	for _, s := range t.receiver.GetTridentStatus() {
+		sb.WriteString(fmt.Sprintf("Vtapid: %d  LastActiveTime: %d %s", s.VTAPID, s.LastLocalTimestamp, time.Unix(int64(s.LastLocalTimestamp), 0)))
+		if s.ClientIdentity != "" {
+			sb.WriteString(fmt.Sprintf("  ClientIdentity: %s", s.ClientIdentity))
+		}
+		sb.WriteString("\n")
 	}
 	return sb.String()
 }
@@ -18,6 +18,7 @@ package receiver
 
 import (
 	"bufio"
+	"crypto/tls"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -55,6 +56,7 @@ const (
 	LOG_INTERVAL              = 60
 	RECORD_STATUS_TIMEOUT     = 30 // 每30秒记录下trident的活跃信息，platformData模块每分钟会上报trisolaris
 	SOCKET_READ_ERROR         = "maybe trident restart."
+	TLS_HANDSHAKE_TIMEOUT     = 10 * time.Second
 	ONE_HOUR                  = 3600
 )
 
@@ -180,9 +182,10 @@ type Status struct {
 	firstSeq             uint64
 	firstRemoteTimestamp uint32 // 第一次收到数据时数据中的时间戳
 	firstLocalTimestamp  uint32 // 第一次收到数据时的本地时间
+	ClientIdentity       string // subject of the verified client certificate, empty if not received by TLS
 }
 
-func NewStatus(now uint32, msgType datatype.MessageType, vtapID uint16, ip net.IP, seq uint64, timestamp uint32, serverType ServerType) *Status {
+func NewStatus(now uint32, msgType datatype.MessageType, vtapID uint16, ip net.IP, seq uint64, timestamp uint32, serverType ServerType, clientIdentity string) *Status {
 	return &Status{
 		msgType:              msgType,
 		serverType:           serverType,
@@ -194,10 +197,11 @@ func NewStatus(now uint32, msgType datatype.MessageType, vtapID uint16, ip net.I
 		firstSeq:             seq,
 		firstRemoteTimestamp: timestamp,
 		firstLocalTimestamp:  now,
+		ClientIdentity:       clientIdentity,
 	}
 }
 
-func (s *Status) update(now uint32, msgType datatype.MessageType, vtapID uint16, ip net.IP, seq uint64, timestamp uint32, serverType ServerType) {
+func (s *Status) update(now uint32, msgType datatype.MessageType, vtapID uint16, ip net.IP, seq uint64, timestamp uint32, serverType ServerType, clientIdentity string) {
 	s.msgType = msgType
 	s.VTAPID = vtapID
 	s.ip = ip
@@ -205,6 +209,7 @@ func (s *Status) update(now uint32, msgType datatype.MessageType, vtapID uint16,
 	s.lastRemoteTimestamp = timestamp
 	s.LastLocalTimestamp = now
 	s.serverType = serverType
+	s.ClientIdentity = clientIdentity
 }
 
 type AdapterStatus struct {
@@ -229,22 +234,22 @@ func (s *AdapterStatus) init() {
 	}
 }
 
-func (s *AdapterStatus) Update(now uint32, msgType datatype.MessageType, vtapID uint16, ip net.IP, seq uint64, timestamp uint32, serverType ServerType) {
+func (s *AdapterStatus) Update(now uint32, msgType datatype.MessageType, vtapID uint16, ip net.IP, seq uint64, timestamp uint32, serverType ServerType, clientIdentity string) {
 	if serverType == UDP { // UDP大部分时间无锁，只有在更新map时加锁, 防止调试命令读取时可能导致异常
 		if vtapID != 0 {
 			if status, ok := s.UDPStatusFlow[msgType][vtapID]; ok {
-				status.update(now, msgType, vtapID, ip, seq, timestamp, serverType)
+				status.update(now, msgType, vtapID, ip, seq, timestamp, serverType, clientIdentity)
 			} else {
 				s.UDPStatusLocks[msgType].Lock()
-				s.UDPStatusFlow[msgType][vtapID] = NewStatus(now, msgType, vtapID, ip, seq, timestamp, serverType)
+				s.UDPStatusFlow[msgType][vtapID] = NewStatus(now, msgType, vtapID, ip, seq, timestamp, serverType, clientIdentity)
 				s.UDPStatusLocks[msgType].Unlock()
 			}
 		} else {
 			if status, ok := s.UDPStatusOthers[msgType][ip.String()]; ok {
-				status.update(now, msgType, vtapID, ip, seq, timestamp, serverType)
+				status.update(now, msgType, vtapID, ip, seq, timestamp, serverType, clientIdentity)
 			} else {
 				s.UDPStatusLocks[msgType].Lock()
-				s.UDPStatusOthers[msgType][ip.String()] = NewStatus(now, msgType, vtapID, ip, seq, timestamp, serverType)
+				s.UDPStatusOthers[msgType][ip.String()] = NewStatus(now, msgType, vtapID, ip, seq, timestamp, serverType, clientIdentity)
 				s.UDPStatusLocks[msgType].Unlock()
 			}
 		}
@@ -264,9 +269,9 @@ func (s *AdapterStatus) Update(now uint32, msgType datatype.MessageType, vtapID
 			status, ok := s.TCPStatusFlow[msgType][vtapID]
 			s.TCPStatusLocks[msgType].RUnlock()
 			if ok {
-				status.update(now, msgType, vtapID, ip, seq, timestamp, serverType)
+				status.update(now, msgType, vtapID, ip, seq, timestamp, serverType, clientIdentity)
 			} else {
-				newStatus := NewStatus(now, msgType, vtapID, ip, seq, timestamp, serverType)
+				newStatus := NewStatus(now, msgType, vtapID, ip, seq, timestamp, serverType, clientIdentity)
 				s.TCPStatusLocks[msgType].Lock()
 				s.TCPStatusFlow[msgType][vtapID] = newStatus
 				s.TCPStatusLocks[msgType].Unlock()
@@ -277,9 +282,9 @@ func (s *AdapterStatus) Update(now uint32, msgType datatype.MessageType, vtapID
 			status, ok := s.TCPStatusOthers[msgType][ip.String()]
 			s.TCPStatusLocks[msgType].RUnlock()
 			if ok {
-				status.update(now, msgType, vtapID, ip, seq, timestamp, serverType)
+				status.update(now, msgType, vtapID, ip, seq, timestamp, serverType, clientIdentity)
 			} else {
-				newStatus := NewStatus(now, msgType, vtapID, ip, seq, timestamp, serverType)
+				newStatus := NewStatus(now, msgType, vtapID, ip, seq, timestamp, serverType, clientIdentity)
 				s.TCPStatusLocks[msgType].Lock()
 				s.TCPStatusOthers[msgType][ip.String()] = newStatus
 				s.TCPStatusLocks[msgType].Unlock()
@@ -317,14 +322,15 @@ func (s *AdapterStatus) GetStatus(msgType datatype.MessageType) string {
 		sort.Slice(allStatus, func(i, j int) bool {
 			return allStatus[i].ip.String() < allStatus[j].ip.String()
 		})
-		status := fmt.Sprintf("MsgType VTAPID TridentIP                                Type LastSeq  LastRemoteTimestamp LastLocalTimestamp  LastDelay LastRecvFromNow FirstSeq FirstRemoteTimestamp FirstLocalTimestamp\n")
-		status += fmt.Sprintf("------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------\n")
+		status := fmt.Sprintf("MsgType VTAPID TridentIP                                Type LastSeq  LastRemoteTimestamp LastLocalTimestamp  LastDelay LastRecvFromNow FirstSeq FirstRemoteTimestamp FirstLocalTimestamp  ClientIdentity\n")
+		status += fmt.Sprintf("----------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------------\n")
 		for _, instance := range allStatus {
-			status += fmt.Sprintf("%-7s %-6d %-40s %-4s %-8d %-19.19s %-19.19s %-9d %-15d %-8d %-19.19s  %-19.19s\n",
+			status += fmt.Sprintf("%-7s %-6d %-40s %-4s %-8d %-19.19s %-19.19s %-9d %-15d %-8d %-19.19s  %-19.19s  %s\n",
 				datatype.MessageTypeString[int(instance.msgType)], instance.VTAPID, instance.ip, instance.serverType,
 				instance.lastSeq, time.Unix(int64(instance.lastRemoteTimestamp), 0), time.Unix(int64(instance.LastLocalTimestamp), 0),
 				instance.LastLocalTimestamp-instance.lastRemoteTimestamp, uint32(time.Now().Unix())-instance.LastLocalTimestamp,
-				instance.firstSeq, time.Unix(int64(instance.firstRemoteTimestamp), 0), time.Unix(int64(instance.firstLocalTimestamp), 0))
+				instance.firstSeq, time.Unix(int64(instance.firstRemoteTimestamp), 0), time.Unix(int64(instance.firstLocalTimestamp), 0),
+				instance.ClientIdentity)
 		}
 		return status
 	}
@@ -345,14 +351,15 @@ func (s *AdapterStatus) GetStatus(msgType datatype.MessageType) string {
 	sort.Slice(allStatus, func(i, j int) bool {
 		return allStatus[i].ip.String() < allStatus[j].ip.String()
 	})
-	status := fmt.Sprintf("MsgType TridentIP                                Type LastLocalTimestamp LastRecvFromNow FirstLocalTimestamp\n")
-	status += fmt.Sprintf("-----------------------------------------------------------------------------------------------------\n")
+	status := fmt.Sprintf("MsgType TridentIP                                Type LastLocalTimestamp LastRecvFromNow FirstLocalTimestamp ClientIdentity\n")
+	status += fmt.Sprintf("--------------------------------------------------------------------------------------------------------------------\n")
 	for _, instance := range allStatus {
-		status += fmt.Sprintf("%-7s %-40s %-4s %-19.19s %-15d %-19.19s\n",
+		status += fmt.Sprintf("%-7s %-40s %-4s %-19.19s %-15d %-19.19s %s\n",
 			datatype.MessageTypeString[int(instance.msgType)], instance.ip, instance.serverType,
 			time.Unix(int64(instance.LastLocalTimestamp), 0),
 			uint32(time.Now().Unix())-instance.LastLocalTimestamp,
-			time.Unix(int64(instance.firstLocalTimestamp), 0))
+			time.Unix(int64(instance.firstLocalTimestamp), 0),
+			instance.ClientIdentity)
 	}
 	return status
 }
@@ -378,6 +385,9 @@ type Receiver struct {
 	TCPReaderBuffer  int
 	TCPListener      net.Listener
 	TCPAddress       string
+	TLSListener      net.Listener
+	TLSAddress       string
+	tlsConfig        *TLSConfig
 	lastUDPFlushTime int64
 	lastTCPFlushTime int64
 	timeNow          int64
@@ -403,6 +413,7 @@ type ReceiverCounter struct {
 	UDPDisorder     uint64 `statsd:"udp_disorder"`      // 乱序个数
 	UDPDisorderSize uint64 `statsd:"udp_disorder_size"` // 乱序最大范围
 	NewBufferCount  uint64 `statsd:"new_buffer_count"`  // If the received data is large, you need to alloc memory, record the times.
+	TLSHandshakeErr uint64 `statsd:"tls_handshake_err"`
 }
 
 func NewReceiver(
@@ -466,6 +477,14 @@ func (r *Receiver) SetServerType(serverType ServerType) {
 	r.serverType = serverType
 }
 
+// SetTLSConfig enables the TLS listener, should be called before Start. nil means disabled.
+func (r *Receiver) SetTLSConfig(config *TLSConfig) {
+	r.tlsConfig = config
+	if config != nil {
+		r.TLSAddress = fmt.Sprintf("0.0.0.0:%d", config.ListenPort)
+	}
+}
+
 func (r *Receiver) GetCounter() interface{} {
 	counter := &ReceiverCounter{MaxDelay: -ONE_HOUR, MinDelay: ONE_HOUR}
 	counter, r.counter = r.counter, counter
@@ -723,7 +742,7 @@ func (r *Receiver) ProcessUDPServer() {
 				r.DropDetection.Detect(getIpHash(remoteAddr.IP), flowHeader.Sequence, metricsTimestamp)
 			}
 		}
-		r.status.Update(uint32(r.timeNow), baseHeader.Type, vtapID, remoteAddr.IP, sequence, metricsTimestamp, UDP)
+		r.status.Update(uint32(r.timeNow), baseHeader.Type, vtapID, remoteAddr.IP, sequence, metricsTimestamp, UDP, "")
 
 		// Unregistered messages are discarded directly after receiving them, but the connection is not disconnected to prevent the Agent from printing exception logs
 		if r.handlers[baseHeader.Type] == nil {
@@ -743,9 +762,18 @@ func (r *Receiver) ProcessUDPServer() {
 }
 
 func (r *Receiver) ProcessTCPServer() {
-	defer r.TCPListener.Close()
+	r.processTCPListener(r.TCPListener, nil)
+}
+
+// ProcessTLSServer is the same as ProcessTCPServer, except that the connections are TLS encrypted
+func (r *Receiver) ProcessTLSServer(reloader *CertReloader) {
+	r.processTCPListener(r.TLSListener, reloader.TLSConfig())
+}
+
+func (r *Receiver) processTCPListener(listener net.Listener, tlsConfig *tls.Config) {
+	defer listener.Close()
 	for !r.exit {
-		conn, err := r.TCPListener.Accept()
+		conn, err := listener.Accept()
 		if err != nil {
 			log.Errorf("Accept error.%s ", err.Error())
 			time.Sleep(3 * time.Second)
@@ -760,6 +788,9 @@ func (r *Receiver) ProcessTCPServer() {
 		} else {
 			log.Infof("TCP client(%s) connect success.", conn.RemoteAddr().String())
 		}
+		if tlsConfig != nil {
+			conn = tls.Server(conn, tlsConfig)
+		}
 		go r.handleTCPConnection(conn)
 	}
 }
@@ -820,6 +851,20 @@ func (r *Receiver) handleTCPConnection(conn net.Conn) {
 	defer r.flushPutTCPQueues()
 	ip := parseRemoteIP(conn)
 
+	clientIdentity := ""
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		// handshake here instead of the accept goroutine, so a slow client will not block others
+		tlsConn.SetDeadline(time.Now().Add(TLS_HANDSHAKE_TIMEOUT))
+		if err := tlsConn.Handshake(); err != nil {
+			atomic.AddUint64(&r.counter.TLSHandshakeErr, 1)
+			log.Warningf("TLS client(%s) handshake failed: %s", conn.RemoteAddr().String(), err)
+			return
+		}
+		tlsConn.SetDeadline(time.Time{})
+		clientIdentity = ClientIdentity(tlsConn.ConnectionState())
+		log.Infof("TLS client(%s) handshake success, client identity: '%s'", conn.RemoteAddr().String(), clientIdentity)
+	}
+
 	baseHeader := &datatype.BaseHeader{}
 	baseHeaderBuffer := make([]byte, datatype.MESSAGE_HEADER_LEN)
 	flowHeader := &datatype.FlowHeader{}
@@ -902,7 +947,7 @@ func (r *Receiver) handleTCPConnection(conn net.Conn) {
 			metricsTimestamp = r.getMetricsTimestamp(recvBuffer.Buffer)
 			r.updateCounter(metricsTimestamp)
 		}
-		r.status.Update(uint32(r.timeNow), baseHeader.Type, vtapID, ip, sequence, metricsTimestamp, TCP)
+		r.status.Update(uint32(r.timeNow), baseHeader.Type, vtapID, ip, sequence, metricsTimestamp, TCP, clientIdentity)
 		atomic.AddUint64(&r.counter.RxPackets, 1)
 
 		// Unregistered messages are discarded directly after receiving them, but the connection is not disconnected to prevent the Agent from printing exception logs
@@ -936,6 +981,19 @@ func (r *Receiver) Start() {
 		}
 		go r.ProcessTCPServer()
 	}
+	if r.tlsConfig != nil {
+		reloader, err := NewCertReloader(r.tlsConfig)
+		if err != nil {
+			log.Errorf("TLS load certificates failed: %s", err)
+			os.Exit(-1)
+		}
+		if r.TLSListener, err = net.Listen("tcp", r.TLSAddress); err != nil {
+			log.Errorf("TLS listen at %s failed: %s", r.TLSAddress, err)
+			os.Exit(-1)
+		}
+		go r.ProcessTLSServer(reloader)
+		go r.reloadCertificates(reloader)
+	}
 
 	stats.RegisterCountableWithModulePrefix("ingester_", "recviver", r)
 }
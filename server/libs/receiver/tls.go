@@ -0,0 +1,196 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package receiver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+const DEFAULT_CERT_RELOAD_INTERVAL = 60 * time.Second
+
+type TLSConfig struct {
+	ListenPort        int
+	CertFile          string
+	KeyFile           string
+	CAFile            string // the client certificates are verified by it if set
+	RequireClientCert bool   // reject the clients without a certificate signed by CAFile
+	ReloadInterval    time.Duration
+}
+
+// CertReloader holds the server certificate and client CAs, and reloads them when the files are modified,
+// so the certificates can be rotated without restarting. The new certificates apply to new connections only.
+type CertReloader struct {
+	config *TLSConfig
+
+	sync.RWMutex
+	certificate *tls.Certificate
+	clientCAs   *x509.CertPool
+	modTimes    []time.Time
+}
+
+func NewCertReloader(config *TLSConfig) (*CertReloader, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, fmt.Errorf("cert file and key file are required")
+	}
+	if config.RequireClientCert && config.CAFile == "" {
+		return nil, fmt.Errorf("ca file is required to verify client certificates")
+	}
+	c := &CertReloader{config: config}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *CertReloader) files() []string {
+	files := []string{c.config.CertFile, c.config.KeyFile}
+	if c.config.CAFile != "" {
+		files = append(files, c.config.CAFile)
+	}
+	return files
+}
+
+func (c *CertReloader) getModTimes() ([]time.Time, error) {
+	files := c.files()
+	modTimes := make([]time.Time, 0, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+	return modTimes, nil
+}
+
+func (c *CertReloader) load() error {
+	modTimes, err := c.getModTimes()
+	if err != nil {
+		return err
+	}
+	certificate, err := tls.LoadX509KeyPair(c.config.CertFile, c.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("load key pair(%s, %s) failed: %s", c.config.CertFile, c.config.KeyFile, err)
+	}
+	var clientCAs *x509.CertPool
+	if c.config.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.config.CAFile)
+		if err != nil {
+			return err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no valid certificate found in ca file %s", c.config.CAFile)
+		}
+	}
+
+	c.Lock()
+	c.certificate = &certificate
+	c.clientCAs = clientCAs
+	c.modTimes = modTimes
+	c.Unlock()
+	return nil
+}
+
+// ReloadIfModified reloads the certificates if any of the files is modified, the old ones are kept if failed
+func (c *CertReloader) ReloadIfModified() (bool, error) {
+	modTimes, err := c.getModTimes()
+	if err != nil {
+		return false, err
+	}
+	c.RLock()
+	modified := false
+	for i := range modTimes {
+		if !modTimes[i].Equal(c.modTimes[i]) {
+			modified = true
+			break
+		}
+	}
+	c.RUnlock()
+	if !modified {
+		return false, nil
+	}
+	if err := c.load(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// TLSConfig returns a config which always uses the latest certificates
+func (c *CertReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c.RLock()
+			defer c.RUnlock()
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*c.certificate},
+				ClientAuth:   tls.NoClientCert,
+			}
+			if c.clientCAs != nil {
+				config.ClientCAs = c.clientCAs
+				config.ClientAuth = tls.VerifyClientCertIfGiven
+				if c.config.RequireClientCert {
+					config.ClientAuth = tls.RequireAndVerifyClientCert
+				}
+			}
+			return config, nil
+		},
+	}
+}
+
+// ClientIdentity returns the subject of the verified client certificate, e.g.: 'CN=agent-1,O=deepflow',
+// the first DNS name is used if the subject is empty.
+func ClientIdentity(state tls.ConnectionState) string {
+	if len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	cert := state.VerifiedChains[0][0]
+	if subject := cert.Subject.String(); subject != "" {
+		return subject
+	}
+	if len(cert.DNSNames) > 0 {
+		return "DNS:" + cert.DNSNames[0]
+	}
+	return fmt.Sprintf("SN:%x", cert.SerialNumber)
+}
+
+func (r *Receiver) reloadCertificates(reloader *CertReloader) {
+	interval := r.tlsConfig.ReloadInterval
+	if interval <= 0 {
+		interval = DEFAULT_CERT_RELOAD_INTERVAL
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if r.exit {
+			return
+		}
+		if reloaded, err := reloader.ReloadIfModified(); err != nil {
+			log.Warningf("TLS reload certificates failed, keep using the old ones: %s", err)
+		} else if reloaded {
+			log.Infof("TLS certificates reloaded")
+		}
+	}
+}
@@ -0,0 +1,277 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package receiver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/deepflowio/deepflow/server/libs/datatype"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCert(t *testing.T, commonName string, serial int64, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signerCert, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	certificate, err := tls.X509KeyPair(c.certPEM, c.keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificate
+}
+
+func writeTestFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, modTime, modTime)
+}
+
+func newTestTLSConfig(t *testing.T, dir string, ca, server *testCert) *TLSConfig {
+	config := &TLSConfig{
+		CertFile:          filepath.Join(dir, "server.crt"),
+		KeyFile:           filepath.Join(dir, "server.key"),
+		CAFile:            filepath.Join(dir, "ca.crt"),
+		RequireClientCert: true,
+	}
+	modTime := time.Now().Add(-time.Minute)
+	writeTestFile(t, config.CertFile, server.certPEM, modTime)
+	writeTestFile(t, config.KeyFile, server.keyPEM, modTime)
+	writeTestFile(t, config.CAFile, ca.certPEM, modTime)
+	return config
+}
+
+// newConnPair returns a connected TCP pair, net.Pipe is not used since the writes of it are unbuffered
+func newConnPair(t *testing.T) (net.Conn, net.Conn) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	clientConn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return serverConn, clientConn
+}
+
+// handshake returns the server side state and the certificate presented by server
+func handshake(t *testing.T, serverConfig *tls.Config, clientConfig *tls.Config) (tls.ConnectionState, *x509.Certificate, error) {
+	serverConn, clientConn := newConnPair(t)
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	client := tls.Client(clientConn, clientConfig)
+	clientErr := make(chan error, 1)
+	go func() {
+		clientErr <- client.Handshake()
+	}()
+	server := tls.Server(serverConn, serverConfig)
+	err := server.Handshake()
+	if err != nil {
+		serverConn.Close()
+		<-clientErr
+		return tls.ConnectionState{}, nil, err
+	}
+	if err := <-clientErr; err != nil {
+		return tls.ConnectionState{}, nil, err
+	}
+	return server.ConnectionState(), client.ConnectionState().PeerCertificates[0], nil
+}
+
+func TestCertReloaderHandshake(t *testing.T) {
+	dir, err := ioutil.TempDir("", "receiver_tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, "test-ca", 1, nil)
+	server := newTestCert(t, "ingester", 2, ca)
+	agent := newTestCert(t, "agent-1", 3, ca)
+	config := newTestTLSConfig(t, dir, ca, server)
+
+	reloader, err := NewCertReloader(config)
+	if err != nil {
+		t.Fatalf("new cert reloader failed: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientConfig := &tls.Config{
+		RootCAs:      roots,
+		ServerName:   "localhost",
+		Certificates: []tls.Certificate{agent.tlsCertificate(t)},
+	}
+
+	state, serverCert, err := handshake(t, reloader.TLSConfig(), clientConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	if identity := ClientIdentity(state); identity != "CN=agent-1" {
+		t.Errorf("client identity == %q, expected %q", identity, "CN=agent-1")
+	}
+	if serverCert.SerialNumber.Int64() != 2 {
+		t.Errorf("unexpected server certificate %s", serverCert.SerialNumber)
+	}
+
+	// client without certificate is rejected
+	if _, _, err := handshake(t, reloader.TLSConfig(), &tls.Config{RootCAs: roots, ServerName: "localhost"}); err == nil {
+		t.Errorf("handshake without client certificate should fail")
+	}
+	// client certificate signed by other CA is rejected
+	other := newTestCert(t, "agent-2", 4, newTestCert(t, "other-ca", 5, nil))
+	clientConfig.Certificates = []tls.Certificate{other.tlsCertificate(t)}
+	if _, _, err := handshake(t, reloader.TLSConfig(), clientConfig); err == nil {
+		t.Errorf("handshake with untrusted client certificate should fail")
+	}
+
+	// rotate server certificate
+	if reloaded, err := reloader.ReloadIfModified(); reloaded || err != nil {
+		t.Fatalf("reload without modification got %v, %v", reloaded, err)
+	}
+	newServer := newTestCert(t, "ingester", 6, ca)
+	writeTestFile(t, config.CertFile, newServer.certPEM, time.Now())
+	writeTestFile(t, config.KeyFile, newServer.keyPEM, time.Now())
+	if reloaded, err := reloader.ReloadIfModified(); !reloaded || err != nil {
+		t.Fatalf("reload after modification got %v, %v", reloaded, err)
+	}
+	clientConfig.Certificates = []tls.Certificate{agent.tlsCertificate(t)}
+	if _, serverCert, err := handshake(t, reloader.TLSConfig(), clientConfig); err != nil || serverCert.SerialNumber.Int64() != 6 {
+		t.Errorf("handshake after reload got %v, %v", serverCert, err)
+	}
+
+	// broken files are not loaded, the old certificate is kept
+	writeTestFile(t, config.CertFile, []byte("broken"), time.Now().Add(time.Minute))
+	if _, err := reloader.ReloadIfModified(); err == nil {
+		t.Errorf("reload broken certificate should fail")
+	}
+	if _, serverCert, err := handshake(t, reloader.TLSConfig(), clientConfig); err != nil || serverCert.SerialNumber.Int64() != 6 {
+		t.Errorf("handshake after failed reload got %v, %v", serverCert, err)
+	}
+}
+
+func TestTLSConnectionStatus(t *testing.T) {
+	dir, err := ioutil.TempDir("", "receiver_tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, "test-ca", 1, nil)
+	reloader, err := NewCertReloader(newTestTLSConfig(t, dir, ca, newTestCert(t, "ingester", 2, ca)))
+	if err != nil {
+		t.Fatalf("new cert reloader failed: %v", err)
+	}
+	r := &Receiver{
+		handlers:        make([]*Handler, datatype.MESSAGE_TYPE_MAX),
+		TCPReaderBuffer: RECV_BUFSIZE_8K,
+		counter:         &ReceiverCounter{},
+		status:          &AdapterStatus{},
+	}
+	r.status.init()
+
+	serverConn, clientConn := newConnPair(t)
+	done := make(chan struct{})
+	go func() {
+		r.handleTCPConnection(tls.Server(serverConn, reloader.TLSConfig()))
+		close(done)
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := tls.Client(clientConn, &tls.Config{
+		RootCAs:      roots,
+		ServerName:   "localhost",
+		Certificates: []tls.Certificate{newTestCert(t, "agent-7", 3, ca).tlsCertificate(t)},
+	})
+	payload := []byte{1, 2, 3, 4}
+	frame := make([]byte, datatype.MESSAGE_HEADER_LEN+datatype.FLOW_HEADER_LEN+len(payload))
+	(&datatype.BaseHeader{FrameSize: uint32(len(frame)), Type: datatype.MESSAGE_TYPE_PROTOCOLLOG}).Encode(frame)
+	(&datatype.FlowHeader{Version: datatype.VERSION, VTAPID: 7}).Encode(frame[datatype.MESSAGE_HEADER_LEN:])
+	copy(frame[datatype.MESSAGE_HEADER_LEN+datatype.FLOW_HEADER_LEN:], payload)
+	if _, err := client.Write(frame); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	client.Close()
+	<-done
+
+	status, ok := r.status.TCPStatusFlow[datatype.MESSAGE_TYPE_PROTOCOLLOG][7]
+	if !ok {
+		t.Fatalf("status of vtap 7 not found")
+	}
+	if status.ClientIdentity != "CN=agent-7" {
+		t.Errorf("client identity == %q, expected %q", status.ClientIdentity, "CN=agent-7")
+	}
+	if r.counter.Unregistered != 1 || r.counter.TLSHandshakeErr != 0 {
+		t.Errorf("unexpected counter %+v", *r.counter)
+	}
+}
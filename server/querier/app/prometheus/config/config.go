@@ -28,6 +28,7 @@ type Prometheus struct {
 	ThanosReplicaLabels     []string        `yaml:"thanos-replica-labels"`
 	OperatorOffloading      bool            `default:"false" yaml:"operator-offloading"`
 	Cache                   PrometheusCache `yaml:"cache"`
+	Rules                   PrometheusRules `yaml:"rules"`
 }
 
 type PrometheusCache struct {
@@ -38,3 +39,14 @@ type PrometheusCache struct {
 	CacheFirstTimeout  int    `default:"10" yaml:"cache-first-timeout"`    // time out for first cache item load, unit: s, default: 10s
 	CacheCleanInterval int    `default:"3600" yaml:"cache-clean-interval"` // clean interval for cache, unit: s, default: 1h
 }
+
+type PrometheusRules struct {
+	Enabled             bool              `default:"false" yaml:"enabled"`
+	Files               []string          `yaml:"files"`                             // rule files in prometheus format, glob patterns are supported
+	EvaluationInterval  int               `default:"60" yaml:"evaluation-interval"`  // default evaluation interval of rule groups, unit: s
+	ExternalURL         string            `yaml:"external-url"`                      // url in the generatorURL of alerts
+	ExternalLabels      map[string]string `yaml:"external-labels"`                   // labels added to the alerts and recording results
+	AlertmanagerURLs    []string          `yaml:"alertmanager-urls"`                 // e.g. http://alertmanager:9093, alerts are posted to /api/v2/alerts
+	AlertmanagerTimeout int               `default:"10" yaml:"alertmanager-timeout"` // timeout of sending alerts, unit: s
+	ResendDelay         int               `default:"60" yaml:"resend-delay"`         // min delay before resending an alert to alertmanager, unit: s
+}
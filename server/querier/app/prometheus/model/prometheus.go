@@ -20,6 +20,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/promql/parser"
 )
 
@@ -52,9 +53,81 @@ type PromMetaParams struct {
 	StartTime string
 	EndTime   string
 	LabelName string
+	Matchers  []string
+	Metric    string
+	Limit     int
 	Context   context.Context
 }
 
+type PromMetricMetadata struct {
+	Type string `json:"type"`
+	Help string `json:"help"`
+	Unit string `json:"unit"`
+}
+
+type PromExemplarQueryResult struct {
+	SeriesLabels labels.Labels  `json:"seriesLabels"`
+	Exemplars    []PromExemplar `json:"exemplars"`
+}
+
+type PromExemplar struct {
+	Labels    labels.Labels `json:"labels"`
+	Value     string        `json:"value"`
+	Timestamp float64       `json:"timestamp"`
+}
+
+type PromRuleGroup struct {
+	Name           string        `json:"name"`
+	File           string        `json:"file"`
+	Rules          []interface{} `json:"rules"` // PromAlertingRule or PromRecordingRule
+	Interval       float64       `json:"interval"`
+	EvaluationTime float64       `json:"evaluationTime"`
+	LastEvaluation time.Time     `json:"lastEvaluation"`
+}
+
+type PromAlertingRule struct {
+	State          string        `json:"state"`
+	Name           string        `json:"name"`
+	Query          string        `json:"query"`
+	Duration       float64       `json:"duration"`
+	Labels         labels.Labels `json:"labels"`
+	Annotations    labels.Labels `json:"annotations"`
+	Alerts         []PromAlert   `json:"alerts"`
+	Health         string        `json:"health"`
+	LastError      string        `json:"lastError,omitempty"`
+	EvaluationTime float64       `json:"evaluationTime"`
+	LastEvaluation time.Time     `json:"lastEvaluation"`
+	Type           string        `json:"type"`
+}
+
+type PromRecordingRule struct {
+	Name           string        `json:"name"`
+	Query          string        `json:"query"`
+	Labels         labels.Labels `json:"labels,omitempty"`
+	Health         string        `json:"health"`
+	LastError      string        `json:"lastError,omitempty"`
+	EvaluationTime float64       `json:"evaluationTime"`
+	LastEvaluation time.Time     `json:"lastEvaluation"`
+	Type           string        `json:"type"`
+}
+
+type PromAlert struct {
+	Labels      labels.Labels `json:"labels"`
+	Annotations labels.Labels `json:"annotations"`
+	State       string        `json:"state"`
+	ActiveAt    *time.Time    `json:"activeAt,omitempty"`
+	Value       string        `json:"value"`
+}
+
+// alert in the format of alertmanager api v2
+type PromAlertmanagerAlert struct {
+	Labels       labels.Labels `json:"labels"`
+	Annotations  labels.Labels `json:"annotations"`
+	StartsAt     time.Time     `json:"startsAt,omitempty"`
+	EndsAt       time.Time     `json:"endsAt,omitempty"`
+	GeneratorURL string        `json:"generatorURL,omitempty"`
+}
+
 type PromQueryStats struct {
 	Duration   float64 `json:"duration,omitempty"`
 	SQL        string  `json:"sql,omitempty"`
@@ -147,6 +147,86 @@ func promTagValuesReader(svc *service.PrometheusService) gin.HandlerFunc {
 	})
 }
 
+func promLabelsReader(svc *service.PrometheusService) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		args := model.PromMetaParams{
+			StartTime: c.Request.FormValue("start"),
+			EndTime:   c.Request.FormValue("end"),
+			Matchers:  c.Request.Form["match[]"],
+			Context:   c.Request.Context(),
+		}
+		// should show tags when get `Series` of the matchers
+		ctx := context.WithValue(c.Request.Context(), service.CtxKeyShowTag{}, true)
+		result, err := svc.PromLabelNamesService(&args, ctx)
+		if err != nil {
+			c.JSON(500, &model.PromQueryResponse{Error: err.Error(), Status: _STATUS_FAIL})
+			return
+		}
+		c.JSON(200, result)
+	})
+}
+
+func promMetadataReader(svc *service.PrometheusService) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		args := model.PromMetaParams{
+			Metric:  c.Request.FormValue("metric"),
+			Context: c.Request.Context(),
+		}
+		limit := c.Request.FormValue("limit")
+		setRouterArgs(limit, &args.Limit, 0, strconv.Atoi)
+		result, err := svc.PromMetadataService(&args, c.Request.Context())
+		if err != nil {
+			c.JSON(500, &model.PromQueryResponse{Error: err.Error(), Status: _STATUS_FAIL})
+			return
+		}
+		c.JSON(200, result)
+	})
+}
+
+func promExemplarsReader(svc *service.PrometheusService) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		args := model.PromQueryParams{
+			Promql:    c.Request.FormValue("query"),
+			StartTime: c.Request.FormValue("start"),
+			EndTime:   c.Request.FormValue("end"),
+			Context:   c.Request.Context(),
+		}
+		debug := c.Request.FormValue("debug")
+		setRouterArgs(debug, &args.Debug, config.Cfg.Prometheus.RequestQueryWithDebug, strconv.ParseBool)
+		// should show tags when get `Series` of the exemplars
+		ctx := context.WithValue(c.Request.Context(), service.CtxKeyShowTag{}, true)
+		result, err := svc.PromExemplarsQueryService(&args, ctx)
+		if err != nil {
+			code, obj := handleError(err)
+			c.JSON(code, obj)
+			return
+		}
+		c.JSON(200, result)
+	})
+}
+
+func promRulesReader(svc *service.PrometheusService) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		result, err := svc.PromRulesService(c.Request.FormValue("type"))
+		if err != nil {
+			c.JSON(400, &model.PromQueryResponse{Error: err.Error(), Status: _STATUS_FAIL})
+			return
+		}
+		c.JSON(200, result)
+	})
+}
+
+func promAlertsReader(svc *service.PrometheusService) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		result, err := svc.PromAlertsService()
+		if err != nil {
+			c.JSON(500, &model.PromQueryResponse{Error: err.Error(), Status: _STATUS_FAIL})
+			return
+		}
+		c.JSON(200, result)
+	})
+}
+
 func promSeriesReader(svc *service.PrometheusService) gin.HandlerFunc {
 	return gin.HandlerFunc(func(c *gin.Context) {
 		args := model.PromQueryParams{
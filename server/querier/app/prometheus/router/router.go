@@ -18,17 +18,25 @@ package router
 
 import (
 	"github.com/gin-gonic/gin"
+	logging "github.com/op/go-logging"
 
 	"github.com/deepflowio/deepflow/server/querier/app/prometheus/router/packet_adapter"
 	"github.com/deepflowio/deepflow/server/querier/app/prometheus/service"
 	"github.com/deepflowio/deepflow/server/querier/config"
 )
 
+var log = logging.MustGetLogger("prometheus.router")
+
 func PrometheusRouter(e *gin.Engine) {
 	// only one instance during server lifetime
 	prometheusService := service.NewPrometheusService()
 	// Both SetRate and Acquire are expanded by 1000 times, making it suitable for small QPS scenarios.
 	prometheusService.QPSLeakyBucket.Init(uint64(config.Cfg.Prometheus.QPSLimit * 1000))
+	if config.Cfg.Prometheus.Rules.Enabled {
+		if err := prometheusService.StartRuleEvaluation(); err != nil {
+			log.Errorf("start prometheus rule evaluation failed: %s", err)
+		}
+	}
 
 	// api router for prometheus
 	e.POST("/api/v1/prom/read", Limiter(prometheusService.QPSLeakyBucket), promReader(prometheusService))
@@ -44,6 +52,13 @@ func PrometheusRouter(e *gin.Engine) {
 		promGroup.GET("/api/v1/series", promSeriesReader(prometheusService))
 		promGroup.POST("/api/v1/series", promSeriesReader(prometheusService))
 		promGroup.GET("/api/v1/label/:labelName/values", promTagValuesReader(prometheusService))
+		promGroup.GET("/api/v1/labels", promLabelsReader(prometheusService))
+		promGroup.POST("/api/v1/labels", promLabelsReader(prometheusService))
+		promGroup.GET("/api/v1/metadata", promMetadataReader(prometheusService))
+		promGroup.GET("/api/v1/query_exemplars", promExemplarsReader(prometheusService))
+		promGroup.POST("/api/v1/query_exemplars", promExemplarsReader(prometheusService))
+		promGroup.GET("/api/v1/rules", promRulesReader(prometheusService))
+		promGroup.GET("/api/v1/alerts", promAlertsReader(prometheusService))
 
 		// not use "/prom/api/v1/adapter/:name", suitable for map[rouer key]counter in statsd
 		for _, v := range []string{"label", "query_range", "query", "series"} {
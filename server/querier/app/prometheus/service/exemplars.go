@@ -0,0 +1,245 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/deepflowio/deepflow/server/querier/app/prometheus/model"
+	"github.com/deepflowio/deepflow/server/querier/common"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse"
+	chCommon "github.com/deepflowio/deepflow/server/querier/engine/clickhouse/common"
+	"github.com/deepflowio/deepflow/server/querier/tempo"
+)
+
+const (
+	EXEMPLAR_LABEL_TRACE_ID = "trace_id"
+	EXEMPLAR_LABEL_SPAN_ID  = "span_id"
+	// the max number of spans of a service to pick the exemplars from, the slowest span of each step is the exemplar
+	EXEMPLARS_SPAN_LIMIT_PER_SERVICE = 10000
+	// the exemplars are picked in at most EXEMPLARS_MAX_STEPS steps, and the step is at least EXEMPLARS_MIN_STEP
+	EXEMPLARS_MAX_STEPS = 100
+	EXEMPLARS_MIN_STEP  = 15 * time.Second
+)
+
+// the labels of the series which identify the `app_service` of l7_flow_log, in order of priority:
+// - DeepFlow metrics: app_service
+// - OpenTelemetry metrics: service_name
+// - Prometheus metrics: service, job
+var exemplarServiceLabels = []string{"app_service", "service_name", "service", "job"}
+
+// exemplarSpan is a span with trace_id of l7_flow_log
+type exemplarSpan struct {
+	traceID     string
+	spanID      string
+	startTimeUs int64
+}
+
+// API Spec: https://prometheus.io/docs/prometheus/latest/querying/api/#querying-exemplars
+// The exemplars are not stored with the samples, they are the spans in `l7_flow_log` of the same service and time
+// window of the series, so that the metrics of Prometheus and DeepFlow could be linked to the traces by `trace_id`.
+// The time range is split into steps, the slowest span in each step is the exemplar of the step, and the value of
+// the exemplar is the sample of the series at the step.
+func (p *prometheusExecutor) queryExemplars(ctx context.Context, args *model.PromQueryParams, engine *promql.Engine) (result *model.PromQueryResponse, err error) {
+	start, err := parseTime(args.StartTime)
+	if err != nil {
+		log.Errorf("Parse StartTime failed: %v", err)
+		return nil, err
+	}
+	end, err := parseTime(args.EndTime)
+	if err != nil {
+		log.Errorf("Parse EndTime failed: %v", err)
+		return nil, err
+	}
+	expr, err := parser.ParseExpr(args.Promql)
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	selectors := parser.ExtractSelectors(expr)
+	data := []model.PromExemplarQueryResult{}
+	if len(selectors) == 0 {
+		return &model.PromQueryResponse{Data: data, Status: _SUCCESS}, nil
+	}
+	step := getExemplarStep(start, end)
+
+	// query the samples of the series at each step, and group the series by service, the series without service
+	// have no exemplar
+	services := []string{}
+	seriesOfService := map[string][]promql.Series{}
+	seriesSeen := map[string]bool{}
+	for _, selector := range selectors {
+		selectorMatchers := make([]string, 0, len(selector))
+		for _, m := range selector {
+			selectorMatchers = append(selectorMatchers, m.String())
+		}
+		seriesResult, err := p.promQueryRangeExecute(ctx, &model.PromQueryParams{
+			Promql:    "{" + strings.Join(selectorMatchers, ",") + "}",
+			StartTime: args.StartTime,
+			EndTime:   args.EndTime,
+			Step:      strconv.FormatFloat(step.Seconds(), 'f', -1, 64),
+			Debug:     args.Debug,
+			Context:   args.Context,
+		}, engine)
+		if err != nil {
+			return nil, err
+		}
+		matrix, ok := seriesResult.Data.(*model.PromQueryData).Result.(promql.Matrix)
+		if !ok {
+			continue
+		}
+		for _, series := range matrix {
+			service := getExemplarService(series.Metric)
+			if service == "" || seriesSeen[series.Metric.String()] {
+				continue
+			}
+			seriesSeen[series.Metric.String()] = true
+			if _, ok := seriesOfService[service]; !ok {
+				services = append(services, service)
+			}
+			seriesOfService[service] = append(seriesOfService[service], series)
+		}
+	}
+	for _, service := range services {
+		spans, err := queryServiceExemplarSpans(ctx, service, start.Unix(), end.Unix())
+		if err != nil {
+			return nil, err
+		}
+		stepSpans := groupExemplarSpansByStep(spans, start, step)
+		if len(stepSpans) == 0 {
+			continue
+		}
+		for _, series := range seriesOfService[service] {
+			exemplars := toPromExemplars(series.Points, stepSpans)
+			if len(exemplars) == 0 {
+				continue
+			}
+			data = append(data, model.PromExemplarQueryResult{SeriesLabels: series.Metric, Exemplars: exemplars})
+		}
+	}
+	return &model.PromQueryResponse{Data: data, Status: _SUCCESS}, nil
+}
+
+func getExemplarService(series labels.Labels) string {
+	for _, name := range exemplarServiceLabels {
+		if service := series.Get(name); service != "" {
+			return service
+		}
+	}
+	return ""
+}
+
+func getExemplarStep(start, end time.Time) time.Duration {
+	step := end.Sub(start) / EXEMPLARS_MAX_STEPS
+	if step < EXEMPLARS_MIN_STEP {
+		return EXEMPLARS_MIN_STEP
+	}
+	return step.Truncate(time.Second)
+}
+
+// queryServiceExemplarSpans returns the spans with trace_id of the service in the time window, the slowest first
+func queryServiceExemplarSpans(ctx context.Context, service string, start, end int64) ([]exemplarSpan, error) {
+	sql := fmt.Sprintf(
+		"SELECT trace_id, span_id, toUnixTimestamp64Micro(start_time) AS start_time_us, response_duration FROM %s "+
+			"WHERE time>=%d AND time<=%d AND %s=%s AND trace_id!='' ORDER BY response_duration DESC LIMIT %d",
+		tempo.TABLE_NAME_L7_FLOW_LOG, start, end, tempo.L7_FLOW_LOG_SERVICE_NAME, tempo.EscapeSQLString(service), EXEMPLARS_SPAN_LIMIT_PER_SERVICE,
+	)
+	querierArgs := common.QuerierParams{
+		DB:         chCommon.DB_NAME_FLOW_LOG,
+		Sql:        sql,
+		DataSource: "",
+		Debug:      "false",
+		QueryUUID:  uuid.NewString(),
+		Context:    ctx,
+	}
+	ckEngine := &clickhouse.CHEngine{DB: querierArgs.DB, DataSource: querierArgs.DataSource}
+	ckEngine.Init()
+	result, debug, err := ckEngine.ExecuteQuery(&querierArgs)
+	if err != nil {
+		log.Errorf("ExecuteQuery failed, debug info = %v, err info = %v", debug, err)
+		return nil, err
+	}
+	return toExemplarSpans(result), nil
+}
+
+func toExemplarSpans(result *common.Result) []exemplarSpan {
+	spans := []exemplarSpan{}
+	if result == nil {
+		return spans
+	}
+	for _, v := range result.Values {
+		value, ok := v.([]interface{})
+		if !ok || len(value) < 3 {
+			continue
+		}
+		span := exemplarSpan{}
+		span.traceID, _ = value[0].(string)
+		span.spanID, _ = value[1].(string)
+		startTimeUs, _ := value[2].(int)
+		span.startTimeUs = int64(startTimeUs)
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+// groupExemplarSpansByStep returns the first span of each step, keyed by the timestamp of the step in milliseconds.
+// The sample at a step is evaluated from the samples before it, so a span belongs to the first step not before it.
+func groupExemplarSpansByStep(spans []exemplarSpan, start time.Time, step time.Duration) map[int64]exemplarSpan {
+	stepSpans := map[int64]exemplarSpan{}
+	startMs, stepUs := start.UnixMilli(), step.Microseconds()
+	for _, span := range spans {
+		index := int64(0)
+		if offsetUs := span.startTimeUs - startMs*1000; offsetUs > 0 {
+			index = (offsetUs + stepUs - 1) / stepUs
+		}
+		stepTime := startMs + index*step.Milliseconds()
+		if _, ok := stepSpans[stepTime]; !ok {
+			stepSpans[stepTime] = span
+		}
+	}
+	return stepSpans
+}
+
+// toPromExemplars returns the exemplars of the series, the value of each exemplar is the sample at its step
+func toPromExemplars(points []promql.Point, stepSpans map[int64]exemplarSpan) []model.PromExemplar {
+	exemplars := []model.PromExemplar{}
+	for _, point := range points {
+		span, ok := stepSpans[point.T]
+		if !ok {
+			continue
+		}
+		exemplarLabels := []labels.Label{{Name: EXEMPLAR_LABEL_TRACE_ID, Value: span.traceID}}
+		if span.spanID != "" {
+			exemplarLabels = append(exemplarLabels, labels.Label{Name: EXEMPLAR_LABEL_SPAN_ID, Value: span.spanID})
+		}
+		exemplars = append(exemplars, model.PromExemplar{
+			Labels:    labels.New(exemplarLabels...),
+			Value:     strconv.FormatFloat(point.V, 'f', -1, 64),
+			Timestamp: float64(span.startTimeUs) / 1e6,
+		})
+	}
+	return exemplars
+}
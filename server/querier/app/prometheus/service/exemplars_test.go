@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+
+	"github.com/deepflowio/deepflow/server/querier/app/prometheus/model"
+	"github.com/deepflowio/deepflow/server/querier/common"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/metrics"
+)
+
+func TestGetExemplarService(t *testing.T) {
+	cases := []struct {
+		series  labels.Labels
+		service string
+	}{
+		{labels.FromStrings("__name__", "flow_log__l7_flow_log__rrt", "app_service", "svc-a", "job", "job-a"), "svc-a"},
+		{labels.FromStrings("__name__", "http_requests_total", "service_name", "svc-b"), "svc-b"},
+		{labels.FromStrings("__name__", "http_requests_total", "job", "job-c"), "job-c"},
+		{labels.FromStrings("__name__", "up", "instance", "127.0.0.1:9090"), ""},
+	}
+	for _, c := range cases {
+		if service := getExemplarService(c.series); service != c.service {
+			t.Errorf("series %s: expected %s, got %s", c.series, c.service, service)
+		}
+	}
+}
+
+func TestToExemplarSpans(t *testing.T) {
+	result := &common.Result{
+		Columns: []interface{}{"trace_id", "span_id", "start_time_us", "response_duration"},
+		Values: []interface{}{
+			[]interface{}{"trace-1", "span-1", 1700000000500000, 1500000},
+			[]interface{}{"trace-2", "", 1700000001000000, 20},
+		},
+	}
+	expected := []exemplarSpan{
+		{traceID: "trace-1", spanID: "span-1", startTimeUs: 1700000000500000},
+		{traceID: "trace-2", startTimeUs: 1700000001000000},
+	}
+	if spans := toExemplarSpans(result); !reflect.DeepEqual(spans, expected) {
+		t.Errorf("expected %v, got %v", expected, spans)
+	}
+	if spans := toExemplarSpans(nil); len(spans) != 0 {
+		t.Errorf("expected no span, got %v", spans)
+	}
+}
+
+func TestGetExemplarStep(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	if step := getExemplarStep(start, start.Add(time.Hour)); step != 36*time.Second {
+		t.Errorf("expected 36s, got %v", step)
+	}
+	if step := getExemplarStep(start, start.Add(5*time.Minute)); step != EXEMPLARS_MIN_STEP {
+		t.Errorf("expected %v, got %v", EXEMPLARS_MIN_STEP, step)
+	}
+}
+
+func TestToPromExemplars(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	// the spans are ordered by the response duration, the slowest span of each step is picked
+	spans := []exemplarSpan{
+		{traceID: "trace-1", spanID: "span-1", startTimeUs: 1700000025000000},
+		{traceID: "trace-2", startTimeUs: 1700000000000000},
+		{traceID: "trace-3", startTimeUs: 1700000050000000},
+		{traceID: "trace-4", startTimeUs: 1700000030000000},
+		{traceID: "trace-5", startTimeUs: 1700000090000000},
+	}
+	stepSpans := groupExemplarSpansByStep(spans, start, 30*time.Second)
+	points := []promql.Point{
+		{T: 1700000000000, V: 1},
+		{T: 1700000030000, V: 2.5},
+		{T: 1700000060000, V: 3},
+	}
+	expected := []model.PromExemplar{
+		{Labels: labels.FromStrings("trace_id", "trace-2"), Value: "1", Timestamp: 1700000000},
+		{Labels: labels.FromStrings("trace_id", "trace-1", "span_id", "span-1"), Value: "2.5", Timestamp: 1700000025},
+		{Labels: labels.FromStrings("trace_id", "trace-3"), Value: "3", Timestamp: 1700000050},
+	}
+	if exemplars := toPromExemplars(points, stepSpans); !reflect.DeepEqual(exemplars, expected) {
+		t.Errorf("expected %v, got %v", expected, exemplars)
+	}
+}
+
+func TestToPromMetricType(t *testing.T) {
+	for metricsType, promType := range map[int]string{
+		metrics.METRICS_TYPE_COUNTER: PROM_METRIC_TYPE_COUNTER,
+		metrics.METRICS_TYPE_DELAY:   PROM_METRIC_TYPE_GAUGE,
+		metrics.METRICS_TYPE_TAG:     PROM_METRIC_TYPE_UNKNOWN,
+	} {
+		if got := toPromMetricType(metricsType); got != promType {
+			t.Errorf("metrics type %d: expected %s, got %s", metricsType, promType, got)
+		}
+	}
+}
@@ -19,10 +19,15 @@ package service
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
+	"github.com/prometheus/prometheus/model/labels"
+
 	"github.com/deepflowio/deepflow/server/querier/app/prometheus/model"
+	"github.com/deepflowio/deepflow/server/querier/config"
 	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/client"
 	chCommon "github.com/deepflowio/deepflow/server/querier/engine/clickhouse/common"
 	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/metrics"
 )
@@ -38,6 +43,12 @@ const (
 	METRICS_CATEGORY_TAG    = "Tag"
 )
 
+const (
+	PROM_METRIC_TYPE_COUNTER = "counter"
+	PROM_METRIC_TYPE_GAUGE   = "gauge"
+	PROM_METRIC_TYPE_UNKNOWN = "unknown"
+)
+
 func (p *prometheusExecutor) getTagValues(ctx context.Context, args *model.PromMetaParams) (result *model.PromQueryResponse, err error) {
 	if args.LabelName == LABEL_NAME_METRICS {
 		return &model.PromQueryResponse{
@@ -48,6 +59,41 @@ func (p *prometheusExecutor) getTagValues(ctx context.Context, args *model.PromM
 }
 
 func getMetrics(ctx context.Context, args *model.PromMetaParams) (resp []string) {
+	metricsMetadata := getMetricsMetadata(ctx, args)
+	resp = make([]string, 0, len(metricsMetadata))
+	for _, m := range metricsMetadata {
+		resp = append(resp, m.name)
+	}
+	return resp
+}
+
+type promMetricMetadata struct {
+	name     string
+	metadata model.PromMetricMetadata
+}
+
+// convert DeepFlow metrics type to Prometheus metric type, the types not exists in Prometheus are `unknown`
+func toPromMetricType(metricsType int) string {
+	switch metricsType {
+	case metrics.METRICS_TYPE_COUNTER:
+		return PROM_METRIC_TYPE_COUNTER
+	case metrics.METRICS_TYPE_GAUGE, metrics.METRICS_TYPE_BOUNDED_GAUGE, metrics.METRICS_TYPE_DELAY,
+		metrics.METRICS_TYPE_PERCENTAGE, metrics.METRICS_TYPE_QUOTIENT:
+		return PROM_METRIC_TYPE_GAUGE
+	default:
+		return PROM_METRIC_TYPE_UNKNOWN
+	}
+}
+
+func newPromMetricMetadata(name string, m *metrics.Metrics) promMetricMetadata {
+	help := m.Description
+	if help == "" {
+		help = m.DisplayName
+	}
+	return promMetricMetadata{name: name, metadata: model.PromMetricMetadata{Type: toPromMetricType(m.Type), Help: help, Unit: m.Unit}}
+}
+
+func getMetricsMetadata(ctx context.Context, args *model.PromMetaParams) (resp []promMetricMetadata) {
 	// We speed up the return of the metrics list by querying the aggregation information in
 	// `flow_tag.ext_metrics_custom_field_value`. Since we do not query the original time series
 	// data, filtering metrics by time is currently not supported.
@@ -60,31 +106,33 @@ func getMetrics(ctx context.Context, args *model.PromMetaParams) (resp []string)
 	//	where = fmt.Sprintf("time<=%s", args.EndTime)
 	//}
 
-	resp = []string{}
+	resp = []promMetricMetadata{}
 	for db, tables := range chCommon.DB_TABLE_MAP {
 		if db == DB_NAME_EXT_METRICS {
 			extMetrics, _ := metrics.GetExtMetrics(DB_NAME_EXT_METRICS, "", where, args.Context)
 			for _, v := range extMetrics {
 				// append telegraf metrics, e.g.: influxdb_internal_statsd__tcp_current_connections[influxdb_target__metric]
 				metricName := fmt.Sprintf("%s__%s__%s__%s", db, "metrics", strings.Replace(v.Table, ".", "_", 1), strings.TrimPrefix(v.DisplayName, "metrics."))
-				resp = append(resp, metricName)
+				resp = append(resp, newPromMetricMetadata(metricName, v))
 			}
 		} else if db == chCommon.DB_NAME_PROMETHEUS {
 			// prometheus samples should get all metrcis from `table`
+			// the type and help of prometheus metrics are not stored, they are all `unknown`
 			samples := clickhouse.GetTables(db, ctx)
 			for _, v := range samples.Values {
 				tableName := v.([]interface{})[0].(string)
+				metadata := model.PromMetricMetadata{Type: PROM_METRIC_TYPE_UNKNOWN}
 				// append ${metrics_name}
-				resp = append(resp, tableName)
+				resp = append(resp, promMetricMetadata{name: tableName, metadata: metadata})
 				// append prometheus__samples__${metrics_name}
 				metricsName := fmt.Sprintf("%s__%s__%s", db, TABLE_NAME_SAMPLES, tableName)
-				resp = append(resp, metricsName)
+				resp = append(resp, promMetricMetadata{name: metricsName, metadata: metadata})
 			}
 		} else if db == DB_NAME_DEEPFLOW_SYSTEM {
 			deepflowSystem, _ := metrics.GetExtMetrics(DB_NAME_DEEPFLOW_SYSTEM, "", where, args.Context)
 			for _, v := range deepflowSystem {
 				metricName := fmt.Sprintf("%s__%s__%s", db, strings.ReplaceAll(v.Table, ".", "_"), strings.TrimPrefix(v.DisplayName, "metrics."))
-				resp = append(resp, metricName)
+				resp = append(resp, newPromMetricMetadata(metricName, v))
 			}
 		} else {
 			for _, table := range tables {
@@ -99,12 +147,12 @@ func getMetrics(ctx context.Context, args *model.PromMetaParams) (resp []string)
 					metricsName := ""
 					if db == DB_NAME_FLOW_METRICS {
 						metricsName = fmt.Sprintf("%s__%s__%s__%s", db, table, field, "1m")
-						resp = append(resp, metricsName)
+						resp = append(resp, newPromMetricMetadata(metricsName, v))
 						metricsName = fmt.Sprintf("%s__%s__%s__%s", db, table, field, "1s")
-						resp = append(resp, metricsName)
+						resp = append(resp, newPromMetricMetadata(metricsName, v))
 					} else {
 						metricsName = fmt.Sprintf("%s__%s__%s", db, table, field)
-						resp = append(resp, metricsName)
+						resp = append(resp, newPromMetricMetadata(metricsName, v))
 					}
 				}
 			}
@@ -112,3 +160,76 @@ func getMetrics(ctx context.Context, args *model.PromMetaParams) (resp []string)
 	}
 	return resp
 }
+
+// API Spec: https://prometheus.io/docs/prometheus/latest/querying/api/#querying-metric-metadata
+func (p *prometheusExecutor) getMetadata(ctx context.Context, args *model.PromMetaParams) (result *model.PromQueryResponse, err error) {
+	data := map[string][]model.PromMetricMetadata{}
+	for _, m := range getMetricsMetadata(ctx, args) {
+		if args.Metric != "" && m.name != args.Metric {
+			continue
+		}
+		if _, ok := data[m.name]; !ok && args.Limit > 0 && len(data) >= args.Limit {
+			continue
+		}
+		data[m.name] = append(data[m.name], m.metadata)
+	}
+	return &model.PromQueryResponse{Data: data, Status: _SUCCESS}, nil
+}
+
+// API Spec: https://prometheus.io/docs/prometheus/latest/querying/api/#getting-label-names
+func (p *prometheusExecutor) getLabelNames(ctx context.Context, args *model.PromMetaParams) (result *model.PromQueryResponse, err error) {
+	labelNames := []string{}
+	if len(args.Matchers) > 0 {
+		// get the label names of the matched series
+		seriesResult, err := p.series(ctx, &model.PromQueryParams{
+			StartTime: args.StartTime,
+			EndTime:   args.EndTime,
+			Matchers:  args.Matchers,
+			Context:   args.Context,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, series := range seriesResult.Data.([]labels.Labels) {
+			for _, l := range series {
+				labelNames = appendWithoutDuplicated(&labelNames, l.Name)
+			}
+		}
+		sort.Strings(labelNames)
+		return &model.PromQueryResponse{Data: labelNames, Status: _SUCCESS}, nil
+	}
+
+	labelNames = append(labelNames, LABEL_NAME_METRICS)
+	chClient := client.Client{
+		Host:     config.Cfg.Clickhouse.Host,
+		Port:     config.Cfg.Clickhouse.Port,
+		UserName: config.Cfg.Clickhouse.User,
+		Password: config.Cfg.Clickhouse.Password,
+		DB:       "flow_tag",
+		Context:  ctx,
+	}
+	// the time of `prometheus_custom_field` is the last time the label is seen, the labels seen after the
+	// end time should also be returned, so only filter by the start time
+	where := ""
+	if args.StartTime != "" {
+		start, err := parseTime(args.StartTime)
+		if err != nil {
+			log.Errorf("Parse StartTime failed: %v", err)
+			return nil, err
+		}
+		where = fmt.Sprintf(" AND time>=%d", start.Unix())
+	}
+	sql := fmt.Sprintf("SELECT field_name FROM flow_tag.%s_custom_field WHERE field_type='tag'%s GROUP BY field_name ORDER BY field_name ASC", chCommon.DB_NAME_PROMETHEUS, where)
+	rst, err := chClient.DoQuery(&client.QueryParams{Sql: sql})
+	if err != nil {
+		log.Error(err)
+		return nil, err
+	}
+	for _, v := range rst.Values {
+		if labelName, ok := v.([]interface{})[0].(string); ok && labelName != LABEL_NAME_METRICS {
+			labelNames = append(labelNames, labelName)
+		}
+	}
+	sort.Strings(labelNames)
+	return &model.PromQueryResponse{Data: labelNames, Status: _SUCCESS}, nil
+}
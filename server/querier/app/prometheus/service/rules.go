@@ -0,0 +1,233 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/util/strutil"
+
+	prometheusConfig "github.com/deepflowio/deepflow/server/querier/app/prometheus/config"
+	"github.com/deepflowio/deepflow/server/querier/app/prometheus/model"
+	"github.com/deepflowio/deepflow/server/querier/config"
+)
+
+const (
+	RULE_TYPE_ALERTING  = "alerting"
+	RULE_TYPE_RECORDING = "recording"
+
+	RULE_TYPE_FILTER_ALERT  = "alert"
+	RULE_TYPE_FILTER_RECORD = "record"
+)
+
+// ruleEvaluator evaluates the recording and alerting rules in prometheus format by the prometheus rule manager,
+// the rules are queried through the same queryable as the PromQL api, the results of recording rules are written
+// to ext_metrics, and the alerts are sent to alertmanager
+type ruleEvaluator struct {
+	manager  *rules.Manager
+	notifier *alertmanagerNotifier
+}
+
+// ruleQueryable queries the time range of each select, as the RemoteReadQuerierable queries by the time range
+// of the args
+type ruleQueryable struct {
+	executor *prometheusExecutor
+}
+
+func (q *ruleQueryable) Querier(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+	args := &model.PromQueryParams{
+		StartTime: strconv.FormatFloat(float64(mint)/1e3, 'f', -1, 64),
+		EndTime:   strconv.FormatFloat(float64(maxt)/1e3, 'f', -1, 64),
+		Context:   ctx,
+	}
+	reader := &prometheusReader{
+		slimit:                  config.Cfg.Prometheus.SeriesLimit,
+		getExternalTagFromCache: q.executor.convertExternalTagToQuerierAllowTag,
+		addExternalTagToCache:   q.executor.addExtraLabelsToCache,
+	}
+	querierable := &RemoteReadQuerierable{Args: args, Ctx: ctx, reader: reader}
+	return querierable.Querier(ctx, mint, maxt)
+}
+
+func newRuleEvaluator(engine *promql.Engine, executor *prometheusExecutor, cfg *prometheusConfig.PrometheusRules) (*ruleEvaluator, error) {
+	files, err := ruleFiles(cfg.Files)
+	if err != nil {
+		return nil, err
+	}
+	externalURL, err := url.Parse(cfg.ExternalURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid external-url %s: %s", cfg.ExternalURL, err)
+	}
+
+	notifier := newAlertmanagerNotifier(cfg.AlertmanagerURLs, time.Duration(cfg.AlertmanagerTimeout)*time.Second)
+	manager := rules.NewManager(&rules.ManagerOptions{
+		ExternalURL: externalURL,
+		QueryFunc:   rules.EngineQueryFunc(engine, &ruleQueryable{executor: executor}),
+		NotifyFunc:  notifier.notifyFunc(cfg.ExternalURL),
+		Context:     context.Background(),
+		Appendable:  &extMetricsAppendable{},
+		// the `for` state of alerts is not restored, as ALERTS_FOR_STATE is not written back
+		Queryable: storage.QueryableFunc(func(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+			return storage.NoopQuerier(), nil
+		}),
+		Logger:      newPrometheusLogger(),
+		ResendDelay: time.Duration(cfg.ResendDelay) * time.Second,
+	})
+	if err := manager.Update(time.Duration(cfg.EvaluationInterval)*time.Second, files, labels.FromMap(cfg.ExternalLabels), cfg.ExternalURL, nil); err != nil {
+		return nil, err
+	}
+	log.Infof("load %d rule groups from %v", len(manager.RuleGroups()), files)
+	return &ruleEvaluator{manager: manager, notifier: notifier}, nil
+}
+
+// ruleFiles expands the glob patterns of the rule files
+func ruleFiles(patterns []string) ([]string, error) {
+	files := []string{}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule files %s: %s", pattern, err)
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+func (e *ruleEvaluator) run() {
+	go e.notifier.run()
+	go e.manager.Run()
+}
+
+// API Spec: https://prometheus.io/docs/prometheus/latest/querying/api/#rules
+func (e *ruleEvaluator) ruleGroups(typeFilter string) (*model.PromQueryResponse, error) {
+	if typeFilter != "" && typeFilter != RULE_TYPE_FILTER_ALERT && typeFilter != RULE_TYPE_FILTER_RECORD {
+		return nil, fmt.Errorf("invalid rule type filter %s", typeFilter)
+	}
+	groups := []model.PromRuleGroup{}
+	for _, g := range e.manager.RuleGroups() {
+		group := model.PromRuleGroup{
+			Name:           g.Name(),
+			File:           g.File(),
+			Rules:          []interface{}{},
+			Interval:       g.Interval().Seconds(),
+			EvaluationTime: g.GetEvaluationTime().Seconds(),
+			LastEvaluation: g.GetLastEvaluation(),
+		}
+		for _, r := range g.Rules() {
+			lastError := ""
+			if r.LastError() != nil {
+				lastError = r.LastError().Error()
+			}
+			switch rule := r.(type) {
+			case *rules.AlertingRule:
+				if typeFilter == RULE_TYPE_FILTER_RECORD {
+					continue
+				}
+				group.Rules = append(group.Rules, model.PromAlertingRule{
+					State:          rule.State().String(),
+					Name:           rule.Name(),
+					Query:          rule.Query().String(),
+					Duration:       rule.HoldDuration().Seconds(),
+					Labels:         rule.Labels(),
+					Annotations:    rule.Annotations(),
+					Alerts:         toPromAlerts(rule.ActiveAlerts()),
+					Health:         string(rule.Health()),
+					LastError:      lastError,
+					EvaluationTime: rule.GetEvaluationDuration().Seconds(),
+					LastEvaluation: rule.GetEvaluationTimestamp(),
+					Type:           RULE_TYPE_ALERTING,
+				})
+			case *rules.RecordingRule:
+				if typeFilter == RULE_TYPE_FILTER_ALERT {
+					continue
+				}
+				group.Rules = append(group.Rules, model.PromRecordingRule{
+					Name:           rule.Name(),
+					Query:          rule.Query().String(),
+					Labels:         rule.Labels(),
+					Health:         string(rule.Health()),
+					LastError:      lastError,
+					EvaluationTime: rule.GetEvaluationDuration().Seconds(),
+					LastEvaluation: rule.GetEvaluationTimestamp(),
+					Type:           RULE_TYPE_RECORDING,
+				})
+			}
+		}
+		if typeFilter != "" && len(group.Rules) == 0 {
+			continue
+		}
+		groups = append(groups, group)
+	}
+	return &model.PromQueryResponse{Data: map[string]interface{}{"groups": groups}, Status: _SUCCESS}, nil
+}
+
+// API Spec: https://prometheus.io/docs/prometheus/latest/querying/api/#alerts
+func (e *ruleEvaluator) alerts() (*model.PromQueryResponse, error) {
+	alerts := []*rules.Alert{}
+	for _, rule := range e.manager.AlertingRules() {
+		alerts = append(alerts, rule.ActiveAlerts()...)
+	}
+	return &model.PromQueryResponse{Data: map[string]interface{}{"alerts": toPromAlerts(alerts)}, Status: _SUCCESS}, nil
+}
+
+func toPromAlerts(alerts []*rules.Alert) []model.PromAlert {
+	result := make([]model.PromAlert, 0, len(alerts))
+	for _, alert := range alerts {
+		activeAt := alert.ActiveAt
+		result = append(result, model.PromAlert{
+			Labels:      alert.Labels,
+			Annotations: alert.Annotations,
+			State:       alert.State.String(),
+			ActiveAt:    &activeAt,
+			Value:       strconv.FormatFloat(alert.Value, 'e', -1, 64),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return labels.Compare(result[i].Labels, result[j].Labels) < 0
+	})
+	return result
+}
+
+// toAlertmanagerAlerts converts the alerts to the format of alertmanager api, refer to `sendAlerts` of prometheus
+func toAlertmanagerAlerts(externalURL, expr string, alerts []*rules.Alert) []model.PromAlertmanagerAlert {
+	result := make([]model.PromAlertmanagerAlert, 0, len(alerts))
+	for _, alert := range alerts {
+		a := model.PromAlertmanagerAlert{
+			StartsAt:     alert.FiredAt,
+			Labels:       alert.Labels,
+			Annotations:  alert.Annotations,
+			GeneratorURL: externalURL + strutil.TableLinkForExpression(expr),
+		}
+		if !alert.ResolvedAt.IsZero() {
+			a.EndsAt = alert.ResolvedAt
+		} else {
+			a.EndsAt = alert.ValidUntil
+		}
+		result = append(result, a)
+	}
+	return result
+}
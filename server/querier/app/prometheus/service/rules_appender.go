@@ -0,0 +1,174 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+
+	"github.com/deepflowio/deepflow/server/querier/config"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/client"
+	chCommon "github.com/deepflowio/deepflow/server/querier/engine/clickhouse/common"
+)
+
+const (
+	extMetricsInsertSQL       = "INSERT INTO ext_metrics.metrics (time, virtual_table_name, tag_names, tag_values, metrics_float_names, metrics_float_values)"
+	customFieldInsertSQL      = "INSERT INTO flow_tag.ext_metrics_custom_field (time, table, vpc_id, pod_ns_id, field_type, field_name, field_value_type)"
+	customFieldValueInsertSQL = "INSERT INTO flow_tag.ext_metrics_custom_field_value (time, table, vpc_id, pod_ns_id, field_type, field_name, field_value_type, field_value, count)"
+
+	// same as the flow_tag cache flush timeout of ingester, the time of the custom fields is refreshed periodically,
+	// so that the labels of recording rules could be found by the label names and values api
+	customFieldRefreshInterval = 30 * time.Minute
+
+	// the synthetic series of alerts written by the rule manager, same as prometheus/rules
+	alertMetricName         = "ALERTS"
+	alertForStateMetricName = "ALERTS_FOR_STATE"
+)
+
+// extMetricsAppendable writes the results of recording rules to ext_metrics, the metric `name` could be queried
+// as `ext_metrics__metrics__prometheus_name` in the same way as the metrics from remote write
+type extMetricsAppendable struct {
+	sync.Mutex
+	// key: table, field type, field name, field value, value: last written time
+	customFields map[[4]string]time.Time
+}
+
+func (a *extMetricsAppendable) Appender(ctx context.Context) storage.Appender {
+	return &extMetricsAppender{ctx: ctx, appendable: a}
+}
+
+// newCustomFields returns the custom fields not written in the refresh interval, and records them as written
+func (a *extMetricsAppendable) newCustomFields(fields [][4]string, now time.Time) [][4]string {
+	a.Lock()
+	defer a.Unlock()
+	if a.customFields == nil {
+		a.customFields = make(map[[4]string]time.Time)
+	}
+	result := [][4]string{}
+	for _, field := range fields {
+		if last, ok := a.customFields[field]; ok && now.Sub(last) < customFieldRefreshInterval {
+			continue
+		}
+		a.customFields[field] = now
+		result = append(result, field)
+	}
+	return result
+}
+
+type extMetricsAppender struct {
+	ctx        context.Context
+	appendable *extMetricsAppendable
+	rows       [][]interface{}
+	fields     [][4]string
+}
+
+func (a *extMetricsAppender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	row, fields := extMetricsRow(l, t, v)
+	if row != nil {
+		a.rows = append(a.rows, row)
+		a.fields = append(a.fields, fields...)
+	}
+	return ref, nil
+}
+
+func (a *extMetricsAppender) AppendExemplar(ref storage.SeriesRef, l labels.Labels, e exemplar.Exemplar) (storage.SeriesRef, error) {
+	return ref, nil
+}
+
+func (a *extMetricsAppender) Commit() error {
+	if len(a.rows) == 0 {
+		return nil
+	}
+	chClient := client.Client{
+		Host:     config.Cfg.Clickhouse.Host,
+		Port:     config.Cfg.Clickhouse.Port,
+		UserName: config.Cfg.Clickhouse.User,
+		Password: config.Cfg.Clickhouse.Password,
+		DB:       "ext_metrics",
+		Context:  a.ctx,
+	}
+	if err := chClient.DoBatchInsert(extMetricsInsertSQL, a.rows); err != nil {
+		return fmt.Errorf("write recording rule results failed: %s", err)
+	}
+	fields := a.appendable.newCustomFields(a.fields, time.Now())
+	if len(fields) == 0 {
+		return nil
+	}
+	fieldRows, fieldValueRows := customFieldRows(fields, time.Now())
+	if err := chClient.DoBatchInsert(customFieldInsertSQL, fieldRows); err != nil {
+		return fmt.Errorf("write custom fields failed: %s", err)
+	}
+	if err := chClient.DoBatchInsert(customFieldValueInsertSQL, fieldValueRows); err != nil {
+		return fmt.Errorf("write custom field values failed: %s", err)
+	}
+	return nil
+}
+
+func (a *extMetricsAppender) Rollback() error {
+	a.rows = nil
+	a.fields = nil
+	return nil
+}
+
+// extMetricsRow converts a sample to the row of ext_metrics and the custom fields of the row, the series of
+// alerts and the stale markers are not written
+func extMetricsRow(l labels.Labels, t int64, v float64) ([]interface{}, [][4]string) {
+	name := l.Get(labels.MetricName)
+	if name == "" || name == alertMetricName || name == alertForStateMetricName || value.IsStaleNaN(v) {
+		return nil, nil
+	}
+	table := fmt.Sprintf("%s.%s", chCommon.DB_NAME_PROMETHEUS, name)
+	tagNames := make([]string, 0, len(l)-1)
+	tagValues := make([]string, 0, len(l)-1)
+	fields := [][4]string{{table, "metrics", name, ""}}
+	for _, label := range l {
+		if label.Name == labels.MetricName {
+			continue
+		}
+		tagNames = append(tagNames, label.Name)
+		tagValues = append(tagValues, label.Value)
+		fields = append(fields, [4]string{table, "tag", label.Name, label.Value})
+	}
+	row := []interface{}{time.UnixMilli(t), table, tagNames, tagValues, []string{name}, []float64{v}}
+	return row, fields
+}
+
+func customFieldRows(fields [][4]string, now time.Time) (fieldRows, fieldValueRows [][]interface{}) {
+	written := make(map[[3]string]bool)
+	for _, field := range fields {
+		table, fieldType, fieldName, fieldValue := field[0], field[1], field[2], field[3]
+		valueType := "string"
+		if fieldType == "metrics" {
+			valueType = "float"
+		}
+		if key := [3]string{table, fieldType, fieldName}; !written[key] {
+			written[key] = true
+			fieldRows = append(fieldRows, []interface{}{now, table, int32(0), uint16(0), fieldType, fieldName, valueType})
+		}
+		if fieldType == "tag" {
+			fieldValueRows = append(fieldValueRows, []interface{}{now, table, int32(0), uint16(0), fieldType, fieldName, valueType, fieldValue, uint64(1)})
+		}
+	}
+	return
+}
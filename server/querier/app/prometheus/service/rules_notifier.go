@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/prometheus/rules"
+
+	"github.com/deepflowio/deepflow/server/querier/app/prometheus/model"
+)
+
+const (
+	alertmanagerAPIPath   = "/api/v2/alerts"
+	alertmanagerQueueSize = 1024
+)
+
+// alertmanagerNotifier posts the alerts to alertmanagers asynchronously, the alerts are dropped when the
+// queue is full, as they are resent by the rule manager after the resend delay
+type alertmanagerNotifier struct {
+	urls   []string
+	client *http.Client
+	queue  chan []model.PromAlertmanagerAlert
+}
+
+func newAlertmanagerNotifier(urls []string, timeout time.Duration) *alertmanagerNotifier {
+	return &alertmanagerNotifier{
+		urls:   urls,
+		client: &http.Client{Timeout: timeout},
+		queue:  make(chan []model.PromAlertmanagerAlert, alertmanagerQueueSize),
+	}
+}
+
+func (n *alertmanagerNotifier) notifyFunc(externalURL string) rules.NotifyFunc {
+	return func(ctx context.Context, expr string, alerts ...*rules.Alert) {
+		if len(n.urls) == 0 || len(alerts) == 0 {
+			return
+		}
+		select {
+		case n.queue <- toAlertmanagerAlerts(externalURL, expr, alerts):
+		default:
+			log.Warningf("alertmanager queue is full, drop %d alerts of %s", len(alerts), expr)
+		}
+	}
+}
+
+func (n *alertmanagerNotifier) run() {
+	for alerts := range n.queue {
+		body, err := json.Marshal(alerts)
+		if err != nil {
+			log.Errorf("marshal alerts failed: %s", err)
+			continue
+		}
+		for _, url := range n.urls {
+			if err := n.send(url, body); err != nil {
+				log.Errorf("send %d alerts to alertmanager %s failed: %s", len(alerts), url, err)
+			}
+		}
+	}
+}
+
+func (n *alertmanagerNotifier) send(url string, body []byte) error {
+	request, err := http.NewRequest("POST", strings.TrimSuffix(url, "/")+alertmanagerAPIPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := n.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, response.Body)
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("bad response status %s", response.Status)
+	}
+	return nil
+}
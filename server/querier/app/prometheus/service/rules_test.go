@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/rules"
+
+	prometheusConfig "github.com/deepflowio/deepflow/server/querier/app/prometheus/config"
+	"github.com/deepflowio/deepflow/server/querier/app/prometheus/model"
+)
+
+func TestExtMetricsRow(t *testing.T) {
+	ts := int64(1700000000500)
+	row, fields := extMetricsRow(labels.FromStrings("__name__", "job:up:sum", "job", "node"), ts, 3)
+	expectedRow := []interface{}{time.UnixMilli(ts), "prometheus.job:up:sum", []string{"job"}, []string{"node"}, []string{"job:up:sum"}, []float64{3}}
+	if !reflect.DeepEqual(row, expectedRow) {
+		t.Errorf("expected %v, got %v", expectedRow, row)
+	}
+	expectedFields := [][4]string{{"prometheus.job:up:sum", "metrics", "job:up:sum", ""}, {"prometheus.job:up:sum", "tag", "job", "node"}}
+	if !reflect.DeepEqual(fields, expectedFields) {
+		t.Errorf("expected %v, got %v", expectedFields, fields)
+	}
+
+	// the alerts and stale markers are not written
+	for _, l := range []labels.Labels{
+		labels.FromStrings("__name__", "ALERTS", "alertname", "InstanceDown"),
+		labels.FromStrings("__name__", "ALERTS_FOR_STATE", "alertname", "InstanceDown"),
+	} {
+		if row, _ := extMetricsRow(l, ts, 1); row != nil {
+			t.Errorf("series %s should not be written", l)
+		}
+	}
+	if row, _ := extMetricsRow(labels.FromStrings("__name__", "job:up:sum"), ts, math.Float64frombits(value.StaleNaN)); row != nil {
+		t.Errorf("stale marker should not be written")
+	}
+}
+
+func TestCustomFields(t *testing.T) {
+	a := &extMetricsAppendable{}
+	now := time.Unix(1700000000, 0)
+	fields := [][4]string{{"prometheus.m", "metrics", "m", ""}, {"prometheus.m", "tag", "job", "a"}, {"prometheus.m", "tag", "job", "b"}}
+	if newFields := a.newCustomFields(fields, now); len(newFields) != 3 {
+		t.Errorf("expected 3 new fields, got %v", newFields)
+	}
+	if newFields := a.newCustomFields(fields, now.Add(time.Minute)); len(newFields) != 0 {
+		t.Errorf("expected no new fields, got %v", newFields)
+	}
+	if newFields := a.newCustomFields(fields, now.Add(customFieldRefreshInterval)); len(newFields) != 3 {
+		t.Errorf("expected fields refreshed, got %v", newFields)
+	}
+
+	fieldRows, fieldValueRows := customFieldRows(fields, now)
+	if len(fieldRows) != 2 || len(fieldValueRows) != 2 {
+		t.Fatalf("expected 2 field rows and 2 field value rows, got %v, %v", fieldRows, fieldValueRows)
+	}
+	if fieldRows[0][6] != "float" || fieldRows[1][6] != "string" || fieldValueRows[1][7] != "b" {
+		t.Errorf("unexpected rows %v, %v", fieldRows, fieldValueRows)
+	}
+}
+
+func TestToAlertmanagerAlerts(t *testing.T) {
+	firedAt := time.Unix(1700000000, 0)
+	alerts := []*rules.Alert{
+		{Labels: labels.FromStrings("alertname", "a"), FiredAt: firedAt, ValidUntil: firedAt.Add(4 * time.Minute)},
+		{Labels: labels.FromStrings("alertname", "b"), FiredAt: firedAt, ValidUntil: firedAt.Add(4 * time.Minute), ResolvedAt: firedAt.Add(time.Minute)},
+	}
+	result := toAlertmanagerAlerts("http://deepflow", "up == 0", alerts)
+	if len(result) != 2 {
+		t.Fatalf("expected 2 alerts, got %v", result)
+	}
+	if !result[0].EndsAt.Equal(firedAt.Add(4*time.Minute)) || !result[1].EndsAt.Equal(firedAt.Add(time.Minute)) {
+		t.Errorf("unexpected endsAt %v, %v", result[0].EndsAt, result[1].EndsAt)
+	}
+	if result[0].GeneratorURL != "http://deepflow/graph?g0.expr=up+%3D%3D+0&g0.tab=1" {
+		t.Errorf("unexpected generatorURL %s", result[0].GeneratorURL)
+	}
+}
+
+func TestRuleGroups(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "rules.yaml")
+	content := `groups:
+- name: example
+  rules:
+  - record: job:up:sum
+    expr: sum by (job) (up)
+  - alert: InstanceDown
+    expr: up == 0
+    for: 5m
+    labels:
+      severity: page
+`
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	engine := promql.NewEngine(promql.EngineOpts{MaxSamples: 1000, Timeout: time.Minute})
+	cfg := &prometheusConfig.PrometheusRules{Files: []string{filepath.Join(filepath.Dir(file), "*.yaml")}, EvaluationInterval: 60}
+	e, err := newRuleEvaluator(engine, nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		typeFilter string
+		ruleTypes  []string
+	}{
+		{"", []string{RULE_TYPE_RECORDING, RULE_TYPE_ALERTING}},
+		{RULE_TYPE_FILTER_RECORD, []string{RULE_TYPE_RECORDING}},
+		{RULE_TYPE_FILTER_ALERT, []string{RULE_TYPE_ALERTING}},
+	}
+	for _, c := range cases {
+		result, err := e.ruleGroups(c.typeFilter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		groups := result.Data.(map[string]interface{})["groups"].([]model.PromRuleGroup)
+		if len(groups) != 1 || groups[0].Name != "example" || groups[0].Interval != 60 {
+			t.Fatalf("unexpected groups %v", groups)
+		}
+		ruleTypes := []string{}
+		for _, r := range groups[0].Rules {
+			switch rule := r.(type) {
+			case model.PromAlertingRule:
+				ruleTypes = append(ruleTypes, rule.Type)
+				if rule.Duration != 300 || rule.Labels.Get("severity") != "page" {
+					t.Errorf("unexpected alerting rule %v", rule)
+				}
+			case model.PromRecordingRule:
+				ruleTypes = append(ruleTypes, rule.Type)
+			}
+		}
+		if !reflect.DeepEqual(ruleTypes, c.ruleTypes) {
+			t.Errorf("type filter %s: expected %v, got %v", c.typeFilter, c.ruleTypes, ruleTypes)
+		}
+	}
+	if _, err := e.ruleGroups("unknown"); err == nil {
+		t.Errorf("expected error of unknown type filter")
+	}
+}
@@ -46,6 +46,8 @@ type PrometheusService struct {
 	executor *prometheusExecutor
 	// prometheus query rate limit
 	QPSLeakyBucket *datastructure.LeakyBucket
+	// nil if the rule evaluation is not enabled
+	ruleEvaluator *ruleEvaluator
 }
 
 func NewPrometheusService() *PrometheusService {
@@ -96,6 +98,43 @@ func (s *PrometheusService) PromLabelValuesService(args *model.PromMetaParams, c
 	return s.executor.getTagValues(ctx, args)
 }
 
+func (s *PrometheusService) PromLabelNamesService(args *model.PromMetaParams, ctx context.Context) (*model.PromQueryResponse, error) {
+	return s.executor.getLabelNames(ctx, args)
+}
+
+func (s *PrometheusService) PromMetadataService(args *model.PromMetaParams, ctx context.Context) (*model.PromQueryResponse, error) {
+	return s.executor.getMetadata(ctx, args)
+}
+
+func (s *PrometheusService) PromExemplarsQueryService(args *model.PromQueryParams, ctx context.Context) (*model.PromQueryResponse, error) {
+	return s.executor.queryExemplars(ctx, args, s.engine)
+}
+
+// StartRuleEvaluation loads the rule files and starts evaluating the rules periodically
+func (s *PrometheusService) StartRuleEvaluation() error {
+	evaluator, err := newRuleEvaluator(s.engine, s.executor, &config.Cfg.Prometheus.Rules)
+	if err != nil {
+		return err
+	}
+	evaluator.run()
+	s.ruleEvaluator = evaluator
+	return nil
+}
+
+func (s *PrometheusService) PromRulesService(typeFilter string) (*model.PromQueryResponse, error) {
+	if s.ruleEvaluator == nil {
+		return &model.PromQueryResponse{Data: map[string]interface{}{"groups": []model.PromRuleGroup{}}, Status: _SUCCESS}, nil
+	}
+	return s.ruleEvaluator.ruleGroups(typeFilter)
+}
+
+func (s *PrometheusService) PromAlertsService() (*model.PromQueryResponse, error) {
+	if s.ruleEvaluator == nil {
+		return &model.PromQueryResponse{Data: map[string]interface{}{"alerts": []model.PromAlert{}}, Status: _SUCCESS}, nil
+	}
+	return s.ruleEvaluator.alerts()
+}
+
 func (s *PrometheusService) PromSeriesQueryService(args *model.PromQueryParams, ctx context.Context) (*model.PromQueryResponse, error) {
 	return s.executor.series(ctx, args)
 }
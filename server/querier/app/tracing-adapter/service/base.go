@@ -32,6 +32,8 @@ func Register() error {
 		Adapters = make(map[string]model.TraceAdapter, 0)
 	}
 	Adapters["skywalking"] = &SkyWalkingAdapter{}
+	Adapters["jaeger"] = &JaegerAdapter{}
+	Adapters["zipkin"] = &ZipkinAdapter{}
 	subServices := packet_service.GetPacketServices()
 	if subServices != nil {
 		for k, v := range subServices {
@@ -0,0 +1,182 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/deepflowio/deepflow/server/querier/app/tracing-adapter/common"
+	"github.com/deepflowio/deepflow/server/querier/app/tracing-adapter/config"
+	"github.com/deepflowio/deepflow/server/querier/app/tracing-adapter/model"
+	"github.com/mitchellh/mapstructure"
+	"github.com/op/go-logging"
+)
+
+const (
+	// query trace by jaeger-query http api: GET /api/traces/{traceID}
+	jaeger_query_url = "api/traces"
+
+	JaegerRefChildOf     = "CHILD_OF"
+	JaegerRefFollowsFrom = "FOLLOWS_FROM"
+)
+
+// refer to: https://github.com/jaegertracing/jaeger/blob/main/model/json/model.go
+type jaegerTraceResponse struct {
+	Data   []jaegerTrace `json:"data"`
+	Errors []struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	} `json:"errors"`
+}
+
+type jaegerTrace struct {
+	TraceID   string                   `json:"traceID"`
+	Spans     []jaegerSpan             `json:"spans"`
+	Processes map[string]jaegerProcess `json:"processes"`
+}
+
+type jaegerSpan struct {
+	TraceID       string            `json:"traceID"`
+	SpanID        string            `json:"spanID"`
+	OperationName string            `json:"operationName"`
+	References    []jaegerReference `json:"references"`
+	StartTime     int64             `json:"startTime"` // microseconds
+	Duration      int64             `json:"duration"`  // microseconds
+	Tags          []jaegerKeyValue  `json:"tags"`
+	ProcessID     string            `json:"processID"`
+}
+
+type jaegerReference struct {
+	RefType string `json:"refType"`
+	TraceID string `json:"traceID"`
+	SpanID  string `json:"spanID"`
+}
+
+type jaegerProcess struct {
+	ServiceName string           `json:"serviceName"`
+	Tags        []jaegerKeyValue `json:"tags"`
+}
+
+type jaegerKeyValue struct {
+	Key   string `json:"key"`
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+type jaegerConfig struct {
+	Auth string `mapstructure:"auth"` // basic auth
+}
+
+type JaegerAdapter struct {
+}
+
+var log_jaeger = logging.MustGetLogger("tracing-adapter.jaeger")
+
+func (j *JaegerAdapter) GetTrace(traceID string, c *config.ExternalAPM) (*model.ExTrace, error) {
+	jaegerConfig := &jaegerConfig{}
+	err := mapstructure.Decode(c.ExtraConfig, jaegerConfig)
+	if err != nil {
+		log_jaeger.Errorf("cannot decode jaeger extra config %v, err: %s", c.ExtraConfig, err)
+		return nil, err
+	}
+	traces, err := j.getTrace(traceID, c, jaegerConfig)
+	if err != nil || traces == nil {
+		return nil, err
+	}
+	return j.jaegerTracesToExTraces(traces), nil
+}
+
+func (j *JaegerAdapter) getTrace(traceID string, c *config.ExternalAPM, jaegerConfig *jaegerConfig) ([]jaegerTrace, error) {
+	addr := fmt.Sprintf("%s/%s/%s", baseURL(c), jaeger_query_url, url.PathEscape(traceID))
+	result, err := common.DoRequest(http.MethodGet, addr, nil, basicAuthHeader(jaegerConfig.Auth), c.Timeout, c.TLS)
+	if err != nil || result == nil {
+		log_jaeger.Errorf("query jaeger trace %s at %s failed! err: %s", traceID, c.Addr, err)
+		return nil, err
+	}
+	resp, err := common.Deserialize[jaegerTraceResponse](result)
+	if err != nil {
+		log_jaeger.Errorf("deserialize failed! err: %s", err)
+		return nil, err
+	}
+	if len(resp.Errors) > 0 {
+		return nil, errors.New(resp.Errors[0].Msg)
+	}
+	return resp.Data, nil
+}
+
+func (j *JaegerAdapter) jaegerTracesToExTraces(traces []jaegerTrace) *model.ExTrace {
+	exTrace := &model.ExTrace{}
+	for _, trace := range traces {
+		if exTrace.Spans == nil {
+			exTrace.Spans = make([]model.ExSpan, 0, len(trace.Spans))
+		}
+		for i, jaegerSpan := range trace.Spans {
+			process := trace.Processes[jaegerSpan.ProcessID]
+			attributes := j.jaegerTagsToAttributes(jaegerSpan.Tags)
+			span := model.ExSpan{
+				Name:            jaegerSpan.OperationName,
+				ID:              generateUniqueID(jaegerSpan.TraceID, jaegerSpan.SpanID, i),
+				StartTimeUs:     jaegerSpan.StartTime,
+				EndTimeUs:       jaegerSpan.StartTime + jaegerSpan.Duration,
+				TraceID:         jaegerSpan.TraceID,
+				SpanID:          jaegerSpan.SpanID,
+				ParentSpanID:    j.jaegerReferencesToParentSpanID(jaegerSpan.References),
+				Endpoint:        jaegerSpan.OperationName,
+				AppService:      process.ServiceName,
+				AppInstance:     j.jaegerProcessToInstance(&process),
+				ServiceUname:    process.ServiceName,
+				RequestResource: jaegerSpan.OperationName, // maybe overwrite by tags
+				Attribute:       attributes,
+			}
+			span.SpanKind, span.TapSide = spanKindToTapSide(attributes[AttributeSpanKind])
+			attributesToSpanRequestInfo(attributes, &span)
+			exTrace.Spans = append(exTrace.Spans, span)
+		}
+	}
+	return exTrace
+}
+
+func (j *JaegerAdapter) jaegerReferencesToParentSpanID(refs []jaegerReference) string {
+	// use CHILD_OF as the parent first, FOLLOWS_FROM is used by the async spans
+	for _, refType := range []string{JaegerRefChildOf, JaegerRefFollowsFrom} {
+		for _, ref := range refs {
+			if ref.RefType == refType {
+				return ref.SpanID
+			}
+		}
+	}
+	return ""
+}
+
+func (j *JaegerAdapter) jaegerProcessToInstance(process *jaegerProcess) string {
+	tags := j.jaegerTagsToAttributes(process.Tags)
+	if instance, ok := tags[AttributeInstanceID]; ok {
+		return instance
+	}
+	return tags[AttributeHostname]
+}
+
+func (j *JaegerAdapter) jaegerTagsToAttributes(tags []jaegerKeyValue) map[string]string {
+	attr := make(map[string]string, len(tags))
+	for _, v := range tags {
+		attr[v.Key] = fmt.Sprint(v.Value)
+	}
+	return attr
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/deepflowio/deepflow/server/querier/app/tracing-adapter/config"
+	. "github.com/smartystreets/goconvey/convey"
+	v1 "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// newFixtureServer serves the recorded response at the path
+func newFixtureServer(t *testing.T, path, fixture string) *httptest.Server {
+	data, err := os.ReadFile(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path || r.Header.Get("Authorization") != "Basic dXNlcjpwYXNz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	}))
+}
+
+func TestGetJaegerTrace(t *testing.T) {
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	server := newFixtureServer(t, "/api/traces/"+traceID, "testdata/jaeger_trace.json")
+	defer server.Close()
+	jaegerAdapter := &JaegerAdapter{}
+	apm := &config.ExternalAPM{Name: "jaeger", Addr: server.URL, Timeout: time.Second, ExtraConfig: map[string]string{"auth": "dXNlcjpwYXNz"}}
+
+	Convey("TestGetJaegerTrace_Success", t, func() {
+		result, err := jaegerAdapter.GetTrace(traceID, apm)
+		So(err, ShouldBeNil)
+		So(len(result.Spans), ShouldEqual, 3)
+
+		server, client, producer := result.Spans[0], result.Spans[1], result.Spans[2]
+		So(server.ID, ShouldEqual, uint64(0x00f067aa0ba902b7))
+		So(server.TraceID, ShouldEqual, traceID)
+		So(server.SpanID, ShouldEqual, "00f067aa0ba902b7")
+		So(server.ParentSpanID, ShouldEqual, "")
+		So(server.StartTimeUs, ShouldEqual, 1694428678774000)
+		So(server.EndTimeUs, ShouldEqual, 1694428678827000)
+		So(server.TapSide, ShouldEqual, "s-app")
+		So(server.SpanKind, ShouldEqual, int(v1.Span_SPAN_KIND_SERVER))
+		So(server.AppService, ShouldEqual, "frontend")
+		So(server.AppInstance, ShouldEqual, "frontend-7d9c8")
+		So(server.L7ProtocolStr, ShouldEqual, "HTTP")
+		So(server.RequestType, ShouldEqual, "GET")
+		So(server.RequestResource, ShouldEqual, "/dispatch?customer=123")
+		So(server.ResponseStatus, ShouldEqual, 200)
+		So(server.Attribute["error"], ShouldEqual, "false")
+
+		So(client.ParentSpanID, ShouldEqual, "00f067aa0ba902b7")
+		So(client.TapSide, ShouldEqual, "c-app")
+		So(client.AppService, ShouldEqual, "mysql")
+		So(client.AppInstance, ShouldEqual, "mysql-0")
+		So(client.RequestResource, ShouldEqual, "SELECT * FROM customer WHERE customer_id=123")
+		So(client.L7ProtocolStr, ShouldEqual, "")
+
+		So(producer.ParentSpanID, ShouldEqual, "00f067aa0ba902b7")
+		So(producer.SpanKind, ShouldEqual, int(v1.Span_SPAN_KIND_PRODUCER))
+		So(producer.RequestResource, ShouldEqual, "send-notification")
+	})
+
+	Convey("TestGetJaegerTrace_NotFound", t, func() {
+		result, err := jaegerAdapter.GetTrace("unknown", apm)
+		So(err, ShouldNotBeNil)
+		So(result, ShouldBeNil)
+	})
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/deepflowio/deepflow/server/querier/app/tracing-adapter/common"
+	"github.com/deepflowio/deepflow/server/querier/app/tracing-adapter/config"
+	"github.com/deepflowio/deepflow/server/querier/app/tracing-adapter/model"
+	v1 "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+const (
+	// semantic conventions of opentracing and opentelemetry
+	AttributeHTTPURL    = "http.url"
+	AttributeHTTPTarget = "http.target"
+	AttributeHTTPPath   = "http.path"
+	AttributeHTTPRoute  = "http.route"
+	AttributeSQLQuery   = "sql.query"
+	AttributeSpanKind   = "span.kind"
+	AttributeHostname   = "hostname"
+	AttributeInstanceID = "service.instance.id"
+
+	SpanKindClient   = "client"
+	SpanKindServer   = "server"
+	SpanKindProducer = "producer"
+	SpanKindConsumer = "consumer"
+	SpanKindInternal = "internal"
+)
+
+// the attributes of the url in priority order
+var httpURLAttributes = []string{AttributeHTTPURL, AttributeHTTPTarget, AttributeHTTPPath, AttributeHTTPRoute}
+
+func baseURL(c *config.ExternalAPM) string {
+	if strings.HasPrefix(c.Addr, "http://") || strings.HasPrefix(c.Addr, "https://") {
+		return strings.TrimSuffix(c.Addr, "/")
+	}
+	scheme := "http"
+	if c.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, strings.TrimSuffix(c.Addr, "/"))
+}
+
+func basicAuthHeader(auth string) map[string]string {
+	header := common.DefaultContentTypeHeader()
+	if auth != "" {
+		header["Authorization"] = fmt.Sprintf("Basic %s", auth)
+	}
+	return header
+}
+
+// generateUniqueID uses the span id as the unique id if it is a 64 bits hex, otherwise the hash of it
+func generateUniqueID(traceID, spanID string, index int) uint64 {
+	if id, err := strconv.ParseUint(spanID, 16, 64); err == nil && id != 0 {
+		return id
+	}
+	h := fnv.New64a()
+	h.Write([]byte(traceID))
+	h.Write([]byte(spanID))
+	h.Write([]byte(strconv.Itoa(index)))
+	return h.Sum64()
+}
+
+// spanKindToTapSide converts the span kind of opentracing, e.g.: client, server, to the span kind of opentelemetry and tap side
+func spanKindToTapSide(kind string) (int, string) {
+	switch strings.ToLower(kind) {
+	case SpanKindClient:
+		return int(v1.Span_SPAN_KIND_CLIENT), "c-app"
+	case SpanKindProducer:
+		return int(v1.Span_SPAN_KIND_PRODUCER), "c-app"
+	case SpanKindServer:
+		return int(v1.Span_SPAN_KIND_SERVER), "s-app"
+	case SpanKindConsumer:
+		return int(v1.Span_SPAN_KIND_CONSUMER), "s-app"
+	case SpanKindInternal:
+		return int(v1.Span_SPAN_KIND_INTERNAL), "app"
+	default:
+		return int(v1.Span_SPAN_KIND_UNSPECIFIED), "app"
+	}
+}
+
+// attributesToSpanRequestInfo fills the request info by the http and db attributes
+func attributesToSpanRequestInfo(attributes map[string]string, span *model.ExSpan) {
+	if method, ok := attributes[AttributeHTTPMethod]; ok {
+		span.L7Protocol, span.L7ProtocolStr = 20, "HTTP"
+		span.RequestType = method
+		for _, key := range httpURLAttributes {
+			if url, ok := attributes[key]; ok && url != "" {
+				span.RequestResource = url
+				break
+			}
+		}
+	}
+	for _, key := range []string{AttributeHTTPStatus_Code, AttributeHTTPStatusCode} {
+		if code, err := strconv.Atoi(attributes[key]); err == nil {
+			span.ResponseStatus = code
+			break
+		}
+	}
+	for _, key := range []string{AttributeDbStatement, AttributeSQLQuery} {
+		if statement, ok := attributes[key]; ok && statement != "" {
+			span.RequestResource = statement
+			break
+		}
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/deepflowio/deepflow/server/querier/app/tracing-adapter/common"
+	"github.com/deepflowio/deepflow/server/querier/app/tracing-adapter/config"
+	"github.com/deepflowio/deepflow/server/querier/app/tracing-adapter/model"
+	"github.com/mitchellh/mapstructure"
+	"github.com/op/go-logging"
+)
+
+const (
+	// query trace by zipkin v2 api: GET /api/v2/trace/{traceId}
+	zipkin_query_url = "api/v2/trace"
+)
+
+// refer to: https://zipkin.io/zipkin-api/#/default/get_trace__traceId_
+type zipkinSpan struct {
+	TraceID        string            `json:"traceId"`
+	ID             string            `json:"id"`
+	ParentID       string            `json:"parentId"`
+	Name           string            `json:"name"`
+	Kind           string            `json:"kind"`      // CLIENT, SERVER, PRODUCER or CONSUMER
+	Timestamp      int64             `json:"timestamp"` // microseconds
+	Duration       int64             `json:"duration"`  // microseconds
+	LocalEndpoint  *zipkinEndpoint   `json:"localEndpoint"`
+	RemoteEndpoint *zipkinEndpoint   `json:"remoteEndpoint"`
+	Tags           map[string]string `json:"tags"`
+}
+
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+	IPv4        string `json:"ipv4"`
+	IPv6        string `json:"ipv6"`
+	Port        int    `json:"port"`
+}
+
+type zipkinConfig struct {
+	Auth string `mapstructure:"auth"` // basic auth
+}
+
+type ZipkinAdapter struct {
+}
+
+var log_zipkin = logging.MustGetLogger("tracing-adapter.zipkin")
+
+func (z *ZipkinAdapter) GetTrace(traceID string, c *config.ExternalAPM) (*model.ExTrace, error) {
+	zipkinConfig := &zipkinConfig{}
+	err := mapstructure.Decode(c.ExtraConfig, zipkinConfig)
+	if err != nil {
+		log_zipkin.Errorf("cannot decode zipkin extra config %v, err: %s", c.ExtraConfig, err)
+		return nil, err
+	}
+	spans, err := z.getTrace(traceID, c, zipkinConfig)
+	if err != nil || spans == nil {
+		return nil, err
+	}
+	return z.zipkinSpansToExTraces(spans), nil
+}
+
+func (z *ZipkinAdapter) getTrace(traceID string, c *config.ExternalAPM, zipkinConfig *zipkinConfig) ([]zipkinSpan, error) {
+	addr := fmt.Sprintf("%s/%s/%s", baseURL(c), zipkin_query_url, url.PathEscape(traceID))
+	result, err := common.DoRequest(http.MethodGet, addr, nil, basicAuthHeader(zipkinConfig.Auth), c.Timeout, c.TLS)
+	if err != nil || result == nil {
+		log_zipkin.Errorf("query zipkin trace %s at %s failed! err: %s", traceID, c.Addr, err)
+		return nil, err
+	}
+	spans, err := common.Deserialize[[]zipkinSpan](result)
+	if err != nil || spans == nil {
+		log_zipkin.Errorf("deserialize failed! err: %s", err)
+		return nil, err
+	}
+	return *spans, nil
+}
+
+func (z *ZipkinAdapter) zipkinSpansToExTraces(spans []zipkinSpan) *model.ExTrace {
+	exTrace := &model.ExTrace{}
+	exTrace.Spans = make([]model.ExSpan, 0, len(spans))
+	for i, zipkinSpan := range spans {
+		attributes := zipkinSpan.Tags
+		if attributes == nil {
+			attributes = map[string]string{}
+		}
+		span := model.ExSpan{
+			Name:            zipkinSpan.Name,
+			ID:              generateUniqueID(zipkinSpan.TraceID, zipkinSpan.ID, i),
+			StartTimeUs:     zipkinSpan.Timestamp,
+			EndTimeUs:       zipkinSpan.Timestamp + zipkinSpan.Duration,
+			TraceID:         zipkinSpan.TraceID,
+			SpanID:          zipkinSpan.ID,
+			ParentSpanID:    zipkinSpan.ParentID,
+			Endpoint:        zipkinSpan.Name,
+			RequestResource: zipkinSpan.Name, // maybe overwrite by tags
+			Attribute:       attributes,
+		}
+		if endpoint := zipkinSpan.LocalEndpoint; endpoint != nil {
+			span.AppService = endpoint.ServiceName
+			span.ServiceUname = endpoint.ServiceName
+			span.AppInstance = endpoint.IPv4
+			if span.AppInstance == "" {
+				span.AppInstance = endpoint.IPv6
+			}
+		}
+		span.SpanKind, span.TapSide = spanKindToTapSide(zipkinSpan.Kind)
+		attributesToSpanRequestInfo(attributes, &span)
+		exTrace.Spans = append(exTrace.Spans, span)
+	}
+	return exTrace
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deepflowio/deepflow/server/querier/app/tracing-adapter/config"
+	. "github.com/smartystreets/goconvey/convey"
+	v1 "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func TestGetZipkinTrace(t *testing.T) {
+	traceID := "463ac35c9f6413ad48485a3953bb6124"
+	server := newFixtureServer(t, "/api/v2/trace/"+traceID, "testdata/zipkin_trace.json")
+	defer server.Close()
+	zipkinAdapter := &ZipkinAdapter{}
+	// addr without scheme as the skywalking config
+	apm := &config.ExternalAPM{Name: "zipkin", Addr: strings.TrimPrefix(server.URL, "http://"), Timeout: time.Second, ExtraConfig: map[string]string{"auth": "dXNlcjpwYXNz"}}
+
+	Convey("TestGetZipkinTrace_Success", t, func() {
+		result, err := zipkinAdapter.GetTrace(traceID, apm)
+		So(err, ShouldBeNil)
+		So(len(result.Spans), ShouldEqual, 3)
+
+		server, client, local := result.Spans[0], result.Spans[1], result.Spans[2]
+		So(server.ID, ShouldEqual, uint64(0xa2fb4a1d1a96d312))
+		So(server.TraceID, ShouldEqual, traceID)
+		So(server.ParentSpanID, ShouldEqual, "")
+		So(server.EndTimeUs-server.StartTimeUs, ShouldEqual, 26000)
+		So(server.TapSide, ShouldEqual, "s-app")
+		So(server.SpanKind, ShouldEqual, int(v1.Span_SPAN_KIND_SERVER))
+		So(server.AppService, ShouldEqual, "orders")
+		So(server.ServiceUname, ShouldEqual, "orders")
+		So(server.AppInstance, ShouldEqual, "10.1.2.4")
+		So(server.RequestType, ShouldEqual, "GET")
+		So(server.RequestResource, ShouldEqual, "/api/orders")
+		So(server.ResponseStatus, ShouldEqual, 500)
+		So(server.Attribute["error"], ShouldEqual, "500")
+
+		So(client.ParentSpanID, ShouldEqual, "a2fb4a1d1a96d312")
+		So(client.TapSide, ShouldEqual, "c-app")
+		So(client.AppInstance, ShouldEqual, "fe80::1")
+		So(client.RequestResource, ShouldEqual, "SELECT * FROM orders")
+
+		So(local.TapSide, ShouldEqual, "app")
+		So(local.SpanKind, ShouldEqual, int(v1.Span_SPAN_KIND_UNSPECIFIED))
+		So(local.Attribute, ShouldNotBeNil)
+	})
+
+	Convey("TestGetZipkinTrace_NotFound", t, func() {
+		result, err := zipkinAdapter.GetTrace("unknown", apm)
+		So(err, ShouldNotBeNil)
+		So(result, ShouldBeNil)
+	})
+}
+
+func TestGenerateUniqueID(t *testing.T) {
+	Convey("TestGenerateUniqueID", t, func() {
+		So(generateUniqueID("t", "00000000000000ff", 0), ShouldEqual, 0xff)
+		So(generateUniqueID("t", "not-hex", 0), ShouldNotEqual, generateUniqueID("t", "not-hex", 1))
+		So(generateUniqueID("t", "0000000000000000", 0), ShouldNotEqual, 0)
+	})
+}
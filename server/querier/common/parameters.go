@@ -30,6 +30,8 @@ type QuerierParams struct {
 	DataSource string
 	Context    context.Context
 	NoPreWhere bool
+	Caller     string
+	Writer     ResultWriter // the result is streamed to the writer if not nil
 }
 
 type TempoParams struct {
@@ -41,6 +43,7 @@ type TempoParams struct {
 	MaxDuration string
 	Limit       string
 	Debug       string
+	Query       string // TraceQL
 	Filters     []*KeyValue
 	Context     context.Context
 }
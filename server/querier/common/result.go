@@ -40,12 +40,13 @@ func (r *Result) ToJson() map[string]interface{} {
 }
 
 type ColumnSchema struct {
-	Name      string
-	Unit      string
-	Type      int
-	ValueType string
-	PreAS     string
-	LabelType string
+	Name         string
+	Unit         string
+	Type         int
+	ValueType    string
+	PreAS        string
+	LabelType    string
+	DatabaseType string // the type of the column in clickhouse, e.g. Nullable(Float64)
 }
 
 func (c *ColumnSchema) ToMap() map[string]interface{} {
@@ -71,3 +72,28 @@ func (c *ColumnSchemas) ToArray() []interface{} {
 	}
 	return schemas
 }
+
+// ResultWriter writes the result row by row instead of collecting it, the values of a row are converted by
+// client.TransType, WriteHeader is called once before the rows
+type ResultWriter interface {
+	WriteHeader(columns []interface{}, schemas ColumnSchemas) error
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+// WriteResult writes the collected result to the writer
+func WriteResult(writer ResultWriter, result *Result) error {
+	if err := writer.WriteHeader(result.Columns, result.Schemas); err != nil {
+		return err
+	}
+	for _, value := range result.Values {
+		values, ok := value.([]interface{})
+		if !ok {
+			continue
+		}
+		if err := writer.WriteRow(values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -58,6 +58,7 @@ type QuerierConfig struct {
 	MaxPrometheusIdSubqueryLruEntry int                           `default:"8000" yaml:"max-prometheus-id-subquery-lru-entry"`
 	PrometheusIdSubqueryLruTimeout  int                           `default:"60" yaml:"prometheus-id-subquery-lru-timeout"`
 	AutoCustomTags                  []AutoCustomTags              `yaml:"auto-custom-tags" binding:"omitempty,dive"`
+	QueryCache                      QueryCache                    `yaml:"query-cache"`
 }
 
 type DeepflowApp struct {
@@ -86,6 +87,14 @@ type Clickhouse struct {
 	ConnectTimeout int    `default:"2" yaml:"connect-timeout"`
 	MaxConnection  int    `default:"20" yaml:"max-connection"`
 }
+type QueryCache struct {
+	Enabled        bool `default:"false" yaml:"enabled"`
+	MaxCount       int  `default:"1024" yaml:"max-count"`      // max count of the cached results
+	MaxItemRows    int  `default:"10000" yaml:"max-item-rows"` // results with more rows are not cached
+	BucketSize     int  `default:"3600" yaml:"bucket-size"`    // time range of each bucket, unit: s
+	ImmutableDelay int  `default:"300" yaml:"immutable-delay"` // data older than now - immutable-delay is immutable, unit: s
+}
+
 type AutoCustomTags struct {
 	TagName     string   `default:"" yaml:"tag-name"`
 	TagFields   []string `yaml:"tag-fields" binding:"omitempty,dive"`
@@ -89,9 +89,11 @@ func (e *CHEngine) ExecuteQuery(args *common.QuerierParams) (*common.Result, map
 	var sqlList []string
 	var err error
 	sql := args.Sql
-	e.Context = args.Context
-	e.NoPreWhere = args.NoPreWhere
 	query_uuid := args.QueryUUID // FIXME: should be queryUUID
+	ctx, unregister := client.RunningQueries.Register(args.Context, query_uuid, sql, args.Caller)
+	defer unregister()
+	e.Context = ctx
+	e.NoPreWhere = args.NoPreWhere
 	log.Debugf("query_uuid: %s | raw sql: %s", query_uuid, sql)
 
 	// Parse withSql
@@ -208,6 +210,29 @@ func (e *CHEngine) ExecuteQuery(args *common.QuerierParams) (*common.Result, map
 	for _, ColumnSchema := range e.ColumnSchemas {
 		ColumnSchemaMap[ColumnSchema.Name] = ColumnSchema
 	}
+	if args.Writer != nil && len(callbacks) == 0 {
+		// stream the rows to the writer as ClickHouse returns them, the query cache is bypassed
+		// as it requires the whole result
+		err := chClient.DoStreamQuery(&client.QueryParams{
+			Sql:             chSql,
+			QueryUUID:       query_uuid,
+			ColumnSchemaMap: ColumnSchemaMap,
+		}, args.Writer)
+		return nil, debug.Get(), err
+	}
+	if queryCache := GetQueryCache(); queryCache != nil {
+		rst, cacheStatus, err := queryCache.Execute(e, chSql, callbacks, func(sql string) (*common.Result, error) {
+			return chClient.DoQuery(&client.QueryParams{
+				Sql:             sql,
+				QueryUUID:       query_uuid,
+				ColumnSchemaMap: ColumnSchemaMap,
+			})
+		})
+		debug.Sql = chSql
+		debugMap := debug.Get()
+		debugMap["query_cache"] = cacheStatus
+		return rst, debugMap, err
+	}
 	params := &client.QueryParams{
 		Sql:             chSql,
 		Callbacks:       callbacks,
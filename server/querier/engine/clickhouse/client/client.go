@@ -102,12 +102,76 @@ func (c *Client) Close() error {
 	return nil
 }
 
+// DoBatchInsert inserts the rows by the sql like 'INSERT INTO db.table (column1, column2)', the values of
+// each row are in the order of the columns
+func (c *Client) DoBatchInsert(sql string, rows [][]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	err := c.init("")
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	ctx := c.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	batch, err := c.connection.PrepareBatch(ctx, sql)
+	if err != nil {
+		log.Errorf("prepare batch failed: %s, sql: %s", err, sql)
+		return err
+	}
+	for _, row := range rows {
+		if err := batch.Append(row...); err != nil {
+			batch.Abort()
+			log.Errorf("append batch failed: %s, sql: %s", err, sql)
+			return err
+		}
+	}
+	if err := batch.Send(); err != nil {
+		log.Errorf("send batch failed: %s, sql: %s", err, sql)
+		return err
+	}
+	return nil
+}
+
 func (c *Client) DoQuery(params *QueryParams) (result *common.Result, err error) {
-	sqlstr, callbacks, query_uuid, columnSchemaMap := params.Sql, params.Callbacks, params.QueryUUID, params.ColumnSchemaMap
-	err = c.init(query_uuid)
+	var values []interface{}
+	columnNames, columnSchemas, err := c.doQuery(params, nil, func(record []interface{}) error {
+		values = append(values, record)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	result = &common.Result{
+		Columns: columnNames,
+		Values:  values,
+		Schemas: columnSchemas,
+	}
+	for _, callback := range params.Callbacks {
+		err := callback(result)
+		if err != nil {
+			log.Error("Execute Callback %v Error: %v", callback, err)
+		}
+	}
+	return result, nil
+}
+
+// DoStreamQuery writes the rows to the writer as ClickHouse returns them, the callbacks of the params are
+// not called as they require the whole result
+func (c *Client) DoStreamQuery(params *QueryParams, writer common.ResultWriter) error {
+	_, _, err := c.doQuery(params, writer.WriteHeader, writer.WriteRow)
+	return err
+}
+
+func (c *Client) doQuery(params *QueryParams, onHeader func([]interface{}, common.ColumnSchemas) error, onRow func([]interface{}) error) (columnNames []interface{}, columnSchemas common.ColumnSchemas, err error) {
+	sqlstr, query_uuid, columnSchemaMap := params.Sql, params.QueryUUID, params.ColumnSchemaMap
+	err = c.init(query_uuid)
+	if err != nil {
+		return nil, nil, err
+	}
 	defer c.Close()
 
 	start := time.Now()
@@ -115,60 +179,73 @@ func (c *Client) DoQuery(params *QueryParams) (result *common.Result, err error)
 	if c.Context == nil {
 		ctx = context.Background()
 	}
+	if query := runningQueryFromContext(ctx); query != nil {
+		// set the query_id, so that the query could be killed by the cancel api
+		ctx = clickhouse.Context(ctx, clickhouse.WithQueryID(query.nextChQueryID(sqlstr)), clickhouse.WithProgress(query.onProgress))
+	}
 	rows, err := c.connection.Query(ctx, sqlstr)
 	c.Debug.Sql = sqlstr
 	if err != nil {
 		log.Errorf("query clickhouse Error: %s, sql: %s, query_uuid: %s", err, sqlstr, c.Debug.QueryUUID)
 		c.Debug.Error = fmt.Sprintf("%s", err)
-		return nil, err
+		return nil, nil, err
 	}
 	defer rows.Close()
 	columns := rows.ColumnTypes()
 	resColumns := len(columns)
-	columnNames := make([]interface{}, 0, len(columns))
-	var columnSchemas common.ColumnSchemas // FIXME: Slice growth should be avoided.
+	columnNames = make([]interface{}, 0, len(columns))
 	// 获取列名和列类型
 	for _, column := range columns {
 		columnNames = append(columnNames, column.Name())
-		if schema, ok := columnSchemaMap[column.Name()]; ok {
-			columnSchemas = append(columnSchemas, schema)
-		} else {
-			columnSchemas = append(columnSchemas, common.NewColumnSchema(column.Name(), "", ""))
+		schema, ok := columnSchemaMap[column.Name()]
+		if !ok {
+			schema = common.NewColumnSchema(column.Name(), "", "")
+		}
+		schema.DatabaseType = column.DatabaseTypeName()
+		columnSchemas = append(columnSchemas, schema)
+	}
+	if onHeader != nil {
+		if err := onHeader(columnNames, columnSchemas); err != nil {
+			c.Debug.Error = fmt.Sprintf("%s", err)
+			return nil, nil, err
 		}
 	}
-	var values []interface{}
 	columnValues := make([]interface{}, len(columns))
 	for i := range columns {
 		columnValues[i] = reflect.New(columns[i].ScanType()).Interface()
 	}
 	resSize := 0
+	resRows := 0
 	for rows.Next() {
 		if err := rows.Scan(columnValues...); err != nil {
 			c.Debug.Error = fmt.Sprintf("%s", err)
-			return nil, err
+			return nil, nil, err
 		}
 		record := make([]interface{}, 0, len(columns))
 		for i, rawValue := range columnValues {
 			value, valueType, err := TransType(rawValue, columns[i].Name(), columns[i].DatabaseTypeName())
 			if err != nil {
 				c.Debug.Error = fmt.Sprintf("%s", err)
-				return nil, err
+				return nil, nil, err
 			}
 			resSize += int(unsafe.Sizeof(value))
 			record = append(record, value)
 			columnSchemas[i].ValueType = valueType
 		}
-		values = append(values, record)
+		if err := onRow(record); err != nil {
+			c.Debug.Error = fmt.Sprintf("%s", err)
+			return nil, nil, err
+		}
+		resRows++
 	}
 	// Even if the query operation produces an error, it does not necessarily return an error in the'err 'parameter,
 	// so the return value of the'rows. Err () ' method must be checked to ensure that the query operation is successful
 	if err := rows.Err(); err != nil {
 		log.Errorf("query clickhouse Error: %s, sql: %s, query_uuid: %s", err, sqlstr, c.Debug.QueryUUID)
 		c.Debug.Error = fmt.Sprintf("%s", err)
-		return nil, err
+		return nil, nil, err
 	}
 	queryTime := time.Since(start)
-	resRows := len(values)
 	statsd.QuerierCounter.WriteCk(
 		&statsd.ClickhouseCounter{
 			ResponseSize: uint64(resSize),
@@ -178,18 +255,7 @@ func (c *Client) DoQuery(params *QueryParams) (result *common.Result, err error)
 		},
 	)
 	c.Debug.QueryTime = int64(queryTime)
-	result = &common.Result{
-		Columns: columnNames,
-		Values:  values,
-		Schemas: columnSchemas,
-	}
-	for _, callback := range callbacks {
-		err := callback(result)
-		if err != nil {
-			log.Error("Execute Callback %v Error: %v", callback, err)
-		}
-	}
 	log.Debugf("sql: %s, query_uuid: %s", sqlstr, c.Debug.QueryUUID)
 	log.Infof("query_uuid: %s. query api statistics: %d rows, %d columns, %d bytes, cost %f ms", c.Debug.QueryUUID, resRows, resColumns, resSize, float64(queryTime.Milliseconds()))
-	return result, nil
+	return columnNames, columnSchemas, nil
 }
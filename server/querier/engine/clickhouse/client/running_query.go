@@ -0,0 +1,201 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	clickhouse "github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/google/uuid"
+
+	"github.com/deepflowio/deepflow/server/querier/config"
+)
+
+type runningQueryKey struct{}
+
+var queryUUIDRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+
+// IsValidQueryUUID checks the query_uuid given by the client, which is used in the ClickHouse query_id
+func IsValidQueryUUID(queryUUID string) bool {
+	return queryUUIDRegexp.MatchString(queryUUID)
+}
+
+var sqlStringEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// RunningQuery is a query in flight, one querier query may be translated into several ClickHouse queries,
+// each ClickHouse query uses the query_id of '<query_uuid>-<request_id>-<index>', the request_id is
+// generated for each registration, so that the requests reusing a query_uuid do not conflict in ClickHouse
+type RunningQuery struct {
+	QueryUUID string    `json:"query_uuid"`
+	Sql       string    `json:"sql"`
+	ChSql     []string  `json:"ch_sql"`
+	Caller    string    `json:"caller"`
+	StartTime time.Time `json:"start_time"`
+	RowsRead  uint64    `json:"rows_read"`
+	BytesRead uint64    `json:"bytes_read"`
+
+	id         uint64
+	requestID  string
+	cancel     context.CancelFunc
+	lock       sync.Mutex
+	chQueryIDs []string
+}
+
+// nextChQueryID records the ClickHouse query and returns its query_id
+func (q *RunningQuery) nextChQueryID(chSql string) string {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	chQueryID := fmt.Sprintf("%s-%s-%d", q.QueryUUID, q.requestID, len(q.chQueryIDs))
+	q.chQueryIDs = append(q.chQueryIDs, chQueryID)
+	q.ChSql = append(q.ChSql, chSql)
+	return chQueryID
+}
+
+func (q *RunningQuery) onProgress(p *clickhouse.Progress) {
+	// the progress packets of ClickHouse are increments
+	atomic.AddUint64(&q.RowsRead, p.Rows)
+	atomic.AddUint64(&q.BytesRead, p.Bytes)
+}
+
+func (q *RunningQuery) snapshot() RunningQuery {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return RunningQuery{
+		QueryUUID: q.QueryUUID,
+		Sql:       q.Sql,
+		ChSql:     append([]string{}, q.ChSql...),
+		Caller:    q.Caller,
+		StartTime: q.StartTime,
+		RowsRead:  atomic.LoadUint64(&q.RowsRead),
+		BytesRead: atomic.LoadUint64(&q.BytesRead),
+	}
+}
+
+type runningQueries struct {
+	lock    sync.Mutex
+	nextID  uint64
+	queries map[uint64]*RunningQuery
+}
+
+var RunningQueries = &runningQueries{queries: map[uint64]*RunningQuery{}}
+
+// Register adds the query to the registry and returns a cancelable context carrying it, the returned
+// function must be called when the query finishes. The sub queries of a registered query share the registration.
+func (r *runningQueries) Register(ctx context.Context, queryUUID, sql, caller string) (context.Context, func()) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if runningQueryFromContext(ctx) != nil {
+		return ctx, func() {}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	query := &RunningQuery{
+		QueryUUID: queryUUID,
+		Sql:       sql,
+		Caller:    caller,
+		StartTime: time.Now(),
+		requestID: uuid.NewString()[:8],
+		cancel:    cancel,
+	}
+	r.lock.Lock()
+	r.nextID++
+	query.id = r.nextID
+	r.queries[query.id] = query
+	r.lock.Unlock()
+
+	return context.WithValue(ctx, runningQueryKey{}, query), func() {
+		r.lock.Lock()
+		delete(r.queries, query.id)
+		r.lock.Unlock()
+		cancel()
+	}
+}
+
+// List returns the snapshots of the running queries, ordered by the start time
+func (r *runningQueries) List() []RunningQuery {
+	r.lock.Lock()
+	queries := make([]*RunningQuery, 0, len(r.queries))
+	for _, query := range r.queries {
+		queries = append(queries, query)
+	}
+	r.lock.Unlock()
+	sort.Slice(queries, func(i, j int) bool {
+		return queries[i].id < queries[j].id
+	})
+	result := make([]RunningQuery, 0, len(queries))
+	for _, query := range queries {
+		result = append(result, query.snapshot())
+	}
+	return result
+}
+
+// Cancel cancels the contexts of the queries with the query_uuid, and kills their ClickHouse queries,
+// returns the number of the canceled queries
+func (r *runningQueries) Cancel(queryUUID string) (int, error) {
+	r.lock.Lock()
+	queries := []*RunningQuery{}
+	for _, query := range r.queries {
+		if query.QueryUUID == queryUUID {
+			queries = append(queries, query)
+		}
+	}
+	r.lock.Unlock()
+
+	chQueryIDs := []string{}
+	for _, query := range queries {
+		query.cancel()
+		query.lock.Lock()
+		for _, chQueryID := range query.chQueryIDs {
+			chQueryIDs = append(chQueryIDs, "'"+sqlStringEscaper.Replace(chQueryID)+"'")
+		}
+		query.lock.Unlock()
+	}
+	if len(chQueryIDs) == 0 {
+		return len(queries), nil
+	}
+	chClient := &Client{
+		Host:     config.Cfg.Clickhouse.Host,
+		Port:     config.Cfg.Clickhouse.Port,
+		UserName: config.Cfg.Clickhouse.User,
+		Password: config.Cfg.Clickhouse.Password,
+	}
+	if err := chClient.init(""); err != nil {
+		return len(queries), err
+	}
+	sql := fmt.Sprintf("KILL QUERY WHERE query_id IN (%s) ASYNC", strings.Join(chQueryIDs, ","))
+	if err := chClient.connection.Exec(context.Background(), sql); err != nil {
+		log.Errorf("kill query %s failed: %s, sql: %s", queryUUID, err, sql)
+		return len(queries), err
+	}
+	log.Infof("query_uuid: %s. %d queries are canceled", queryUUID, len(queries))
+	return len(queries), nil
+}
+
+func runningQueryFromContext(ctx context.Context) *RunningQuery {
+	if ctx == nil {
+		return nil
+	}
+	query, _ := ctx.Value(runningQueryKey{}).(*RunningQuery)
+	return query
+}
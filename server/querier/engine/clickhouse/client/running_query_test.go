@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	clickhouse "github.com/ClickHouse/clickhouse-go/v2"
+)
+
+func TestRunningQueries(t *testing.T) {
+	r := &runningQueries{queries: map[uint64]*RunningQuery{}}
+	ctx, unregister := r.Register(context.Background(), "uuid-1", "SELECT 1", "127.0.0.1")
+	// the sub queries share the registration
+	subCtx, subUnregister := r.Register(ctx, "uuid-1", "SELECT 2", "")
+	subUnregister()
+	if subCtx != ctx || len(r.List()) != 1 {
+		t.Fatalf("sub query should not be registered")
+	}
+
+	query := runningQueryFromContext(ctx)
+	if chQueryID := query.nextChQueryID("SELECT 1 FROM t"); chQueryID != "uuid-1-"+query.requestID+"-0" {
+		t.Errorf("chQueryID = %s", chQueryID)
+	}
+	// the requests reusing a query_uuid use different ClickHouse query_ids
+	otherCtx, otherUnregister := r.Register(context.Background(), "uuid-1", "SELECT 1", "127.0.0.1")
+	if other := runningQueryFromContext(otherCtx); other.nextChQueryID("SELECT 1 FROM t") == query.chQueryIDs[0] {
+		t.Errorf("ClickHouse query_ids of different requests should not conflict")
+	}
+	otherUnregister()
+	query.onProgress(&clickhouse.Progress{Rows: 10, Bytes: 100})
+	query.onProgress(&clickhouse.Progress{Rows: 5, Bytes: 50})
+	queries := r.List()
+	if len(queries) != 1 || queries[0].Sql != "SELECT 1" || queries[0].Caller != "127.0.0.1" ||
+		len(queries[0].ChSql) != 1 || queries[0].RowsRead != 15 || queries[0].BytesRead != 150 {
+		t.Errorf("unexpected queries %+v", queries)
+	}
+
+	if count, _ := r.Cancel("unknown"); count != 0 {
+		t.Errorf("count = %d", count)
+	}
+	// do not kill the ClickHouse query, as there is no ClickHouse in the test
+	query.chQueryIDs = nil
+	if count, err := r.Cancel("uuid-1"); count != 1 || err != nil {
+		t.Errorf("count = %d, err = %v", count, err)
+	}
+	if ctx.Err() != context.Canceled {
+		t.Errorf("context should be canceled")
+	}
+	unregister()
+	if len(r.List()) != 0 {
+		t.Errorf("query should be unregistered")
+	}
+}
+
+func TestIsValidQueryUUID(t *testing.T) {
+	for _, queryUUID := range []string{"c2b5f7e2-8f4e-4bb4-9ae1-8c3ff3a6d0a1", "my_query-1"} {
+		if !IsValidQueryUUID(queryUUID) {
+			t.Errorf("%s should be valid", queryUUID)
+		}
+	}
+	for _, queryUUID := range []string{"", `x\' OR 1=1 OR query_id IN (\'`, "a b", "a'b"} {
+		if IsValidQueryUUID(queryUUID) {
+			t.Errorf("%s should be invalid", queryUUID)
+		}
+	}
+	if escaped := sqlStringEscaper.Replace(`x\' OR 1=1`); escaped != `x\\\' OR 1=1` {
+		t.Errorf("escaped = %s", escaped)
+	}
+}
@@ -34,11 +34,11 @@ const TagServerEnPrefix = "Server"
 const TagClientEnPrefix = "Client"
 
 var DB_TABLE_MAP = map[string][]string{
-	DB_NAME_FLOW_LOG:        []string{"l4_flow_log", "l7_flow_log", "l4_packet", "l7_packet"},
+	DB_NAME_FLOW_LOG:        []string{"l4_flow_log", "l7_flow_log", "l4_packet", "l7_packet", "application_log"},
 	DB_NAME_FLOW_METRICS:    []string{"vtap_flow_port", "vtap_flow_edge_port", "vtap_app_port", "vtap_app_edge_port", "vtap_acl"},
 	DB_NAME_EXT_METRICS:     []string{"ext_common"},
 	DB_NAME_DEEPFLOW_SYSTEM: []string{"deepflow_system_common"},
-	DB_NAME_EVENT:           []string{"event", "perf_event", "alarm_event"},
+	DB_NAME_EVENT:           []string{"event", "perf_event", "alarm_event", "agent_log"},
 	DB_NAME_PROFILE:         []string{"in_process"},
 	DB_NAME_PROMETHEUS:      []string{"samples"},
 }
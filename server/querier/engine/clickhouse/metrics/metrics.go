@@ -266,6 +266,8 @@ func GetMetricsByDBTableStatic(db string, table string, where string) (map[strin
 			return GetL7FlowLogMetrics(), err
 		case "l7_packet":
 			return GetL7PacketMetrics(), err
+		case "application_log":
+			return GetApplicationLogMetrics(), err
 		}
 	case "flow_metrics":
 		switch table {
@@ -288,6 +290,8 @@ func GetMetricsByDBTableStatic(db string, table string, where string) (map[strin
 			return GetResourcePerfEventMetrics(), err
 		case "alarm_event":
 			return GetAlarmEventMetrics(), err
+		case "agent_log":
+			return GetAgentLogMetrics(), err
 		}
 	case ckcommon.DB_NAME_PROFILE:
 		switch table {
@@ -309,6 +313,8 @@ func GetMetricsByDBTable(db string, table string, where string, ctx context.Cont
 			return GetL4PacketMetrics(), err
 		case "l7_packet":
 			return GetL7PacketMetrics(), err
+		case "application_log":
+			return GetApplicationLogMetrics(), err
 		case "l7_flow_log":
 			metrics := make(map[string]*Metrics)
 			loads := GetL7FlowLogMetrics()
@@ -353,6 +359,8 @@ func GetMetricsByDBTable(db string, table string, where string, ctx context.Cont
 			return GetResourcePerfEventMetrics(), err
 		case "alarm_event":
 			return GetAlarmEventMetrics(), err
+		case "agent_log":
+			return GetAgentLogMetrics(), err
 		}
 	case ckcommon.DB_NAME_PROFILE:
 		switch table {
@@ -659,6 +667,9 @@ func MergeMetrics(db string, table string, loadMetrics map[string]*Metrics) erro
 		case "l7_flow_log":
 			metrics = L7_FLOW_LOG_METRICS
 			replaceMetrics = L7_FLOW_LOG_METRICS_REPLACE
+		case "application_log":
+			metrics = APPLICATION_LOG_METRICS
+			replaceMetrics = APPLICATION_LOG_METRICS_REPLACE
 		}
 	case "flow_metrics":
 		switch table {
@@ -689,6 +700,9 @@ func MergeMetrics(db string, table string, loadMetrics map[string]*Metrics) erro
 		case "alarm_event":
 			metrics = ALARM_EVENT_METRICS
 			replaceMetrics = ALARM_EVENT_METRICS_REPLACE
+		case "agent_log":
+			metrics = AGENT_LOG_METRICS
+			replaceMetrics = AGENT_LOG_METRICS_REPLACE
 		}
 	case ckcommon.DB_NAME_PROFILE:
 		switch table {
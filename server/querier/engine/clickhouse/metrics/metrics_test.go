@@ -61,6 +61,9 @@ func TestCheckDBField(t *testing.T) {
 					case "l7_flow_log":
 						metrics = L7_FLOW_LOG_METRICS
 						replaceMetrics = L7_FLOW_LOG_METRICS_REPLACE
+					case "application_log":
+						metrics = APPLICATION_LOG_METRICS
+						replaceMetrics = APPLICATION_LOG_METRICS_REPLACE
 					}
 				case "flow_metrics":
 					switch table {
@@ -88,6 +91,9 @@ func TestCheckDBField(t *testing.T) {
 					case "perf_event":
 						metrics = RESOURCE_PERF_EVENT_METRICS
 						replaceMetrics = RESOURCE_PERF_EVENT_METRICS_REPLACE
+					case "agent_log":
+						metrics = AGENT_LOG_METRICS
+						replaceMetrics = AGENT_LOG_METRICS_REPLACE
 					}
 				}
 				if metrics == nil {
@@ -0,0 +1,376 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clickhouse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/deepflowio/deepflow/server/libs/lru"
+	"github.com/deepflowio/deepflow/server/querier/common"
+	"github.com/deepflowio/deepflow/server/querier/config"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/view"
+	"github.com/deepflowio/deepflow/server/querier/statsd"
+)
+
+const (
+	QUERY_CACHE_HIT     = "hit"
+	QUERY_CACHE_PARTIAL = "partial"
+	QUERY_CACHE_MISS    = "miss"
+	QUERY_CACHE_BYPASS  = "bypass"
+
+	queryCacheTimeStart = "\x00time_start\x00"
+	queryCacheTimeEnd   = "\x00time_end\x00"
+)
+
+var (
+	queryCacheTimeStartRegexp = regexp.MustCompile("`time` >= (\\d+)")
+	queryCacheTimeEndRegexp   = regexp.MustCompile("`time` <= (\\d+)")
+	queryCacheIntervalRegexp  = regexp.MustCompile(`toStartOfInterval\(time, toIntervalSecond\((\d+)\)\) \+ toIntervalSecond\(arrayJoin\(\[0\]\)`)
+)
+
+type QueryCacheStats struct {
+	CacheHit        uint64 `statsd:"cache_hit"`
+	CachePartialHit uint64 `statsd:"cache_partial_hit"`
+	CacheMiss       uint64 `statsd:"cache_miss"`
+	BucketHit       uint64 `statsd:"bucket_hit"`
+	BucketMiss      uint64 `statsd:"bucket_miss"`
+}
+
+type QueryCacheCounter struct {
+	stats  QueryCacheStats
+	exited bool
+}
+
+func (c *QueryCacheCounter) GetCounter() interface{} {
+	return &QueryCacheStats{
+		CacheHit:        atomic.SwapUint64(&c.stats.CacheHit, 0),
+		CachePartialHit: atomic.SwapUint64(&c.stats.CachePartialHit, 0),
+		CacheMiss:       atomic.SwapUint64(&c.stats.CacheMiss, 0),
+		BucketHit:       atomic.SwapUint64(&c.stats.BucketHit, 0),
+		BucketMiss:      atomic.SwapUint64(&c.stats.BucketMiss, 0),
+	}
+}
+
+func (c *QueryCacheCounter) Close() {
+	c.exited = true
+}
+
+func (c *QueryCacheCounter) Closed() bool {
+	return c.exited
+}
+
+// QueryCache caches the results of the immutable time ranges. The queries grouped by time are split into
+// the buckets aligned to the bucket size, the full buckets older than now - immutable delay are cached,
+// the other buckets (the head, the tail and the missing buckets) are queried from ClickHouse.
+type QueryCache struct {
+	cfg     config.QueryCache
+	entries *lru.Cache[string, *common.Result]
+	lock    sync.Mutex
+	counter *QueryCacheCounter
+	now     func() time.Time
+}
+
+var (
+	queryCache     *QueryCache
+	queryCacheOnce sync.Once
+)
+
+// GetQueryCache returns nil if the query cache is disabled
+func GetQueryCache() *QueryCache {
+	if config.Cfg == nil || !config.Cfg.QueryCache.Enabled {
+		return nil
+	}
+	queryCacheOnce.Do(func() {
+		queryCache = NewQueryCache(config.Cfg.QueryCache)
+		statsd.RegisterCountableForIngester("query_cache_counter", queryCache.counter)
+	})
+	return queryCache
+}
+
+func NewQueryCache(cfg config.QueryCache) *QueryCache {
+	return &QueryCache{
+		cfg:     cfg,
+		entries: lru.NewCache[string, *common.Result](cfg.MaxCount),
+		counter: &QueryCacheCounter{},
+		now:     time.Now,
+	}
+}
+
+type queryCacheBucket struct {
+	start  int64 // inclusive
+	end    int64 // inclusive
+	key    string
+	result *common.Result
+}
+
+// Execute gets the result of the chSql through the cache, the doQuery must not call the callbacks,
+// which are called on the merged result
+func (c *QueryCache) Execute(e *CHEngine, chSql string, callbacks map[string]func(*common.Result) error, doQuery func(sql string) (*common.Result, error)) (*common.Result, string, error) {
+	immutableBefore := c.now().Unix() - int64(c.cfg.ImmutableDelay)
+	template, bucketSize := c.parse(e, chSql)
+	if template == "" || strings.Contains(strings.ToLower(chSql), "now(") {
+		result, err := doQuery(chSql)
+		return queryCacheCallback(result, callbacks), QUERY_CACHE_BYPASS, err
+	}
+
+	var buckets []*queryCacheBucket
+	timeStart, timeEnd := e.Model.Time.TimeStart, e.Model.Time.TimeEnd
+	if bucketSize == 0 {
+		// the query could not be split, cache the whole result if it is immutable
+		if timeEnd >= immutableBefore {
+			result, err := doQuery(chSql)
+			return queryCacheCallback(result, callbacks), QUERY_CACHE_BYPASS, err
+		}
+		buckets = append(buckets, &queryCacheBucket{start: timeStart, end: timeEnd, key: c.key(e.DB, chSql, 0)})
+	} else {
+		for bucketStart := timeStart / bucketSize * bucketSize; bucketStart <= timeEnd; bucketStart += bucketSize {
+			bucket := &queryCacheBucket{start: bucketStart, end: bucketStart + bucketSize - 1}
+			if bucket.end < immutableBefore && bucket.start >= timeStart && bucket.end <= timeEnd {
+				bucket.key = c.key(e.DB, template, bucketStart)
+			}
+			if bucket.start < timeStart {
+				bucket.start = timeStart
+			}
+			if bucket.end > timeEnd {
+				bucket.end = timeEnd
+			}
+			buckets = append(buckets, bucket)
+		}
+	}
+
+	hits := 0
+	c.lock.Lock()
+	for _, bucket := range buckets {
+		if bucket.key == "" {
+			continue
+		}
+		if result, ok := c.entries.Get(bucket.key); ok {
+			bucket.result = result
+			hits++
+		}
+	}
+	c.lock.Unlock()
+	atomic.AddUint64(&c.counter.stats.BucketHit, uint64(hits))
+	atomic.AddUint64(&c.counter.stats.BucketMiss, uint64(len(buckets)-hits))
+
+	limit, _ := strconv.Atoi(e.Model.Limit.Limit)
+	timeAlias := strings.Trim(e.Model.Time.Alias, "`")
+	// query the consecutive missing buckets at once, and split the result by the time column
+	for i := 0; i < len(buckets); {
+		if buckets[i].result != nil {
+			i++
+			continue
+		}
+		j := i
+		for j+1 < len(buckets) && buckets[j+1].result == nil {
+			j++
+		}
+		sql := chSql
+		if bucketSize > 0 {
+			sql = strings.NewReplacer(
+				queryCacheTimeStart, strconv.FormatInt(buckets[i].start, 10),
+				queryCacheTimeEnd, strconv.FormatInt(buckets[j].end, 10),
+			).Replace(template)
+		}
+		result, err := doQuery(sql)
+		if err != nil {
+			return nil, QUERY_CACHE_MISS, err
+		}
+		if result == nil {
+			result = &common.Result{}
+		}
+		// the result may be truncated by the limit, do not cache it
+		truncated := limit > 0 && len(result.Values) >= limit
+		c.split(result, buckets[i:j+1], bucketSize, timeAlias)
+		c.lock.Lock()
+		for _, bucket := range buckets[i : j+1] {
+			if bucket.key != "" && !truncated && len(bucket.result.Values) <= c.cfg.MaxItemRows {
+				c.entries.Add(bucket.key, bucket.result)
+			}
+		}
+		c.lock.Unlock()
+		i = j + 1
+	}
+
+	status := QUERY_CACHE_PARTIAL
+	if hits == 0 {
+		status = QUERY_CACHE_MISS
+		atomic.AddUint64(&c.counter.stats.CacheMiss, 1)
+	} else if hits == len(buckets) {
+		status = QUERY_CACHE_HIT
+		atomic.AddUint64(&c.counter.stats.CacheHit, 1)
+	} else {
+		atomic.AddUint64(&c.counter.stats.CachePartialHit, 1)
+	}
+	return queryCacheCallback(c.merge(e, buckets, limit, timeAlias), callbacks), status, nil
+}
+
+// parse returns the sql template with the time range placeholders and the bucket size, the bucket size is 0
+// if the query could not be split by time. An empty template means that the query could not be cached.
+func (c *QueryCache) parse(e *CHEngine, chSql string) (string, int64) {
+	if e.Model == nil || e.Model.Time == nil {
+		return "", 0
+	}
+	t := e.Model.Time
+	starts := queryCacheTimeStartRegexp.FindAllStringSubmatchIndex(chSql, -1)
+	ends := queryCacheTimeEndRegexp.FindAllStringSubmatchIndex(chSql, -1)
+	if len(starts) != 1 || len(ends) != 1 || t.TimeStartOperator != ">=" || t.TimeEndOperator != "<=" ||
+		chSql[starts[0][2]:starts[0][3]] != strconv.FormatInt(t.TimeStart, 10) ||
+		chSql[ends[0][2]:ends[0][3]] != strconv.FormatInt(t.TimeEnd, 10) || t.TimeStart > t.TimeEnd {
+		return "", 0
+	}
+	if starts[0][0] > ends[0][0] {
+		return "", 0
+	}
+	template := chSql[:starts[0][2]] + queryCacheTimeStart + chSql[starts[0][3]:ends[0][2]] + queryCacheTimeEnd + chSql[ends[0][3]:]
+
+	// only the queries grouped by time without window, offset, derivative, or the offset of limit
+	// have the rows belonging to the aligned buckets
+	if t.Alias == "" || t.WindowSize > 1 || t.Offset != 0 || e.IsDerivative || e.Model.Limit.Offset != "" {
+		return template, 0
+	}
+	if len(e.Model.Orders.Orders) > 0 {
+		if order, ok := e.Model.Orders.Orders[0].(*view.Order); !ok || strings.Trim(order.SortBy, "`") != strings.Trim(t.Alias, "`") {
+			return template, 0
+		}
+	}
+	intervals := queryCacheIntervalRegexp.FindAllStringSubmatch(chSql, -1)
+	if len(intervals) == 0 {
+		return template, 0
+	}
+	interval, _ := strconv.ParseInt(intervals[0][1], 10, 64)
+	for _, i := range intervals[1:] {
+		if i[1] != intervals[0][1] {
+			return template, 0
+		}
+	}
+	if interval <= 0 || c.cfg.BucketSize <= 0 {
+		return template, 0
+	}
+	// the bucket size is a multiple of the interval, so that each time group belongs to one bucket
+	bucketSize := (int64(c.cfg.BucketSize) + interval - 1) / interval * interval
+	return template, bucketSize
+}
+
+func (c *QueryCache) key(db, sql string, bucketStart int64) string {
+	return fmt.Sprintf("%s:%d:%s", db, bucketStart, strings.Join(strings.Fields(sql), " "))
+}
+
+// split distributes the rows of the result into the buckets by the time column
+func (c *QueryCache) split(result *common.Result, buckets []*queryCacheBucket, bucketSize int64, timeAlias string) {
+	for _, bucket := range buckets {
+		bucket.result = &common.Result{Columns: result.Columns, Schemas: result.Schemas}
+	}
+	timeIndex := -1
+	for i, column := range result.Columns {
+		if column == timeAlias {
+			timeIndex = i
+		}
+	}
+	for _, value := range result.Values {
+		bucket := buckets[0]
+		if bucketSize > 0 && timeIndex >= 0 {
+			row, _ := value.([]interface{})
+			if t, ok := queryCacheTimeOf(row, timeIndex); ok {
+				for _, b := range buckets {
+					if t >= b.start/bucketSize*bucketSize && t <= b.end {
+						bucket = b
+						break
+					}
+				}
+			} else {
+				// the bucket of the row is unknown, do not cache the result
+				for _, b := range buckets {
+					b.key = ""
+				}
+			}
+		}
+		bucket.result.Values = append(bucket.result.Values, value)
+	}
+}
+
+// merge copies the rows of the buckets, so that the callbacks do not modify the cached results
+func (c *QueryCache) merge(e *CHEngine, buckets []*queryCacheBucket, limit int, timeAlias string) *common.Result {
+	result := &common.Result{}
+	reverse := false
+	if len(e.Model.Orders.Orders) > 0 {
+		if order, ok := e.Model.Orders.Orders[0].(*view.Order); ok && strings.ToLower(order.OrderBy) == "desc" {
+			reverse = true
+		}
+	}
+	for i := range buckets {
+		bucket := buckets[i]
+		if reverse {
+			bucket = buckets[len(buckets)-1-i]
+		}
+		if result.Columns == nil && bucket.result.Columns != nil {
+			result.Columns = bucket.result.Columns
+			for _, schema := range bucket.result.Schemas {
+				s := *schema
+				result.Schemas = append(result.Schemas, &s)
+			}
+		}
+		for _, value := range bucket.result.Values {
+			if limit > 0 && len(result.Values) >= limit {
+				break
+			}
+			if row, ok := value.([]interface{}); ok {
+				value = append([]interface{}{}, row...)
+			}
+			result.Values = append(result.Values, value)
+		}
+	}
+	return result
+}
+
+func queryCacheTimeOf(row []interface{}, index int) (int64, bool) {
+	if index >= len(row) {
+		return 0, false
+	}
+	switch v := row[index].(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), true
+	case time.Time:
+		return v.Unix(), true
+	}
+	return 0, false
+}
+
+func queryCacheCallback(result *common.Result, callbacks map[string]func(*common.Result) error) *common.Result {
+	if result == nil {
+		return nil
+	}
+	for _, callback := range callbacks {
+		err := callback(result)
+		if err != nil {
+			log.Error("Execute Callback %v Error: %v", callback, err)
+		}
+	}
+	return result
+}
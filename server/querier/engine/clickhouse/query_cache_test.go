@@ -0,0 +1,120 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clickhouse
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/deepflowio/deepflow/server/querier/common"
+	"github.com/deepflowio/deepflow/server/querier/config"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/view"
+	"github.com/deepflowio/deepflow/server/querier/parse"
+)
+
+func newQueryCacheEngine(t *testing.T, sql string) (*CHEngine, string) {
+	e := &CHEngine{DB: "flow_log", Context: context.Background()}
+	e.Init()
+	parser := parse.Parser{Engine: e}
+	if err := parser.ParseSQL(sql); err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range e.Statements {
+		stmt.Format(e.Model)
+	}
+	FormatModel(e.Model)
+	e.View = view.NewView(e.Model)
+	return e, e.ToSQLString()
+}
+
+// fakeQuery returns a row of each minute in the time range of the sql
+func fakeQuery(sqls *[]string) func(sql string) (*common.Result, error) {
+	return func(sql string) (*common.Result, error) {
+		*sqls = append(*sqls, sql)
+		start, _ := strconv.ParseInt(queryCacheTimeStartRegexp.FindStringSubmatch(sql)[1], 10, 64)
+		end, _ := strconv.ParseInt(queryCacheTimeEndRegexp.FindStringSubmatch(sql)[1], 10, 64)
+		result := &common.Result{Columns: []interface{}{"toi", "sum_byte"}}
+		for t := start / 60 * 60; t <= end; t += 60 {
+			result.Values = append(result.Values, []interface{}{int(t), 1})
+		}
+		return result, nil
+	}
+}
+
+func TestQueryCache(t *testing.T) {
+	Load()
+	c := NewQueryCache(config.QueryCache{Enabled: true, MaxCount: 16, MaxItemRows: 10000, BucketSize: 3600, ImmutableDelay: 300})
+	c.now = func() time.Time { return time.Unix(1700020000, 0) }
+	sql := "select time(time, 60) as toi, Sum(byte) as sum_byte from l4_flow_log where time>=1700000000 and time<=1700010799 group by toi limit 100000"
+
+	e, chSql := newQueryCacheEngine(t, sql)
+	template, bucketSize := c.parse(e, chSql)
+	if template == "" || bucketSize != 3600 {
+		t.Fatalf("sql %s should be split, bucket size %d", chSql, bucketSize)
+	}
+
+	// the first query misses, all the buckets are queried at once
+	sqls := []string{}
+	first, status, err := c.Execute(e, chSql, nil, fakeQuery(&sqls))
+	if err != nil || status != QUERY_CACHE_MISS || len(sqls) != 1 || sqls[0] != chSql {
+		t.Fatalf("status %s, sqls %v, err %v", status, sqls, err)
+	}
+	expected, _ := fakeQuery(&[]string{})(chSql)
+	if !reflect.DeepEqual(first.Values, expected.Values) {
+		t.Errorf("unexpected values %v", first.Values)
+	}
+
+	// the full buckets are reused, only the head and the tail are queried
+	e, chSql = newQueryCacheEngine(t, sql)
+	sqls = []string{}
+	second, status, err := c.Execute(e, chSql, nil, fakeQuery(&sqls))
+	if err != nil || status != QUERY_CACHE_PARTIAL || len(sqls) != 2 {
+		t.Fatalf("status %s, sqls %v, err %v", status, sqls, err)
+	}
+	if !reflect.DeepEqual(second.Values, expected.Values) {
+		t.Errorf("unexpected values %v", second.Values)
+	}
+	if queryCacheTimeEndRegexp.FindStringSubmatch(sqls[0])[1] != "1700002799" ||
+		queryCacheTimeStartRegexp.FindStringSubmatch(sqls[1])[1] != "1700010000" {
+		t.Errorf("unexpected sqls %v", sqls)
+	}
+
+	// the callbacks do not modify the cached results
+	e, chSql = newQueryCacheEngine(t, sql)
+	callbacks := map[string]func(*common.Result) error{"test": func(result *common.Result) error {
+		for _, value := range result.Values {
+			value.([]interface{})[1] = 0
+		}
+		return nil
+	}}
+	c.Execute(e, chSql, callbacks, fakeQuery(&[]string{}))
+	e, chSql = newQueryCacheEngine(t, sql)
+	third, _, _ := c.Execute(e, chSql, nil, fakeQuery(&[]string{}))
+	if !reflect.DeepEqual(third.Values, expected.Values) {
+		t.Errorf("cached values are modified %v", third.Values)
+	}
+
+	// the recent queries not grouped by time are not cached
+	e, chSql = newQueryCacheEngine(t, "select Sum(byte) as sum_byte from l4_flow_log where time>=1700019000 and time<=1700019999")
+	sqls = []string{}
+	if _, status, _ := c.Execute(e, chSql, nil, fakeQuery(&sqls)); status != QUERY_CACHE_BYPASS || len(sqls) != 1 {
+		t.Errorf("status %s, sqls %v", status, sqls)
+	}
+}
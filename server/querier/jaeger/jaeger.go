@@ -0,0 +1,514 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jaeger
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deepflowio/tempopb"
+	"github.com/google/uuid"
+	logging "github.com/op/go-logging"
+
+	"github.com/deepflowio/deepflow/server/querier/common"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse"
+	"github.com/deepflowio/deepflow/server/querier/tempo"
+)
+
+var log = logging.MustGetLogger("querier.jaeger")
+
+const (
+	DEFAULT_SEARCH_LIMIT = 20
+	DEFAULT_LOOKBACK     = time.Hour
+	OPERATION_LOOKBACK   = 24 * time.Hour
+	OPERATION_LIMIT      = 1000
+	PROCESS_TAG_LIMIT    = 10000
+	SEARCH_SPAN_LIMIT    = 100000
+
+	OTEL_LIBRARY_NAME    = "otel.library.name"
+	OTEL_LIBRARY_VERSION = "otel.library.version"
+)
+
+// the universal tags of the service side added to the process tags
+var PROCESS_TAGS = []string{
+	"region", "az", "host", "chost", "vpc", "subnet", "pod_cluster", "pod_ns", "pod_node", "pod_group", "pod", "ip",
+}
+
+// the span fields of l7_flow_log queried by SearchTraces, they follow the fields of the process tags
+var SPAN_FIELDS = []string{
+	"trace_id", "span_id", "parent_span_id", tempo.L7_TRACING_ENDPOINT, "request_resource",
+	"toUnixTimestamp64Micro(start_time) AS start_time_us", "toUnixTimestamp64Micro(end_time) AS end_time_us", "attribute",
+}
+
+var tagKeyRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.\-/]*$`)
+
+type SearchParams struct {
+	Service     string
+	Operation   string
+	Tags        map[string]string
+	StartTime   int64 // us
+	EndTime     int64 // us
+	MinDuration string
+	MaxDuration string
+	Limit       int
+	Context     context.Context
+}
+
+func NewResponse(data interface{}, total int) *Response {
+	return &Response{Data: data, Total: total}
+}
+
+func NewErrorResponse(code int, err error) *Response {
+	return &Response{Errors: []ResponseError{{Code: code, Msg: err.Error()}}}
+}
+
+func query(ctx context.Context, sql string) (*common.Result, map[string]interface{}, error) {
+	querierArgs := common.QuerierParams{
+		DB:         "flow_log",
+		Sql:        sql,
+		DataSource: "",
+		Debug:      "false",
+		QueryUUID:  uuid.New().String(),
+		Context:    ctx,
+	}
+	ckEngine := &clickhouse.CHEngine{DB: querierArgs.DB, DataSource: querierArgs.DataSource}
+	ckEngine.Init()
+	return ckEngine.ExecuteQuery(&querierArgs)
+}
+
+// GetServices returns the app_service of l7_flow_log
+func GetServices(ctx context.Context) ([]string, error) {
+	result, debug, err := query(ctx, fmt.Sprintf("show tag %s values from %s", tempo.L7_FLOW_LOG_SERVICE_NAME, tempo.TABLE_NAME_L7_FLOW_LOG))
+	if err != nil {
+		log.Errorf("%v %v", debug, err)
+		return nil, err
+	}
+	services := []string{}
+	for _, d := range result.Values {
+		if service, ok := d.([]interface{})[0].(string); ok && service != "" {
+			services = append(services, service)
+		}
+	}
+	return services, nil
+}
+
+// GetOperations returns the endpoints of the service in the last OPERATION_LOOKBACK
+func GetOperations(ctx context.Context, service string) ([]string, error) {
+	sql := fmt.Sprintf("SELECT %s FROM %s WHERE %s=%s AND %s!='' AND time>=%d GROUP BY %s LIMIT %d",
+		tempo.L7_TRACING_ENDPOINT, tempo.TABLE_NAME_L7_FLOW_LOG, tempo.L7_FLOW_LOG_SERVICE_NAME, tempo.EscapeSQLString(service),
+		tempo.L7_TRACING_ENDPOINT, time.Now().Add(-OPERATION_LOOKBACK).Unix(), tempo.L7_TRACING_ENDPOINT, OPERATION_LIMIT)
+	result, debug, err := query(ctx, sql)
+	if err != nil {
+		log.Errorf("%v %v", debug, err)
+		return nil, err
+	}
+	operations := []string{}
+	for _, d := range result.Values {
+		if operation, ok := d.([]interface{})[0].(string); ok {
+			operations = append(operations, operation)
+		}
+	}
+	sort.Strings(operations)
+	return operations, nil
+}
+
+// TraceQL converts the search params to TraceQL, the tags are matched as unscoped attributes
+func (p *SearchParams) TraceQL() (string, error) {
+	conditions := []string{}
+	if p.Service != "" {
+		conditions = append(conditions, "resource.service.name = "+strconv.Quote(p.Service))
+	}
+	if p.Operation != "" {
+		conditions = append(conditions, "name = "+strconv.Quote(p.Operation))
+	}
+	keys := make([]string, 0, len(p.Tags))
+	for k := range p.Tags {
+		if !tagKeyRegexp.MatchString(k) {
+			return "", fmt.Errorf("invalid tag %s", k)
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		conditions = append(conditions, fmt.Sprintf(".%s = %s", k, strconv.Quote(p.Tags[k])))
+	}
+	return "{ " + strings.Join(conditions, " && ") + " }", nil
+}
+
+// SearchTraces searches the trace ids by TraceQL, and then finds the spans of all the traces by one query
+func SearchTraces(p *SearchParams) ([]*Trace, error) {
+	q, err := p.TraceQL()
+	if err != nil {
+		return nil, err
+	}
+	if p.EndTime == 0 {
+		p.EndTime = time.Now().UnixMicro()
+	}
+	if p.StartTime == 0 {
+		p.StartTime = p.EndTime - DEFAULT_LOOKBACK.Microseconds()
+	}
+	if p.Limit <= 0 {
+		p.Limit = DEFAULT_SEARCH_LIMIT
+	}
+	startTime, endTime := microsToSeconds(p.StartTime, p.EndTime)
+	args := common.TempoParams{
+		StartTime:   startTime,
+		EndTime:     endTime,
+		MinDuration: p.MinDuration,
+		MaxDuration: p.MaxDuration,
+		Limit:       strconv.Itoa(p.Limit),
+		Query:       q,
+		Context:     p.Context,
+	}
+	resp, debug, err := tempo.TraceSearch(&args)
+	if err != nil {
+		log.Errorf("%v %v", debug, err)
+		return nil, err
+	}
+
+	traceIDs := []string{}
+	for _, t := range resp["traces"].([]map[string]interface{}) {
+		if traceID, _ := t["traceID"].(string); traceID != "" {
+			traceIDs = append(traceIDs, traceID)
+		}
+	}
+	traces := []*Trace{}
+	if len(traceIDs) == 0 {
+		return traces, nil
+	}
+	traceMap, err := findTraces(p.Context, traceIDs, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	// keep the order of the search result
+	for _, traceID := range traceIDs {
+		if trace, ok := traceMap[traceID]; ok {
+			traces = append(traces, trace)
+		}
+	}
+	return traces, nil
+}
+
+// findTraces queries the app spans of the traces with their process tags, and groups them by trace id
+func findTraces(ctx context.Context, traceIDs []string, startTime, endTime string) (map[string]*Trace, error) {
+	ids := make([]string, len(traceIDs))
+	for i, traceID := range traceIDs {
+		ids[i] = tempo.EscapeSQLString(traceID)
+	}
+	fields := append(processTagFields(), SPAN_FIELDS...)
+	sql := fmt.Sprintf("SELECT %s FROM %s WHERE trace_id IN (%s) AND %s!='' AND time>=%s AND time<=%s LIMIT %d",
+		strings.Join(fields, ", "), tempo.TABLE_NAME_L7_FLOW_LOG, strings.Join(ids, ", "), tempo.L7_FLOW_LOG_SERVICE_NAME,
+		startTime, endTime, SEARCH_SPAN_LIMIT)
+	result, debug, err := query(ctx, sql)
+	if err != nil {
+		log.Errorf("%v %v", debug, err)
+		return nil, err
+	}
+	if len(result.Values) >= SEARCH_SPAN_LIMIT {
+		log.Warningf("the spans of %d traces exceed the limit %d, some spans are missing", len(traceIDs), SEARCH_SPAN_LIMIT)
+	}
+	return convertSpanRows(result.Values), nil
+}
+
+// convertSpanRows converts the rows of processTagFields and SPAN_FIELDS to traces, each service is converted to a process
+func convertSpanRows(rows []interface{}) map[string]*Trace {
+	spanOffset := len(processTagFields())
+	traceRows := map[string][]interface{}{}
+	for _, row := range rows {
+		traceID, _ := row.([]interface{})[spanOffset].(string)
+		traceRows[traceID] = append(traceRows[traceID], row)
+	}
+
+	traces := make(map[string]*Trace, len(traceRows))
+	for traceID, rows := range traceRows {
+		trace := &Trace{
+			TraceID:   strings.ReplaceAll(traceID, "-", ""),
+			Spans:     []Span{},
+			Processes: map[string]Process{},
+		}
+		processTags := parseProcessTags(rows)
+		processIDs := map[string]string{}
+		for _, row := range rows {
+			values := row.([]interface{})
+			service, _ := values[0].(string)
+			tapSide, _ := values[1].(string)
+			processID, ok := processIDs[service]
+			if !ok {
+				processID = fmt.Sprintf("p%d", len(processIDs)+1)
+				processIDs[service] = processID
+				trace.Processes[processID] = Process{ServiceName: service, Tags: append([]KeyValue{}, processTags[service]...)}
+			}
+			trace.Spans = append(trace.Spans, convertSpanRow(trace.TraceID, processID, tapSide, values[spanOffset:]))
+		}
+		sort.SliceStable(trace.Spans, func(i, j int) bool { return trace.Spans[i].StartTime < trace.Spans[j].StartTime })
+		removeDanglingReferences(trace)
+		traces[traceID] = trace
+	}
+	return traces
+}
+
+func convertSpanRow(traceID, processID, tapSide string, values []interface{}) Span {
+	spanID, _ := values[1].(string)
+	parentSpanID, _ := values[2].(string)
+	operationName, _ := values[3].(string)
+	if operationName == "" {
+		operationName, _ = values[4].(string)
+	}
+	startTime, endTime := uint64Value(values[5]), uint64Value(values[6])
+	span := Span{
+		TraceID:       traceID,
+		SpanID:        strings.TrimPrefix(spanID, "0x"),
+		OperationName: operationName,
+		References:    []Reference{},
+		StartTime:     startTime,
+		Tags:          []KeyValue{},
+		Logs:          []Log{},
+		ProcessID:     processID,
+	}
+	if span.SpanID == "" {
+		span.SpanID = fmt.Sprintf("%016x", rand.Uint64())
+	}
+	if endTime > startTime {
+		span.Duration = endTime - startTime
+	}
+	if parentSpanID = strings.TrimPrefix(parentSpanID, "0x"); parentSpanID != "" {
+		span.References = append(span.References, Reference{RefType: REF_CHILD_OF, TraceID: traceID, SpanID: parentSpanID})
+	}
+
+	attrs := map[string]string{}
+	if attribute, _ := values[7].(string); attribute != "" {
+		json.Unmarshal([]byte(attribute), &attrs)
+	}
+	if sdkName := attrs[tempo.L7_TRACING_OTEL_SDK_NAME]; sdkName != "" {
+		span.Tags = append(span.Tags,
+			stringTag(OTEL_LIBRARY_NAME, sdkName),
+			stringTag(OTEL_LIBRARY_VERSION, attrs[tempo.L7_TRACING_OTEL_SDK_VERSION]))
+	}
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		span.Tags = append(span.Tags, stringTag(k, attrs[k]))
+	}
+	span.Tags = append(span.Tags, stringTag("tap_side", tapSide))
+	return span
+}
+
+func uint64Value(value interface{}) uint64 {
+	switch v := value.(type) {
+	case int:
+		return uint64(v)
+	case float64:
+		return uint64(v)
+	}
+	return 0
+}
+
+// FindTrace returns nil if the trace is not found, startTime and endTime are optional
+func FindTrace(ctx context.Context, traceID string, startTime, endTime int64) (*Trace, error) {
+	args := common.TempoParams{
+		TraceId: traceID,
+		Context: ctx,
+	}
+	if startTime > 0 && endTime > 0 {
+		args.StartTime, args.EndTime = microsToSeconds(startTime, endTime)
+	}
+	t, err := tempo.FindTraceByTraceID(&args)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil || len(t.Batches) == 0 {
+		return nil, nil
+	}
+	trace := ConvertTempoTrace(t, traceID)
+	processTags, err := getProcessTags(&args)
+	if err != nil {
+		log.Warningf("get process tags of trace %s failed: %s", traceID, err)
+		trace.Warnings = append(trace.Warnings, fmt.Sprintf("get process tags failed: %s", err))
+		return trace, nil
+	}
+	for id, process := range trace.Processes {
+		process.Tags = append(process.Tags, processTags[process.ServiceName]...)
+		trace.Processes[id] = process
+	}
+	return trace, nil
+}
+
+func microsToSeconds(startTime, endTime int64) (string, string) {
+	return strconv.FormatInt(startTime/1000000, 10), strconv.FormatInt((endTime+999999)/1000000, 10)
+}
+
+func stringTag(key, value string) KeyValue {
+	return KeyValue{Key: key, Type: VALUE_TYPE_STRING, Value: value}
+}
+
+func isZeroID(id []byte) bool {
+	for _, b := range id {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ConvertTempoTrace converts the trace built by tempo.ConvertL7TracingRespToProto to the jaeger model,
+// each ResourceSpans is converted to a process
+func ConvertTempoTrace(t *tempopb.Trace, traceID string) *Trace {
+	trace := &Trace{
+		TraceID:   traceID,
+		Spans:     []Span{},
+		Processes: map[string]Process{},
+	}
+	for i, batch := range t.Batches {
+		processID := fmt.Sprintf("p%d", i+1)
+		process := Process{Tags: []KeyValue{}}
+		if batch.Resource != nil {
+			for _, attr := range batch.Resource.Attributes {
+				if attr.Key == "" {
+					continue
+				}
+				if attr.Key == "service.name" {
+					process.ServiceName = attr.Value.GetStringValue()
+				} else {
+					process.Tags = append(process.Tags, stringTag(attr.Key, attr.Value.GetStringValue()))
+				}
+			}
+		}
+		trace.Processes[processID] = process
+
+		for _, il := range batch.InstrumentationLibrarySpans {
+			for _, s := range il.Spans {
+				span := Span{
+					TraceID:       hex.EncodeToString(s.TraceId),
+					SpanID:        hex.EncodeToString(s.SpanId),
+					OperationName: s.Name,
+					References:    []Reference{},
+					StartTime:     s.StartTimeUnixNano / 1000,
+					Tags:          []KeyValue{},
+					Logs:          []Log{},
+					ProcessID:     processID,
+				}
+				if s.EndTimeUnixNano > s.StartTimeUnixNano {
+					span.Duration = (s.EndTimeUnixNano - s.StartTimeUnixNano) / 1000
+				}
+				if len(s.ParentSpanId) > 0 && !isZeroID(s.ParentSpanId) {
+					span.References = append(span.References, Reference{
+						RefType: REF_CHILD_OF,
+						TraceID: span.TraceID,
+						SpanID:  hex.EncodeToString(s.ParentSpanId),
+					})
+				}
+				if il.InstrumentationLibrary != nil && il.InstrumentationLibrary.Name != "" {
+					span.Tags = append(span.Tags,
+						stringTag(OTEL_LIBRARY_NAME, il.InstrumentationLibrary.Name),
+						stringTag(OTEL_LIBRARY_VERSION, il.InstrumentationLibrary.Version))
+				}
+				for _, attr := range s.Attributes {
+					span.Tags = append(span.Tags, stringTag(attr.Key, attr.Value.GetStringValue()))
+				}
+				trace.Spans = append(trace.Spans, span)
+			}
+		}
+	}
+	// the ids which are not hex, including the empty parent ids of the root spans, are replaced by random bytes
+	// in the conversion, so the references to the spans out of the trace are removed
+	removeDanglingReferences(trace)
+	if len(trace.Spans) > 0 {
+		trace.TraceID = trace.Spans[0].TraceID
+	}
+	return trace
+}
+
+// removeDanglingReferences removes the references to the spans out of the trace
+func removeDanglingReferences(trace *Trace) {
+	spanIDs := make(map[string]bool, len(trace.Spans))
+	for _, span := range trace.Spans {
+		spanIDs[span.SpanID] = true
+	}
+	for i := range trace.Spans {
+		span := &trace.Spans[i]
+		if len(span.References) > 0 && !spanIDs[span.References[0].SpanID] {
+			span.References = span.References[:0]
+		}
+	}
+}
+
+// getProcessTags returns the universal tags of the services in the trace, the server side is used except for client spans
+func getProcessTags(args *common.TempoParams) (map[string][]KeyValue, error) {
+	fields := processTagFields()
+	filters := []string{
+		"trace_id=" + tempo.EscapeSQLString(args.TraceId),
+		tempo.L7_FLOW_LOG_SERVICE_NAME + "!=''",
+	}
+	if args.StartTime != "" && args.EndTime != "" {
+		filters = append(filters, "time>="+args.StartTime, "time<="+args.EndTime)
+	}
+	sql := fmt.Sprintf("SELECT %s FROM %s WHERE %s LIMIT %d",
+		strings.Join(fields, ", "), tempo.TABLE_NAME_L7_FLOW_LOG, strings.Join(filters, " AND "), PROCESS_TAG_LIMIT)
+	result, debug, err := query(args.Context, sql)
+	if err != nil {
+		log.Errorf("%v %v", debug, err)
+		return nil, err
+	}
+	return parseProcessTags(result.Values), nil
+}
+
+// processTagFields returns the service, the tap side and the client and server side of PROCESS_TAGS
+func processTagFields() []string {
+	fields := []string{tempo.L7_FLOW_LOG_SERVICE_NAME, "tap_side"}
+	for _, tag := range PROCESS_TAGS {
+		fields = append(fields, tag+"_0", tag+"_1")
+	}
+	return fields
+}
+
+func parseProcessTags(rows []interface{}) map[string][]KeyValue {
+	processTags := map[string][]KeyValue{}
+	for _, row := range rows {
+		values := row.([]interface{})
+		service, _ := values[0].(string)
+		if _, ok := processTags[service]; ok {
+			continue
+		}
+		side := 1
+		if tapSide, _ := values[1].(string); strings.HasPrefix(tapSide, "c") {
+			side = 0
+		}
+		tags := []KeyValue{}
+		for i, tag := range PROCESS_TAGS {
+			value := values[2+i*2+side]
+			if value == nil {
+				continue
+			}
+			if s := fmt.Sprint(value); s != "" {
+				tags = append(tags, stringTag(tag, s))
+			}
+		}
+		processTags[service] = tags
+	}
+	return processTags
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jaeger
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/deepflowio/deepflow/server/querier/tempo"
+)
+
+func TestConvertTempoTrace(t *testing.T) {
+	testData := `{"services": [{"service_uid": "-cart", "service_uname": "cart"}], "tracing": [
+		{"_ids": ["1"], "start_time_us": 1669188027800930, "end_time_us": 1669188027825683, "tap_side": "s-app", "endpoint": "/cart", "request_resource": "/cart", "trace_id": "5455e8b558250c7bfd2eed1bba623314", "service_uid": "-cart", "deepflow_span_id": "98576ec1ece19bb2", "deepflow_parent_span_id": "", "attributes": "{\"telemetry.sdk.name\": \"opentelemetry\", \"telemetry.sdk.version\": \"1.0\"}"},
+		{"_ids": ["2"], "start_time_us": 1669188027800940, "end_time_us": 1669188027800950, "tap_side": "c-app", "endpoint": "", "request_resource": "SELECT", "trace_id": "5455e8b558250c7bfd2eed1bba623314", "service_uid": "-cart", "deepflow_span_id": "0x98576ec1ece19bb3", "deepflow_parent_span_id": "98576ec1ece19bb2"}]}`
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(testData), &data); err != nil {
+		t.Fatal(err)
+	}
+	trace := ConvertTempoTrace(tempo.ConvertL7TracingRespToProto(data, "5455e8b558250c7bfd2eed1bba623314"), "5455e8b558250c7bfd2eed1bba623314")
+
+	if trace.TraceID != "5455e8b558250c7bfd2eed1bba623314" || len(trace.Spans) != 2 {
+		t.Fatalf("unexpected trace %+v", trace)
+	}
+	process, ok := trace.Processes["p1"]
+	if !ok || process.ServiceName != "cart" || !reflect.DeepEqual(process.Tags, []KeyValue{stringTag("service.id", "-cart")}) {
+		t.Errorf("unexpected processes %+v", trace.Processes)
+	}
+
+	root, child := trace.Spans[0], trace.Spans[1]
+	if root.SpanID != "98576ec1ece19bb2" || len(root.References) != 0 || root.Duration != 24753 || root.StartTime != 1669188027800930 || root.OperationName != "/cart" {
+		t.Errorf("unexpected root span %+v", root)
+	}
+	if root.Tags[0] != stringTag(OTEL_LIBRARY_NAME, "opentelemetry") || root.Tags[1] != stringTag(OTEL_LIBRARY_VERSION, "1.0") {
+		t.Errorf("unexpected root span tags %+v", root.Tags)
+	}
+	expectedReferences := []Reference{{RefType: REF_CHILD_OF, TraceID: trace.TraceID, SpanID: "98576ec1ece19bb2"}}
+	if child.SpanID != "98576ec1ece19bb3" || !reflect.DeepEqual(child.References, expectedReferences) || child.OperationName != "SELECT" || child.ProcessID != "p1" {
+		t.Errorf("unexpected child span %+v", child)
+	}
+}
+
+func TestSearchParamsTraceQL(t *testing.T) {
+	p := &SearchParams{Service: "cart", Operation: `GET "/"`, Tags: map[string]string{"http.status_code": "500", "error": "true"}}
+	q, err := p.TraceQL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{ resource.service.name = "cart" && name = "GET \"/\"" && .error = "true" && .http.status_code = "500" }`
+	if q != expected {
+		t.Errorf("got %s, want %s", q, expected)
+	}
+	if _, err := tempo.CompileTraceQL(q); err != nil {
+		t.Errorf("compile %s failed: %s", q, err)
+	}
+
+	p = &SearchParams{Service: "cart", Tags: map[string]string{"a = 1 } || {": ""}}
+	if _, err := p.TraceQL(); err == nil {
+		t.Error("invalid tag should fail")
+	}
+}
+
+func TestParseProcessTags(t *testing.T) {
+	row := func(service, tapSide string) []interface{} {
+		values := []interface{}{service, tapSide}
+		for _, tag := range PROCESS_TAGS {
+			values = append(values, tag+"-client", tag+"-server")
+		}
+		return values
+	}
+	clientRow := row("db", "c-app")
+	clientRow[2] = ""
+	tags := parseProcessTags([]interface{}{row("cart", "s-app"), row("cart", "c-app"), clientRow})
+	if len(tags["cart"]) != len(PROCESS_TAGS) || tags["cart"][0] != stringTag("region", "region-server") {
+		t.Errorf("unexpected tags of cart %+v", tags["cart"])
+	}
+	if len(tags["db"]) != len(PROCESS_TAGS)-1 || tags["db"][0] != stringTag("az", "az-client") {
+		t.Errorf("unexpected tags of db %+v", tags["db"])
+	}
+}
+
+func TestConvertSpanRows(t *testing.T) {
+	row := func(service, tapSide, traceID, spanID, parentSpanID, endpoint string, startTime int, attribute string) []interface{} {
+		values := []interface{}{service, tapSide}
+		for _, tag := range PROCESS_TAGS {
+			values = append(values, tag+"-client", tag+"-server")
+		}
+		return append(values, traceID, spanID, parentSpanID, endpoint, "SELECT", startTime, startTime+10, attribute)
+	}
+	rows := []interface{}{
+		row("db", "c-app", "trace-1", "0x98576ec1ece19bb3", "98576ec1ece19bb2", "", 1669188027800940, ""),
+		row("cart", "s-app", "trace-1", "98576ec1ece19bb2", "ffffffffffffffff", "/cart", 1669188027800930, `{"telemetry.sdk.name":"opentelemetry","telemetry.sdk.version":"1.0","http.method":"GET"}`),
+		row("cart", "s-app", "trace-2", "98576ec1ece19bb4", "", "/cart", 1669188027800950, "{}"),
+	}
+	traces := convertSpanRows(rows)
+	if len(traces) != 2 {
+		t.Fatalf("unexpected traces %+v", traces)
+	}
+
+	trace := traces["trace-1"]
+	if trace.TraceID != "trace1" || len(trace.Spans) != 2 || len(trace.Processes) != 2 {
+		t.Fatalf("unexpected trace %+v", trace)
+	}
+	// the spans are sorted by the start time
+	root, child := trace.Spans[0], trace.Spans[1]
+	if root.SpanID != "98576ec1ece19bb2" || len(root.References) != 0 || root.Duration != 10 || root.OperationName != "/cart" {
+		t.Errorf("unexpected root span %+v", root)
+	}
+	expectedTags := []KeyValue{
+		stringTag(OTEL_LIBRARY_NAME, "opentelemetry"), stringTag(OTEL_LIBRARY_VERSION, "1.0"), stringTag("http.method", "GET"),
+		stringTag("telemetry.sdk.name", "opentelemetry"), stringTag("telemetry.sdk.version", "1.0"), stringTag("tap_side", "s-app"),
+	}
+	if !reflect.DeepEqual(root.Tags, expectedTags) {
+		t.Errorf("unexpected root span tags %+v", root.Tags)
+	}
+	expectedReferences := []Reference{{RefType: REF_CHILD_OF, TraceID: "trace1", SpanID: "98576ec1ece19bb2"}}
+	if child.SpanID != "98576ec1ece19bb3" || !reflect.DeepEqual(child.References, expectedReferences) || child.OperationName != "SELECT" {
+		t.Errorf("unexpected child span %+v", child)
+	}
+	if process := trace.Processes[child.ProcessID]; process.ServiceName != "db" || process.Tags[0] != stringTag("region", "region-client") {
+		t.Errorf("unexpected process of db %+v", process)
+	}
+	if process := trace.Processes[root.ProcessID]; process.ServiceName != "cart" || process.Tags[0] != stringTag("region", "region-server") {
+		t.Errorf("unexpected process of cart %+v", process)
+	}
+
+	if trace := traces["trace-2"]; len(trace.Spans) != 1 || trace.Spans[0].ProcessID != "p1" || len(trace.Spans[0].References) != 0 {
+		t.Errorf("unexpected trace %+v", trace)
+	}
+}
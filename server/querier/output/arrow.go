@@ -0,0 +1,159 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package output
+
+import (
+	"io"
+	"strings"
+
+	"github.com/apache/arrow/go/v11/arrow"
+	"github.com/apache/arrow/go/v11/arrow/array"
+	"github.com/apache/arrow/go/v11/arrow/ipc"
+	"github.com/apache/arrow/go/v11/arrow/memory"
+
+	"github.com/deepflowio/deepflow/server/querier/common"
+)
+
+const ARROW_BATCH_ROWS = 4096
+
+// ArrowWriter writes the rows as the Arrow IPC streaming format in record batches of ARROW_BATCH_ROWS rows,
+// the types of the fields are mapped from the clickhouse types of the columns, refer to: arrowDataType
+type ArrowWriter struct {
+	w       io.Writer
+	writer  *ipc.Writer
+	builder *array.RecordBuilder
+	rows    int
+}
+
+func NewArrowWriter(w io.Writer) *ArrowWriter {
+	return &ArrowWriter{w: w}
+}
+
+func (a *ArrowWriter) WriteHeader(columns []interface{}, schemas common.ColumnSchemas) error {
+	names := columnNames(columns)
+	fields := make([]arrow.Field, len(names))
+	for i, name := range names {
+		var schema *common.ColumnSchema
+		if i < len(schemas) {
+			schema = schemas[i]
+		}
+		fields[i] = arrow.Field{Name: name, Type: arrowDataType(schema), Nullable: true}
+	}
+	schema := arrow.NewSchema(fields, nil)
+	a.writer = ipc.NewWriter(a.w, ipc.WithSchema(schema))
+	a.builder = array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	return nil
+}
+
+func (a *ArrowWriter) WriteRow(values []interface{}) error {
+	for i, field := range a.builder.Fields() {
+		var value interface{}
+		if i < len(values) {
+			value = values[i]
+		}
+		switch b := field.(type) {
+		case *array.Int64Builder:
+			if v, ok := value.(int); ok {
+				b.Append(int64(v))
+			} else {
+				b.AppendNull()
+			}
+		case *array.Uint64Builder:
+			// the UInt64 values are converted to int by client.TransType
+			if v, ok := value.(int); ok {
+				b.Append(uint64(v))
+			} else {
+				b.AppendNull()
+			}
+		case *array.Float64Builder:
+			switch v := value.(type) {
+			case float64:
+				b.Append(v)
+			case int:
+				b.Append(float64(v))
+			default:
+				b.AppendNull()
+			}
+		case *array.StringBuilder:
+			if value == nil {
+				b.AppendNull()
+			} else {
+				b.Append(toString(value))
+			}
+		}
+	}
+	a.rows++
+	if a.rows >= ARROW_BATCH_ROWS {
+		return a.flush()
+	}
+	return nil
+}
+
+// Close writes the buffered rows and the end of the stream, the schema is written even if there is no row
+func (a *ArrowWriter) Close() error {
+	if a.writer == nil {
+		return nil
+	}
+	defer a.builder.Release()
+	if err := a.flush(); err != nil {
+		return err
+	}
+	return a.writer.Close()
+}
+
+func (a *ArrowWriter) flush() error {
+	if a.rows == 0 {
+		return nil
+	}
+	record := a.builder.NewRecord()
+	defer record.Release()
+	a.rows = 0
+	return a.writer.Write(record)
+}
+
+// arrowDataType maps the clickhouse type of the column to the arrow type: Int64 for the ints, Uint64 for UInt64,
+// Float64 for Float64 and Utf8 for the others, which are converted by toString. The collected results may have
+// no clickhouse type, the value type of the column is used instead.
+func arrowDataType(schema *common.ColumnSchema) arrow.DataType {
+	if schema == nil {
+		return arrow.BinaryTypes.String
+	}
+	if schema.DatabaseType == "" {
+		switch schema.ValueType {
+		case "Int":
+			return arrow.PrimitiveTypes.Int64
+		case "Float64":
+			return arrow.PrimitiveTypes.Float64
+		}
+		return arrow.BinaryTypes.String
+	}
+	databaseType := schema.DatabaseType
+	for _, wrapper := range []string{"LowCardinality", "Nullable"} {
+		if strings.HasPrefix(databaseType, wrapper+"(") && strings.HasSuffix(databaseType, ")") {
+			databaseType = databaseType[len(wrapper)+1 : len(databaseType)-1]
+		}
+	}
+	switch databaseType {
+	case "Int8", "Int16", "Int32", "Int64", "UInt8", "UInt16", "UInt32":
+		return arrow.PrimitiveTypes.Int64
+	case "UInt64":
+		return arrow.PrimitiveTypes.Uint64
+	case "Float64":
+		return arrow.PrimitiveTypes.Float64
+	}
+	return arrow.BinaryTypes.String
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package output
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/deepflowio/deepflow/server/querier/common"
+)
+
+// CSVWriter writes a header line of the column names and a line per row
+type CSVWriter struct {
+	w      *csv.Writer
+	record []string
+	rows   int
+}
+
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w)}
+}
+
+func (c *CSVWriter) WriteHeader(columns []interface{}, schemas common.ColumnSchemas) error {
+	c.record = make([]string, len(columns))
+	return c.w.Write(columnNames(columns))
+}
+
+func (c *CSVWriter) WriteRow(values []interface{}) error {
+	for i := range c.record {
+		if i < len(values) {
+			c.record[i] = toString(values[i])
+		} else {
+			c.record[i] = ""
+		}
+	}
+	if err := c.w.Write(c.record); err != nil {
+		return err
+	}
+	c.rows++
+	if c.rows%1024 == 0 {
+		c.w.Flush()
+		return c.w.Error()
+	}
+	return nil
+}
+
+func (c *CSVWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/deepflowio/deepflow/server/querier/common"
+)
+
+// NDJSONWriter writes each row as a json object of the column names and values, one line per row
+type NDJSONWriter struct {
+	w    *bufio.Writer
+	keys [][]byte
+}
+
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	return &NDJSONWriter{w: bufio.NewWriter(w)}
+}
+
+func (n *NDJSONWriter) WriteHeader(columns []interface{}, schemas common.ColumnSchemas) error {
+	n.keys = make([][]byte, len(columns))
+	for i, name := range columnNames(columns) {
+		key, err := json.Marshal(name)
+		if err != nil {
+			return err
+		}
+		n.keys[i] = key
+	}
+	return nil
+}
+
+func (n *NDJSONWriter) WriteRow(values []interface{}) error {
+	n.w.WriteByte('{')
+	for i, value := range values {
+		if i >= len(n.keys) {
+			break
+		}
+		if i > 0 {
+			n.w.WriteByte(',')
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		n.w.Write(n.keys[i])
+		n.w.WriteByte(':')
+		n.w.Write(data)
+	}
+	n.w.WriteByte('}')
+	if err := n.w.WriteByte('\n'); err != nil {
+		return err
+	}
+	// flush the full buffers only, so that the rows are streamed without a write per row
+	if n.w.Available() < n.w.Size()/4 {
+		return n.w.Flush()
+	}
+	return nil
+}
+
+func (n *NDJSONWriter) Close() error {
+	return n.w.Flush()
+}
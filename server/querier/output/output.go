@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/deepflowio/deepflow/server/querier/common"
+)
+
+const (
+	FORMAT_JSON   = "json"
+	FORMAT_NDJSON = "ndjson"
+	FORMAT_CSV    = "csv"
+	FORMAT_ARROW  = "arrow"
+)
+
+const (
+	CONTENT_TYPE_NDJSON = "application/x-ndjson"
+	CONTENT_TYPE_CSV    = "text/csv; charset=utf-8"
+	CONTENT_TYPE_ARROW  = "application/vnd.apache.arrow.stream"
+)
+
+// NewWriter returns the result writer of the format and its content type, the json format is not
+// a streaming format, the caller should encode the result as before
+func NewWriter(format string, w io.Writer) (common.ResultWriter, string, error) {
+	switch strings.ToLower(format) {
+	case FORMAT_NDJSON:
+		return NewNDJSONWriter(w), CONTENT_TYPE_NDJSON, nil
+	case FORMAT_CSV:
+		return NewCSVWriter(w), CONTENT_TYPE_CSV, nil
+	case FORMAT_ARROW:
+		return NewArrowWriter(w), CONTENT_TYPE_ARROW, nil
+	}
+	return nil, "", fmt.Errorf("unsupported output format %s", format)
+}
+
+// toString converts the value to the text of csv and arrow, nil is converted to the empty string,
+// the arrays and tuples are encoded as json
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	// the time and ip are encoded as json strings
+	if s, err := strconv.Unquote(string(data)); err == nil {
+		return s
+	}
+	return string(data)
+}
+
+func columnNames(columns []interface{}) []string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = toString(column)
+	}
+	return names
+}
@@ -0,0 +1,217 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package output
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/apache/arrow/go/v11/arrow"
+	"github.com/apache/arrow/go/v11/arrow/array"
+	"github.com/apache/arrow/go/v11/arrow/ipc"
+
+	"github.com/deepflowio/deepflow/server/querier/common"
+)
+
+var (
+	testColumns = []interface{}{"ip", "byte", "rtt", "tags"}
+	testRows    = [][]interface{}{
+		{"1.1.1.1", 100, 1.5, []string{"a", "b"}},
+		{"2.2.2.2", nil, 2, nil},
+	}
+)
+
+func writeTestRows(t *testing.T, writer common.ResultWriter) {
+	if err := writer.WriteHeader(testColumns, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range testRows {
+		if err := writer.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writeTestRows(t, NewNDJSONWriter(&buf))
+	expected := `{"ip":"1.1.1.1","byte":100,"rtt":1.5,"tags":["a","b"]}
+{"ip":"2.2.2.2","byte":null,"rtt":2,"tags":null}
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected ndjson %s", buf.String())
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writeTestRows(t, NewCSVWriter(&buf))
+	expected := `ip,byte,rtt,tags
+1.1.1.1,100,1.5,"[""a"",""b""]"
+2.2.2.2,,2,
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected csv %s", buf.String())
+	}
+}
+
+func TestNewWriter(t *testing.T) {
+	if _, _, err := NewWriter("xml", &bytes.Buffer{}); err == nil {
+		t.Errorf("xml should be unsupported")
+	}
+	if _, contentType, err := NewWriter("CSV", &bytes.Buffer{}); err != nil || contentType != CONTENT_TYPE_CSV {
+		t.Errorf("content type %s, err %v", contentType, err)
+	}
+}
+
+// readArrowRecords reads the stream by the arrow library, the records are retained and should be released by the caller
+func readArrowRecords(t *testing.T, data []byte) (*arrow.Schema, []arrow.Record) {
+	reader, err := ipc.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Release()
+	records := []arrow.Record{}
+	for reader.Next() {
+		record := reader.Record()
+		record.Retain()
+		records = append(records, record)
+	}
+	if err := reader.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return reader.Schema(), records
+}
+
+func checkArrowSchema(t *testing.T, schema *arrow.Schema, names []string, types []arrow.DataType) {
+	if len(schema.Fields()) != len(names) {
+		t.Fatalf("%d fields", len(schema.Fields()))
+	}
+	for i, field := range schema.Fields() {
+		if field.Name != names[i] || !arrow.TypeEqual(field.Type, types[i]) || !field.Nullable {
+			t.Errorf("field %d: name %s, type %s", i, field.Name, field.Type)
+		}
+	}
+}
+
+func TestArrowWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewArrowWriter(&buf)
+	schemas := common.ColumnSchemas{
+		{Name: "ip", DatabaseType: "String"},
+		{Name: "byte", DatabaseType: "Nullable(UInt32)"},
+		{Name: "rtt", DatabaseType: "Float64"},
+		{Name: "tags", DatabaseType: "Array(String)"},
+	}
+	if err := writer.WriteHeader(testColumns, schemas); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range testRows {
+		if err := writer.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	schema, records := readArrowRecords(t, buf.Bytes())
+	checkArrowSchema(t, schema, []string{"ip", "byte", "rtt", "tags"},
+		[]arrow.DataType{arrow.BinaryTypes.String, arrow.PrimitiveTypes.Int64, arrow.PrimitiveTypes.Float64, arrow.BinaryTypes.String})
+	if len(records) != 1 || records[0].NumRows() != int64(len(testRows)) {
+		t.Fatalf("unexpected records %v", records)
+	}
+	defer records[0].Release()
+
+	ip := records[0].Column(0).(*array.String)
+	if ip.NullN() != 0 || ip.Value(0) != "1.1.1.1" || ip.Value(1) != "2.2.2.2" {
+		t.Errorf("unexpected ip %v", ip)
+	}
+	byteColumn := records[0].Column(1).(*array.Int64)
+	if byteColumn.Value(0) != 100 || !byteColumn.IsNull(1) {
+		t.Errorf("unexpected byte %v", byteColumn)
+	}
+	rtt := records[0].Column(2).(*array.Float64)
+	if rtt.NullN() != 0 || rtt.Value(0) != 1.5 || rtt.Value(1) != 2 {
+		t.Errorf("unexpected rtt %v", rtt)
+	}
+	tags := records[0].Column(3).(*array.String)
+	if tags.Value(0) != `["a","b"]` || !tags.IsNull(1) {
+		t.Errorf("unexpected tags %v", tags)
+	}
+}
+
+func TestArrowWriterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewArrowWriter(&buf)
+	writer.WriteHeader([]interface{}{"a", "b"}, common.ColumnSchemas{{Name: "a", ValueType: "Int"}, {Name: "b"}})
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	schema, records := readArrowRecords(t, buf.Bytes())
+	// the collected results have no clickhouse type, the type is mapped from the value type
+	checkArrowSchema(t, schema, []string{"a", "b"}, []arrow.DataType{arrow.PrimitiveTypes.Int64, arrow.BinaryTypes.String})
+	if len(records) != 0 {
+		t.Errorf("only the schema should be written")
+	}
+}
+
+func TestArrowWriterBatches(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewArrowWriter(&buf)
+	schemas := common.ColumnSchemas{
+		{Name: "byte", DatabaseType: "Nullable(Int64)"},
+		{Name: "packet", DatabaseType: "LowCardinality(Nullable(UInt64))"},
+		{Name: "rtt", DatabaseType: "Nullable(Float64)"},
+	}
+	writer.WriteHeader([]interface{}{"byte", "packet", "rtt"}, schemas)
+	// the types are not affected by the nulls of the first batch
+	var err error
+	for i := 0; i < ARROW_BATCH_ROWS && err == nil; i++ {
+		err = writer.WriteRow([]interface{}{nil, nil, nil})
+	}
+	if err == nil {
+		var maxUint64 uint64 = math.MaxUint64
+		err = writer.WriteRow([]interface{}{1, int(maxUint64), 1.5})
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema, records := readArrowRecords(t, buf.Bytes())
+	checkArrowSchema(t, schema, []string{"byte", "packet", "rtt"},
+		[]arrow.DataType{arrow.PrimitiveTypes.Int64, arrow.PrimitiveTypes.Uint64, arrow.PrimitiveTypes.Float64})
+	if len(records) != 2 || records[0].NumRows() != ARROW_BATCH_ROWS || records[1].NumRows() != 1 {
+		t.Fatalf("unexpected records %v", records)
+	}
+	defer records[0].Release()
+	defer records[1].Release()
+	if records[0].Column(0).NullN() != ARROW_BATCH_ROWS {
+		t.Errorf("unexpected first batch %v", records[0])
+	}
+	byteColumn := records[1].Column(0).(*array.Int64)
+	packet := records[1].Column(1).(*array.Uint64)
+	rtt := records[1].Column(2).(*array.Float64)
+	if byteColumn.Value(0) != 1 || packet.Value(0) != math.MaxUint64 || rtt.Value(0) != 1.5 {
+		t.Errorf("unexpected second batch %v", records[1])
+	}
+}
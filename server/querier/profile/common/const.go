@@ -29,4 +29,10 @@ const (
 	TABLE_PROFILE        = "in_process"
 	PROFILE_LOCATION_STR = "profile_location_str"
 	PROFILE_VALUE        = "profile_value"
+	PROFILE_VALUE_UNIT   = "profile_value_unit"
+)
+
+const (
+	EXPORT_FORMAT_PPROF      = "pprof"
+	EXPORT_FORMAT_SPEEDSCOPE = "speedscope"
 )
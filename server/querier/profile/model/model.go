@@ -30,6 +30,28 @@ type ProfileTracing struct {
 	Context             context.Context
 }
 
+type ProfileExport struct {
+	ProfileTracing
+	Format string `json:"format" binding:"required,oneof=pprof speedscope"`
+}
+
+// ProfileSelector selects the rows of one side of the ProfileDiff
+type ProfileSelector struct {
+	TagFilter string `json:"tag_filter"`
+	TimeStart int    `json:"time_start" binding:"required"`
+	TimeEnd   int    `json:"time_end" binding:"required"`
+}
+
+type ProfileDiff struct {
+	AppService          string          `json:"app_service" binding:"required"`
+	ProfileEventType    string          `json:"profile_event_type" binding:"required"`
+	ProfileLanguageType string          `json:"profile_language_type" binding:"required"`
+	Baseline            ProfileSelector `json:"baseline"`
+	Comparison          ProfileSelector `json:"comparison"`
+	Debug               bool            `json:"debug"`
+	Context             context.Context
+}
+
 type ProfileTreeNode struct {
 	ProfileLocationStr string `json:"profile_location_str"`
 	NodeID             string `json:"node_id"`
@@ -38,6 +60,20 @@ type ProfileTreeNode struct {
 	TotalValue         int    `json:"total_value"`
 }
 
+// ProfileDiffNode compares the values of a node, the delta values are the comparison values
+// normalized to the total value of the baseline, minus the baseline values
+type ProfileDiffNode struct {
+	ProfileLocationStr   string `json:"profile_location_str"`
+	NodeID               string `json:"node_id"`
+	ParentNodeID         string `json:"parent_node_id"`
+	BaselineSelfValue    int    `json:"baseline_self_value"`
+	BaselineTotalValue   int    `json:"baseline_total_value"`
+	ComparisonSelfValue  int    `json:"comparison_self_value"`
+	ComparisonTotalValue int    `json:"comparison_total_value"`
+	DeltaSelfValue       int    `json:"delta_self_value"`
+	DeltaTotalValue      int    `json:"delta_total_value"`
+}
+
 type Debug struct {
 	IP        string `json:"ip"`
 	Sql       string `json:"sql"`
@@ -17,6 +17,9 @@
 package router
 
 import (
+	"fmt"
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 
@@ -29,6 +32,8 @@ import (
 
 func ProfileRouter(e *gin.Engine, cfg *config.QuerierConfig) {
 	e.POST("/v1/profile/ProfileTracing", profileTracing(cfg))
+	e.POST("/v1/profile/ProfileExport", profileExport(cfg))
+	e.POST("/v1/profile/ProfileDiff", profileDiff(cfg))
 
 }
 
@@ -50,3 +55,48 @@ func profileTracing(cfg *config.QuerierConfig) gin.HandlerFunc {
 		router.JsonResponse(c, result, debug, err)
 	})
 }
+
+func profileExport(cfg *config.QuerierConfig) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		var profileExport model.ProfileExport
+
+		// 参数校验
+		err := c.ShouldBindBodyWith(&profileExport, binding.JSON)
+		if err != nil {
+			router.BadRequestResponse(c, common.INVALID_POST_DATA, err.Error())
+			return
+		}
+		profileExport.Context = c.Request.Context()
+		data, debug, err := service.Export(profileExport, cfg)
+		if err != nil {
+			router.JsonResponse(c, nil, debug, err)
+			return
+		}
+		// the file is downloaded directly, so the debug info is not returned
+		contentType, fileName := "application/octet-stream", fmt.Sprintf("%s.pb.gz", profileExport.AppService)
+		if profileExport.Format == common.EXPORT_FORMAT_SPEEDSCOPE {
+			contentType, fileName = "application/json", fmt.Sprintf("%s.speedscope.json", profileExport.AppService)
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+		c.Data(http.StatusOK, contentType, data)
+	})
+}
+
+func profileDiff(cfg *config.QuerierConfig) gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		var profileDiff model.ProfileDiff
+
+		// 参数校验
+		err := c.ShouldBindBodyWith(&profileDiff, binding.JSON)
+		if err != nil {
+			router.BadRequestResponse(c, common.INVALID_POST_DATA, err.Error())
+			return
+		}
+		profileDiff.Context = c.Request.Context()
+		result, debug, err := service.Diff(profileDiff, cfg)
+		if err == nil && !profileDiff.Debug {
+			debug = nil
+		}
+		router.JsonResponse(c, result, debug, err)
+	})
+}
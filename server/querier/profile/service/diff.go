@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	controller_common "github.com/deepflowio/deepflow/server/controller/common"
+	"github.com/deepflowio/deepflow/server/querier/config"
+	"github.com/deepflowio/deepflow/server/querier/profile/model"
+)
+
+// Diff compares the flame graphs of the baseline and the comparison, which may be different time ranges or tag filters
+func Diff(args model.ProfileDiff, cfg *config.QuerierConfig) (result []*model.ProfileDiffNode, debug interface{}, err error) {
+	debugs := model.ProfileDebug{}
+	sides := [][]*ProfileStack{}
+	for _, selector := range []model.ProfileSelector{args.Baseline, args.Comparison} {
+		tracingArgs := model.ProfileTracing{
+			AppService:          args.AppService,
+			ProfileEventType:    args.ProfileEventType,
+			ProfileLanguageType: args.ProfileLanguageType,
+			TagFilter:           selector.TagFilter,
+			TimeStart:           selector.TimeStart,
+			TimeEnd:             selector.TimeEnd,
+			Debug:               args.Debug,
+			Context:             args.Context,
+		}
+		querierResult, queryErr := queryProfile(tracingArgs, cfg, &debugs)
+		if queryErr != nil {
+			return nil, debugs, queryErr
+		}
+		stacks, _, aggregateErr := aggregateStacks(querierResult)
+		if aggregateErr != nil {
+			return nil, debugs, aggregateErr
+		}
+		sides = append(sides, stacks)
+	}
+	formatStartTime := time.Now()
+	result = DiffStacks(sides[0], sides[1])
+	debugs.FormatTime = fmt.Sprintf("%.9fs", float64(time.Since(formatStartTime))/1e9)
+	return result, debugs, nil
+}
+
+// DiffStacks merges the stacks of both sides into one tree, the first node of the result is the root node
+func DiffStacks(baseline, comparison []*ProfileStack) []*model.ProfileDiffNode {
+	rootNode := &model.ProfileDiffNode{ProfileLocationStr: "root", ParentNodeID: "-1"}
+	nodes := []*model.ProfileDiffNode{rootNode}
+	nodeIDToNode := map[string]*model.ProfileDiffNode{}
+	merge := func(stacks []*ProfileStack, isBaseline bool) {
+		for _, stack := range stacks {
+			value := int(stack.Value)
+			if isBaseline {
+				rootNode.BaselineTotalValue += value
+			} else {
+				rootNode.ComparisonTotalValue += value
+			}
+			parentNodeID := ""
+			for i, location := range stack.Locations {
+				nodeID := controller_common.GenerateUUID(strings.Join(stack.Locations[:i+1], ";"))
+				node, ok := nodeIDToNode[nodeID]
+				if !ok {
+					node = &model.ProfileDiffNode{ProfileLocationStr: location, NodeID: nodeID, ParentNodeID: parentNodeID}
+					nodeIDToNode[nodeID] = node
+					nodes = append(nodes, node)
+				}
+				isLeaf := i == len(stack.Locations)-1
+				if isBaseline {
+					node.BaselineTotalValue += value
+					if isLeaf {
+						node.BaselineSelfValue += value
+					}
+				} else {
+					node.ComparisonTotalValue += value
+					if isLeaf {
+						node.ComparisonSelfValue += value
+					}
+				}
+				parentNodeID = nodeID
+			}
+		}
+	}
+	merge(baseline, true)
+	merge(comparison, false)
+
+	// normalize the comparison to the total of the baseline, so that the
+	// ranges of different lengths or the tag sets of different sizes are comparable
+	scale := 1.0
+	if rootNode.BaselineTotalValue > 0 && rootNode.ComparisonTotalValue > 0 {
+		scale = float64(rootNode.BaselineTotalValue) / float64(rootNode.ComparisonTotalValue)
+	}
+	for _, node := range nodes {
+		node.DeltaSelfValue = int(math.Round(float64(node.ComparisonSelfValue)*scale)) - node.BaselineSelfValue
+		node.DeltaTotalValue = int(math.Round(float64(node.ComparisonTotalValue)*scale)) - node.BaselineTotalValue
+	}
+	return nodes
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"testing"
+
+	"github.com/deepflowio/deepflow/server/querier/profile/model"
+)
+
+func TestDiffStacks(t *testing.T) {
+	baseline := []*ProfileStack{
+		{Locations: []string{"main", "foo"}, Value: 60},
+		{Locations: []string{"main", "bar"}, Value: 40},
+	}
+	// twice as long as the baseline, and bar regresses
+	comparison := []*ProfileStack{
+		{Locations: []string{"main", "foo"}, Value: 100},
+		{Locations: []string{"main", "bar"}, Value: 100},
+		{Locations: []string{"main", "baz"}, Value: 200},
+	}
+	nodes := DiffStacks(baseline, comparison)
+	nameToNode := map[string]*model.ProfileDiffNode{}
+	for _, node := range nodes {
+		nameToNode[node.ProfileLocationStr] = node
+	}
+	if len(nodes) != 5 || nodes[0].ProfileLocationStr != "root" || nodes[0].ParentNodeID != "-1" {
+		t.Fatalf("unexpected nodes %v", nodes)
+	}
+
+	cases := []struct {
+		name                                  string
+		baselineTotal, comparisonTotal, delta int
+	}{
+		{"root", 100, 400, 0},
+		{"main", 100, 400, 0},
+		{"foo", 60, 100, -35},
+		{"bar", 40, 100, -15},
+		{"baz", 0, 200, 50},
+	}
+	for _, c := range cases {
+		node := nameToNode[c.name]
+		if node.BaselineTotalValue != c.baselineTotal || node.ComparisonTotalValue != c.comparisonTotal || node.DeltaTotalValue != c.delta {
+			t.Errorf("%s: baseline %d, comparison %d, delta %d", c.name, node.BaselineTotalValue, node.ComparisonTotalValue, node.DeltaTotalValue)
+		}
+	}
+	if nameToNode["foo"].ParentNodeID != nameToNode["main"].NodeID || nameToNode["main"].ParentNodeID != "" {
+		t.Errorf("unexpected parents")
+	}
+	if nameToNode["baz"].ComparisonSelfValue != 200 || nameToNode["main"].BaselineSelfValue != 0 {
+		t.Errorf("unexpected self values")
+	}
+}
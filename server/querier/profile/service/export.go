@@ -0,0 +1,280 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pyroscope-io/pyroscope/pkg/storage/tree"
+
+	ingester_common "github.com/deepflowio/deepflow/server/ingester/profile/common"
+	querier_common "github.com/deepflowio/deepflow/server/querier/common"
+	"github.com/deepflowio/deepflow/server/querier/config"
+	"github.com/deepflowio/deepflow/server/querier/profile/common"
+	"github.com/deepflowio/deepflow/server/querier/profile/model"
+)
+
+const (
+	SPEEDSCOPE_SCHEMA   = "https://www.speedscope.app/file-format-schema.json"
+	SPEEDSCOPE_EXPORTER = "deepflow"
+)
+
+// units of pprof for the profile_value_unit
+var pprofUnits = map[string]string{
+	"samples":          "count",
+	"objects":          "count",
+	"goroutines":       "count",
+	"lock_samples":     "count",
+	"lock_nanoseconds": "nanoseconds",
+	"bytes":            "bytes",
+}
+
+// units of speedscope for the profile_value_unit, the others are 'none'
+var speedscopeUnits = map[string]string{
+	"lock_nanoseconds": "nanoseconds",
+	"bytes":            "bytes",
+}
+
+// ProfileStack is the aggregated value of a stack, the locations are from root to leaf
+type ProfileStack struct {
+	Locations []string
+	Value     int64
+}
+
+// Export aggregates the same rows as Tracing, and encodes them as a gzipped pprof profile or a speedscope json file
+func Export(args model.ProfileExport, cfg *config.QuerierConfig) (data []byte, debug interface{}, err error) {
+	debugs := model.ProfileDebug{}
+	querierResult, err := queryProfile(args.ProfileTracing, cfg, &debugs)
+	if err != nil {
+		return nil, debugs, err
+	}
+	stacks, unit, err := aggregateStacks(querierResult)
+	if err != nil {
+		return nil, debugs, err
+	}
+	switch args.Format {
+	case common.EXPORT_FORMAT_PPROF:
+		data, err = ToPprof(stacks, args.ProfileEventType, unit, args.TimeStart, args.TimeEnd)
+	case common.EXPORT_FORMAT_SPEEDSCOPE:
+		data, err = ToSpeedscope(stacks, fmt.Sprintf("%s %s", args.AppService, args.ProfileEventType), unit)
+	default:
+		err = NewError(common.INVALID_PARAMETERS, fmt.Sprintf("unsupported format %s", args.Format))
+	}
+	return data, debugs, err
+}
+
+// aggregateStacks merges the values of the same stacks, and returns them in the order of the stacks
+func aggregateStacks(result *querier_common.Result) ([]*ProfileStack, string, error) {
+	profileLocationStrIndex, profileValueIndex, profileValueUnitIndex := -1, -1, -1
+	for columnIndex, col := range result.Columns {
+		switch col {
+		case common.PROFILE_LOCATION_STR:
+			profileLocationStrIndex = columnIndex
+		case common.PROFILE_VALUE:
+			profileValueIndex = columnIndex
+		case common.PROFILE_VALUE_UNIT:
+			profileValueUnitIndex = columnIndex
+		}
+	}
+	if profileLocationStrIndex == -1 || profileValueIndex == -1 || profileValueUnitIndex == -1 {
+		log.Error("Not all fields found")
+		return nil, "", NewError(common.SERVER_ERROR, "Not all fields found")
+	}
+
+	unit := ""
+	stackMap := map[string]*ProfileStack{}
+	for _, value := range result.Values {
+		valueSlice, ok := value.([]interface{})
+		if !ok {
+			continue
+		}
+		profileLocationStr, _ := valueSlice[profileLocationStrIndex].(string)
+		profileValue, _ := valueSlice[profileValueIndex].(int)
+		if unit == "" {
+			unit, _ = valueSlice[profileValueUnitIndex].(string)
+		}
+		dst := make([]byte, 0, len(profileLocationStr))
+		profileLocationStrByte, err := ingester_common.ZstdDecompress(dst, []byte(profileLocationStr))
+		if err != nil || len(profileLocationStrByte) == 0 {
+			continue
+		}
+		key := string(profileLocationStrByte)
+		if stack, ok := stackMap[key]; ok {
+			stack.Value += int64(profileValue)
+		} else {
+			stackMap[key] = &ProfileStack{Locations: strings.Split(key, ";"), Value: int64(profileValue)}
+		}
+	}
+	keys := make([]string, 0, len(stackMap))
+	for key := range stackMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	stacks := make([]*ProfileStack, 0, len(keys))
+	for _, key := range keys {
+		stacks = append(stacks, stackMap[key])
+	}
+	return stacks, unit, nil
+}
+
+type pprofBuilder struct {
+	profile   *tree.Profile
+	strings   map[string]int64
+	locations map[string]uint64
+}
+
+func (b *pprofBuilder) stringIndex(s string) int64 {
+	if index, ok := b.strings[s]; ok {
+		return index
+	}
+	index := int64(len(b.profile.StringTable))
+	b.profile.StringTable = append(b.profile.StringTable, s)
+	b.strings[s] = index
+	return index
+}
+
+// locationID returns the location of the function, as there is no address or line in the stacks,
+// each function has only one location
+func (b *pprofBuilder) locationID(name string) uint64 {
+	if id, ok := b.locations[name]; ok {
+		return id
+	}
+	id := uint64(len(b.profile.Location) + 1)
+	b.profile.Function = append(b.profile.Function, &tree.Function{
+		Id:         id,
+		Name:       b.stringIndex(name),
+		SystemName: b.stringIndex(name),
+	})
+	b.profile.Location = append(b.profile.Location, &tree.Location{
+		Id:   id,
+		Line: []*tree.Line{{FunctionId: id}},
+	})
+	b.locations[name] = id
+	return id
+}
+
+// ToPprof encodes the stacks as a gzipped pprof profile with one sample type of the event type
+func ToPprof(stacks []*ProfileStack, eventType, unit string, timeStart, timeEnd int) ([]byte, error) {
+	b := &pprofBuilder{
+		profile:   &tree.Profile{StringTable: []string{""}},
+		strings:   map[string]int64{"": 0},
+		locations: map[string]uint64{},
+	}
+	if pprofUnit, ok := pprofUnits[unit]; ok {
+		unit = pprofUnit
+	}
+	sampleType := &tree.ValueType{Type: b.stringIndex(eventType), Unit: b.stringIndex(unit)}
+	b.profile.SampleType = []*tree.ValueType{sampleType}
+	b.profile.PeriodType = sampleType
+	b.profile.TimeNanos = int64(timeStart) * 1e9
+	b.profile.DurationNanos = int64(timeEnd-timeStart) * 1e9
+	for _, stack := range stacks {
+		// the locations of pprof samples are from leaf to root
+		locationIDs := make([]uint64, len(stack.Locations))
+		for i, location := range stack.Locations {
+			locationIDs[len(stack.Locations)-1-i] = b.locationID(location)
+		}
+		b.profile.Sample = append(b.profile.Sample, &tree.Sample{LocationId: locationIDs, Value: []int64{stack.Value}})
+	}
+
+	data, err := b.profile.MarshalVT()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// the sampled profile of speedscope, refer to: https://github.com/jlfwong/speedscope/blob/main/src/lib/file-format-spec.ts
+type speedscopeFile struct {
+	Schema             string              `json:"$schema"`
+	Shared             speedscopeShared    `json:"shared"`
+	Profiles           []speedscopeProfile `json:"profiles"`
+	Name               string              `json:"name"`
+	ActiveProfileIndex int                 `json:"activeProfileIndex"`
+	Exporter           string              `json:"exporter"`
+}
+
+type speedscopeShared struct {
+	Frames []speedscopeFrame `json:"frames"`
+}
+
+type speedscopeFrame struct {
+	Name string `json:"name"`
+}
+
+type speedscopeProfile struct {
+	Type       string  `json:"type"`
+	Name       string  `json:"name"`
+	Unit       string  `json:"unit"`
+	StartValue int64   `json:"startValue"`
+	EndValue   int64   `json:"endValue"`
+	Samples    [][]int `json:"samples"`
+	Weights    []int64 `json:"weights"`
+}
+
+// ToSpeedscope encodes the stacks as a speedscope json file with one sampled profile
+func ToSpeedscope(stacks []*ProfileStack, name, unit string) ([]byte, error) {
+	speedscopeUnit, ok := speedscopeUnits[unit]
+	if !ok {
+		speedscopeUnit = "none"
+	}
+	file := &speedscopeFile{
+		Schema:   SPEEDSCOPE_SCHEMA,
+		Shared:   speedscopeShared{Frames: []speedscopeFrame{}},
+		Name:     name,
+		Exporter: SPEEDSCOPE_EXPORTER,
+	}
+	profile := speedscopeProfile{
+		Type:    "sampled",
+		Name:    name,
+		Unit:    speedscopeUnit,
+		Samples: make([][]int, 0, len(stacks)),
+		Weights: make([]int64, 0, len(stacks)),
+	}
+	frameIndexes := map[string]int{}
+	for _, stack := range stacks {
+		// the frames of speedscope samples are from root to leaf
+		sample := make([]int, 0, len(stack.Locations))
+		for _, location := range stack.Locations {
+			index, ok := frameIndexes[location]
+			if !ok {
+				index = len(file.Shared.Frames)
+				file.Shared.Frames = append(file.Shared.Frames, speedscopeFrame{Name: location})
+				frameIndexes[location] = index
+			}
+			sample = append(sample, index)
+		}
+		profile.Samples = append(profile.Samples, sample)
+		profile.Weights = append(profile.Weights, stack.Value)
+		profile.EndValue += stack.Value
+	}
+	file.Profiles = []speedscopeProfile{profile}
+	return json.Marshal(file)
+}
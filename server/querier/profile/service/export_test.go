@@ -0,0 +1,143 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pyroscope-io/pyroscope/pkg/storage/tree"
+
+	ingester_common "github.com/deepflowio/deepflow/server/ingester/profile/common"
+	querier_common "github.com/deepflowio/deepflow/server/querier/common"
+	"github.com/deepflowio/deepflow/server/querier/profile/common"
+)
+
+func newProfileResult(t *testing.T, rows map[string][]int) *querier_common.Result {
+	result := &querier_common.Result{
+		Columns: []interface{}{common.PROFILE_LOCATION_STR, common.PROFILE_VALUE, common.PROFILE_VALUE_UNIT},
+	}
+	for location, values := range rows {
+		compressed, err := ingester_common.ZstdCompress(nil, []byte(location), zstd.SpeedDefault)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, value := range values {
+			result.Values = append(result.Values, []interface{}{string(compressed), value, "bytes"})
+		}
+	}
+	return result
+}
+
+func TestAggregateStacks(t *testing.T) {
+	result := newProfileResult(t, map[string][]int{
+		"main;foo;bar": {10, 5},
+		"main;foo":     {3},
+	})
+	stacks, unit, err := aggregateStacks(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unit != "bytes" {
+		t.Errorf("unit = %s, want bytes", unit)
+	}
+	expected := []*ProfileStack{
+		{Locations: []string{"main", "foo"}, Value: 3},
+		{Locations: []string{"main", "foo", "bar"}, Value: 15},
+	}
+	if !reflect.DeepEqual(stacks, expected) {
+		t.Errorf("stacks = %v, want %v", stacks, expected)
+	}
+}
+
+func TestToPprof(t *testing.T) {
+	stacks := []*ProfileStack{
+		{Locations: []string{"main", "foo"}, Value: 3},
+		{Locations: []string{"main", "foo", "bar"}, Value: 15},
+	}
+	data, err := ToPprof(stacks, "inuse_space", "bytes", 100, 160)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := &tree.Profile{}
+	if err := profile.UnmarshalVT(raw); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(profile.SampleType) != 1 ||
+		profile.StringTable[profile.SampleType[0].Type] != "inuse_space" ||
+		profile.StringTable[profile.SampleType[0].Unit] != "bytes" {
+		t.Errorf("unexpected sample type %v", profile.SampleType)
+	}
+	if profile.TimeNanos != 100e9 || profile.DurationNanos != 60e9 {
+		t.Errorf("time = %d, duration = %d", profile.TimeNanos, profile.DurationNanos)
+	}
+	if len(profile.Function) != 3 || len(profile.Location) != 3 || len(profile.Sample) != 2 {
+		t.Fatalf("functions = %d, locations = %d, samples = %d", len(profile.Function), len(profile.Location), len(profile.Sample))
+	}
+	functionNames := map[uint64]string{}
+	for _, function := range profile.Function {
+		functionNames[function.Id] = profile.StringTable[function.Name]
+	}
+	// the locations of the sample are from leaf to root
+	sample := profile.Sample[1]
+	names := []string{}
+	for _, id := range sample.LocationId {
+		names = append(names, functionNames[profile.Location[id-1].Line[0].FunctionId])
+	}
+	if !reflect.DeepEqual(names, []string{"bar", "foo", "main"}) || sample.Value[0] != 15 {
+		t.Errorf("sample = %v %v", names, sample.Value)
+	}
+}
+
+func TestToSpeedscope(t *testing.T) {
+	stacks := []*ProfileStack{
+		{Locations: []string{"main", "foo"}, Value: 3},
+		{Locations: []string{"main", "foo", "bar"}, Value: 15},
+	}
+	data, err := ToSpeedscope(stacks, "svc cpu", "samples")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := speedscopeFile{}
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	if file.Schema != SPEEDSCOPE_SCHEMA || len(file.Shared.Frames) != 3 || len(file.Profiles) != 1 {
+		t.Fatalf("unexpected file %s", data)
+	}
+	profile := file.Profiles[0]
+	if profile.Type != "sampled" || profile.Unit != "none" || profile.EndValue != 18 {
+		t.Errorf("unexpected profile %v", profile)
+	}
+	if !reflect.DeepEqual(profile.Samples, [][]int{{0, 1}, {0, 1, 2}}) || !reflect.DeepEqual(profile.Weights, []int64{3, 15}) {
+		t.Errorf("samples = %v, weights = %v", profile.Samples, profile.Weights)
+	}
+}
@@ -41,88 +41,10 @@ var InstanceProfileEventType = []string{"inuse_objects", "alloc_objects", "inuse
 
 func Tracing(args model.ProfileTracing, cfg *config.QuerierConfig) (result []*model.ProfileTreeNode, debug interface{}, err error) {
 	debugs := model.ProfileDebug{}
-	whereSlice := []string{}
-	whereSlice = append(whereSlice, fmt.Sprintf(" time>=%d", args.TimeStart))
-	whereSlice = append(whereSlice, fmt.Sprintf(" time<=%d", args.TimeEnd))
-	whereSlice = append(whereSlice, fmt.Sprintf(" app_service='%s'", args.AppService))
-	whereSlice = append(whereSlice, fmt.Sprintf(" profile_language_type='%s'", args.ProfileLanguageType))
-	whereSlice = append(whereSlice, fmt.Sprintf(" profile_event_type='%s'", args.ProfileEventType))
-	if args.TagFilter != "" {
-		whereSlice = append(whereSlice, " "+args.TagFilter)
-	}
-	whereSql := strings.Join(whereSlice, " AND")
-	limitSql := cfg.Profile.FlameQueryLimit
-	sql := fmt.Sprintf(
-		"SELECT %s, %s FROM %s WHERE %s LIMIT %d",
-		common.PROFILE_LOCATION_STR, common.PROFILE_VALUE, common.TABLE_PROFILE, whereSql, limitSql,
-	)
-
-	if slices.Contains[[]string, string](InstanceProfileEventType, args.ProfileEventType) {
-		timeSql := fmt.Sprintf(
-			"SELECT time FROM %s WHERE %s ORDER BY time DESC LIMIT 1",
-			common.TABLE_PROFILE, whereSql,
-		)
-		timeArgs := querier_common.QuerierParams{
-			DB:      common.DATABASE_PROFILE,
-			Sql:     timeSql,
-			Debug:   strconv.FormatBool(args.Debug),
-			Context: args.Context,
-		}
-		timeEngine := &clickhouse.CHEngine{DB: common.DATABASE_PROFILE}
-		timeEngine.Init()
-		timeResult, timeDebug, timeError := timeEngine.ExecuteQuery(&timeArgs)
-		if timeError != nil {
-			log.Errorf("ExecuteQuery failed: %v", timeDebug, timeError)
-			return
-		}
-		profileTimeDebug := model.Debug{}
-		profileTimeDebug.Sql = timeSql
-		profileTimeDebug.IP = timeDebug["ip"].(string)
-		profileTimeDebug.QueryUUID = timeDebug["query_uuid"].(string)
-		profileTimeDebug.SqlCH = timeDebug["sql"].(string)
-		profileTimeDebug.Error = timeDebug["error"].(string)
-		profileTimeDebug.QueryTime = timeDebug["query_time"].(string)
-		debugs.QuerierDebug = append(debugs.QuerierDebug, profileTimeDebug)
-		var timeValue int64
-		timeValues := timeResult.Values
-		for _, value := range timeValues {
-			switch valueSlice := value.(type) {
-			case []interface{}:
-				if timeValueTime, ok := valueSlice[0].(time.Time); ok {
-					timeValue = timeValueTime.Unix()
-					break
-				}
-			}
-		}
-		if timeValue > 0 {
-			sql = fmt.Sprintf(
-				"SELECT %s, %s FROM %s WHERE %s AND time=%d LIMIT %d",
-				common.PROFILE_LOCATION_STR, common.PROFILE_VALUE, common.TABLE_PROFILE, whereSql, timeValue, limitSql,
-			)
-		}
-
-	}
-	ckEngine := &clickhouse.CHEngine{DB: common.DATABASE_PROFILE}
-	ckEngine.Init()
-	querierArgs := querier_common.QuerierParams{
-		DB:      common.DATABASE_PROFILE,
-		Sql:     sql,
-		Debug:   strconv.FormatBool(args.Debug),
-		Context: args.Context,
-	}
-	querierResult, querierDebug, err := ckEngine.ExecuteQuery(&querierArgs)
+	querierResult, err := queryProfile(args, cfg, &debugs)
 	if err != nil {
-		log.Errorf("ExecuteQuery failed: %v", querierDebug, err)
 		return
 	}
-	profileDebug := model.Debug{}
-	profileDebug.Sql = sql
-	profileDebug.IP = querierDebug["ip"].(string)
-	profileDebug.QueryUUID = querierDebug["query_uuid"].(string)
-	profileDebug.SqlCH = querierDebug["sql"].(string)
-	profileDebug.Error = querierDebug["error"].(string)
-	profileDebug.QueryTime = querierDebug["query_time"].(string)
-	debugs.QuerierDebug = append(debugs.QuerierDebug, profileDebug)
 	formatStartTime := time.Now()
 	profileLocationStrIndex := -1
 	profileValueIndex := -1
@@ -237,3 +159,91 @@ func UpdateNodeTotalValue(node *model.ProfileTreeNode, parentNode *model.Profile
 		UpdateNodeTotalValue(node, newParentNode, NodeIDToProfileTree)
 	}
 }
+
+// queryProfile queries the in_process rows of the time range and filter, for the instance profile event types,
+// e.g.: inuse_space, only the rows of the latest time are queried
+func queryProfile(args model.ProfileTracing, cfg *config.QuerierConfig, debugs *model.ProfileDebug) (*querier_common.Result, error) {
+	whereSlice := []string{}
+	whereSlice = append(whereSlice, fmt.Sprintf(" time>=%d", args.TimeStart))
+	whereSlice = append(whereSlice, fmt.Sprintf(" time<=%d", args.TimeEnd))
+	whereSlice = append(whereSlice, fmt.Sprintf(" app_service='%s'", args.AppService))
+	whereSlice = append(whereSlice, fmt.Sprintf(" profile_language_type='%s'", args.ProfileLanguageType))
+	whereSlice = append(whereSlice, fmt.Sprintf(" profile_event_type='%s'", args.ProfileEventType))
+	if args.TagFilter != "" {
+		whereSlice = append(whereSlice, " "+args.TagFilter)
+	}
+	whereSql := strings.Join(whereSlice, " AND")
+	limitSql := cfg.Profile.FlameQueryLimit
+	sql := fmt.Sprintf(
+		"SELECT %s, %s, %s FROM %s WHERE %s LIMIT %d",
+		common.PROFILE_LOCATION_STR, common.PROFILE_VALUE, common.PROFILE_VALUE_UNIT, common.TABLE_PROFILE, whereSql, limitSql,
+	)
+
+	if slices.Contains[[]string, string](InstanceProfileEventType, args.ProfileEventType) {
+		timeSql := fmt.Sprintf(
+			"SELECT time FROM %s WHERE %s ORDER BY time DESC LIMIT 1",
+			common.TABLE_PROFILE, whereSql,
+		)
+		timeArgs := querier_common.QuerierParams{
+			DB:      common.DATABASE_PROFILE,
+			Sql:     timeSql,
+			Debug:   strconv.FormatBool(args.Debug),
+			Context: args.Context,
+		}
+		timeEngine := &clickhouse.CHEngine{DB: common.DATABASE_PROFILE}
+		timeEngine.Init()
+		timeResult, timeDebug, timeError := timeEngine.ExecuteQuery(&timeArgs)
+		if timeError != nil {
+			log.Errorf("ExecuteQuery failed: %v", timeDebug, timeError)
+			return nil, timeError
+		}
+		profileTimeDebug := model.Debug{}
+		profileTimeDebug.Sql = timeSql
+		profileTimeDebug.IP = timeDebug["ip"].(string)
+		profileTimeDebug.QueryUUID = timeDebug["query_uuid"].(string)
+		profileTimeDebug.SqlCH = timeDebug["sql"].(string)
+		profileTimeDebug.Error = timeDebug["error"].(string)
+		profileTimeDebug.QueryTime = timeDebug["query_time"].(string)
+		debugs.QuerierDebug = append(debugs.QuerierDebug, profileTimeDebug)
+		var timeValue int64
+		timeValues := timeResult.Values
+		for _, value := range timeValues {
+			switch valueSlice := value.(type) {
+			case []interface{}:
+				if timeValueTime, ok := valueSlice[0].(time.Time); ok {
+					timeValue = timeValueTime.Unix()
+					break
+				}
+			}
+		}
+		if timeValue > 0 {
+			sql = fmt.Sprintf(
+				"SELECT %s, %s, %s FROM %s WHERE %s AND time=%d LIMIT %d",
+				common.PROFILE_LOCATION_STR, common.PROFILE_VALUE, common.PROFILE_VALUE_UNIT, common.TABLE_PROFILE, whereSql, timeValue, limitSql,
+			)
+		}
+
+	}
+	ckEngine := &clickhouse.CHEngine{DB: common.DATABASE_PROFILE}
+	ckEngine.Init()
+	querierArgs := querier_common.QuerierParams{
+		DB:      common.DATABASE_PROFILE,
+		Sql:     sql,
+		Debug:   strconv.FormatBool(args.Debug),
+		Context: args.Context,
+	}
+	querierResult, querierDebug, err := ckEngine.ExecuteQuery(&querierArgs)
+	if err != nil {
+		log.Errorf("ExecuteQuery failed: %v", querierDebug, err)
+		return nil, err
+	}
+	profileDebug := model.Debug{}
+	profileDebug.Sql = sql
+	profileDebug.IP = querierDebug["ip"].(string)
+	profileDebug.QueryUUID = querierDebug["query_uuid"].(string)
+	profileDebug.SqlCH = querierDebug["sql"].(string)
+	profileDebug.Error = querierDebug["error"].(string)
+	profileDebug.QueryTime = querierDebug["query_time"].(string)
+	debugs.QuerierDebug = append(debugs.QuerierDebug, profileDebug)
+	return querierResult, nil
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/deepflowio/deepflow/server/querier/jaeger"
+)
+
+func jaegerServicesReader() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		services, err := jaeger.GetServices(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, jaeger.NewErrorResponse(http.StatusInternalServerError, err))
+			return
+		}
+		c.JSON(http.StatusOK, jaeger.NewResponse(services, len(services)))
+	})
+}
+
+func jaegerOperationsReader() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		operations, err := jaeger.GetOperations(c.Request.Context(), c.Param("service"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, jaeger.NewErrorResponse(http.StatusInternalServerError, err))
+			return
+		}
+		c.JSON(http.StatusOK, jaeger.NewResponse(operations, len(operations)))
+	})
+}
+
+func jaegerSearchReader() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		args, err := parseJaegerSearchParams(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, jaeger.NewErrorResponse(http.StatusBadRequest, err))
+			return
+		}
+		traces, err := jaeger.SearchTraces(args)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, jaeger.NewErrorResponse(http.StatusInternalServerError, err))
+			return
+		}
+		c.JSON(http.StatusOK, jaeger.NewResponse(traces, len(traces)))
+	})
+}
+
+func jaegerTraceReader() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		traceID := c.Param("traceId")
+		startTime, _ := strconv.ParseInt(c.Query("start"), 10, 64)
+		endTime, _ := strconv.ParseInt(c.Query("end"), 10, 64)
+		trace, err := jaeger.FindTrace(c.Request.Context(), traceID, startTime, endTime)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, jaeger.NewErrorResponse(http.StatusInternalServerError, err))
+			return
+		}
+		if trace == nil {
+			c.JSON(http.StatusNotFound, jaeger.NewErrorResponse(http.StatusNotFound, errors.New("trace not found")))
+			return
+		}
+		c.JSON(http.StatusOK, jaeger.NewResponse([]*jaeger.Trace{trace}, 1))
+	})
+}
+
+// parseJaegerSearchParams parses the query of jaeger ui, the tags are in json `tags={"k":"v"}` or in `tag=k:v`
+func parseJaegerSearchParams(c *gin.Context) (*jaeger.SearchParams, error) {
+	args := &jaeger.SearchParams{
+		Service:     c.Query("service"),
+		Operation:   c.Query("operation"),
+		Tags:        map[string]string{},
+		MinDuration: c.Query("minDuration"),
+		MaxDuration: c.Query("maxDuration"),
+		Context:     c.Request.Context(),
+	}
+	if args.Service == "" {
+		return nil, errors.New("parameter service is required")
+	}
+	var err error
+	for key, value := range map[string]*int64{"start": &args.StartTime, "end": &args.EndTime} {
+		if s := c.Query(key); s != "" {
+			if *value, err = strconv.ParseInt(s, 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid %s %s", key, s)
+			}
+		}
+	}
+	if s := c.Query("limit"); s != "" {
+		if args.Limit, err = strconv.Atoi(s); err != nil {
+			return nil, fmt.Errorf("invalid limit %s", s)
+		}
+	}
+	if s := c.Query("tags"); s != "" {
+		if err := json.Unmarshal([]byte(s), &args.Tags); err != nil {
+			return nil, fmt.Errorf("invalid tags %s: %s", s, err)
+		}
+	}
+	for _, tag := range c.QueryArray("tag") {
+		kv := strings.SplitN(tag, ":", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid tag %s", tag)
+		}
+		args.Tags[kv[0]] = kv[1]
+	}
+	return args, nil
+}
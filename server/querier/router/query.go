@@ -21,13 +21,20 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	logging "github.com/op/go-logging"
 
 	"github.com/deepflowio/deepflow/server/querier/common"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/client"
+	"github.com/deepflowio/deepflow/server/querier/output"
 	"github.com/deepflowio/deepflow/server/querier/service"
 )
 
+var log = logging.MustGetLogger("querier.router")
+
 func QueryRouter(e *gin.Engine) {
 	e.POST("/v1/query/", executeQuery())
+	e.GET("/v1/query/running", runningQueries())
+	e.DELETE("/v1/query/running/:queryUUID", cancelQuery())
 
 	// api router for tempo
 	e.GET("/api/traces/:traceId", tempoTraceReader())
@@ -35,6 +42,12 @@ func QueryRouter(e *gin.Engine) {
 	e.GET("/api/search/tags", tempoTagsReader())
 	e.GET("/api/search/tag/:tagName/values", tempoTagValuesReader())
 	e.GET("/api/search", tempoSearchReader())
+
+	// api router for jaeger, prefixed as /api/traces/:traceId is used by tempo
+	e.GET("/jaeger/api/services", jaegerServicesReader())
+	e.GET("/jaeger/api/services/:service/operations", jaegerOperationsReader())
+	e.GET("/jaeger/api/traces", jaegerSearchReader())
+	e.GET("/jaeger/api/traces/:traceId", jaegerTraceReader())
 }
 
 func executeQuery() gin.HandlerFunc {
@@ -44,9 +57,13 @@ func executeQuery() gin.HandlerFunc {
 		args.Debug = c.Query("debug")
 		args.QueryUUID = c.Query("query_uuid")
 		args.NoPreWhere, _ = strconv.ParseBool(c.DefaultQuery("no_prewhere", "false"))
+		args.Caller = c.ClientIP()
 		if args.QueryUUID == "" {
 			query_uuid := uuid.New()
 			args.QueryUUID = query_uuid.String()
+		} else if !client.IsValidQueryUUID(args.QueryUUID) {
+			BadRequestResponse(c, common.INVALID_PARAMETERS, "invalid query_uuid, only letters, digits, '_' and '-' are allowed")
+			return
 		}
 		args.DB = c.PostForm("db")
 		args.Sql = c.PostForm("sql")
@@ -57,10 +74,71 @@ func executeQuery() gin.HandlerFunc {
 			args.DB, _ = json["db"].(string)
 			args.Sql, _ = json["sql"].(string)
 		}
+		format := c.Query("format")
+		var writer *lazyContentTypeWriter
+		if format != "" && format != output.FORMAT_JSON {
+			writer = &lazyContentTypeWriter{c: c}
+			resultWriter, contentType, err := output.NewWriter(format, writer)
+			if err != nil {
+				BadRequestResponse(c, common.INVALID_PARAMETERS, err.Error())
+				return
+			}
+			writer.contentType = contentType
+			args.Writer = resultWriter
+		}
 		result, debug, err := service.Execute(&args)
 		if err == nil && args.Debug != "true" {
 			debug = nil
 		}
-		JsonResponse(c, result, debug, err)
+		if writer == nil {
+			JsonResponse(c, result, debug, err)
+			return
+		}
+		if err == nil {
+			err = args.Writer.Close()
+		}
+		if err != nil {
+			if !writer.written {
+				JsonResponse(c, nil, debug, err)
+				return
+			}
+			// the status has been sent, the truncated body is the only signal to the client
+			log.Errorf("query_uuid: %s. write %s result failed: %s", args.QueryUUID, format, err)
+		}
+	})
+}
+
+// lazyContentTypeWriter sets the content type of the format on the first write, so that the errors before
+// any row is written are responded as json
+type lazyContentTypeWriter struct {
+	c           *gin.Context
+	contentType string
+	written     bool
+}
+
+func (w *lazyContentTypeWriter) Write(p []byte) (int, error) {
+	if !w.written {
+		w.written = true
+		w.c.Header("Content-Type", w.contentType)
+		w.c.Status(200)
+	}
+	return w.c.Writer.Write(p)
+}
+
+func runningQueries() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		JsonResponse(c, service.ListRunningQueries(), nil, nil)
+	})
+}
+
+func cancelQuery() gin.HandlerFunc {
+	return gin.HandlerFunc(func(c *gin.Context) {
+		queryUUID := c.Param("queryUUID")
+		if !client.IsValidQueryUUID(queryUUID) {
+			BadRequestResponse(c, common.INVALID_PARAMETERS, "invalid query_uuid, only letters, digits, '_' and '-' are allowed")
+			return
+		}
+		err := service.CancelQuery(queryUUID)
+		JsonResponse(c, nil, nil, err)
 	})
 }
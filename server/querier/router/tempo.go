@@ -70,6 +70,7 @@ func tempoSearchReader() gin.HandlerFunc {
 			StartTime:   c.Query("start"),
 			EndTime:     c.Query("end"),
 			Debug:       c.Query("debug"),
+			Query:       c.Query("q"),
 			Context:     c.Request.Context(),
 		}
 		args.SetFilters(c.Query("tags"))
@@ -17,9 +17,12 @@
 package service
 
 import (
+	"fmt"
+
 	"github.com/deepflowio/deepflow/server/querier/common"
 	"github.com/deepflowio/deepflow/server/querier/engine"
 	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse"
+	"github.com/deepflowio/deepflow/server/querier/engine/clickhouse/client"
 )
 
 func Execute(args *common.QuerierParams) (jsonData map[string]interface{}, debug map[string]interface{}, err error) {
@@ -32,6 +35,10 @@ func Execute(args *common.QuerierParams) (jsonData map[string]interface{}, debug
 	}
 	result, debug, err := engine.ExecuteQuery(args)
 	if result != nil {
+		if args.Writer != nil {
+			// the result is not streamed by the engine, e.g. it has callbacks or is from the cache
+			return nil, debug, common.WriteResult(args.Writer, result)
+		}
 		jsonData = result.ToJson()
 	}
 	return jsonData, debug, err
@@ -40,3 +47,18 @@ func Execute(args *common.QuerierParams) (jsonData map[string]interface{}, debug
 func getDbBy() string {
 	return "clickhouse"
 }
+
+func ListRunningQueries() []client.RunningQuery {
+	return client.RunningQueries.List()
+}
+
+func CancelQuery(queryUUID string) error {
+	count, err := client.RunningQueries.Cancel(queryUUID)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return common.NewError(common.RESOURCE_NOT_FOUND, fmt.Sprintf("query %s is not running", queryUUID))
+	}
+	return nil
+}
@@ -40,6 +40,8 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"net/http"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -60,6 +62,8 @@ var SEARCH_FIELDS = []string{
 	"trace_id as traceID", "app_service as rootServiceName", "endpoint as rootTraceName", "toUnixTimestamp64Micro(start_time) as startTimeUnixNano", "response_duration/1000 as durationMs",
 }
 
+var tagKeyRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.]*$`)
+
 var SPAN_ATTRS_MAP = map[string]string{
 	"service.name": L7_FLOW_LOG_SERVICE_NAME,
 	"name":         L7_TRACING_ENDPOINT,
@@ -359,21 +363,21 @@ func TraceSearch(args *common.TempoParams) (resp map[string]interface{}, debug m
 		},
 		"traces": []map[string]interface{}{},
 	}
-	sql := fmt.Sprintf("select %s from %s", strings.Join(SEARCH_FIELDS, ", "), TABLE_NAME_L7_FLOW_LOG)
 	filters := []string{"trace_id != ''"}
+	timeFilters := []string{}
 	if args.StartTime != "" {
-		filters = append(filters, fmt.Sprintf("time>=%s", args.StartTime))
+		if _, err := strconv.ParseInt(args.StartTime, 10, 64); err != nil {
+			return nil, nil, fmt.Errorf("invalid start %s", args.StartTime)
+		}
+		timeFilters = append(timeFilters, fmt.Sprintf("time>=%s", args.StartTime))
 	}
 	if args.EndTime != "" {
-		filters = append(filters, fmt.Sprintf("time<=%s", args.EndTime))
-	}
-	for _, kv := range args.Filters {
-		key := kv.Key
-		if k, ok := SPAN_ATTRS_MAP[kv.Key]; ok {
-			key = k
+		if _, err := strconv.ParseInt(args.EndTime, 10, 64); err != nil {
+			return nil, nil, fmt.Errorf("invalid end %s", args.EndTime)
 		}
-		filters = append(filters, fmt.Sprintf("%s='%s'", key, kv.Value))
+		timeFilters = append(timeFilters, fmt.Sprintf("time<=%s", args.EndTime))
 	}
+	filters = append(filters, timeFilters...)
 	if args.MinDuration != "" {
 		minDuration, err := time.ParseDuration(args.MinDuration)
 		if err != nil {
@@ -388,31 +392,29 @@ func TraceSearch(args *common.TempoParams) (resp map[string]interface{}, debug m
 		}
 		filters = append(filters, fmt.Sprintf("response_duration<=%s", strconv.FormatInt(MaxDuration.Microseconds(), 10)))
 	}
-	if filters != nil {
-		where := strings.Join(filters, " AND ")
-		sql = fmt.Sprintf("%s WHERE %s", sql, where)
-	}
-	sql = fmt.Sprintf("%s ORDER BY startTimeUnixNano desc", sql)
 	if args.Limit != "" {
-		sql = fmt.Sprintf("%s LIMIT %s", sql, args.Limit)
+		if _, err := strconv.Atoi(args.Limit); err != nil {
+			return nil, nil, fmt.Errorf("invalid limit %s", args.Limit)
+		}
 	}
 
-	query_uuid := uuid.New()
-	querierArgs := common.QuerierParams{
-		DB:         "flow_log",
-		Sql:        sql,
-		DataSource: "",
-		Debug:      "false",
-		QueryUUID:  query_uuid.String(),
-		Context:    args.Context,
+	var result *common.Result
+	if args.Query != "" {
+		// the tag filters are replaced by TraceQL
+		result, debug, err = traceQLSearch(args, filters, timeFilters)
+	} else {
+		for _, kv := range args.Filters {
+			key := kv.Key
+			if k, ok := SPAN_ATTRS_MAP[kv.Key]; ok {
+				key = k
+			} else if !tagKeyRegexp.MatchString(key) {
+				return nil, nil, fmt.Errorf("invalid tag %s", key)
+			}
+			filters = append(filters, fmt.Sprintf("%s=%s", key, EscapeSQLString(kv.Value)))
+		}
+		result, debug, err = tagSearch(args, filters)
 	}
-	ckEngine := &clickhouse.CHEngine{DB: querierArgs.DB, DataSource: querierArgs.DataSource}
-	ckEngine.Init()
-	//fmt.Println(sql)
-	result, debug, err := ckEngine.ExecuteQuery(&querierArgs)
 	if err != nil {
-		// TODO
-		//log.Errorf("%v %v", debug, err)
 		return nil, debug, err
 	}
 	respValues := []map[string]interface{}{}
@@ -430,6 +432,196 @@ func TraceSearch(args *common.TempoParams) (resp map[string]interface{}, debug m
 	return resp, debug, err
 }
 
+func tagSearch(args *common.TempoParams, filters []string) (*common.Result, map[string]interface{}, error) {
+	sql := fmt.Sprintf("select %s from %s WHERE %s ORDER BY startTimeUnixNano desc", strings.Join(SEARCH_FIELDS, ", "), TABLE_NAME_L7_FLOW_LOG, strings.Join(filters, " AND "))
+	if args.Limit != "" {
+		sql = fmt.Sprintf("%s LIMIT %s", sql, args.Limit)
+	}
+	return searchQuery(args, sql)
+}
+
+// traceQLSearch finds the traces by TraceQL, and returns the root span of each trace, or the earliest span if the
+// root span is missing. The span-set filters are queried with the filters, and the root spans are queried with
+// the time filters only.
+func traceQLSearch(args *common.TempoParams, filters []string, timeFilters []string) (*common.Result, map[string]interface{}, error) {
+	expr, err := CompileTraceQL(args.Query)
+	if err != nil {
+		return nil, nil, common.NewError(common.INVALID_PARAMETERS, err.Error())
+	}
+	var debug map[string]interface{}
+	evaluator := &traceQLEvaluator{
+		filterSpans: func(condition string) ([]traceQLSpan, error) {
+			spanFilters := append([]string{}, filters...)
+			if condition != "" {
+				spanFilters = append(spanFilters, "("+condition+")")
+			}
+			spans, spanDebug, err := queryTraceQLSpans(args, spanFilters)
+			debug = spanDebug
+			return spans, err
+		},
+		traceSpans: func(traceIDs []string) ([]traceQLSpan, error) {
+			spans, spanDebug, err := queryTraceQLSpans(args, append([]string{traceIDFilter(traceIDs)}, timeFilters...))
+			debug = spanDebug
+			return spans, err
+		},
+	}
+	spans, err := expr.spans(evaluator)
+	if err != nil {
+		return nil, debug, err
+	}
+	limit, _ := strconv.Atoi(args.Limit)
+	traceIDs := latestTraceIDs(spans, limit)
+	if len(traceIDs) == 0 {
+		return &common.Result{}, debug, nil
+	}
+
+	sql := fmt.Sprintf("SELECT %s, parent_span_id as parentSpanID FROM %s WHERE %s ORDER BY startTimeUnixNano ASC LIMIT %d",
+		strings.Join(SEARCH_FIELDS, ", "), TABLE_NAME_L7_FLOW_LOG,
+		strings.Join(append([]string{traceIDFilter(traceIDs)}, timeFilters...), " AND "), TRACEQL_SPAN_LIMIT)
+	result, debug, err := searchQuery(args, sql)
+	if err != nil {
+		return nil, debug, err
+	}
+	result.Columns, result.Values = rootSpans(result.Columns, result.Values)
+	return result, debug, nil
+}
+
+// the fields of the spans queried by the span-set filters of TraceQL
+var TRACEQL_SPAN_FIELDS = []string{
+	"trace_id as traceID", "span_id as spanID", "parent_span_id as parentSpanID", "toUnixTimestamp64Micro(start_time) as startTimeUnixNano",
+}
+
+func queryTraceQLSpans(args *common.TempoParams, filters []string) ([]traceQLSpan, map[string]interface{}, error) {
+	sql := fmt.Sprintf("SELECT %s FROM %s WHERE %s LIMIT %d",
+		strings.Join(TRACEQL_SPAN_FIELDS, ", "), TABLE_NAME_L7_FLOW_LOG, strings.Join(filters, " AND "), TRACEQL_SPAN_LIMIT)
+	result, debug, err := searchQuery(args, sql)
+	if err != nil {
+		return nil, debug, err
+	}
+	if len(result.Values) >= TRACEQL_SPAN_LIMIT {
+		log.Warningf("the spans of %s exceed the limit %d, some traces may be missing", args.Query, TRACEQL_SPAN_LIMIT)
+	}
+	indexes := map[interface{}]int{}
+	for i, column := range result.Columns {
+		indexes[column] = i
+	}
+	spans := make([]traceQLSpan, 0, len(result.Values))
+	for _, row := range result.Values {
+		values := row.([]interface{})
+		span := traceQLSpan{}
+		span.TraceID, _ = values[indexes["traceID"]].(string)
+		span.SpanID, _ = values[indexes["spanID"]].(string)
+		span.ParentSpanID, _ = values[indexes["parentSpanID"]].(string)
+		span.StartTime, _ = values[indexes["startTimeUnixNano"]].(int)
+		spans = append(spans, span)
+	}
+	return spans, debug, nil
+}
+
+// latestTraceIDs returns the ids of the traces ordered by their latest matched spans, at most limit traces are
+// returned if limit is not 0
+func latestTraceIDs(spans []traceQLSpan, limit int) []string {
+	startTimes := map[string]int{}
+	for _, span := range spans {
+		if startTime, ok := startTimes[span.TraceID]; !ok || span.StartTime > startTime {
+			startTimes[span.TraceID] = span.StartTime
+		}
+	}
+	traceIDs := make([]string, 0, len(startTimes))
+	for traceID := range startTimes {
+		traceIDs = append(traceIDs, traceID)
+	}
+	sort.Slice(traceIDs, func(i, j int) bool {
+		if startTimes[traceIDs[i]] != startTimes[traceIDs[j]] {
+			return startTimes[traceIDs[i]] > startTimes[traceIDs[j]]
+		}
+		return traceIDs[i] < traceIDs[j]
+	})
+	if limit > 0 && len(traceIDs) > limit {
+		traceIDs = traceIDs[:limit]
+	}
+	return traceIDs
+}
+
+// rootSpans picks a span of each trace from the rows of SEARCH_FIELDS and parentSpanID ordered by the start time,
+// the root span with the empty parent span id is picked, or the earliest span if there is no root span. The picked
+// rows are ordered by the start time desc, and parentSpanID is removed.
+func rootSpans(columns []interface{}, rows []interface{}) ([]interface{}, []interface{}) {
+	traceIDIndex, startTimeIndex, parentSpanIDIndex := -1, -1, -1
+	for i, column := range columns {
+		switch column {
+		case "traceID":
+			traceIDIndex = i
+		case "startTimeUnixNano":
+			startTimeIndex = i
+		case "parentSpanID":
+			parentSpanIDIndex = i
+		}
+	}
+	if traceIDIndex < 0 || startTimeIndex < 0 || parentSpanIDIndex < 0 {
+		return columns, rows
+	}
+	roots := map[string][]interface{}{}
+	for _, row := range rows {
+		values := row.([]interface{})
+		traceID, _ := values[traceIDIndex].(string)
+		if root, ok := roots[traceID]; ok && (root[parentSpanIDIndex] == "" || values[parentSpanIDIndex] != "") {
+			continue
+		}
+		roots[traceID] = values
+	}
+	picked := make([][]interface{}, 0, len(roots))
+	for _, root := range roots {
+		picked = append(picked, root)
+	}
+	sort.Slice(picked, func(i, j int) bool {
+		startTimeI, _ := picked[i][startTimeIndex].(int)
+		startTimeJ, _ := picked[j][startTimeIndex].(int)
+		if startTimeI != startTimeJ {
+			return startTimeI > startTimeJ
+		}
+		traceIDI, _ := picked[i][traceIDIndex].(string)
+		traceIDJ, _ := picked[j][traceIDIndex].(string)
+		return traceIDI < traceIDJ
+	})
+	// parentSpanID is only used to find the root span, drop it from the result
+	values := make([]interface{}, 0, len(picked))
+	for _, root := range picked {
+		values = append(values, withoutIndex(root, parentSpanIDIndex))
+	}
+	return withoutIndex(columns, parentSpanIDIndex), values
+}
+
+func withoutIndex(values []interface{}, index int) []interface{} {
+	result := make([]interface{}, 0, len(values)-1)
+	result = append(result, values[:index]...)
+	return append(result, values[index+1:]...)
+}
+
+func traceIDFilter(traceIDs []string) string {
+	ids := make([]string, len(traceIDs))
+	for i, traceID := range traceIDs {
+		ids[i] = EscapeSQLString(traceID)
+	}
+	return fmt.Sprintf("trace_id IN (%s)", strings.Join(ids, ", "))
+}
+
+// searchQuery executes the sql on flow_log by the querier engine
+func searchQuery(args *common.TempoParams, sql string) (*common.Result, map[string]interface{}, error) {
+	query_uuid := uuid.New()
+	querierArgs := common.QuerierParams{
+		DB:         "flow_log",
+		Sql:        sql,
+		DataSource: "",
+		Debug:      "false",
+		QueryUUID:  query_uuid.String(),
+		Context:    args.Context,
+	}
+	ckEngine := &clickhouse.CHEngine{DB: querierArgs.DB, DataSource: querierArgs.DataSource}
+	ckEngine.Init()
+	return ckEngine.ExecuteQuery(&querierArgs)
+}
+
 func decodeIdBytes(id string, length int, idMap map[string][]byte) []byte {
 	idBytes := []byte{}
 	if len(id) == length*2 {
@@ -17,8 +17,8 @@
 package tempo
 
 import (
-	//"reflect"
 	"encoding/json"
+	"reflect"
 	//"fmt"
 	"testing"
 )
@@ -31,3 +31,33 @@ func TestConvertL7TracingRespToProto(t *testing.T) {
 	ConvertL7TracingRespToProto(result, "test")
 	//fmt.Println(proto)
 }
+
+func TestRootSpans(t *testing.T) {
+	// the rows of SEARCH_FIELDS and parentSpanID ordered by the start time
+	columns := []interface{}{"traceID", "rootServiceName", "rootTraceName", "startTimeUnixNano", "parentSpanID", "durationMs"}
+	rows := []interface{}{
+		[]interface{}{"t1", "svc-b", "GET /b", 1, "s1", 1.0},
+		[]interface{}{"t1", "svc-a", "GET /a", 2, "", 2.0},
+		[]interface{}{"t2", "svc-c", "GET /c", 3, "s2", 3.0},
+		[]interface{}{"t1", "svc-d", "GET /d", 4, "", 4.0},
+		[]interface{}{"t2", "svc-d", "GET /d", 5, "s3", 5.0},
+	}
+	expected := []interface{}{
+		[]interface{}{"t2", "svc-c", "GET /c", 3, 3.0},
+		[]interface{}{"t1", "svc-a", "GET /a", 2, 2.0},
+	}
+	columns, values := rootSpans(columns, rows)
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("got %v, want %v", values, expected)
+	}
+	if expectedColumns := []interface{}{"traceID", "rootServiceName", "rootTraceName", "startTimeUnixNano", "durationMs"}; !reflect.DeepEqual(columns, expectedColumns) {
+		t.Errorf("got columns %v, want %v", columns, expectedColumns)
+	}
+}
+
+func TestLatestTraceIDs(t *testing.T) {
+	spans := []traceQLSpan{{"t1", "1", "", 3}, {"t2", "2", "", 2}, {"t1", "3", "1", 1}, {"t3", "4", "", 4}}
+	if traceIDs := latestTraceIDs(spans, 2); !reflect.DeepEqual(traceIDs, []string{"t3", "t1"}) {
+		t.Errorf("unexpected trace ids %v", traceIDs)
+	}
+}
@@ -0,0 +1,794 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tempo
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// the max number of the spans queried by each span-set filter
+const TRACEQL_SPAN_LIMIT = 10000
+
+type columnType int
+
+const (
+	COLUMN_STRING columnType = iota
+	COLUMN_NUMBER
+	COLUMN_DURATION // us
+	COLUMN_STATUS
+	COLUMN_KIND
+)
+
+type traceQLColumn struct {
+	Name string
+	Type columnType
+}
+
+// intrinsics could be written as `duration` or `span:duration`
+var TRACEQL_INTRINSIC_MAP = map[string]traceQLColumn{
+	"duration":      {"response_duration", COLUMN_DURATION},
+	"name":          {L7_TRACING_ENDPOINT, COLUMN_STRING},
+	"status":        {"response_status", COLUMN_STATUS},
+	"statusMessage": {"response_exception", COLUMN_STRING},
+	"kind":          {"span_kind", COLUMN_KIND},
+}
+
+var TRACEQL_SPAN_ATTRIBUTE_MAP = map[string]traceQLColumn{
+	"http.status_code": {"response_code", COLUMN_NUMBER},
+	"http.method":      {"request_type", COLUMN_STRING},
+	"http.url":         {"request_resource", COLUMN_STRING},
+	"http.host":        {"request_domain", COLUMN_STRING},
+}
+
+var TRACEQL_RESOURCE_ATTRIBUTE_MAP = map[string]traceQLColumn{
+	"service.name":        {L7_FLOW_LOG_SERVICE_NAME, COLUMN_STRING},
+	"service.instance.id": {"app_instance", COLUMN_STRING},
+}
+
+// values of response_status, 0: normal, 1: exception, 2: not exist, 3: server error, 4: client error
+var TRACEQL_STATUS_MAP = map[string][]int{
+	"ok":    {0},
+	"error": {1, 3, 4},
+	"unset": {2},
+}
+
+// values of the OpenTelemetry SpanKind
+var TRACEQL_KIND_MAP = map[string][]int{
+	"unspecified": {0},
+	"internal":    {1},
+	"server":      {2},
+	"client":      {3},
+	"producer":    {4},
+	"consumer":    {5},
+}
+
+var sqlStringEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// EscapeSQLString quotes the string as a ClickHouse string literal
+func EscapeSQLString(s string) string {
+	return "'" + sqlStringEscaper.Replace(s) + "'"
+}
+
+// lexer
+
+type tokenType int
+
+const (
+	TOKEN_EOF tokenType = iota
+	TOKEN_OPERATOR
+	TOKEN_IDENTIFIER
+	TOKEN_STRING
+	TOKEN_NUMBER
+	TOKEN_DURATION
+)
+
+type token struct {
+	Type tokenType
+	Text string
+	Pos  int
+}
+
+// longer operators must be in front of their prefixes
+var traceQLOperators = []string{
+	"&&", "||", "=~", "!~", "!=", ">=", "<=", ">>",
+	"{", "}", "(", ")", "=", ">", "<", "~", "!", "|",
+}
+
+func isIdentifierStart(r rune) bool {
+	return r == '.' || r == '_' || unicode.IsLetter(r)
+}
+
+func isIdentifierPart(r rune) bool {
+	return isIdentifierStart(r) || unicode.IsDigit(r) || r == '-' || r == '/' || r == ':'
+}
+
+func lexTraceQL(q string) ([]token, error) {
+	tokens := []token{}
+	for i := 0; i < len(q); {
+		r, size := utf8.DecodeRuneInString(q[i:])
+		if unicode.IsSpace(r) {
+			i += size
+			continue
+		}
+		start := i
+		switch {
+		case r == '"' || r == '`':
+			end := i + 1
+			for ; end < len(q) && q[end] != byte(r); end++ {
+				if q[end] == '\\' && r == '"' {
+					end++
+				}
+			}
+			if end >= len(q) {
+				return nil, fmt.Errorf("unterminated string at %d", start)
+			}
+			text := q[i+1 : end]
+			if r == '"' {
+				var err error
+				if text, err = strconv.Unquote(q[i : end+1]); err != nil {
+					return nil, fmt.Errorf("invalid string at %d: %s", start, err)
+				}
+			}
+			tokens = append(tokens, token{TOKEN_STRING, text, start})
+			i = end + 1
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(q) && q[i+1] >= '0' && q[i+1] <= '9'):
+			end, hasUnit := i+1, false
+			for end < len(q) {
+				c, n := utf8.DecodeRuneInString(q[end:])
+				if unicode.IsLetter(c) {
+					hasUnit = true
+				} else if !unicode.IsDigit(c) && c != '.' {
+					break
+				}
+				end += n
+			}
+			text := q[i:end]
+			if hasUnit {
+				if _, err := time.ParseDuration(text); err != nil {
+					return nil, fmt.Errorf("invalid duration %s at %d", text, start)
+				}
+				tokens = append(tokens, token{TOKEN_DURATION, text, start})
+			} else {
+				if _, err := strconv.ParseFloat(text, 64); err != nil {
+					return nil, fmt.Errorf("invalid number %s at %d", text, start)
+				}
+				tokens = append(tokens, token{TOKEN_NUMBER, text, start})
+			}
+			i = end
+		case isIdentifierStart(r):
+			end := i + size
+			for end < len(q) {
+				c, n := utf8.DecodeRuneInString(q[end:])
+				if !isIdentifierPart(c) {
+					break
+				}
+				end += n
+			}
+			tokens = append(tokens, token{TOKEN_IDENTIFIER, q[i:end], start})
+			i = end
+		default:
+			matched := false
+			for _, op := range traceQLOperators {
+				if strings.HasPrefix(q[i:], op) {
+					tokens = append(tokens, token{TOKEN_OPERATOR, op, start})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at %d", r, start)
+			}
+		}
+	}
+	return append(tokens, token{TOKEN_EOF, "", len(q)}), nil
+}
+
+// AST
+
+// SpansetExpr selects spans, it is a span-set filter or an operation of two span-sets
+type SpansetExpr interface {
+	// compile compiles the span-set filters to the conditions of the querier engine
+	compile() error
+	spans(e *traceQLEvaluator) ([]traceQLSpan, error)
+}
+
+// SpansetFilter is `{ expr }`, an empty filter matches all of the spans
+type SpansetFilter struct {
+	Expr      FieldExpr
+	condition string
+}
+
+// SpansetOperation is one of `&&`, `||`, `>`, `>>` and `~` between two span-sets
+type SpansetOperation struct {
+	Op  string
+	LHS SpansetExpr
+	RHS SpansetExpr
+}
+
+// FieldExpr is the boolean expression of the span fields in a span-set filter
+type FieldExpr interface {
+	condition() (string, error)
+}
+
+type BinaryFieldExpr struct {
+	Op  string // && or ||
+	LHS FieldExpr
+	RHS FieldExpr
+}
+
+type NotFieldExpr struct {
+	Expr FieldExpr
+}
+
+type Attribute struct {
+	Scope string // span, resource, intrinsic or empty for the unscoped attributes
+	Name  string
+}
+
+type Static struct {
+	Type tokenType // TOKEN_IDENTIFIER for the keywords, e.g.: true, error, server
+	Text string
+}
+
+type Comparison struct {
+	Attribute Attribute
+	Op        string
+	Value     Static
+}
+
+// parser
+
+type traceQLParser struct {
+	tokens []token
+	i      int
+}
+
+// ParseTraceQL parses the span-set expression of TraceQL, e.g.:
+//
+//	{ span.http.status_code >= 500 && resource.service.name = "cart" } >> { duration > 1s }
+//
+// the precedence of the span-set operators from low to high is `||`, `&&` and the structural ones `>>`, `>`, `~`
+func ParseTraceQL(q string) (SpansetExpr, error) {
+	tokens, err := lexTraceQL(q)
+	if err != nil {
+		return nil, err
+	}
+	p := &traceQLParser{tokens: tokens}
+	expr, err := p.parseSpansetOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.Type != TOKEN_EOF {
+		if t.Text == "|" {
+			return nil, fmt.Errorf("pipeline at %d is not supported", t.Pos)
+		}
+		return nil, fmt.Errorf("unexpected %s at %d", t.Text, t.Pos)
+	}
+	return expr, nil
+}
+
+func (p *traceQLParser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *traceQLParser) next() token {
+	t := p.tokens[p.i]
+	if t.Type != TOKEN_EOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *traceQLParser) isOperator(ops ...string) bool {
+	t := p.peek()
+	if t.Type != TOKEN_OPERATOR {
+		return false
+	}
+	for _, op := range ops {
+		if t.Text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *traceQLParser) expect(op string) error {
+	if !p.isOperator(op) {
+		t := p.peek()
+		if t.Type == TOKEN_EOF {
+			return fmt.Errorf("expected %s at the end", op)
+		}
+		return fmt.Errorf("expected %s but got %s at %d", op, t.Text, t.Pos)
+	}
+	p.next()
+	return nil
+}
+
+func (p *traceQLParser) parseSpansetOr() (SpansetExpr, error) {
+	lhs, err := p.parseSpansetAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("||") {
+		op := p.next().Text
+		rhs, err := p.parseSpansetAnd()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &SpansetOperation{Op: op, LHS: lhs, RHS: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *traceQLParser) parseSpansetAnd() (SpansetExpr, error) {
+	lhs, err := p.parseSpansetStructural()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("&&") {
+		op := p.next().Text
+		rhs, err := p.parseSpansetStructural()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &SpansetOperation{Op: op, LHS: lhs, RHS: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *traceQLParser) parseSpansetStructural() (SpansetExpr, error) {
+	lhs, err := p.parseSpansetPrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator(">>", ">", "~") {
+		op := p.next().Text
+		rhs, err := p.parseSpansetPrimary()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &SpansetOperation{Op: op, LHS: lhs, RHS: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *traceQLParser) parseSpansetPrimary() (SpansetExpr, error) {
+	if p.isOperator("(") {
+		p.next()
+		expr, err := p.parseSpansetOr()
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.expect(")")
+	}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	filter := &SpansetFilter{}
+	if !p.isOperator("}") {
+		expr, err := p.parseFieldOr()
+		if err != nil {
+			return nil, err
+		}
+		filter.Expr = expr
+	}
+	return filter, p.expect("}")
+}
+
+func (p *traceQLParser) parseFieldOr() (FieldExpr, error) {
+	lhs, err := p.parseFieldAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("||") {
+		op := p.next().Text
+		rhs, err := p.parseFieldAnd()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &BinaryFieldExpr{Op: op, LHS: lhs, RHS: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *traceQLParser) parseFieldAnd() (FieldExpr, error) {
+	lhs, err := p.parseFieldUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("&&") {
+		op := p.next().Text
+		rhs, err := p.parseFieldUnary()
+		if err != nil {
+			return nil, err
+		}
+		lhs = &BinaryFieldExpr{Op: op, LHS: lhs, RHS: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *traceQLParser) parseFieldUnary() (FieldExpr, error) {
+	if p.isOperator("!") {
+		p.next()
+		expr, err := p.parseFieldUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &NotFieldExpr{Expr: expr}, nil
+	}
+	if p.isOperator("(") {
+		p.next()
+		expr, err := p.parseFieldOr()
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.expect(")")
+	}
+	return p.parseComparison()
+}
+
+func (p *traceQLParser) parseComparison() (FieldExpr, error) {
+	t := p.next()
+	if t.Type != TOKEN_IDENTIFIER {
+		return nil, fmt.Errorf("expected attribute but got %s at %d", t.Text, t.Pos)
+	}
+	attribute, err := parseAttribute(t.Text)
+	if err != nil {
+		return nil, fmt.Errorf("%s at %d", err, t.Pos)
+	}
+	if !p.isOperator("=", "!=", ">", ">=", "<", "<=", "=~", "!~") {
+		t := p.peek()
+		return nil, fmt.Errorf("expected comparison operator but got %s at %d", t.Text, t.Pos)
+	}
+	op := p.next().Text
+	v := p.next()
+	if v.Type == TOKEN_EOF || v.Type == TOKEN_OPERATOR {
+		return nil, fmt.Errorf("expected value but got %s at %d", v.Text, v.Pos)
+	}
+	return &Comparison{Attribute: attribute, Op: op, Value: Static{Type: v.Type, Text: v.Text}}, nil
+}
+
+func parseAttribute(s string) (Attribute, error) {
+	switch {
+	case strings.HasPrefix(s, "span."):
+		return Attribute{Scope: "span", Name: s[len("span."):]}, nil
+	case strings.HasPrefix(s, "resource."):
+		return Attribute{Scope: "resource", Name: s[len("resource."):]}, nil
+	case strings.HasPrefix(s, "."):
+		return Attribute{Name: s[1:]}, nil
+	}
+	name := strings.TrimPrefix(s, "span:")
+	if _, ok := TRACEQL_INTRINSIC_MAP[name]; ok {
+		return Attribute{Scope: "intrinsic", Name: name}, nil
+	}
+	return Attribute{}, fmt.Errorf("unknown attribute %s", s)
+}
+
+// compiler
+
+// CompileTraceQL parses the TraceQL and compiles its span-set filters to the conditions of l7_flow_log in the SQL
+// of the querier engine, all of the values are escaped
+func CompileTraceQL(q string) (SpansetExpr, error) {
+	expr, err := ParseTraceQL(q)
+	if err != nil {
+		return nil, err
+	}
+	if err := expr.compile(); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+func (f *SpansetFilter) compile() error {
+	if f.Expr == nil {
+		f.condition = ""
+		return nil
+	}
+	condition, err := f.Expr.condition()
+	f.condition = condition
+	return err
+}
+
+func (o *SpansetOperation) compile() error {
+	if err := o.LHS.compile(); err != nil {
+		return err
+	}
+	return o.RHS.compile()
+}
+
+func (e *BinaryFieldExpr) condition() (string, error) {
+	lhs, err := e.LHS.condition()
+	if err != nil {
+		return "", err
+	}
+	rhs, err := e.RHS.condition()
+	if err != nil {
+		return "", err
+	}
+	if e.Op == "&&" {
+		return fmt.Sprintf("(%s AND %s)", lhs, rhs), nil
+	}
+	return fmt.Sprintf("(%s OR %s)", lhs, rhs), nil
+}
+
+func (e *NotFieldExpr) condition() (string, error) {
+	expr, err := e.Expr.condition()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("NOT %s", expr), nil
+}
+
+// column returns the column of the attribute and the condition that the attribute exists
+func (a Attribute) column() (traceQLColumn, string) {
+	if a.Scope == "intrinsic" {
+		return TRACEQL_INTRINSIC_MAP[a.Name], ""
+	}
+	if a.Scope != "resource" {
+		if column, ok := TRACEQL_SPAN_ATTRIBUTE_MAP[a.Name]; ok {
+			return column, ""
+		}
+	}
+	if a.Scope != "span" {
+		if column, ok := TRACEQL_RESOURCE_ATTRIBUTE_MAP[a.Name]; ok {
+			return column, ""
+		}
+	}
+	// the custom attributes are the `attribute.` tags of the querier engine, an absent attribute is empty
+	name := fmt.Sprintf("`attribute.%s`", a.Name)
+	return traceQLColumn{name, COLUMN_STRING}, fmt.Sprintf("%s != ''", name)
+}
+
+func (cmp *Comparison) condition() (string, error) {
+	column, exists := cmp.Attribute.column()
+	condition, err := cmp.compare(column)
+	if err != nil {
+		return "", err
+	}
+	if exists != "" {
+		return fmt.Sprintf("(%s AND %s)", exists, condition), nil
+	}
+	return condition, nil
+}
+
+func (cmp *Comparison) compare(column traceQLColumn) (string, error) {
+	op, v := cmp.Op, cmp.Value
+	if op == "=~" || op == "!~" {
+		if v.Type != TOKEN_STRING {
+			return "", fmt.Errorf("regex of %s should be a string", cmp.Attribute.Name)
+		}
+		// regexes are fully anchored as in tempo
+		re := "^(?:" + v.Text + ")$"
+		if _, err := regexp.Compile(re); err != nil {
+			return "", fmt.Errorf("invalid regex %s: %s", v.Text, err)
+		}
+		if column.Type != COLUMN_STRING {
+			return "", fmt.Errorf("regex is not supported by %s", cmp.Attribute.Name)
+		}
+		if op == "=~" {
+			return fmt.Sprintf("%s REGEXP %s", column.Name, EscapeSQLString(re)), nil
+		}
+		return fmt.Sprintf("%s NOT REGEXP %s", column.Name, EscapeSQLString(re)), nil
+	}
+
+	switch column.Type {
+	case COLUMN_DURATION:
+		if v.Type != TOKEN_DURATION {
+			return "", fmt.Errorf("value of %s should be a duration, e.g.: 100ms", cmp.Attribute.Name)
+		}
+		d, _ := time.ParseDuration(v.Text)
+		return fmt.Sprintf("%s %s %d", column.Name, op, d.Microseconds()), nil
+	case COLUMN_STATUS, COLUMN_KIND:
+		enums := TRACEQL_STATUS_MAP
+		if column.Type == COLUMN_KIND {
+			enums = TRACEQL_KIND_MAP
+		}
+		values, ok := enums[v.Text]
+		if v.Type != TOKEN_IDENTIFIER || !ok {
+			return "", fmt.Errorf("invalid value %s of %s", v.Text, cmp.Attribute.Name)
+		}
+		if op != "=" && op != "!=" {
+			return "", fmt.Errorf("operator %s is not supported by %s", op, cmp.Attribute.Name)
+		}
+		valueStrs := make([]string, 0, len(values))
+		for _, value := range values {
+			valueStrs = append(valueStrs, strconv.Itoa(value))
+		}
+		in := "IN"
+		if op == "!=" {
+			in = "NOT IN"
+		}
+		return fmt.Sprintf("%s %s (%s)", column.Name, in, strings.Join(valueStrs, ", ")), nil
+	}
+
+	switch v.Type {
+	case TOKEN_NUMBER:
+		if column.Type == COLUMN_STRING {
+			// the querier engine can not convert the strings to numbers, the number is compared as a string
+			if op != "=" && op != "!=" {
+				return "", fmt.Errorf("operator %s is not supported by the string attribute %s", op, cmp.Attribute.Name)
+			}
+			return fmt.Sprintf("%s %s %s", column.Name, op, EscapeSQLString(v.Text)), nil
+		}
+		return fmt.Sprintf("%s %s %s", column.Name, op, v.Text), nil
+	case TOKEN_STRING:
+		if column.Type != COLUMN_STRING {
+			return fmt.Sprintf("toString(%s) %s %s", column.Name, op, EscapeSQLString(v.Text)), nil
+		}
+		return fmt.Sprintf("%s %s %s", column.Name, op, EscapeSQLString(v.Text)), nil
+	case TOKEN_IDENTIFIER:
+		if (v.Text == "true" || v.Text == "false") && (op == "=" || op == "!=") && column.Type == COLUMN_STRING {
+			return fmt.Sprintf("%s %s %s", column.Name, op, EscapeSQLString(v.Text)), nil
+		}
+	}
+	return "", fmt.Errorf("invalid value %s of %s", v.Text, cmp.Attribute.Name)
+}
+
+// evaluator
+
+// traceQLSpan is a span matched by a span-set expression
+type traceQLSpan struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	StartTime    int // us
+}
+
+type spanKey struct {
+	traceID string
+	spanID  string
+}
+
+// traceQLEvaluator evaluates the span-set operations on the spans of the span-set filters, as the subqueries
+// are not supported by the querier engine
+type traceQLEvaluator struct {
+	// filterSpans queries the spans matching the condition of a span-set filter
+	filterSpans func(condition string) ([]traceQLSpan, error)
+	// traceSpans queries all of the spans of the traces, the ancestors of `>>` are searched in them
+	traceSpans func(traceIDs []string) ([]traceQLSpan, error)
+}
+
+func (f *SpansetFilter) spans(e *traceQLEvaluator) ([]traceQLSpan, error) {
+	return e.filterSpans(f.condition)
+}
+
+func (o *SpansetOperation) spans(e *traceQLEvaluator) ([]traceQLSpan, error) {
+	lhs, err := o.LHS.spans(e)
+	if err != nil {
+		return nil, err
+	}
+	if len(lhs) == 0 && o.Op != "||" {
+		return nil, nil
+	}
+	rhs, err := o.RHS.spans(e)
+	if err != nil {
+		return nil, err
+	}
+	switch o.Op {
+	case "||":
+		return uniqueSpans(append(lhs, rhs...)), nil
+	case "&&":
+		// spans of both sides in the traces which have both of them
+		lhsTraces, rhsTraces := traceIDSet(lhs), traceIDSet(rhs)
+		return uniqueSpans(selectSpans(append(lhs, rhs...), func(span traceQLSpan) bool {
+			return lhsTraces[span.TraceID] && rhsTraces[span.TraceID]
+		})), nil
+	case ">":
+		parents := map[spanKey]bool{}
+		for _, span := range lhs {
+			if span.SpanID != "" {
+				parents[spanKey{span.TraceID, span.SpanID}] = true
+			}
+		}
+		return selectSpans(rhs, func(span traceQLSpan) bool {
+			return span.ParentSpanID != "" && parents[spanKey{span.TraceID, span.ParentSpanID}]
+		}), nil
+	case "~":
+		// NOTE: a span matching both sides is considered as a sibling of itself
+		parents := map[spanKey]bool{}
+		for _, span := range lhs {
+			if span.ParentSpanID != "" {
+				parents[spanKey{span.TraceID, span.ParentSpanID}] = true
+			}
+		}
+		return selectSpans(rhs, func(span traceQLSpan) bool {
+			return span.ParentSpanID != "" && parents[spanKey{span.TraceID, span.ParentSpanID}]
+		}), nil
+	case ">>":
+		return e.descendants(lhs, rhs)
+	}
+	return nil, fmt.Errorf("unsupported span-set operator %s", o.Op)
+}
+
+// descendants returns the spans of rhs having an ancestor in lhs, the parent chains are built from all of the
+// spans of the traces which have both sides
+func (e *traceQLEvaluator) descendants(lhs, rhs []traceQLSpan) ([]traceQLSpan, error) {
+	lhsTraces := traceIDSet(lhs)
+	traceIDs := []string{}
+	for traceID := range traceIDSet(rhs) {
+		if lhsTraces[traceID] {
+			traceIDs = append(traceIDs, traceID)
+		}
+	}
+	if len(traceIDs) == 0 {
+		return nil, nil
+	}
+	sort.Strings(traceIDs)
+	spans, err := e.traceSpans(traceIDs)
+	if err != nil {
+		return nil, err
+	}
+	parents := map[spanKey]string{}
+	for _, span := range spans {
+		if span.SpanID != "" {
+			parents[spanKey{span.TraceID, span.SpanID}] = span.ParentSpanID
+		}
+	}
+	ancestors := map[spanKey]bool{}
+	for _, span := range lhs {
+		if span.SpanID != "" {
+			ancestors[spanKey{span.TraceID, span.SpanID}] = true
+		}
+	}
+	return selectSpans(rhs, func(span traceQLSpan) bool {
+		visited := map[string]bool{}
+		for parent := span.ParentSpanID; parent != "" && !visited[parent]; parent = parents[spanKey{span.TraceID, parent}] {
+			if ancestors[spanKey{span.TraceID, parent}] {
+				return true
+			}
+			visited[parent] = true
+		}
+		return false
+	}), nil
+}
+
+func traceIDSet(spans []traceQLSpan) map[string]bool {
+	traceIDs := map[string]bool{}
+	for _, span := range spans {
+		traceIDs[span.TraceID] = true
+	}
+	return traceIDs
+}
+
+func selectSpans(spans []traceQLSpan, match func(traceQLSpan) bool) []traceQLSpan {
+	selected := []traceQLSpan{}
+	for _, span := range spans {
+		if match(span) {
+			selected = append(selected, span)
+		}
+	}
+	return selected
+}
+
+func uniqueSpans(spans []traceQLSpan) []traceQLSpan {
+	seen := map[traceQLSpan]bool{}
+	return selectSpans(spans, func(span traceQLSpan) bool {
+		if seen[span] {
+			return false
+		}
+		seen[span] = true
+		return true
+	})
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright (c) 2024 Yunshan Networks
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tempo
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestCompileTraceQL(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected string
+	}{
+		{
+			query:    `{}`,
+			expected: ``,
+		},
+		{
+			query:    `{ resource.service.name = "cart" && duration > 1.5s }`,
+			expected: `(app_service = 'cart' AND response_duration > 1500000)`,
+		},
+		{
+			query:    `{ span.http.status_code >= 500 || status = error }`,
+			expected: `(response_code >= 500 OR response_status IN (1, 3, 4))`,
+		},
+		{
+			query:    `{ name =~ "GET /api/.*" && kind != server }`,
+			expected: `(endpoint REGEXP '^(?:GET /api/.*)$' AND span_kind NOT IN (2))`,
+		},
+		{
+			query:    `{ .db.system = "it's" && !(span.retries = 3) }`,
+			expected: "((`attribute.db.system` != '' AND `attribute.db.system` = 'it\\'s') AND NOT (`attribute.retries` != '' AND `attribute.retries` = '3'))",
+		},
+		{
+			query:    `{ name = "a\\'b" }`,
+			expected: `endpoint = 'a\\\'b'`,
+		},
+		{
+			query:    `{ span.http.status_code = "500" }`,
+			expected: `toString(response_code) = '500'`,
+		},
+	}
+	for _, tc := range testCases {
+		expr, err := CompileTraceQL(tc.query)
+		if err != nil {
+			t.Errorf("compile %s failed: %s", tc.query, err)
+			continue
+		}
+		if condition := expr.(*SpansetFilter).condition; condition != tc.expected {
+			t.Errorf("compile %s\n got %s\nwant %s", tc.query, condition, tc.expected)
+		}
+	}
+}
+
+// the spans of two traces:
+//
+//	t1: a(1) -> b(2) -> c(3)
+//	t2: a(4) -> b(5), a(4) -> d(6), and e(7) without span id
+var testTraceQLSpans = map[string][]traceQLSpan{
+	"a": {{"t1", "1", "", 10}, {"t2", "4", "", 20}},
+	"b": {{"t1", "2", "1", 11}, {"t2", "5", "4", 21}},
+	"c": {{"t1", "3", "2", 12}},
+	"d": {{"t2", "6", "4", 22}},
+	"e": {{"t2", "", "", 23}},
+}
+
+func TestEvaluateTraceQL(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected []traceQLSpan
+	}{
+		{
+			query:    `{ name = "a" } > { name = "b" }`,
+			expected: []traceQLSpan{{"t1", "2", "1", 11}, {"t2", "5", "4", 21}},
+		},
+		{
+			query:    `{ name = "a" } > { name = "c" }`,
+			expected: []traceQLSpan{},
+		},
+		{
+			query:    `{ name = "a" } >> { name = "c" }`,
+			expected: []traceQLSpan{{"t1", "3", "2", 12}},
+		},
+		{
+			query:    `{ name = "b" } ~ { name = "d" }`,
+			expected: []traceQLSpan{{"t2", "6", "4", 22}},
+		},
+		{
+			query:    `{ name = "c" } && { name = "b" }`,
+			expected: []traceQLSpan{{"t1", "3", "2", 12}, {"t1", "2", "1", 11}},
+		},
+		{
+			query:    `{ name = "c" } || { name = "e" } || { name = "c" }`,
+			expected: []traceQLSpan{{"t1", "3", "2", 12}, {"t2", "", "", 23}},
+		},
+		{
+			query:    `{ name = "x" } >> { name = "c" }`,
+			expected: nil,
+		},
+	}
+	for _, tc := range testCases {
+		expr, err := CompileTraceQL(tc.query)
+		if err != nil {
+			t.Fatalf("compile %s failed: %s", tc.query, err)
+		}
+		queries := 0
+		evaluator := &traceQLEvaluator{
+			filterSpans: func(condition string) ([]traceQLSpan, error) {
+				queries++
+				for name, spans := range testTraceQLSpans {
+					if condition == fmt.Sprintf("endpoint = '%s'", name) {
+						return spans, nil
+					}
+				}
+				return nil, nil
+			},
+			traceSpans: func(traceIDs []string) ([]traceQLSpan, error) {
+				queries++
+				spans := []traceQLSpan{}
+				for _, traceID := range traceIDs {
+					for _, nameSpans := range testTraceQLSpans {
+						spans = append(spans, selectSpans(nameSpans, func(span traceQLSpan) bool { return span.TraceID == traceID })...)
+					}
+				}
+				return spans, nil
+			},
+		}
+		spans, err := expr.spans(evaluator)
+		if err != nil {
+			t.Fatalf("evaluate %s failed: %s", tc.query, err)
+		}
+		if !reflect.DeepEqual(spans, tc.expected) {
+			t.Errorf("evaluate %s\n got %v\nwant %v", tc.query, spans, tc.expected)
+		}
+		if tc.expected == nil && queries != 1 {
+			t.Errorf("evaluate %s: the right side should not be queried if the left side matches nothing", tc.query)
+		}
+	}
+}
+
+func TestCompileTraceQLInvalid(t *testing.T) {
+	for _, query := range []string{
+		``,
+		`{`,
+		`{ name = "a" `,
+		`{ name }`,
+		`{ unknown = 1 }`,
+		`{ duration > 100 }`,
+		`{ status = failed }`,
+		`{ status > ok }`,
+		`{ name =~ "(" }`,
+		`{ name =~ 1 }`,
+		`{ span.http.status_code =~ "5.*" }`,
+		`{ span.retries < 3 }`,
+		`{ name = "a" } | count() > 1`,
+		`{ name = "a" } {}`,
+		`{ name = "a }`,
+		`{ name = 'a' }`,
+	} {
+		if _, err := CompileTraceQL(query); err == nil {
+			t.Errorf("compile %s should fail", query)
+		}
+	}
+}